@@ -3,6 +3,8 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -20,6 +22,7 @@ var (
 	host       string
 	port       int
 	logLevel   string
+	adminAddr  string
 )
 
 var rootCmd = &cobra.Command{
@@ -51,17 +54,49 @@ var rootCmd = &cobra.Command{
 		}
 		log.Info().Str("addr", addr).Msg("mock server started")
 
+		var adminServer *http.Server
+		if adminAddr != "" {
+			adminListener, err := listenAdmin(adminAddr)
+			if err != nil {
+				grpcServer.Stop()
+				return err
+			}
+			adminServer = &http.Server{Handler: server.AdminHandler(), ReadHeaderTimeout: 10 * time.Second}
+			go func() { _ = adminServer.Serve(adminListener) }()
+			log.Info().Str("addr", adminListener.Addr().String()).Msg("mock admin API started")
+		}
+
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
 		<-ctx.Done()
 
 		log.Info().Msg("shutting down mock server")
+		if adminServer != nil {
+			_ = adminServer.Close()
+		}
 		grpcServer.Stop()
 		_ = listener.Close()
 		return nil
 	},
 }
 
+// listenAdmin listens on addr, which must be a loopback address: the admin API has no
+// authentication.
+func listenAdmin(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid --admin-listen %q", addr)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, errors.Errorf("refusing to serve the admin API on %q: use a loopback address", addr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listen on %s", addr)
+	}
+	return listener, nil
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
@@ -74,6 +109,7 @@ func init() {
 	rootCmd.Flags().StringVar(&host, "host", "127.0.0.1", "Bind host for mock server")
 	rootCmd.Flags().IntVar(&port, "port", 10431, "Bind port for mock server")
 	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level (trace,debug,info,warn,error,fatal,panic)")
+	rootCmd.Flags().StringVar(&adminAddr, "admin-listen", "", "Serve the HTTP admin API on this loopback address (e.g. 127.0.0.1:10432); disabled if empty")
 
 	_ = rootCmd.MarkFlagRequired("config")
 }
@@ -6,7 +6,7 @@ import (
 
 	pb "github.com/go-go-golems/salad/gen/saleae/automation"
 	saladconfig "github.com/go-go-golems/salad/internal/config"
-	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -65,11 +65,11 @@ var analyzerAddCmd = &cobra.Command{
 			return err
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		id, err := c.AddAnalyzer(ctx, analyzerCaptureID, analyzerName, analyzerLabel, settings)
 		if err != nil {
@@ -92,11 +92,11 @@ var analyzerRemoveCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.RemoveAnalyzer(ctx, analyzerCaptureID, analyzerID); err != nil {
 			return err
@@ -112,6 +112,7 @@ func init() {
 	_ = analyzerAddCmd.MarkFlagRequired("capture-id")
 	analyzerAddCmd.Flags().StringVar(&analyzerName, "name", "", "Analyzer name (exact UI name, e.g. \"SPI\", \"I2C\", \"Async Serial\")")
 	_ = analyzerAddCmd.MarkFlagRequired("name")
+	_ = analyzerAddCmd.RegisterFlagCompletionFunc("name", cobra.FixedCompletions(shell.KnownAnalyzers, cobra.ShellCompDirectiveNoFileComp))
 	analyzerAddCmd.Flags().StringVar(&analyzerLabel, "label", "", "Analyzer label (user-facing name)")
 
 	analyzerAddCmd.Flags().StringVar(&analyzerSettingsJSON, "settings-json", "", "Path to analyzer settings JSON file")
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/go-go-golems/salad/internal/saleae"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -20,15 +19,11 @@ var appinfoCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{
-			Host:    host,
-			Port:    port,
-			Timeout: timeout,
-		})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		info, err := c.GetAppInfo(ctx)
 		if err != nil {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/go-go-golems/salad/internal/saleae"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -30,11 +29,11 @@ var captureLoadCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		id, err := c.LoadCapture(ctx, filepath)
 		if err != nil {
@@ -57,11 +56,11 @@ var captureSaveCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.SaveCapture(ctx, captureID, filepath); err != nil {
 			return err
@@ -83,11 +82,11 @@ var captureStopCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.StopCapture(ctx, captureID); err != nil {
 			return err
@@ -109,11 +108,11 @@ var captureWaitCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.WaitCapture(ctx, captureID); err != nil {
 			return err
@@ -135,11 +134,11 @@ var captureCloseCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.CloseCapture(ctx, captureID); err != nil {
 			return err
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/go-go-golems/salad/internal/sigrok"
+	"github.com/go-go-golems/salad/internal/tableconv"
+	"github.com/go-go-golems/salad/internal/vcd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertDirectory   string
+	convertOutput      string
+	convertChannelsCSV string
+
+	convertNames []string
+
+	convertVcdTimescale string
+	convertVcdTable     string
+
+	convertSigrokSampleRate uint64
+
+	convertTableInput     string
+	convertTableTo        string
+	convertTableName      string
+	convertTableRadixHint string
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert Logic 2 exports to other tools' formats (offline, no Logic 2 needed)",
+}
+
+var convertVcdCmd = &cobra.Command{
+	Use:   "vcd",
+	Short: "Convert a raw digital export (CSV or binary) to an IEEE 1364 VCD file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ts vcd.Timescale
+		if convertVcdTimescale != "" {
+			var err error
+			if ts, err = vcd.ParseTimescale(convertVcdTimescale); err != nil {
+				return err
+			}
+		}
+		names, err := parseRenames(convertNames)
+		if err != nil {
+			return err
+		}
+
+		export, err := rawdata.OpenExport(convertDirectory, parseStringCSV(convertChannelsCSV)...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = export.Close() }()
+
+		opts := vcd.ConvertOptions{Timescale: ts, Digital: export.Digital, Names: names}
+		if convertVcdTable != "" {
+			if opts.Table, err = datatable.Open(convertVcdTable); err != nil {
+				return err
+			}
+		}
+
+		f, err := os.Create(convertOutput)
+		if err != nil {
+			return errors.Wrapf(err, "create %s", convertOutput)
+		}
+		res, err := vcd.Convert(f, opts)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return errors.Wrapf(err, "close %s", convertOutput)
+		}
+
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "output=%s\ntimescale=%s\nsignals=%d\nok\n", convertOutput, res.Timescale, res.Signals)
+		return errors.Wrap(err, "write output")
+	},
+}
+
+var convertSigrokCmd = &cobra.Command{
+	Use:   "sigrok",
+	Short: "Convert a raw export (CSV or binary) to a sigrok .sr session for PulseView / sigrok-cli",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := parseRenames(convertNames)
+		if err != nil {
+			return err
+		}
+
+		export, err := rawdata.OpenExport(convertDirectory, parseStringCSV(convertChannelsCSV)...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = export.Close() }()
+
+		f, err := os.Create(convertOutput)
+		if err != nil {
+			return errors.Wrapf(err, "create %s", convertOutput)
+		}
+		res, err := sigrok.Convert(f, sigrok.ConvertOptions{
+			SampleRate: convertSigrokSampleRate,
+			Digital:    export.Digital,
+			Analog:     export.Analog,
+			Names:      names,
+		})
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return errors.Wrapf(err, "close %s", convertOutput)
+		}
+
+		_, err = fmt.Fprintf(
+			cmd.OutOrStdout(),
+			"output=%s\nsamplerate=%d\nlogic_samples=%d\nanalog_channels=%d\nok\n",
+			convertOutput, res.SampleRate, res.LogicSamples, len(res.AnalogSamples),
+		)
+		return errors.Wrap(err, "write output")
+	},
+}
+
+var convertTableCmd = &cobra.Command{
+	Use:   "table",
+	Short: "Convert a data table CSV export to a typed SQLite, Parquet or JSONL file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := tableconv.ParseFormat(convertTableTo)
+		if err != nil {
+			return err
+		}
+		return convertDataTable(cmd, convertTableInput, tableconv.Options{
+			Format:    format,
+			Output:    convertOutput,
+			TableName: convertTableName,
+			RadixHint: convertTableRadixHint,
+		})
+	},
+}
+
+// convertDataTable converts a data table CSV and prints the result summary.
+func convertDataTable(cmd *cobra.Command, input string, opts tableconv.Options) error {
+	table, err := datatable.Open(input)
+	if err != nil {
+		return err
+	}
+	res, err := tableconv.Convert(table, opts)
+	if err != nil {
+		return err
+	}
+	for _, c := range res.Columns {
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "column=%s kind=%s radix=%s\n", c.Name, c.Kind, c.Radix); err != nil {
+			return errors.Wrap(err, "write output")
+		}
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "output=%s\nrows=%d\nok\n", opts.Output, res.Rows)
+	return errors.Wrap(err, "write output")
+}
+
+// parseRenames parses repeated "<old>=<new>" flags.
+func parseRenames(values []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, v := range values {
+		from, to, ok := strings.Cut(v, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, errors.Errorf("invalid --name %q (expected <column>=<signal>, e.g. \"Channel 0=CLK\")", v)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
+
+func init() {
+	convertVcdCmd.Flags().StringVar(&convertDirectory, "directory", "", "Raw export directory (digital_<n>.bin or digital.csv)")
+	_ = convertVcdCmd.MarkFlagRequired("directory")
+	convertVcdCmd.Flags().StringVar(&convertOutput, "output", "", "Path to write the VCD file to")
+	_ = convertVcdCmd.MarkFlagRequired("output")
+	convertVcdCmd.Flags().StringVar(&convertChannelsCSV, "channels", "", "Channel columns to include (comma-separated headers, e.g. \"Channel 0,CLK\"). If empty, all digital channels are included.")
+	convertVcdCmd.Flags().StringVar(&convertVcdTimescale, "timescale", "", "VCD timescale (1|10|100 followed by s|ms|us|ns|ps|fs). Defaults to the sample period of binary exports, or the coarsest one that places every transition of a CSV export exactly.")
+	convertVcdCmd.Flags().StringArrayVar(&convertNames, "name", nil, "Rename a signal (<column or analyzer>=<name>). Can be repeated.")
+	convertVcdCmd.Flags().StringVar(&convertVcdTable, "table", "", "Data table CSV (from `salad export table`) to add as string-valued analyzer signals")
+
+	convertSigrokCmd.Flags().StringVar(&convertDirectory, "directory", "", "Raw export directory (*.bin or digital.csv/analog.csv)")
+	_ = convertSigrokCmd.MarkFlagRequired("directory")
+	convertSigrokCmd.Flags().StringVar(&convertOutput, "output", "", "Path to write the .sr session to")
+	_ = convertSigrokCmd.MarkFlagRequired("output")
+	convertSigrokCmd.Flags().StringVar(&convertChannelsCSV, "channels", "", "Channel columns to include (comma-separated headers). If empty, all channels are included.")
+	convertSigrokCmd.Flags().StringArrayVar(&convertNames, "name", nil, "Rename a probe (<column>=<name>). Can be repeated.")
+	convertSigrokCmd.Flags().Uint64Var(&convertSigrokSampleRate, "samplerate", 0, "Session sample rate in Hz. Defaults to the binary export's rate; required for CSV exports.")
+
+	convertTableCmd.Flags().StringVar(&convertTableInput, "input", "", "Data table CSV (from `salad export table`)")
+	_ = convertTableCmd.MarkFlagRequired("input")
+	convertTableCmd.Flags().StringVar(&convertOutput, "output", "", "Path to write the converted file to (replaced if it exists)")
+	_ = convertTableCmd.MarkFlagRequired("output")
+	convertTableCmd.Flags().StringVar(&convertTableTo, "to", "", "Output format (sqlite|parquet|jsonl)")
+	_ = convertTableCmd.MarkFlagRequired("to")
+	convertTableCmd.Flags().StringVar(&convertTableName, "table-name", tableconv.DefaultTableName, "SQLite table / Parquet schema name")
+	convertTableCmd.Flags().StringVar(&convertTableRadixHint, "radix", "", "Radix the table was exported with (hex|dec|bin|ascii); only needed to tag ascii text columns")
+
+	convertCmd.AddCommand(convertVcdCmd, convertSigrokCmd, convertTableCmd)
+}
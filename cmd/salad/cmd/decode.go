@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	saladconfig "github.com/go-go-golems/salad/internal/config"
+	"github.com/go-go-golems/salad/internal/decode"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decodeDirectory string
+	decodeOutput    string
+	decodeName      string
+	decodeLabel     string
+	decodeRadix     string
+
+	decodeSettingsJSON string
+	decodeSettingsYAML string
+
+	decodeSet      []string
+	decodeSetBool  []string
+	decodeSetInt   []string
+	decodeSetFloat []string
+)
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode",
+	Short: "Decode SPI, I2C or Async Serial from a raw export into a data table CSV (offline, no Logic 2 needed)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if decodeSettingsJSON != "" && decodeSettingsYAML != "" {
+			return errors.New("only one of --settings-json or --settings-yaml may be specified")
+		}
+		if _, err := pipeline.ParseRadixType(decodeRadix); err != nil {
+			return err
+		}
+		radix := strings.ToLower(strings.TrimSpace(decodeRadix))
+
+		var settings map[string]*pb.AnalyzerSettingValue
+		var err error
+		switch {
+		case decodeSettingsJSON != "":
+			settings, err = saladconfig.LoadAnalyzerSettingsJSON(decodeSettingsJSON)
+		case decodeSettingsYAML != "":
+			settings, err = saladconfig.LoadAnalyzerSettingsYAML(decodeSettingsYAML)
+		default:
+			settings = map[string]*pb.AnalyzerSettingValue{}
+		}
+		if err != nil {
+			return err
+		}
+		settings, err = saladconfig.ApplyAnalyzerSettingOverrides(settings, decodeSet, decodeSetBool, decodeSetInt, decodeSetFloat)
+		if err != nil {
+			return err
+		}
+
+		dec, err := decode.New(decodeName, settings)
+		if err != nil {
+			return err
+		}
+		label := decodeLabel
+		if label == "" {
+			label = decodeName
+		}
+
+		export, err := rawdata.OpenExport(decodeDirectory)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = export.Close() }()
+
+		frames := decode.DecodeExport(dec, export)
+		if decodeOutput == "" {
+			_, err := decode.WriteTable(cmd.OutOrStdout(), label, decode.FieldColumns(dec), frames, radix)
+			return err
+		}
+
+		f, err := os.Create(decodeOutput)
+		if err != nil {
+			return errors.Wrapf(err, "create %s", decodeOutput)
+		}
+		n, err := decode.WriteTable(f, label, decode.FieldColumns(dec), frames, radix)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return errors.Wrapf(err, "close %s", decodeOutput)
+		}
+
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "output=%s\nframes=%d\nok\n", decodeOutput, n)
+		return errors.Wrap(err, "write output")
+	},
+}
+
+func init() {
+	decodeCmd.Flags().StringVar(&decodeDirectory, "directory", "", "Raw export directory (digital_<n>.bin or digital.csv)")
+	_ = decodeCmd.MarkFlagRequired("directory")
+	decodeCmd.Flags().StringVar(&decodeOutput, "output", "", "Path to write the data table CSV to. If empty, the table is written to stdout.")
+	decodeCmd.Flags().StringVar(&decodeName, "name", "", "Analyzer name (\"SPI\", \"I2C\", \"Async Serial\"/\"UART\")")
+	_ = decodeCmd.MarkFlagRequired("name")
+	_ = decodeCmd.RegisterFlagCompletionFunc("name", cobra.FixedCompletions(decode.Names(), cobra.ShellCompDirectiveNoFileComp))
+	decodeCmd.Flags().StringVar(&decodeLabel, "label", "", "Value of the table's name column (defaults to --name)")
+	decodeCmd.Flags().StringVar(&decodeRadix, "radix", "hex", "Radix for data values (hex|dec|bin|ascii)")
+
+	decodeCmd.Flags().StringVar(&decodeSettingsJSON, "settings-json", "", "Path to analyzer settings JSON file")
+	decodeCmd.Flags().StringVar(&decodeSettingsYAML, "settings-yaml", "", "Path to analyzer settings YAML file (e.g. configs/analyzers/spi.yaml)")
+
+	decodeCmd.Flags().StringArrayVar(&decodeSet, "set", nil, "Set string setting (key=value). Can be repeated.")
+	decodeCmd.Flags().StringArrayVar(&decodeSetBool, "set-bool", nil, "Set bool setting (key=true/false). Can be repeated.")
+	decodeCmd.Flags().StringArrayVar(&decodeSetInt, "set-int", nil, "Set int setting (key=123). Can be repeated.")
+	decodeCmd.Flags().StringArrayVar(&decodeSetFloat, "set-float", nil, "Set float setting (key=12.34). Can be repeated.")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/go-go-golems/salad/internal/saleae"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -22,15 +21,11 @@ var devicesCmd = &cobra.Command{
 			defer cancel()
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{
-			Host:    host,
-			Port:    port,
-			Timeout: timeout,
-		})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		devices, err := c.GetDevices(ctx, includeSimulationDevices)
 		if err != nil {
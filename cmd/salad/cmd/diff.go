@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/output"
+	"github.com/go-go-golems/salad/internal/tablediff"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffTableAlign     string
+	diffTableTolerance string
+	diffTableRadixA    string
+	diffTableRadixB    string
+	diffTableIgnoreCSV string
+	diffTableContext   int
+	diffTableJSON      bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare exports (offline, no Logic 2 needed)",
+}
+
+var diffTableCmd = &cobra.Command{
+	Use:   "table <a.csv> <b.csv>",
+	Short: "Compare two data table exports frame by frame, per analyzer (exit status 1 if they differ)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		align, err := tablediff.ParseAlign(diffTableAlign)
+		if err != nil {
+			return err
+		}
+		tolerance, err := parseSeconds(diffTableTolerance, 0)
+		if err != nil {
+			return errors.Wrap(err, "--tolerance")
+		}
+		a, err := datatable.Open(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := datatable.Open(args[1])
+		if err != nil {
+			return err
+		}
+		res, err := tablediff.Diff(a, b, tablediff.Options{
+			Align:     align,
+			Tolerance: tolerance,
+			RadixA:    diffTableRadixA,
+			RadixB:    diffTableRadixB,
+			Ignore:    parseStringCSV(diffTableIgnoreCSV),
+		})
+		if err != nil {
+			return err
+		}
+		return writeTableDiff(cmd, res, args[0], args[1], diffTableContext, diffTableJSON)
+	},
+}
+
+// writeTableDiff prints a table diff (unified text or JSON records) followed by one
+// summary record per analyzer, and returns an error if the tables differ.
+func writeTableDiff(cmd *cobra.Command, res tablediff.Result, nameA, nameB string, context int, json bool) error {
+	out := output.New(cmd.OutOrStdout(), outputFormat(json))
+	if json {
+		for _, d := range res.Analyzers {
+			for _, op := range d.Ops {
+				if op.Kind == tablediff.Equal {
+					continue
+				}
+				r := output.Record{}.
+					Add("diff", "frame").
+					Add("analyzer", d.Analyzer).
+					Add("op", op.Kind.String())
+				if op.Fields != nil {
+					r = r.Add("fields", op.Fields)
+				}
+				if op.A != nil {
+					r = r.Add("a", frameJSON(op.A))
+				}
+				if op.B != nil {
+					r = r.Add("b", frameJSON(op.B))
+				}
+				if err := out.Write(r); err != nil {
+					return err
+				}
+			}
+		}
+	} else if res.Differs() {
+		if err := tablediff.WriteUnified(cmd.OutOrStdout(), res, nameA, nameB, context); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range res.Analyzers {
+		r := output.Record{}.
+			Add("diff", "analyzer").
+			Add("analyzer", d.Analyzer).
+			Add("equal", d.Equal).
+			Add("removed", d.Removed).
+			Add("inserted", d.Inserted).
+			Add("changed", d.Changed)
+		if err := out.Write(r); err != nil {
+			return err
+		}
+	}
+	if err := out.Finish(!res.Differs()); err != nil {
+		return err
+	}
+	if res.Differs() {
+		// A difference is a result, not a usage error: skip cobra's usage dump.
+		cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		return errors.Errorf("%s and %s differ", nameA, nameB)
+	}
+	return nil
+}
+
+func frameJSON(f *tablediff.Frame) map[string]any {
+	return map[string]any{
+		"line":     f.Line,
+		"type":     f.Type,
+		"start":    f.Start,
+		"duration": f.Duration,
+		"fields":   f.Fields,
+	}
+}
+
+func init() {
+	diffTableCmd.Flags().StringVar(&diffTableAlign, "align", "order", "Frame alignment: order (edit script over frame contents, timestamps ignored) or time (start times within --tolerance)")
+	diffTableCmd.Flags().StringVar(&diffTableTolerance, "tolerance", "1us", "Start time tolerance for --align time (seconds, or a duration like 10us)")
+	diffTableCmd.Flags().StringVar(&diffTableRadixA, "radix-a", "", "Radix the first table was exported with; only needed for ascii")
+	diffTableCmd.Flags().StringVar(&diffTableRadixB, "radix-b", "", "Radix the second table was exported with; only needed for ascii")
+	diffTableCmd.Flags().StringVar(&diffTableIgnoreCSV, "ignore", "", "Field columns to leave out of the comparison (comma-separated)")
+	diffTableCmd.Flags().IntVar(&diffTableContext, "context", 3, "Unchanged frames to show around each change")
+	addJSONFlag(diffTableCmd, &diffTableJSON)
+
+	diffCmd.AddCommand(diffTableCmd)
+}
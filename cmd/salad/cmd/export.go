@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	pb "github.com/go-go-golems/salad/gen/saleae/automation"
-	"github.com/go-go-golems/salad/internal/saleae"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -58,11 +57,11 @@ var exportRawCsvCmd = &cobra.Command{
 			return err
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.ExportRawDataCsv(ctx, exportCaptureID, exportDirectory, ch, exportAnalogDownsample, exportIso8601Timestamps); err != nil {
 			return err
@@ -89,11 +88,11 @@ var exportRawBinaryCmd = &cobra.Command{
 			return err
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.ExportRawDataBinary(ctx, exportCaptureID, exportDirectory, ch, exportAnalogDownsample); err != nil {
 			return err
@@ -7,7 +7,8 @@ import (
 	"strings"
 
 	pb "github.com/go-go-golems/salad/gen/saleae/automation"
-	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/tableconv"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -21,22 +22,10 @@ var (
 	exportTableColumnsCSV       string
 	exportTableFilterQuery      string
 	exportTableFilterColumnsCSV string
-)
 
-func parseRadixType(s string) (pb.RadixType, error) {
-	switch strings.ToLower(strings.TrimSpace(s)) {
-	case "hex":
-		return pb.RadixType_RADIX_TYPE_HEXADECIMAL, nil
-	case "dec":
-		return pb.RadixType_RADIX_TYPE_DECIMAL, nil
-	case "bin":
-		return pb.RadixType_RADIX_TYPE_BINARY, nil
-	case "ascii":
-		return pb.RadixType_RADIX_TYPE_ASCII, nil
-	default:
-		return pb.RadixType_RADIX_TYPE_UNSPECIFIED, errors.Errorf("unknown radix %q (expected: hex|dec|bin|ascii)", s)
-	}
-}
+	exportTableTo     string
+	exportTableOutput string
+)
 
 func parseDataTableAnalyzerSelectors(selectors []string) ([]*pb.DataTableAnalyzerConfiguration, error) {
 	out := make([]*pb.DataTableAnalyzerConfiguration, 0, len(selectors))
@@ -61,7 +50,7 @@ func parseDataTableAnalyzerSelectors(selectors []string) ([]*pb.DataTableAnalyze
 		if id == 0 {
 			return nil, errors.Errorf("analyzer id must be non-zero (got %q)", idStr)
 		}
-		radixType, err := parseRadixType(radixStr)
+		radixType, err := pipeline.ParseRadixType(radixStr)
 		if err != nil {
 			return nil, err
 		}
@@ -101,6 +90,18 @@ var exportTableCmd = &cobra.Command{
 			return errors.New("--filter-columns requires --filter-query")
 		}
 
+		var convert *tableconv.Options
+		if exportTableTo != "" && exportTableTo != "csv" {
+			format, err := tableconv.ParseFormat(exportTableTo)
+			if err != nil {
+				return err
+			}
+			if exportTableOutput == "" {
+				return errors.Errorf("--to %s requires --output", format)
+			}
+			convert = &tableconv.Options{Format: format, Output: exportTableOutput, RadixHint: selectorRadix(exportTableAnalyzers)}
+		}
+
 		var filter *pb.DataTableFilter
 		if exportTableFilterQuery != "" {
 			filter = &pb.DataTableFilter{
@@ -109,11 +110,11 @@ var exportTableCmd = &cobra.Command{
 			}
 		}
 
-		c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+		c, release, err := connect(ctx)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = c.Close() }()
+		defer release()
 
 		if err := c.ExportDataTableCsv(
 			ctx,
@@ -127,11 +128,27 @@ var exportTableCmd = &cobra.Command{
 			return err
 		}
 
+		if convert != nil {
+			// Logic 2 wrote the CSV; this only works when it runs on this machine.
+			return convertDataTable(cmd, exportTableFilepath, *convert)
+		}
+
 		_, err = fmt.Fprintln(cmd.OutOrStdout(), "ok")
 		return errors.Wrap(err, "write output")
 	},
 }
 
+// selectorRadix returns the radix shared by all <id>:<radix> selectors, or "" if they differ.
+func selectorRadix(selectors []string) string {
+	var radixes []string
+	for _, sel := range selectors {
+		if _, r, ok := strings.Cut(sel, ":"); ok {
+			radixes = append(radixes, r)
+		}
+	}
+	return pipeline.UniformRadix(radixes)
+}
+
 func init() {
 	exportTableCmd.Flags().Uint64Var(&exportTableCaptureID, "capture-id", 0, "Capture ID")
 	_ = exportTableCmd.MarkFlagRequired("capture-id")
@@ -143,5 +160,7 @@ func init() {
 	exportTableCmd.Flags().BoolVar(&exportTableIso8601Timestamp, "iso8601-timestamp", false, "Use ISO8601 timestamps in CSV export")
 	exportTableCmd.Flags().StringVar(&exportTableColumnsCSV, "columns", "", "Columns to export (comma-separated). If empty, all columns are exported.")
 	exportTableCmd.Flags().StringVar(&exportTableFilterQuery, "filter-query", "", "Query to filter data table rows")
+	exportTableCmd.Flags().StringVar(&exportTableTo, "to", "csv", "Output format: csv (Logic 2's CSV only) or sqlite|parquet|jsonl (converted from the CSV at --filepath)")
+	exportTableCmd.Flags().StringVar(&exportTableOutput, "output", "", "Path for the converted file when --to is not csv")
 	exportTableCmd.Flags().StringVar(&exportTableFilterColumnsCSV, "filter-columns", "", "Columns to apply the filter query to (comma-separated). If empty, all columns are searched.")
 }
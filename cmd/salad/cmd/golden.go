@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-go-golems/salad/internal/golden"
+	"github.com/go-go-golems/salad/internal/output"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/tablediff"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	goldenConfigPath string
+	goldenDir        string
+	goldenCapture    string
+	goldenCaptureID  uint64
+
+	goldenTolerance string
+	goldenIgnoreCSV string
+
+	goldenContext int
+	goldenJSON    bool
+)
+
+var goldenCmd = &cobra.Command{
+	Use:   "golden",
+	Short: "Snapshot tests for decoded bus traffic (record/check pipeline data tables)",
+}
+
+var goldenRecordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Run a pipeline and save its normalized data tables as goldens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables, err := runGoldenPipeline(cmd)
+		if err != nil {
+			return err
+		}
+		m, err := golden.Record(goldenDir, tables, golden.Options{
+			Config:    goldenConfigPath,
+			Tolerance: goldenTolerance,
+			Ignore:    parseStringCSV(goldenIgnoreCSV),
+		})
+		if err != nil {
+			return err
+		}
+		for _, t := range m.Tables {
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "golden=%s export=%d rows=%d\n", t.File, t.Export, t.Rows); err != nil {
+				return errors.Wrap(err, "write output")
+			}
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "ok")
+		return errors.Wrap(err, "write output")
+	},
+}
+
+var goldenCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Run a pipeline and compare its data tables with the goldens (exit status 1 on differences)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables, err := runGoldenPipeline(cmd)
+		if err != nil {
+			return err
+		}
+		results, err := golden.Check(goldenDir, tables)
+		if err != nil {
+			return err
+		}
+
+		out := output.New(cmd.OutOrStdout(), outputFormat(goldenJSON))
+		failed := 0
+		for _, r := range results {
+			if r.Diff.Differs() {
+				failed++
+				if !goldenJSON {
+					if err := tablediff.WriteUnified(cmd.OutOrStdout(), r.Diff, r.Golden, r.Actual, goldenContext); err != nil {
+						return err
+					}
+				}
+			}
+			for _, d := range r.Diff.Analyzers {
+				rec := output.Record{}.
+					Add("golden", r.Golden).
+					Add("analyzer", d.Analyzer).
+					Add("equal", d.Equal).
+					Add("removed", d.Removed).
+					Add("inserted", d.Inserted).
+					Add("changed", d.Changed)
+				if err := out.Write(rec); err != nil {
+					return err
+				}
+			}
+		}
+		if err := out.Finish(failed == 0); err != nil {
+			return err
+		}
+		if failed > 0 {
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+			return errors.Errorf("golden check failed: %d of %d table(s) differ", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// runGoldenPipeline runs --config, optionally on another .sal file (--capture) or an
+// open capture (--capture-id), and returns its data tables.
+func runGoldenPipeline(cmd *cobra.Command) ([]golden.Table, error) {
+	if goldenCapture != "" && goldenCaptureID != 0 {
+		return nil, errors.New("only one of --capture or --capture-id may be specified")
+	}
+	ctx := cmd.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cfg, err := pipeline.Load(goldenConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if goldenCapture != "" {
+		cfg.Capture.Load = &pipeline.CaptureLoadConfig{Filepath: goldenCapture}
+	}
+
+	r := &pipeline.Runner{
+		SaleaeConfig: saleae.Config{Host: host, Port: port, Timeout: timeout},
+		CaptureID:    goldenCaptureID,
+		Client:       shellClient,
+	}
+	res, err := r.Run(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]golden.Table, 0, len(res.Tables))
+	for _, t := range res.Tables {
+		tables = append(tables, golden.Table{Export: t.Export, Path: t.Filepath, Radix: t.Radix})
+	}
+	return tables, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{goldenRecordCmd, goldenCheckCmd} {
+		c.Flags().StringVar(&goldenConfigPath, "config", "", "Pipeline config file (.yaml/.yml/.json) with at least one table-csv export")
+		_ = c.MarkFlagRequired("config")
+		c.Flags().StringVar(&goldenDir, "golden-dir", "", "Directory holding golden.yaml and the golden tables")
+		_ = c.MarkFlagRequired("golden-dir")
+		c.Flags().StringVar(&goldenCapture, "capture", "", "Capture file to load instead of the config's capture.load.filepath")
+		c.Flags().Uint64Var(&goldenCaptureID, "capture-id", 0, "Run against this open capture (e.g. a live capture) instead of loading a file")
+	}
+	goldenRecordCmd.Flags().StringVar(&goldenTolerance, "tolerance", "", "Compare frames by start time within this duration (e.g. 5us). Default: compare by order, ignoring timing.")
+	goldenRecordCmd.Flags().StringVar(&goldenIgnoreCSV, "ignore", "", "Field columns to leave out of comparisons (comma-separated)")
+
+	goldenCheckCmd.Flags().IntVar(&goldenContext, "context", 3, "Unchanged frames to show around each change")
+	addJSONFlag(goldenCheckCmd, &goldenJSON)
+
+	goldenCmd.AddCommand(goldenRecordCmd, goldenCheckCmd)
+}
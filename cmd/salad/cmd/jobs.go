@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/jobqueue"
+	"github.com/go-go-golems/salad/internal/output"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/server"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Submit and manage pipeline jobs on a `salad serve` instance",
+}
+
+var (
+	jobsServer    string
+	jobsJSON      bool
+	jobsID        uint64
+	jobsConfig    string
+	jobsPriority  int
+	jobsDevices   []string
+	jobsCaptureID uint64
+	jobsWait      bool
+	jobsFollow    bool
+)
+
+// jobsPollInterval is how often --wait and --follow poll the job log.
+const jobsPollInterval = 500 * time.Millisecond
+
+var jobsSubmitCmd = &cobra.Command{
+	Use:   "submit",
+	Short: "Queue a pipeline config (extends/include are resolved locally before sending)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := pipeline.Load(jobsConfig)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(cfg)
+		if err != nil {
+			return errors.Wrap(err, "encode pipeline config")
+		}
+
+		c := server.NewClient(jobsServer)
+		j, err := c.SubmitJob(cmd.Context(), server.SubmitJobRequest{
+			Config:    b,
+			CaptureID: jobsCaptureID,
+			Priority:  jobsPriority,
+			Devices:   jobsDevices,
+		})
+		if err != nil {
+			return err
+		}
+		if jobsWait {
+			if j, err = followJob(cmd.Context(), c, j.ID, cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+		}
+		if err := writeJobs(cmd, j); err != nil {
+			return err
+		}
+		return jobFailure(j, jobsWait)
+	},
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the jobs of the server (including its persisted history)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobs, err := server.NewClient(jobsServer).Jobs(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return writeJobs(cmd, jobs...)
+	},
+}
+
+var jobsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show a job",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		j, err := server.NewClient(jobsServer).Job(cmd.Context(), jobsID)
+		if err != nil {
+			return err
+		}
+		return writeJobs(cmd, j)
+	},
+}
+
+var jobsCancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel a queued or running job",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		j, err := server.NewClient(jobsServer).CancelJob(cmd.Context(), jobsID)
+		if err != nil {
+			return err
+		}
+		return writeJobs(cmd, j)
+	},
+}
+
+var jobsLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the log of a job",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := server.NewClient(jobsServer)
+		if jobsFollow {
+			j, err := followJob(cmd.Context(), c, jobsID, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return jobFailure(j, true)
+		}
+		l, err := c.JobLog(cmd.Context(), jobsID, 0)
+		if err != nil {
+			return err
+		}
+		for _, line := range l.Lines {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), line)
+		}
+		return nil
+	},
+}
+
+// followJob prints the log of a job to w as it grows and returns the job once it is done.
+func followJob(ctx context.Context, c *server.Client, id uint64, w io.Writer) (jobqueue.Job, error) {
+	since := 0
+	for {
+		l, err := c.JobLog(ctx, id, since)
+		if err != nil {
+			return jobqueue.Job{}, err
+		}
+		for _, line := range l.Lines {
+			_, _ = fmt.Fprintln(w, line)
+		}
+		since = l.Next
+		if l.Status.Done() {
+			return c.Job(ctx, id)
+		}
+		select {
+		case <-ctx.Done():
+			return jobqueue.Job{}, errors.Wrapf(ctx.Err(), "follow job %d", id)
+		case <-time.After(jobsPollInterval):
+		}
+	}
+}
+
+// jobFailure turns a finished unsuccessful job into an error when waited for.
+func jobFailure(j jobqueue.Job, waited bool) error {
+	if !waited || j.Status == jobqueue.Succeeded {
+		return nil
+	}
+	if j.Error != "" {
+		return errors.Errorf("job %d %s: %s", j.ID, j.Status, j.Error)
+	}
+	return errors.Errorf("job %d %s", j.ID, j.Status)
+}
+
+func writeJobs(cmd *cobra.Command, jobs ...jobqueue.Job) error {
+	out := output.New(cmd.OutOrStdout(), outputFormat(jobsJSON))
+	for _, j := range jobs {
+		rec := output.Record{}.
+			Add("job_id", j.ID).
+			Add("status", string(j.Status)).
+			Add("priority", j.Priority)
+		if len(j.Devices) > 0 {
+			rec = rec.Add("devices", strings.Join(j.Devices, ","))
+		}
+		if j.ConfigPath != "" {
+			rec = rec.Add("config_path", j.ConfigPath)
+		}
+		if j.CaptureID != 0 {
+			rec = rec.Add("capture_id", j.CaptureID)
+		}
+		if j.Status == jobqueue.Succeeded {
+			rec = rec.Add("artifacts", len(j.Artifacts))
+		}
+		if j.Error != "" {
+			rec = rec.Add("error", j.Error)
+		}
+		if err := out.Write(rec); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+func init() {
+	jobsCmd.PersistentFlags().StringVar(&jobsServer, "server", "http://127.0.0.1:8080", "Base URL of the salad serve instance")
+
+	jobsSubmitCmd.Flags().StringVar(&jobsConfig, "config", "", "Pipeline config file (.yaml/.yml/.json)")
+	_ = jobsSubmitCmd.MarkFlagRequired("config")
+	jobsSubmitCmd.Flags().IntVar(&jobsPriority, "priority", 0, "Job priority (higher runs first)")
+	jobsSubmitCmd.Flags().StringArrayVar(&jobsDevices, "device", nil, "Device the job uses (locked while it runs with --parallel-jobs). Can be repeated.")
+	jobsSubmitCmd.Flags().Uint64Var(&jobsCaptureID, "capture-id", 0, "Run against this open capture instead of capture.load")
+	jobsSubmitCmd.Flags().BoolVar(&jobsWait, "wait", false, "Wait for the job, printing its log to stderr; exit non-zero unless it succeeds")
+	addJSONFlag(jobsSubmitCmd, &jobsJSON)
+
+	addJSONFlag(jobsListCmd, &jobsJSON)
+
+	jobsStatusCmd.Flags().Uint64Var(&jobsID, "job-id", 0, "Job ID")
+	_ = jobsStatusCmd.MarkFlagRequired("job-id")
+	addJSONFlag(jobsStatusCmd, &jobsJSON)
+
+	jobsCancelCmd.Flags().Uint64Var(&jobsID, "job-id", 0, "Job ID")
+	_ = jobsCancelCmd.MarkFlagRequired("job-id")
+	addJSONFlag(jobsCancelCmd, &jobsJSON)
+
+	jobsLogsCmd.Flags().Uint64Var(&jobsID, "job-id", 0, "Job ID")
+	_ = jobsLogsCmd.MarkFlagRequired("job-id")
+	jobsLogsCmd.Flags().BoolVar(&jobsFollow, "follow", false, "Keep printing new lines until the job finishes; exit non-zero unless it succeeds")
+
+	jobsCmd.AddCommand(jobsSubmitCmd, jobsListCmd, jobsStatusCmd, jobsCancelCmd, jobsLogsCmd)
+}
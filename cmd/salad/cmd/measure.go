@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/measure"
+	"github.com/go-go-golems/salad/internal/output"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	measureDirectory   string
+	measureChannelsCSV string
+	measureNames       []string
+	measureFrom        string
+	measureTo          string
+	measureSetupHold   []string
+	measureJSON        bool
+)
+
+var measureCmd = &cobra.Command{
+	Use:   "measure",
+	Short: "Measure frequency, duty cycle, pulse widths and setup/hold times in a raw digital export (offline)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := parseRenames(measureNames)
+		if err != nil {
+			return err
+		}
+		w := measure.All
+		if w.From, err = parseSeconds(measureFrom, w.From); err != nil {
+			return errors.Wrap(err, "--from")
+		}
+		if w.To, err = parseSeconds(measureTo, w.To); err != nil {
+			return errors.Wrap(err, "--to")
+		}
+		if w.To < w.From {
+			return errors.Errorf("--to (%g) is before --from (%g)", w.To, w.From)
+		}
+		pairs, err := parseSetupHold(measureSetupHold)
+		if err != nil {
+			return err
+		}
+
+		out := output.New(cmd.OutOrStdout(), outputFormat(measureJSON))
+		displayName := func(src rawdata.DigitalSource) string {
+			if n, ok := names[src.Name()]; ok {
+				return n
+			}
+			return src.Name()
+		}
+
+		export, err := rawdata.OpenExport(measureDirectory, parseStringCSV(measureChannelsCSV)...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = export.Close() }()
+		for _, src := range export.Digital {
+			st, err := measure.Channel(src, w)
+			if err != nil {
+				return err
+			}
+			if err := out.Write(channelRecord(st, displayName(src))); err != nil {
+				return err
+			}
+		}
+
+		// Binary sources can be read only once, so each pair gets a fresh export.
+		for _, p := range pairs {
+			st, err := measurePair(p, names, w)
+			if err != nil {
+				return err
+			}
+			if err := out.Write(setupHoldRecord(st, p)); err != nil {
+				return err
+			}
+		}
+		return out.Close()
+	},
+}
+
+type setupHoldPair struct {
+	data, clock string
+	edge        measure.Edge
+}
+
+// parseSetupHold parses repeated "<data>,<clock>[,rising|falling]" flags.
+func parseSetupHold(values []string) ([]setupHoldPair, error) {
+	out := make([]setupHoldPair, 0, len(values))
+	for _, v := range values {
+		parts := parseStringCSV(v)
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, errors.Errorf("invalid --setup-hold %q (expected <data>,<clock>[,rising|falling])", v)
+		}
+		p := setupHoldPair{data: parts[0], clock: parts[1]}
+		if len(parts) == 3 {
+			edge, err := measure.ParseEdge(strings.ToLower(parts[2]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "--setup-hold %q", v)
+			}
+			p.edge = edge
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func measurePair(p setupHoldPair, names map[string]string, w measure.Window) (measure.SetupHoldStats, error) {
+	export, err := rawdata.OpenExport(measureDirectory)
+	if err != nil {
+		return measure.SetupHoldStats{}, err
+	}
+	defer func() { _ = export.Close() }()
+	data, err := findDigital(export.Digital, p.data, names)
+	if err != nil {
+		return measure.SetupHoldStats{}, err
+	}
+	clock, err := findDigital(export.Digital, p.clock, names)
+	if err != nil {
+		return measure.SetupHoldStats{}, err
+	}
+	st, err := measure.SetupHold(data, clock, p.edge, w)
+	return st, errors.Wrapf(err, "--setup-hold %s,%s", p.data, p.clock)
+}
+
+// findDigital resolves a channel reference: a renamed signal, a column header or
+// "Channel <n>" (case-insensitive), or a bare channel number.
+func findDigital(sources []rawdata.DigitalSource, ref string, names map[string]string) (rawdata.DigitalSource, error) {
+	for from, to := range names {
+		if strings.EqualFold(to, ref) {
+			ref = from
+			break
+		}
+	}
+	n, numErr := strconv.Atoi(ref)
+	for _, src := range sources {
+		if strings.EqualFold(src.Name(), ref) || (numErr == nil && src.Channel() == n) {
+			return src, nil
+		}
+	}
+	return nil, errors.Errorf("channel %q not found in export", ref)
+}
+
+// parseSeconds parses a time as seconds ("0.002") or a Go duration ("2ms", "-500us").
+// Empty returns def.
+func parseSeconds(s string, def float64) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time %q (expected seconds or a duration like 2ms)", s)
+	}
+	return d.Seconds(), nil
+}
+
+func statsFields(r output.Record, prefix string, s measure.Stats) output.Record {
+	r = r.Add(prefix+"_count", s.Count)
+	r = r.Add(prefix+"_min_s", s.Min)
+	r = r.Add(prefix+"_max_s", s.Max)
+	r = r.Add(prefix+"_mean_s", s.Mean)
+	r = r.Add(prefix+"_p50_s", s.P50)
+	r = r.Add(prefix+"_p90_s", s.P90)
+	return r.Add(prefix+"_p99_s", s.P99)
+}
+
+func channelRecord(st measure.ChannelStats, name string) output.Record {
+	r := output.Record{}.
+		Add("measure", "channel").
+		Add("channel", st.Channel).
+		Add("name", name).
+		Add("start_s", st.Start).
+		Add("end_s", st.End).
+		Add("rising", st.Rising).
+		Add("falling", st.Falling).
+		Add("frequency_hz", st.Frequency).
+		Add("duty_cycle", st.DutyCycle)
+	r = statsFields(r, "period", st.Period)
+	r = r.Add("period_jitter_rms_s", st.Period.Stddev)
+	r = r.Add("period_jitter_pp_s", st.Period.Max-st.Period.Min)
+	r = statsFields(r, "high", st.High)
+	return statsFields(r, "low", st.Low)
+}
+
+func setupHoldRecord(st measure.SetupHoldStats, p setupHoldPair) output.Record {
+	r := output.Record{}.
+		Add("measure", "setup_hold").
+		Add("data", p.data).
+		Add("clock", p.clock).
+		Add("edge", st.Edge.String()).
+		Add("clock_edges", st.ClockEdges)
+	r = statsFields(r, "setup", st.Setup)
+	return statsFields(r, "hold", st.Hold)
+}
+
+func init() {
+	measureCmd.Flags().StringVar(&measureDirectory, "directory", "", "Raw export directory (digital_<n>.bin or digital.csv)")
+	_ = measureCmd.MarkFlagRequired("directory")
+	measureCmd.Flags().StringVar(&measureChannelsCSV, "channels", "", "Channel columns to measure (comma-separated headers). If empty, all digital channels are measured.")
+	measureCmd.Flags().StringArrayVar(&measureNames, "name", nil, "Rename a channel (<column>=<name>); the name can be used in --setup-hold. Can be repeated.")
+	measureCmd.Flags().StringVar(&measureFrom, "from", "", "Window start (seconds, or a duration like 2ms). Default: capture start.")
+	measureCmd.Flags().StringVar(&measureTo, "to", "", "Window end (seconds, or a duration like 2ms). Default: capture end.")
+	measureCmd.Flags().StringArrayVar(&measureSetupHold, "setup-hold", nil, "Measure setup/hold of a data channel around clock edges (<data>,<clock>[,rising|falling]). Can be repeated.")
+	addJSONFlag(measureCmd, &measureJSON)
+}
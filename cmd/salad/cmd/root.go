@@ -53,4 +53,14 @@ func init() {
 	rootCmd.AddCommand(captureCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(decodeCmd)
+	rootCmd.AddCommand(measureCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(goldenCmd)
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(tuiCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(jobsCmd)
 }
@@ -8,10 +8,12 @@ import (
 	"github.com/go-go-golems/salad/internal/saleae"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	runConfigPath string
+	runConfigPath  string
+	runPrintConfig bool
 )
 
 var runCmd = &cobra.Command{
@@ -30,8 +32,18 @@ var runCmd = &cobra.Command{
 			return err
 		}
 
+		if runPrintConfig {
+			enc := yaml.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent(2)
+			if err := enc.Encode(cfg); err != nil {
+				return errors.Wrap(err, "write pipeline config")
+			}
+			return errors.Wrap(enc.Close(), "write pipeline config")
+		}
+
 		r := &pipeline.Runner{
 			SaleaeConfig: saleae.Config{Host: host, Port: port, Timeout: timeout},
+			Client:       shellClient,
 		}
 		res, err := r.Run(ctx, cfg)
 		if err != nil {
@@ -57,4 +69,5 @@ var runCmd = &cobra.Command{
 func init() {
 	runCmd.Flags().StringVar(&runConfigPath, "config", "", "Pipeline config file (.yaml/.yml/.json)")
 	_ = runCmd.MarkFlagRequired("config")
+	runCmd.Flags().BoolVar(&runPrintConfig, "print-config", false, "Print the fully merged pipeline config (after extends/include) and exit without connecting")
 }
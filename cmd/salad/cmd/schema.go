@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	saladconfig "github.com/go-go-golems/salad/internal/config"
+	mock "github.com/go-go-golems/salad/internal/mock/saleae"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/schema"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// schemaGenerators maps `salad schema <format>` to the generator for that format.
+var schemaGenerators = map[string]func() (*schema.Schema, error){
+	"pipeline": pipeline.JSONSchema,
+	"mock":     mock.JSONSchema,
+	"analyzer-settings": func() (*schema.Schema, error) {
+		return saladconfig.AnalyzerSettingsJSONSchema(), nil
+	},
+}
+
+var schemaCmd = &cobra.Command{
+	Use:       "schema pipeline|mock|analyzer-settings",
+	Short:     "Print the JSON Schema for a salad config format",
+	Long:      "Print the JSON Schema for a salad config format. Point your YAML editor at the output (e.g. `# yaml-language-server: $schema=...`) for completion and validation.",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"pipeline", "mock", "analyzer-settings"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := schemaGenerators[args[0]]()
+		if err != nil {
+			return errors.Wrapf(err, "generate %s schema", args[0])
+		}
+		b, err := schema.MarshalIndent(s)
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(b)
+		return errors.Wrap(err, "write output")
+	},
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/server"
+	"github.com/go-go-golems/salad/internal/shell"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+var (
+	serveListen       string
+	serveJobTimeout   time.Duration
+	serveParallelJobs bool
+	serveJobHistory   string
+	serveConfigDir    string
+)
+
+var serveCmd = &cobra.Command{
+	Use:     "serve",
+	Aliases: []string{"daemon"},
+	Short:   "Serve a JSON REST API (captures, analyzers, exports, pipeline jobs) on local and private interfaces",
+	Long: "Serve a JSON REST API over one Logic 2 connection. The OpenAPI document is at /openapi.json.\n\n" +
+		"Only loopback and private addresses are accepted; a wildcard host (\":8080\") listens on each\n" +
+		"loopback and private address of the machine. The API has no authentication.\n\n" +
+		"Pipeline jobs (POST /v1/jobs, or `salad jobs submit`) are queued by priority and run one at a\n" +
+		"time, or with --parallel-jobs concurrently unless they share a device or capture.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Listen first so that a bad address fails before queued jobs resume.
+		listeners, err := server.Listen(ctx, serveListen)
+		if err != nil {
+			return err
+		}
+		closeListeners := func() {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+		}
+
+		dialCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		state := shell.NewState()
+		c, err := saleae.New(dialCtx, saleae.Config{
+			Host:        host,
+			Port:        port,
+			Timeout:     timeout,
+			DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(state.Interceptor())},
+		})
+		if err != nil {
+			closeListeners()
+			return err
+		}
+		defer func() { _ = c.Close() }()
+
+		srv, err := server.New(server.Options{
+			Client:       c,
+			State:        state,
+			Timeout:      timeout,
+			JobTimeout:   serveJobTimeout,
+			ParallelJobs: serveParallelJobs,
+			JobHistory:   serveJobHistory,
+			ConfigDir:    serveConfigDir,
+		})
+		if err != nil {
+			closeListeners()
+			return err
+		}
+
+		httpServer := &http.Server{Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+		errc := make(chan error, len(listeners))
+		for _, l := range listeners {
+			log.Info().Str("addr", l.Addr().String()).Msg("serving REST API")
+			go func() { errc <- httpServer.Serve(l) }()
+		}
+
+		select {
+		case <-ctx.Done():
+			err = nil
+		case err = <-errc:
+			err = errors.Wrap(err, "serve")
+		}
+		log.Info().Msg("shutting down REST API")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+		srv.Close()
+		return err
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Listen address (loopback or private only; \":8080\" listens on every such address)")
+	serveCmd.Flags().DurationVar(&serveJobTimeout, "job-timeout", 30*time.Minute, "Time limit of each pipeline job (0: none)")
+	serveCmd.Flags().BoolVar(&serveParallelJobs, "parallel-jobs", false, "Run pipeline jobs concurrently unless they share a device or capture (default: one at a time)")
+	serveCmd.Flags().StringVar(&serveJobHistory, "job-history", "", "Directory to persist jobs and their logs in; queued jobs resume on restart (default: in memory)")
+	serveCmd.Flags().StringVar(&serveConfigDir, "config-dir", "", "Directory of pipeline configs that jobs may name with config_path (default: config_path is refused)")
+}
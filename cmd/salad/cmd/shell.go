@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/output"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
+	"github.com/peterh/liner"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+)
+
+var (
+	shellHistory   string
+	shellTemplates string
+)
+
+// shellBuiltins are handled by the shell itself rather than the command tree.
+var shellBuiltins = []string{"analyzers", "captures", "exit", "quit", "use"}
+
+var errShellExit = errors.New("exit")
+
+const shellHelp = `
+Shell commands:
+  captures    List captures opened in this shell ($c1, $c2, ...; $c is the current one)
+  analyzers   List analyzers added in this shell ($a1, $a2, ... or $<label>)
+  use <c>     Make a capture current
+  run <file>  Run a pipeline file (same as run --config <file>)
+  exit, quit  Leave the shell
+`
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Interactive shell keeping one Logic 2 connection open, with short names for captures and analyzers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if shellClient != nil {
+			return errors.New("already running inside salad shell")
+		}
+		ctx := cmd.Context()
+		dialCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		state := shell.NewState()
+		c, err := saleae.New(dialCtx, saleae.Config{
+			Host:        host,
+			Port:        port,
+			Timeout:     timeout,
+			DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(state.Interceptor())},
+		})
+		if err != nil {
+			return err
+		}
+		shellClient = c
+		defer func() {
+			shellClient = nil
+			_ = c.Close()
+		}()
+
+		sh := &replShell{
+			root:     cmd.Root(),
+			state:    state,
+			out:      cmd.OutOrStdout(),
+			errOut:   cmd.ErrOrStderr(),
+			defaults: persistentDefaults(cmd.Root()),
+		}
+		return sh.loop(ctx)
+	},
+}
+
+type replShell struct {
+	root     *cobra.Command
+	state    *shell.State
+	out      io.Writer
+	errOut   io.Writer
+	defaults map[string]string // root persistent flag values the shell was started with
+}
+
+func (sh *replShell) loop(ctx context.Context) error {
+	ln := liner.NewLiner()
+	defer func() { _ = ln.Close() }()
+	ln.SetCtrlCAborts(true)
+	ln.SetTabCompletionStyle(liner.TabPrints)
+	comp := &shell.Completer{Root: sh.root, State: sh.state, Builtins: shellBuiltins, Templates: shellTemplates}
+	ln.SetWordCompleter(comp.Complete)
+
+	// Read before the first command resets flags (including this command's own).
+	history := shellHistory
+	if history != "" {
+		if f, err := os.Open(history); err == nil {
+			_, _ = ln.ReadHistory(f)
+			_ = f.Close()
+		}
+		defer func() {
+			if f, err := os.Create(history); err == nil {
+				_, _ = ln.WriteHistory(f)
+				_ = f.Close()
+			}
+		}()
+	}
+
+	_, _ = fmt.Fprintf(sh.errOut, "connected to %s (type help for commands, exit to quit)\n", saleae.Config{Host: host, Port: port}.Addr())
+	for {
+		line, err := ln.Prompt(sh.prompt())
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			_, _ = fmt.Fprintln(sh.errOut)
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "read command")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ln.AppendHistory(line)
+
+		err = sh.exec(ctx, line)
+		if errors.Is(err, errShellExit) {
+			return nil
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(sh.errOut, "error: %v\n", err)
+		}
+	}
+}
+
+func (sh *replShell) prompt() string {
+	id := sh.state.Current()
+	for _, c := range sh.state.Captures() {
+		if c.ID == id {
+			return fmt.Sprintf("salad %s> ", c.Name)
+		}
+	}
+	return "salad> "
+}
+
+// exec runs one shell line: a builtin, or a salad command on the shared connection.
+func (sh *replShell) exec(ctx context.Context, line string) error {
+	args, err := shell.Split(line, sh.state.Expand)
+	if err != nil || len(args) == 0 {
+		return err
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return errShellExit
+	case "captures":
+		out := output.New(sh.out, output.FormatText)
+		current := sh.state.Current()
+		for _, c := range sh.state.Captures() {
+			if err := out.Write(output.Record{}.
+				Add("capture", c.Name).
+				Add("capture_id", c.ID).
+				Add("source", c.Source).
+				Add("current", c.ID == current)); err != nil {
+				return err
+			}
+		}
+		return out.Close()
+	case "analyzers":
+		out := output.New(sh.out, output.FormatText)
+		for _, a := range sh.state.Analyzers() {
+			if err := out.Write(output.Record{}.
+				Add("analyzer", a.Name).
+				Add("analyzer_id", a.ID).
+				Add("capture_id", a.CaptureID).
+				Add("name", a.Analyzer).
+				Add("label", a.Label)); err != nil {
+				return err
+			}
+		}
+		return out.Close()
+	case "use":
+		if len(args) != 2 {
+			return errors.New("usage: use <capture> (c1, c2, ... or a capture ID)")
+		}
+		if err := sh.state.Use(strings.TrimPrefix(args[1], "$")); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(sh.out, "ok")
+		return errors.Wrap(err, "write output")
+	case "shell":
+		return errors.New("already running inside salad shell")
+	}
+
+	args = sh.rewrite(args)
+	// Ctrl-C cancels the running command instead of leaving the shell.
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	sh.reset(runCtx, sh.root)
+	sh.root.SetArgs(args)
+	sh.root.SilenceErrors = true
+	defer func() { sh.root.SilenceErrors = false }()
+	err = sh.root.ExecuteContext(runCtx)
+
+	if len(args) == 1 && args[0] == "help" {
+		_, _ = fmt.Fprint(sh.out, shellHelp)
+	}
+	return err
+}
+
+// rewrite applies the shell's shorthands: `run <file>` means `run --config <file>`, and
+// commands that require --capture-id get the current capture unless one is given.
+func (sh *replShell) rewrite(args []string) []string {
+	if args[0] == "run" && len(args) >= 2 && !strings.HasPrefix(args[1], "-") {
+		args = append([]string{"run", "--config"}, args[1:]...)
+	}
+
+	target, _, err := sh.root.Find(args)
+	if err != nil {
+		return args
+	}
+	f := target.Flags().Lookup("capture-id")
+	if f == nil || !slices.Contains(f.Annotations[cobra.BashCompOneRequiredFlag], "true") {
+		return args
+	}
+	current := sh.state.Current()
+	if current == 0 {
+		return args
+	}
+	for _, a := range args {
+		if a == "--capture-id" || strings.HasPrefix(a, "--capture-id=") {
+			return args
+		}
+	}
+	return append(args, "--capture-id", strconv.FormatUint(current, 10))
+}
+
+// reset prepares the command tree for the next command: cobra keeps flag values and
+// each subcommand's first context between executions, so every flag goes back to its
+// default (root persistent flags to the values the shell was started with) and every
+// command gets ctx.
+func (sh *replShell) reset(ctx context.Context, c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if v, ok := sh.defaults[f.Name]; ok && sh.root.PersistentFlags().Lookup(f.Name) == f {
+			_ = f.Value.Set(v)
+		} else if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	c.SetContext(ctx)
+	for _, sub := range c.Commands() {
+		sh.reset(ctx, sub)
+	}
+}
+
+func persistentDefaults(root *cobra.Command) map[string]string {
+	out := map[string]string{}
+	root.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		out[f.Name] = f.Value.String()
+	})
+	return out
+}
+
+func defaultShellHistory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home + string(os.PathSeparator) + ".salad_history"
+}
+
+func init() {
+	shellCmd.Flags().StringVar(&shellHistory, "history", defaultShellHistory(), "Command history file (empty to disable)")
+	shellCmd.Flags().StringVar(&shellTemplates, "templates", "configs/analyzers", "Analyzer settings templates whose keys complete --set/--set-int/... values")
+}
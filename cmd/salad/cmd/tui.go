@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
+	"github.com/go-go-golems/salad/internal/tui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+var tuiTemplates string
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Full-screen terminal dashboard: devices, captures, analyzers and a live RPC log",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		dialCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			dialCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		state, rpcs := shell.NewState(), tui.NewRPCLog()
+		cfg := saleae.Config{
+			Host:        host,
+			Port:        port,
+			Timeout:     timeout,
+			DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(rpcs.Interceptor(), state.Interceptor())},
+		}
+		c, err := saleae.New(dialCtx, cfg)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = c.Close() }()
+
+		m := tui.New(tui.Options{
+			Client:    c,
+			State:     state,
+			RPCs:      rpcs,
+			Addr:      cfg.Addr(),
+			Timeout:   timeout,
+			Templates: tuiTemplates,
+		})
+		_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+		return errors.Wrap(err, "run tui")
+	},
+}
+
+func init() {
+	tuiCmd.Flags().StringVar(&tuiTemplates, "templates", "configs/analyzers", "Directory of analyzer settings templates offered when adding an analyzer")
+}
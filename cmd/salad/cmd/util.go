@@ -1,12 +1,44 @@
 package cmd
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
+	"github.com/go-go-golems/salad/internal/output"
+	"github.com/go-go-golems/salad/internal/saleae"
 	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
 )
 
+// shellClient is the persistent connection of `salad shell`; nil outside the shell.
+var shellClient *saleae.Client
+
+// connect returns the Logic 2 client for a command: the shell's connection when running
+// inside `salad shell`, a new connection otherwise. Call release when done.
+func connect(ctx context.Context) (*saleae.Client, func(), error) {
+	if shellClient != nil {
+		return shellClient, func() {}, nil
+	}
+	c, err := saleae.New(ctx, saleae.Config{Host: host, Port: port, Timeout: timeout})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, func() { _ = c.Close() }, nil
+}
+
+// addJSONFlag registers --json on commands that write through internal/output.
+func addJSONFlag(cmd *cobra.Command, v *bool) {
+	cmd.Flags().BoolVar(v, "json", false, "Write results as JSON instead of key=value lines")
+}
+
+func outputFormat(json bool) output.Format {
+	if json {
+		return output.FormatJSON
+	}
+	return output.FormatText
+}
+
 func parseUint32CSV(s string) ([]uint32, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
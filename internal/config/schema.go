@@ -0,0 +1,35 @@
+package config
+
+import "github.com/go-go-golems/salad/internal/schema"
+
+// AnalyzerSettingsJSONSchema returns the JSON Schema for analyzer settings files
+// (configs/analyzers/*.yaml). Settings are free-form by design, so the schema only
+// constrains the two supported shapes and the scalar value types.
+func AnalyzerSettingsJSONSchema() *schema.Schema {
+	settingsMap := func() *schema.Schema {
+		return &schema.Schema{
+			Type: "object",
+			AdditionalProperties: &schema.Schema{
+				Description: "Setting value; keys and string values must match the Logic 2 UI labels exactly.",
+				Type:        []string{"string", "boolean", "integer", "number"},
+			},
+		}
+	}
+
+	wrapped := &schema.Schema{
+		Type:                 "object",
+		Properties:           map[string]*schema.Schema{"settings": settingsMap()},
+		Required:             []string{"settings"},
+		AdditionalProperties: false,
+	}
+	flat := settingsMap()
+	flat.Not = &schema.Schema{Required: []string{"settings"}}
+
+	return &schema.Schema{
+		Schema:      schema.Draft,
+		ID:          "https://github.com/go-go-golems/salad/configs/schema/analyzer-settings.schema.json",
+		Title:       "salad analyzer settings",
+		Description: "Analyzer settings file: either {settings: {key: value}} or a flat {key: value} mapping.",
+		AnyOf:       []*schema.Schema{wrapped, flat},
+	}
+}
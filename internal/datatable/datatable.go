@@ -0,0 +1,242 @@
+// Package datatable reads Logic 2 decoded data table CSV exports (ExportDataTableCsv).
+//
+// A data table export has the fixed columns name, type, start_time and duration,
+// followed by the union of the analyzers' frame fields (data, address, read, ...):
+//
+//	name,type,start_time,duration,"data","address"
+//	"spi","result",0.000120000,0.000002000,0x3C,
+//	"i2c","address",0.000400000,0.000010000,,0x50
+//
+// Rows are streamed so multi-GB tables can be processed with bounded memory.
+package datatable
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ColumnName      = "name"
+	ColumnType      = "type"
+	ColumnStartTime = "start_time"
+	ColumnDuration  = "duration"
+)
+
+// FixedColumns are the columns Logic 2 writes before the analyzer fields.
+var FixedColumns = []string{ColumnName, ColumnType, ColumnStartTime, ColumnDuration}
+
+// Row is one decoded frame.
+type Row struct {
+	// Line is the 1-based line number in the file.
+	Line int
+	// Name is the analyzer label.
+	Name string
+	// Type is the frame type (e.g. "result", "address", "data").
+	Type string
+	// Start is the frame start, in seconds (relative to the first row for ISO8601 tables).
+	Start float64
+	// Duration is the frame length, in seconds.
+	Duration float64
+	// Values holds all columns in Header order, including the fixed ones. The slice is
+	// reused between rows; copy it if you keep it.
+	Values []string
+}
+
+// Table is an opened data table CSV.
+type Table struct {
+	path      string
+	header    []string
+	index     map[string]int
+	iso       bool
+	startTime time.Time
+}
+
+// Open reads the header of a data table CSV and checks the fixed columns are present.
+func Open(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open data table %s", path)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := newReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "read data table header %s", path)
+	}
+	t := &Table{path: path, header: append([]string(nil), header...), index: map[string]int{}}
+	for i, h := range t.header {
+		h = strings.TrimSpace(h)
+		t.header[i] = h
+		if _, ok := t.index[h]; !ok {
+			t.index[h] = i
+		}
+	}
+	for _, c := range FixedColumns {
+		if _, ok := t.index[c]; !ok {
+			return nil, errors.Errorf("data table %s: missing column %q (have %s)", path, c, strings.Join(t.header, ", "))
+		}
+	}
+
+	first, err := r.Read()
+	if err == io.EOF {
+		return t, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read data table %s", path)
+	}
+	raw := strings.TrimSpace(first[t.index[ColumnStartTime]])
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		ts, perr := time.Parse(time.RFC3339Nano, raw)
+		if perr != nil {
+			return nil, errors.Errorf("data table %s: unrecognized start_time %q", path, raw)
+		}
+		t.iso = true
+		t.startTime = ts
+	}
+	return t, nil
+}
+
+func newReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(bufio.NewReaderSize(r, 64*1024))
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+	return cr
+}
+
+// Path returns the file the table was opened from.
+func (t *Table) Path() string { return t.path }
+
+// Header returns all column names, fixed columns included.
+func (t *Table) Header() []string { return t.header }
+
+// FieldColumns returns the analyzer field columns (everything after the fixed ones).
+func (t *Table) FieldColumns() []string {
+	out := make([]string, 0, len(t.header))
+	for _, h := range t.header {
+		if !isFixed(h) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// Index returns the position of a column in Header.
+func (t *Table) Index(column string) (int, bool) {
+	i, ok := t.index[column]
+	return i, ok
+}
+
+// ISO8601 reports whether start_time holds absolute timestamps.
+func (t *Table) ISO8601() bool { return t.iso }
+
+// StartTime is the absolute start of the first row for ISO8601 tables (zero otherwise).
+func (t *Table) StartTime() time.Time { return t.startTime }
+
+func isFixed(column string) bool {
+	for _, c := range FixedColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Rows streams the table. Each call re-reads the file; iteration stops at the first error.
+func (t *Table) Rows() iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		f, err := os.Open(t.path)
+		if err != nil {
+			yield(Row{}, errors.Wrapf(err, "open data table %s", t.path))
+			return
+		}
+		defer func() { _ = f.Close() }()
+
+		r := newReader(f)
+		if _, err := r.Read(); err != nil {
+			yield(Row{}, errors.Wrapf(err, "read data table header %s", t.path))
+			return
+		}
+		row := Row{Values: make([]string, len(t.header))}
+		for line := 2; ; line++ {
+			rec, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(Row{}, errors.Wrapf(err, "%s:%d", t.path, line))
+				return
+			}
+			if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+				continue
+			}
+			for i := range row.Values {
+				row.Values[i] = ""
+				if i < len(rec) {
+					row.Values[i] = rec[i]
+				}
+			}
+			row.Line = line
+			if err := t.fillFixed(&row); err != nil {
+				yield(Row{}, errors.Wrapf(err, "%s:%d", t.path, line))
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (t *Table) fillFixed(row *Row) error {
+	row.Name = row.Values[t.index[ColumnName]]
+	row.Type = row.Values[t.index[ColumnType]]
+
+	start := strings.TrimSpace(row.Values[t.index[ColumnStartTime]])
+	if t.iso {
+		ts, err := time.Parse(time.RFC3339Nano, start)
+		if err != nil {
+			return errors.Wrapf(err, "parse start_time %q", start)
+		}
+		row.Start = ts.Sub(t.startTime).Seconds()
+	} else {
+		v, err := strconv.ParseFloat(start, 64)
+		if err != nil {
+			return errors.Wrapf(err, "parse start_time %q", start)
+		}
+		row.Start = v
+	}
+
+	dur := strings.TrimSpace(row.Values[t.index[ColumnDuration]])
+	if dur == "" {
+		row.Duration = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(dur, 64)
+	if err != nil {
+		return errors.Wrapf(err, "parse duration %q", dur)
+	}
+	row.Duration = v
+	return nil
+}
+
+// Fields returns the non-empty analyzer field values of row as column=value pairs, in
+// Header order.
+func (t *Table) Fields(row Row) []string {
+	var out []string
+	for i, h := range t.header {
+		if isFixed(h) || i >= len(row.Values) || row.Values[i] == "" {
+			continue
+		}
+		out = append(out, h+"="+row.Values[i])
+	}
+	return out
+}
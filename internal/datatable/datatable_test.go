@@ -0,0 +1,99 @@
+package datatable
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTable_RowsAndFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.csv")
+	if err := os.WriteFile(path, []byte(`name,type,start_time,duration,"data","address"
+"spi","result",2025-01-02T03:04:05.000000000Z,0.000002000,0x3C,
+"i2c","address",2025-01-02T03:04:05.500000000Z,,,0x50
+`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	table, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !table.ISO8601() {
+		t.Fatalf("expected ISO8601 table")
+	}
+	if got := strings.Join(table.FieldColumns(), ","); got != "data,address" {
+		t.Fatalf("field columns: expected data,address, got %s", got)
+	}
+
+	var starts []float64
+	var fields []string
+	for row, err := range table.Rows() {
+		if err != nil {
+			t.Fatalf("Rows: %v", err)
+		}
+		starts = append(starts, row.Start)
+		fields = append(fields, strings.Join(table.Fields(row), ";"))
+	}
+	if len(starts) != 2 || starts[0] != 0 || starts[1] != 0.5 {
+		t.Fatalf("starts: expected [0 0.5], got %v", starts)
+	}
+	if fields[0] != "data=0x3C" || fields[1] != "address=0x50" {
+		t.Fatalf("fields: expected [data=0x3C address=0x50], got %v", fields)
+	}
+}
+
+func TestOpen_RequiresFixedColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.csv")
+	if err := os.WriteFile(path, []byte("name,type,data\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "start_time") {
+		t.Fatalf("expected missing start_time error, got %v", err)
+	}
+}
+
+func TestWriter_ISO8601(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	w, err := NewWriter(f, []string{"data"})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.UseISO8601(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err := w.Write("uart", "data", 0.25, 0.001, map[string]string{"data": "0x41"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Write("uart", "data", 0.75, 0.001, map[string]string{"data": "0x42"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	table, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !table.ISO8601() || !table.StartTime().Equal(time.Date(2025, 1, 2, 3, 4, 5, 250_000_000, time.UTC)) {
+		t.Fatalf("expected an ISO8601 table starting at 03:04:05.25, got iso=%v start=%s", table.ISO8601(), table.StartTime())
+	}
+	var starts []float64
+	for row, err := range table.Rows() {
+		if err != nil {
+			t.Fatalf("Rows: %v", err)
+		}
+		starts = append(starts, row.Start)
+	}
+	if len(starts) != 2 || starts[0] != 0 || starts[1] != 0.5 {
+		t.Fatalf("starts: expected [0 0.5], got %v", starts)
+	}
+}
@@ -0,0 +1,100 @@
+package datatable
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// isoLayout is RFC 3339 with a fixed nanosecond fraction.
+const isoLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Writer writes a data table CSV in Logic 2's layout: the fixed columns followed by
+// the given field columns.
+type Writer struct {
+	w      *csv.Writer
+	fields []string
+	index  map[string]int
+	record []string
+	// iso is set by UseISO8601; start is then the absolute time of offset 0.
+	iso   bool
+	start time.Time
+}
+
+// NewWriter writes the header and returns a writer for rows with the given field columns.
+func NewWriter(w io.Writer, fields []string) (*Writer, error) {
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, FixedColumns...), fields...)
+	if err := cw.Write(header); err != nil {
+		return nil, errors.Wrap(err, "write data table header")
+	}
+	index := make(map[string]int, len(fields))
+	for i, f := range fields {
+		index[f] = len(FixedColumns) + i
+	}
+	return &Writer{w: cw, fields: fields, index: index, record: make([]string, len(header))}, nil
+}
+
+// UseISO8601 makes Write render start_time as an absolute timestamp, offset from start,
+// like exports with iso8601_timestamp set.
+func (w *Writer) UseISO8601(start time.Time) {
+	w.iso = true
+	w.start = start
+}
+
+// Write appends one row. values maps field columns to already formatted cells; fields
+// not declared in NewWriter are an error.
+func (w *Writer) Write(name string, typ string, start float64, duration float64, values map[string]string) error {
+	for i := range w.record {
+		w.record[i] = ""
+	}
+	w.record[0] = name
+	w.record[1] = typ
+	w.record[2] = strconv.FormatFloat(start, 'f', 9, 64)
+	if w.iso {
+		w.record[2] = w.start.Add(time.Duration(math.Round(start * float64(time.Second)))).Format(isoLayout)
+	}
+	w.record[3] = strconv.FormatFloat(duration, 'f', 9, 64)
+	for k, v := range values {
+		i, ok := w.index[k]
+		if !ok {
+			return errors.Errorf("data table: field %q is not a declared column", k)
+		}
+		w.record[i] = v
+	}
+	return errors.Wrap(w.w.Write(w.record), "write data table row")
+}
+
+// Flush writes buffered rows.
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return errors.Wrap(w.w.Error(), "write data table")
+}
+
+// FormatInt renders an integer the way Logic 2 does for a radix: hex "0x3C" and binary
+// "0b00111100" are zero-padded to bits, dec is plain, ascii is the character (or an
+// escape for non-printable bytes).
+func FormatInt(v uint64, bits int, radix string) string {
+	if bits <= 0 {
+		bits = 8
+	}
+	switch strings.ToLower(radix) {
+	case "hex":
+		return fmt.Sprintf("0x%0*X", (bits+3)/4, v)
+	case "bin":
+		return fmt.Sprintf("0b%0*b", bits, v)
+	case "ascii":
+		if v < 0x80 && strconv.IsPrint(rune(v)) {
+			return string(rune(v))
+		}
+		return fmt.Sprintf("\\x%02X", v)
+	default:
+		return strconv.FormatUint(v, 10)
+	}
+}
@@ -0,0 +1,155 @@
+// Package decode decodes protocols offline from raw export transitions (see
+// internal/rawdata), without Logic 2.
+//
+// Decoders take the same settings maps as `salad analyzer add` (UI-visible keys such as
+// "Clock", "MOSI", "Bit Rate (Bits/s)"), so one configs/analyzers/*.yaml template can
+// drive either Logic 2 or the offline decoder. Frames mirror Logic 2's data table rows.
+package decode
+
+import (
+	"io"
+	"iter"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+// Field is one named value of a frame. Value is uint64 (formatted with Bits and the
+// output radix), bool or string.
+type Field struct {
+	Name  string
+	Value any
+	Bits  int
+}
+
+// Frame is one decoded protocol event, like a row of a Logic 2 data table.
+type Frame struct {
+	Type   string
+	Start  float64
+	End    float64
+	Fields []Field
+}
+
+// Decoder turns digital transitions into frames.
+type Decoder interface {
+	// Channels returns the digital channels to read, in the order Decode expects them.
+	Channels() []ChannelRef
+	// Decode streams frames in start-time order from one source per channel.
+	Decode(sources []rawdata.DigitalSource) iter.Seq2[Frame, error]
+}
+
+// Factory builds a decoder from analyzer settings.
+type Factory func(settings Settings) (Decoder, error)
+
+// factories is keyed by the Logic 2 analyzer name.
+var factories = map[string]Factory{
+	"SPI":          NewSPI,
+	"I2C":          NewI2C,
+	"Async Serial": NewUART,
+}
+
+var aliases = map[string]string{
+	"uart":   "Async Serial",
+	"serial": "Async Serial",
+}
+
+// Names returns the supported analyzer names.
+func Names() []string {
+	out := make([]string, 0, len(factories))
+	for name := range factories {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// New returns the decoder for a Logic 2 analyzer name (case-insensitive; "UART" is
+// accepted for "Async Serial").
+func New(name string, settings map[string]*pb.AnalyzerSettingValue) (Decoder, error) {
+	key := strings.TrimSpace(name)
+	if alias, ok := aliases[strings.ToLower(key)]; ok {
+		key = alias
+	}
+	for n, f := range factories {
+		if strings.EqualFold(n, key) {
+			d, err := f(Settings(settings))
+			return d, errors.Wrapf(err, "%s settings", n)
+		}
+	}
+	return nil, errors.Errorf("no offline decoder for analyzer %q (have: %s)", name, strings.Join(Names(), ", "))
+}
+
+// DecodeExport runs dec over the matching channels of a raw export. Channels are matched
+// by index, or by column header for renamed CSV columns.
+func DecodeExport(dec Decoder, export *rawdata.Export) iter.Seq2[Frame, error] {
+	sources := make([]rawdata.DigitalSource, 0, len(dec.Channels()))
+	for _, ch := range dec.Channels() {
+		i := slices.IndexFunc(export.Digital, ch.matches)
+		if i < 0 {
+			return func(yield func(Frame, error) bool) {
+				yield(Frame{}, errors.Errorf("channel %s not found in export", ch))
+			}
+		}
+		sources = append(sources, export.Digital[i])
+	}
+	return dec.Decode(sources)
+}
+
+// WriteTable writes frames as a Logic 2 style data table CSV, labelled name, with
+// integer fields rendered in radix.
+func WriteTable(w io.Writer, name string, fields []string, frames iter.Seq2[Frame, error], radix string) (int, error) {
+	tw, err := datatable.NewWriter(w, fields)
+	if err != nil {
+		return 0, err
+	}
+	values := map[string]string{}
+	n := 0
+	for f, err := range frames {
+		if err != nil {
+			return n, err
+		}
+		clear(values)
+		for _, field := range f.Fields {
+			values[field.Name] = FormatValue(field, radix)
+		}
+		if err := tw.Write(name, f.Type, f.Start, f.End-f.Start, values); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, tw.Flush()
+}
+
+// FormatValue renders a field the way data table cells show it, integers in radix.
+func FormatValue(f Field, radix string) string {
+	switch v := f.Value.(type) {
+	case uint64:
+		return datatable.FormatInt(v, f.Bits, radix)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// FieldColumns lists the field names each decoder can emit, for the table header.
+func FieldColumns(dec Decoder) []string {
+	switch dec.(type) {
+	case *SPI:
+		return []string{"mosi", "miso"}
+	case *I2C:
+		return []string{"address", "read", "data", "ack"}
+	case *UART:
+		return []string{"data", "error"}
+	default:
+		return nil
+	}
+}
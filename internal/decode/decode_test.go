@@ -0,0 +1,218 @@
+package decode
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/rawdata"
+)
+
+// line builds a digital source from one level per time step.
+func line(t *testing.T, channel int, step float64, levels []uint8) rawdata.DigitalSource {
+	t.Helper()
+	var toggles []float64
+	for i := 1; i < len(levels); i++ {
+		if levels[i] != levels[i-1] {
+			toggles = append(toggles, float64(i)*step)
+		}
+	}
+	var buf bytes.Buffer
+	if err := rawdata.WriteDigitalBinary(&buf, levels[0], 0, float64(len(levels))*step, toggles); err != nil {
+		t.Fatalf("WriteDigitalBinary: %v", err)
+	}
+	src, err := rawdata.NewDigitalBinary(&buf, channel)
+	if err != nil {
+		t.Fatalf("NewDigitalBinary: %v", err)
+	}
+	return src
+}
+
+func intSetting(v int64) *pb.AnalyzerSettingValue {
+	return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: v}}
+}
+
+func strSetting(v string) *pb.AnalyzerSettingValue {
+	return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_StringValue{StringValue: v}}
+}
+
+func decodeAll(t *testing.T, dec Decoder, sources ...rawdata.DigitalSource) []string {
+	t.Helper()
+	var out []string
+	for f, err := range DecodeExport(dec, &rawdata.Export{Digital: sources}) {
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		s := f.Type
+		for _, field := range f.Fields {
+			s += fmt.Sprintf(" %s=%s", field.Name, FormatValue(field, "hex"))
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestSPI(t *testing.T) {
+	dec, err := New("SPI", map[string]*pb.AnalyzerSettingValue{
+		"Clock":             intSetting(0),
+		"MOSI":              intSetting(1),
+		"Enable":            intSetting(3),
+		"Clock Phase":       strSetting("Data is Valid on Clock Leading Edge (CPHA = 0)"),
+		"Clock State":       strSetting("Clock is Low when inactive (CPOL = 0)"),
+		"Bits per Transfer": strSetting("8 Bits per Transfer (Standard)"),
+		"Significant Bit":   strSetting("Most Significant Bit First (Standard)"),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Two steps per bit: data set with clock low, sampled on the rising edge.
+	clk, mosi, cs := []uint8{0, 0}, []uint8{0, 0}, []uint8{1, 0}
+	for _, b := range []byte{0x3C, 0xA5} {
+		for i := 7; i >= 0; i-- {
+			bit := b >> i & 1
+			clk = append(clk, 0, 1)
+			mosi = append(mosi, bit, bit)
+			cs = append(cs, 0, 0)
+		}
+	}
+	clk, mosi, cs = append(clk, 0, 0), append(mosi, 0, 0), append(cs, 0, 1)
+
+	got := decodeAll(t, dec, line(t, 0, 1e-6, clk), line(t, 1, 1e-6, mosi), line(t, 3, 1e-6, cs))
+	want := []string{"enable", "result mosi=0x3C", "result mosi=0xA5", "disable"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDecodeExport_RenamedColumns(t *testing.T) {
+	// A Logic 2 CSV export with channel 0 renamed to CLK: the clock is found by name,
+	// MOSI by its "Channel <n>" header.
+	dec, err := New("SPI", map[string]*pb.AnalyzerSettingValue{"Clock": strSetting("clk"), "MOSI": strSetting("Channel 1")})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	csv := "Time [s],CLK,Channel 1\n0.000000,0,0\n"
+	step := 1
+	for i := 7; i >= 0; i-- {
+		bit := 0x5A >> i & 1
+		csv += fmt.Sprintf("%.6f,0,%d\n%.6f,1,%d\n", float64(step)*1e-6, bit, float64(step+1)*1e-6, bit)
+		step += 2
+	}
+	csv += fmt.Sprintf("%.6f,0,0\n", float64(step)*1e-6)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, rawdata.DigitalCSVFilename), []byte(csv), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	export, err := rawdata.OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+	if got := decodeAll(t, dec, export.Digital...); strings.Join(got, "|") != "result mosi=0x5A" {
+		t.Fatalf("expected one 0x5A word, got %v", got)
+	}
+
+	dec, err = New("SPI", map[string]*pb.AnalyzerSettingValue{"Clock": strSetting("SCK"), "MOSI": intSetting(1)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, err := range DecodeExport(dec, export) {
+		if err == nil || !strings.Contains(err.Error(), `channel "SCK" not found`) {
+			t.Fatalf("expected a missing channel error, got %v", err)
+		}
+	}
+}
+
+func TestI2C(t *testing.T) {
+	dec, err := New("I2C", map[string]*pb.AnalyzerSettingValue{"SCL": intSetting(0), "SDA": intSetting(1)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	scl, sda := []uint8{1, 1}, []uint8{1, 0} // start
+	byteBits := func(b byte, ack bool) {
+		for i := 7; i >= -1; i-- {
+			bit := uint8(1)
+			if i >= 0 {
+				bit = b >> i & 1
+			} else if ack {
+				bit = 0
+			}
+			scl = append(scl, 0, 0, 1, 1)
+			sda = append(sda, sda[len(sda)-1], bit, bit, bit)
+		}
+	}
+	byteBits(0x50<<1|1, true)
+	byteBits(0x42, false)
+	scl, sda = append(scl, 0, 0, 1, 1), append(sda, 0, 0, 0, 1) // stop
+
+	got := decodeAll(t, dec, line(t, 0, 1e-6, scl), line(t, 1, 1e-6, sda))
+	want := []string{"start", "address address=0x50 read=true ack=true", "data data=0x42 ack=false", "stop"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestUART(t *testing.T) {
+	dec, err := New("uart", map[string]*pb.AnalyzerSettingValue{
+		"Input Channel":     intSetting(2),
+		"Bit Rate (Bits/s)": intSetting(100000),
+		"Bits per Frame":    strSetting("8 Bits per Transfer (Standard)"),
+		"Parity Bit":        strSetting("Even Parity Bit"),
+		"Stop Bits":         strSetting("1 Stop Bit (Standard)"),
+		"Significant Bit":   strSetting("Least Significant Bit Sent First (Standard)"),
+		"Signal inversion":  strSetting("Non Inverted (Standard)"),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	levels := []uint8{1, 1, 1}
+	frame := func(b byte, parity uint8, stop uint8) {
+		levels = append(levels, 0)
+		for i := 0; i < 8; i++ {
+			levels = append(levels, b>>i&1)
+		}
+		levels = append(levels, parity, stop, 1, 1)
+	}
+	frame('A', 0, 1) // 0x41 has two ones: even parity bit 0
+	frame('C', 0, 1) // three ones: bad parity
+	frame('D', 0, 0) // missing stop bit
+
+	got := decodeAll(t, dec, line(t, 2, 1e-5, levels))
+	want := []string{"data data=0x41", "data data=0x43 error=parity", "data data=0x44 error=framing"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New("CAN", nil); err == nil || !strings.Contains(err.Error(), "no offline decoder") {
+		t.Fatalf("expected unsupported analyzer error, got %v", err)
+	}
+	if _, err := New("SPI", map[string]*pb.AnalyzerSettingValue{"Clock": intSetting(0)}); err == nil {
+		t.Fatalf("expected error without MOSI/MISO")
+	}
+	if _, err := New("Async Serial", map[string]*pb.AnalyzerSettingValue{"Input Channel": intSetting(0)}); err == nil {
+		t.Fatalf("expected error without bit rate")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	frames := func(yield func(Frame, error) bool) {
+		yield(Frame{Type: "data", Start: 0.001, End: 0.0011, Fields: []Field{{Name: "data", Value: uint64(0x41), Bits: 8}}}, nil)
+	}
+	var out bytes.Buffer
+	n, err := WriteTable(&out, "uart", []string{"data", "error"}, frames, "ascii")
+	if err != nil || n != 1 {
+		t.Fatalf("WriteTable: expected 1 row, got %d (%v)", n, err)
+	}
+	want := "name,type,start_time,duration,data,error\nuart,data,0.001000000,0.000100000,A,\n"
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+}
@@ -0,0 +1,104 @@
+package decode
+
+import (
+	"iter"
+
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+// I2C decodes start/stop conditions and bytes. Frames are "start", "address"
+// (address, read, ack), "data" (data, ack) and "stop", as in Logic 2's I2C table.
+type I2C struct {
+	SCL, SDA ChannelRef
+}
+
+// NewI2C reads the keys of configs/analyzers/i2c.yaml.
+func NewI2C(s Settings) (Decoder, error) {
+	d := &I2C{}
+	var err error
+	if d.SCL, err = s.Channel("SCL", true); err != nil {
+		return nil, err
+	}
+	if d.SDA, err = s.Channel("SDA", true); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Channels returns SCL, SDA.
+func (d *I2C) Channels() []ChannelRef { return []ChannelRef{d.SCL, d.SDA} }
+
+// Decode samples SDA on SCL rising edges, nine bits per byte (eight data bits MSB
+// first, then ACK when SDA is low). SDA changing while SCL is high marks a start
+// (falling) or stop (rising); a byte cut short by either is dropped.
+func (d *I2C) Decode(sources []rawdata.DigitalSource) iter.Seq2[Frame, error] {
+	return func(yield func(Frame, error) bool) {
+		if len(sources) != 2 {
+			yield(Frame{}, errors.Errorf("i2c: expected 2 sources, got %d", len(sources)))
+			return
+		}
+		const scl, sda = 0, 1
+		levels := []uint8{sources[scl].InitialState(), sources[sda].InitialState()}
+
+		inTransaction := false
+		first := false // next byte is the address byte
+		var value uint64
+		var n int
+		var start float64
+		for e, err := range rawdata.Merge(sources) {
+			if err != nil {
+				yield(Frame{}, err)
+				return
+			}
+			prev := levels[e.Index]
+			levels[e.Index] = e.State
+			if prev == e.State {
+				continue
+			}
+			if e.Index == sda {
+				if levels[scl] == 0 {
+					continue
+				}
+				typ := "stop"
+				if e.State == 0 {
+					typ = "start"
+				}
+				inTransaction, first, value, n = typ == "start", true, 0, 0
+				if !yield(Frame{Type: typ, Start: e.Time, End: e.Time}, nil) {
+					return
+				}
+				continue
+			}
+			if !inTransaction || e.State != 1 {
+				continue
+			}
+			if n == 0 {
+				start = e.Time
+			}
+			if n < 8 {
+				value = value<<1 | uint64(levels[sda])
+				n++
+				continue
+			}
+			ack := levels[sda] == 0
+			var f Frame
+			if first {
+				f = Frame{Type: "address", Start: start, End: e.Time, Fields: []Field{
+					{Name: "address", Value: value >> 1, Bits: 7},
+					{Name: "read", Value: value&1 == 1},
+					{Name: "ack", Value: ack},
+				}}
+			} else {
+				f = Frame{Type: "data", Start: start, End: e.Time, Fields: []Field{
+					{Name: "data", Value: value, Bits: 8},
+					{Name: "ack", Value: ack},
+				}}
+			}
+			first, value, n = false, 0, 0
+			if !yield(f, nil) {
+				return
+			}
+		}
+	}
+}
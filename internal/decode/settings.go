@@ -0,0 +1,167 @@
+package decode
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+// Settings are analyzer settings keyed by their UI-visible names. Values may be given
+// the short way (Stop Bits: 1) or as the Logic 2 dropdown text
+// ("1 Stop Bit (Standard)"); the accessors accept both.
+type Settings map[string]*pb.AnalyzerSettingValue
+
+// ChannelRef is a digital channel of a raw export: its index, or the column header of
+// a channel renamed in Logic 2 (whose CSV column has no index).
+type ChannelRef struct {
+	Index int
+	Name  string
+}
+
+// NoChannel marks an optional channel that is not connected.
+var NoChannel = ChannelRef{Index: -1}
+
+// Ch refers to channel n.
+func Ch(n int) ChannelRef { return ChannelRef{Index: n} }
+
+func (c ChannelRef) String() string {
+	if c.Name != "" {
+		return strconv.Quote(c.Name)
+	}
+	return strconv.Itoa(c.Index)
+}
+
+// matches reports whether src is the channel c refers to: by column header
+// (case-insensitive) for names, by index otherwise.
+func (c ChannelRef) matches(src rawdata.DigitalSource) bool {
+	if c.Name != "" {
+		return strings.EqualFold(src.Name(), c.Name)
+	}
+	return src.Channel() == c.Index
+}
+
+var channelHeader = regexp.MustCompile(`(?i)^channel\s+([0-9]+)$`)
+
+var leadingNumber = regexp.MustCompile(`^\s*([0-9]+(?:\.[0-9]+)?)`)
+
+// Channel returns a channel reference: an index given as a number or "Channel <n>", or
+// any other text as the header of a renamed column. Optional channels may be missing,
+// "None" or empty and then return NoChannel.
+func (s Settings) Channel(key string, required bool) (ChannelRef, error) {
+	sv, ok := s[key]
+	if !ok || sv == nil {
+		if required {
+			return NoChannel, errors.Errorf("missing channel setting %q", key)
+		}
+		return NoChannel, nil
+	}
+	switch v := sv.GetValue().(type) {
+	case *pb.AnalyzerSettingValue_Int64Value:
+		if v.Int64Value < 0 {
+			return NoChannel, errors.Errorf("%q: invalid channel %d", key, v.Int64Value)
+		}
+		return Ch(int(v.Int64Value)), nil
+	case *pb.AnalyzerSettingValue_StringValue:
+		str := strings.TrimSpace(v.StringValue)
+		if str == "" || strings.EqualFold(str, "none") {
+			if required {
+				return NoChannel, errors.Errorf("channel setting %q is required", key)
+			}
+			return NoChannel, nil
+		}
+		if m := channelHeader.FindStringSubmatch(str); m != nil {
+			str = m[1]
+		}
+		if n, err := strconv.Atoi(str); err == nil {
+			if n < 0 {
+				return NoChannel, errors.Errorf("%q: invalid channel %q", key, v.StringValue)
+			}
+			return Ch(n), nil
+		}
+		return ChannelRef{Index: NoChannel.Index, Name: str}, nil
+	default:
+		return NoChannel, errors.Errorf("%q: channel must be an integer or a column name", key)
+	}
+}
+
+// Number returns a numeric setting. Strings are read up to the first non-numeric
+// character, so "8 Bits per Transfer (Standard)" is 8.
+func (s Settings) Number(key string, def float64) (float64, error) {
+	sv, ok := s[key]
+	if !ok || sv == nil {
+		return def, nil
+	}
+	switch v := sv.GetValue().(type) {
+	case *pb.AnalyzerSettingValue_Int64Value:
+		return float64(v.Int64Value), nil
+	case *pb.AnalyzerSettingValue_DoubleValue:
+		return v.DoubleValue, nil
+	case *pb.AnalyzerSettingValue_StringValue:
+		m := leadingNumber.FindStringSubmatch(v.StringValue)
+		if m == nil {
+			return 0, errors.Errorf("%q: expected a number, got %q", key, v.StringValue)
+		}
+		return strconv.ParseFloat(m[1], 64)
+	default:
+		return 0, errors.Errorf("%q: expected a number", key)
+	}
+}
+
+// Int is Number for settings that must be whole.
+func (s Settings) Int(key string, def int) (int, error) {
+	f, err := s.Number(key, float64(def))
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, errors.Errorf("%q: expected an integer, got %v", key, f)
+	}
+	return int(f), nil
+}
+
+// Text returns a setting as lower-case text (numbers and bools formatted), or "" when
+// missing.
+func (s Settings) Text(key string) string {
+	sv, ok := s[key]
+	if !ok || sv == nil {
+		return ""
+	}
+	switch v := sv.GetValue().(type) {
+	case *pb.AnalyzerSettingValue_StringValue:
+		return strings.ToLower(strings.TrimSpace(v.StringValue))
+	case *pb.AnalyzerSettingValue_Int64Value:
+		return strconv.FormatInt(v.Int64Value, 10)
+	case *pb.AnalyzerSettingValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *pb.AnalyzerSettingValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	default:
+		return ""
+	}
+}
+
+// Has reports whether key is set.
+func (s Settings) Has(key string) bool {
+	sv, ok := s[key]
+	return ok && sv != nil
+}
+
+// msbFirst reads a "Significant Bit" setting ("MSB", "LSB", or the dropdown text).
+func (s Settings) msbFirst(def bool) (bool, error) {
+	t := s.Text("Significant Bit")
+	switch {
+	case t == "":
+		return def, nil
+	case t == "msb" || strings.HasPrefix(t, "most"):
+		return true, nil
+	case t == "lsb" || strings.HasPrefix(t, "least"):
+		return false, nil
+	default:
+		return false, errors.Errorf("\"Significant Bit\": unknown value %q", t)
+	}
+}
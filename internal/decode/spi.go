@@ -0,0 +1,187 @@
+package decode
+
+import (
+	"iter"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+// SPI decodes words on MOSI/MISO, framed by an optional enable line. Frames are
+// "enable", "result" (mosi, miso) and "disable", as in Logic 2's SPI table.
+type SPI struct {
+	Clock, MOSI, MISO, Enable ChannelRef
+	// CPOL is the idle clock level; CPHA 0 samples on the leading edge, 1 on the trailing.
+	CPOL, CPHA       uint8
+	BitsPerTransfer  int
+	MSBFirst         bool
+	EnableActiveHigh bool
+}
+
+// NewSPI reads the keys of configs/analyzers/spi.yaml.
+func NewSPI(s Settings) (Decoder, error) {
+	d := &SPI{}
+	var err error
+	if d.Clock, err = s.Channel("Clock", true); err != nil {
+		return nil, err
+	}
+	if d.MOSI, err = s.Channel("MOSI", false); err != nil {
+		return nil, err
+	}
+	if d.MISO, err = s.Channel("MISO", false); err != nil {
+		return nil, err
+	}
+	if d.Enable, err = s.Channel("Enable", false); err != nil {
+		return nil, err
+	}
+	if d.MOSI == NoChannel && d.MISO == NoChannel {
+		return nil, errors.New("at least one of MOSI and MISO is required")
+	}
+	if d.BitsPerTransfer, err = s.Int("Bits per Transfer", 8); err != nil {
+		return nil, err
+	}
+	if d.BitsPerTransfer < 1 || d.BitsPerTransfer > 64 {
+		return nil, errors.Errorf("\"Bits per Transfer\": expected 1..64, got %d", d.BitsPerTransfer)
+	}
+	if d.MSBFirst, err = s.msbFirst(true); err != nil {
+		return nil, err
+	}
+	if d.CPOL, err = clockSetting(s.Text("Clock State"), "cpol = 1", "high"); err != nil {
+		return nil, errors.Wrap(err, "\"Clock State\"")
+	}
+	if d.CPHA, err = clockSetting(s.Text("Clock Phase"), "cpha = 1", "trailing"); err != nil {
+		return nil, errors.Wrap(err, "\"Clock Phase\"")
+	}
+	d.EnableActiveHigh = strings.Contains(s.Text("Enable Line"), "active high")
+	return d, nil
+}
+
+// clockSetting reads CPOL/CPHA given as 0/1 or as dropdown text.
+func clockSetting(text string, one ...string) (uint8, error) {
+	switch text {
+	case "", "0":
+		return 0, nil
+	case "1":
+		return 1, nil
+	}
+	for _, marker := range one {
+		if strings.Contains(text, marker) {
+			return 1, nil
+		}
+	}
+	if strings.Contains(text, "= 0") || strings.Contains(text, "low") || strings.Contains(text, "leading") {
+		return 0, nil
+	}
+	return 0, errors.Errorf("unknown value %q", text)
+}
+
+// Channels returns clock, then the connected MOSI, MISO and Enable channels.
+func (d *SPI) Channels() []ChannelRef {
+	out := []ChannelRef{d.Clock}
+	for _, ch := range []ChannelRef{d.MOSI, d.MISO, d.Enable} {
+		if ch != NoChannel {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+type spiWord struct {
+	start float64
+	end   float64
+	mosi  uint64
+	miso  uint64
+	n     int
+}
+
+// Decode samples MOSI/MISO on the configured clock edge. A word cut short by the
+// enable line going inactive is dropped.
+func (d *SPI) Decode(sources []rawdata.DigitalSource) iter.Seq2[Frame, error] {
+	return func(yield func(Frame, error) bool) {
+		if len(sources) != len(d.Channels()) {
+			yield(Frame{}, errors.Errorf("spi: expected %d sources, got %d", len(d.Channels()), len(sources)))
+			return
+		}
+		// Source positions follow Channels(); -1 for unconnected lines.
+		mosi, miso, enable := -1, -1, -1
+		pos := 1
+		for _, line := range []struct {
+			ch  ChannelRef
+			idx *int
+		}{{d.MOSI, &mosi}, {d.MISO, &miso}, {d.Enable, &enable}} {
+			if line.ch != NoChannel {
+				*line.idx = pos
+				pos++
+			}
+		}
+		levels := make([]uint8, len(sources))
+		for i, src := range sources {
+			levels[i] = src.InitialState()
+		}
+		active := func() bool {
+			if enable < 0 {
+				return true
+			}
+			return (levels[enable] == 1) == d.EnableActiveHigh
+		}
+		bit := func(idx int) uint64 {
+			if idx < 0 {
+				return 0
+			}
+			return uint64(levels[idx])
+		}
+
+		var word spiWord
+		for e, err := range rawdata.Merge(sources) {
+			if err != nil {
+				yield(Frame{}, err)
+				return
+			}
+			wasActive := active()
+			levels[e.Index] = e.State
+			switch {
+			case e.Index == enable:
+				if nowActive := active(); nowActive != wasActive {
+					word = spiWord{}
+					typ := "disable"
+					if nowActive {
+						typ = "enable"
+					}
+					if !yield(Frame{Type: typ, Start: e.Time, End: e.Time}, nil) {
+						return
+					}
+				}
+			case e.Index == 0 && wasActive:
+				if (e.State != d.CPOL) != (d.CPHA == 0) {
+					continue
+				}
+				if word.n == 0 {
+					word.start = e.Time
+				}
+				word.end = e.Time
+				shift := word.n
+				if d.MSBFirst {
+					shift = d.BitsPerTransfer - 1 - word.n
+				}
+				word.mosi |= bit(mosi) << shift
+				word.miso |= bit(miso) << shift
+				word.n++
+				if word.n < d.BitsPerTransfer {
+					continue
+				}
+				f := Frame{Type: "result", Start: word.start, End: word.end}
+				if mosi >= 0 {
+					f.Fields = append(f.Fields, Field{Name: "mosi", Value: word.mosi, Bits: d.BitsPerTransfer})
+				}
+				if miso >= 0 {
+					f.Fields = append(f.Fields, Field{Name: "miso", Value: word.miso, Bits: d.BitsPerTransfer})
+				}
+				word = spiWord{}
+				if !yield(f, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package decode
+
+import (
+	"iter"
+	"math/bits"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+// Parity is the UART parity mode.
+type Parity int
+
+const (
+	ParityNone Parity = iota
+	ParityEven
+	ParityOdd
+)
+
+// UART decodes asynchronous serial frames (Logic 2's "Async Serial"). Frames are
+// "data" with an "error" field set to "framing" or "parity" when the frame is bad.
+type UART struct {
+	Input        ChannelRef
+	BitRate      float64
+	BitsPerFrame int
+	Parity       Parity
+	StopBits     float64
+	MSBFirst     bool
+	Inverted     bool
+}
+
+// NewUART reads the keys of configs/analyzers/uart.yaml, in either the short form or
+// the dropdown text Logic 2 saves.
+func NewUART(s Settings) (Decoder, error) {
+	d := &UART{}
+	var err error
+	if d.Input, err = s.Channel("Input Channel", true); err != nil {
+		return nil, err
+	}
+	if d.BitRate, err = s.Number("Bit Rate (Bits/s)", 0); err != nil {
+		return nil, err
+	}
+	if d.BitRate <= 0 {
+		return nil, errors.New("\"Bit Rate (Bits/s)\" is required")
+	}
+	if d.BitsPerFrame, err = s.Int("Bits per Frame", 8); err != nil {
+		return nil, err
+	}
+	if d.BitsPerFrame < 1 || d.BitsPerFrame > 64 {
+		return nil, errors.Errorf("\"Bits per Frame\": expected 1..64, got %d", d.BitsPerFrame)
+	}
+	if d.StopBits, err = s.Number("Stop Bits", 1); err != nil {
+		return nil, err
+	}
+	if d.StopBits <= 0 {
+		return nil, errors.Errorf("\"Stop Bits\": expected a positive count, got %v", d.StopBits)
+	}
+	if d.MSBFirst, err = s.msbFirst(false); err != nil {
+		return nil, err
+	}
+	switch p := s.Text("Parity Bit"); {
+	case strings.Contains(p, "even"):
+		d.Parity = ParityEven
+	case strings.Contains(p, "odd"):
+		d.Parity = ParityOdd
+	case p == "" || p == "none" || strings.HasPrefix(p, "no "):
+		d.Parity = ParityNone
+	default:
+		return nil, errors.Errorf("\"Parity Bit\": unknown value %q", p)
+	}
+	d.Inverted = s.Text("Inverted") == "true"
+	if inv := s.Text("Signal inversion"); inv != "" && !strings.HasPrefix(inv, "non") {
+		d.Inverted = true
+	}
+	if mode := s.Text("Mode"); mode != "" && mode != "normal" {
+		return nil, errors.Errorf("\"Mode\": only Normal is supported offline, got %q", mode)
+	}
+	return d, nil
+}
+
+// Channels returns the input channel.
+func (d *UART) Channels() []ChannelRef { return []ChannelRef{d.Input} }
+
+// Decode finds each start bit's leading edge and samples the following bits at their
+// centres. A frame running past the end of the capture is dropped.
+func (d *UART) Decode(sources []rawdata.DigitalSource) iter.Seq2[Frame, error] {
+	return func(yield func(Frame, error) bool) {
+		if len(sources) != 1 {
+			yield(Frame{}, errors.Errorf("uart: expected 1 source, got %d", len(sources)))
+			return
+		}
+		src := sources[0]
+		idle := uint8(1)
+		if d.Inverted {
+			idle = 0
+		}
+		next, stop := iter.Pull2(src.Transitions())
+		defer stop()
+
+		level := src.InitialState()
+		var head rawdata.Transition
+		more := true
+		advance := func() error {
+			tr, err, ok := next()
+			head, more = tr, ok
+			return err
+		}
+		// levelAt consumes transitions up to t and returns the line level there.
+		levelAt := func(t float64) (uint8, error) {
+			for more && head.Time <= t {
+				level = head.State
+				if err := advance(); err != nil {
+					return 0, err
+				}
+			}
+			return level, nil
+		}
+		if err := advance(); err != nil {
+			yield(Frame{}, err)
+			return
+		}
+
+		period := 1 / d.BitRate
+		parityBits := 0
+		if d.Parity != ParityNone {
+			parityBits = 1
+		}
+		for more {
+			// Wait for the line to leave idle: that edge starts a frame.
+			if !(level == idle && head.State != idle) {
+				level = head.State
+				if err := advance(); err != nil {
+					yield(Frame{}, err)
+					return
+				}
+				continue
+			}
+			t0 := head.Time
+			last := t0 + (1.5+float64(d.BitsPerFrame+parityBits))*period
+			sample := func(i int) (uint8, error) {
+				l, err := levelAt(t0 + (1.5+float64(i))*period)
+				if d.Inverted {
+					l ^= 1
+				}
+				return l, err
+			}
+
+			var value uint64
+			for i := 0; i < d.BitsPerFrame; i++ {
+				b, err := sample(i)
+				if err != nil {
+					yield(Frame{}, err)
+					return
+				}
+				shift := i
+				if d.MSBFirst {
+					shift = d.BitsPerFrame - 1 - i
+				}
+				value |= uint64(b) << shift
+			}
+			errKind := ""
+			if parityBits > 0 {
+				b, err := sample(d.BitsPerFrame)
+				if err != nil {
+					yield(Frame{}, err)
+					return
+				}
+				ones := bits.OnesCount64(value) + int(b)
+				if (d.Parity == ParityEven) != (ones%2 == 0) {
+					errKind = "parity"
+				}
+			}
+			b, err := sample(d.BitsPerFrame + parityBits)
+			if err != nil {
+				yield(Frame{}, err)
+				return
+			}
+			if !more && last > src.EndTime() {
+				return
+			}
+			if b != 1 {
+				errKind = "framing"
+			}
+			f := Frame{
+				Type:   "data",
+				Start:  t0,
+				End:    t0 + (1+float64(d.BitsPerFrame+parityBits)+d.StopBits)*period,
+				Fields: []Field{{Name: "data", Value: value, Bits: d.BitsPerFrame}},
+			}
+			if errKind != "" {
+				f.Fields = append(f.Fields, Field{Name: "error", Value: errKind})
+			}
+			if !yield(f, nil) {
+				return
+			}
+		}
+	}
+}
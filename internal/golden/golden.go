@@ -0,0 +1,272 @@
+// Package golden implements snapshot tests for decoded bus traffic: the data tables a
+// pipeline exports are recorded once into a golden directory and later runs are
+// compared against them with internal/tablediff.
+//
+// Golden tables are normalized so they only change when the decoded traffic does:
+// start times are relative to the first row (no absolute or ISO8601 timestamps) and
+// nothing refers to capture or analyzer IDs. A golden directory holds the tables plus
+// a manifest:
+//
+//	golden.yaml
+//	00-table.csv
+//	02-spi.csv
+package golden
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/tablediff"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the manifest name inside a golden directory.
+const ManifestFile = "golden.yaml"
+
+// Manifest describes a golden directory.
+type Manifest struct {
+	Version int `yaml:"version"`
+	// Config is the pipeline config the goldens were recorded with (informational).
+	Config string `yaml:"config,omitempty"`
+	// Tolerance, if set, compares frames by start time within this duration ("5us");
+	// otherwise frames are compared by order and timing is ignored.
+	Tolerance string `yaml:"tolerance,omitempty"`
+	// Ignore lists field columns left out of comparisons.
+	Ignore []string     `yaml:"ignore,omitempty"`
+	Tables []TableEntry `yaml:"tables"`
+}
+
+// TableEntry is one recorded data table.
+type TableEntry struct {
+	// Export is the index of the table-csv export in the pipeline config.
+	Export int `yaml:"export"`
+	// File is the golden table, relative to the golden directory.
+	File  string `yaml:"file"`
+	Radix string `yaml:"radix,omitempty"`
+	Rows  int    `yaml:"rows"`
+}
+
+// Table is a data table produced by a pipeline run.
+type Table struct {
+	Export int
+	Path   string
+	Radix  string
+}
+
+// Options are the comparison settings stored in the manifest.
+type Options struct {
+	Config    string
+	Tolerance string
+	Ignore    []string
+}
+
+// Record normalizes tables into dir and writes the manifest, replacing earlier goldens.
+// Everything is written to a staging directory first and moved into place only once
+// it is complete, so a failed recording leaves the previous goldens intact.
+func Record(dir string, tables []Table, opts Options) (*Manifest, error) {
+	if len(tables) == 0 {
+		return nil, errors.New("no table-csv exports to record (add a table-csv export to the pipeline)")
+	}
+	if _, err := parseTolerance(opts.Tolerance); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "create golden directory %s", dir)
+	}
+	staging, err := os.MkdirTemp(dir, ".record-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "create staging directory in %s", dir)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	m := &Manifest{Version: 1, Config: opts.Config, Tolerance: opts.Tolerance, Ignore: opts.Ignore}
+	for _, t := range tables {
+		name := fmt.Sprintf("%02d-%s", t.Export, filepath.Base(t.Path))
+		rows, err := normalizeFile(t.Path, filepath.Join(staging, name))
+		if err != nil {
+			return nil, err
+		}
+		m.Tables = append(m.Tables, TableEntry{Export: t.Export, File: name, Radix: t.Radix, Rows: rows})
+	}
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode golden manifest")
+	}
+	if err := os.WriteFile(filepath.Join(staging, ManifestFile), b, 0o644); err != nil {
+		return nil, errors.Wrapf(err, "write %s", ManifestFile)
+	}
+
+	old, _ := LoadManifest(dir)
+	// The manifest goes last: until it is replaced, the old one still names the files
+	// it was recorded with.
+	keep := map[string]bool{}
+	for _, name := range append(tableFiles(m), ManifestFile) {
+		keep[name] = true
+		if err := os.Rename(filepath.Join(staging, name), filepath.Join(dir, name)); err != nil {
+			return nil, errors.Wrapf(err, "move %s into %s", name, dir)
+		}
+	}
+	if old != nil {
+		for _, name := range tableFiles(old) {
+			if !keep[name] {
+				_ = os.Remove(filepath.Join(dir, name))
+			}
+		}
+	}
+	return m, nil
+}
+
+func tableFiles(m *Manifest) []string {
+	names := make([]string, len(m.Tables))
+	for i, t := range m.Tables {
+		names[i] = t.File
+	}
+	return names
+}
+
+// LoadManifest reads dir/golden.yaml.
+func LoadManifest(dir string) (*Manifest, error) {
+	path := filepath.Join(dir, ManifestFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read golden manifest %s", path)
+	}
+	m := &Manifest{}
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, errors.Wrapf(err, "decode golden manifest %s", path)
+	}
+	if m.Version != 1 {
+		return nil, errors.Errorf("%s: unsupported golden manifest version %d", path, m.Version)
+	}
+	return m, nil
+}
+
+// TableResult is the comparison of one golden table.
+type TableResult struct {
+	Golden string
+	Actual string
+	Diff   tablediff.Result
+}
+
+// Check compares tables against the goldens in dir. Every golden must have a table
+// from the same export; extra tables are an error too, since they mean the pipeline
+// changed without re-recording.
+func Check(dir string, tables []Table) ([]TableResult, error) {
+	m, err := LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	tolerance, err := parseTolerance(m.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+	opts := tablediff.Options{Ignore: m.Ignore}
+	if m.Tolerance != "" {
+		opts.Align, opts.Tolerance = tablediff.AlignTime, tolerance
+	}
+
+	byExport := map[int]Table{}
+	for _, t := range tables {
+		byExport[t.Export] = t
+	}
+	var out []TableResult
+	for _, g := range m.Tables {
+		t, ok := byExport[g.Export]
+		if !ok {
+			return nil, errors.Errorf("golden %s: the pipeline no longer has table-csv export #%d", g.File, g.Export)
+		}
+		delete(byExport, g.Export)
+
+		golden, err := datatable.Open(filepath.Join(dir, g.File))
+		if err != nil {
+			return nil, err
+		}
+		actual, err := datatable.Open(t.Path)
+		if err != nil {
+			return nil, err
+		}
+		o := opts
+		o.RadixA, o.RadixB = g.Radix, t.Radix
+		res, err := tablediff.Diff(golden, actual, o)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, TableResult{Golden: filepath.Join(dir, g.File), Actual: t.Path, Diff: res})
+	}
+	if len(byExport) > 0 {
+		extra := make([]int, 0, len(byExport))
+		for export := range byExport {
+			extra = append(extra, export)
+		}
+		sort.Ints(extra)
+		return nil, errors.Errorf("table-csv export #%d has no golden; re-record with `salad golden record`", extra[0])
+	}
+	return out, nil
+}
+
+func parseTolerance(s string) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d < 0 {
+		return 0, errors.Errorf("invalid tolerance %q (expected a duration like 5us)", s)
+	}
+	return d.Seconds(), nil
+}
+
+// normalizeFile rewrites a data table with start times relative to its first row.
+func normalizeFile(src, dst string) (int, error) {
+	table, err := datatable.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return 0, errors.Wrapf(err, "create %s", dst)
+	}
+	w, err := datatable.NewWriter(f, table.FieldColumns())
+	if err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+
+	fields := table.FieldColumns()
+	index := make([]int, len(fields))
+	for i, name := range fields {
+		index[i], _ = table.Index(name)
+	}
+	values := map[string]string{}
+	origin := math.NaN()
+	rows := 0
+	for row, err := range table.Rows() {
+		if err != nil {
+			_ = f.Close()
+			return 0, err
+		}
+		if math.IsNaN(origin) {
+			origin = row.Start
+		}
+		clear(values)
+		for i, name := range fields {
+			values[name] = row.Values[index[i]]
+		}
+		if err := w.Write(row.Name, row.Type, row.Start-origin, row.Duration, values); err != nil {
+			_ = f.Close()
+			return 0, err
+		}
+		rows++
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+	return rows, errors.Wrapf(f.Close(), "close %s", dst)
+}
@@ -0,0 +1,115 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const recorded = `name,type,start_time,duration,"data"
+"spi","result",12.000100000,0.000002000,0x3C
+"spi","result",12.000300000,0.000002000,0xFF
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestRecordAndCheck(t *testing.T) {
+	dir := t.TempDir()
+	goldenDir := filepath.Join(dir, "golden")
+	path := writeFile(t, dir, "table.csv", recorded)
+
+	m, err := Record(goldenDir, []Table{{Export: 2, Path: path, Radix: "hex"}}, Options{Tolerance: "5us"})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if len(m.Tables) != 1 || m.Tables[0].File != "02-table.csv" || m.Tables[0].Rows != 2 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	b, err := os.ReadFile(filepath.Join(goldenDir, "02-table.csv"))
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if !strings.Contains(string(b), "spi,result,0.000000000,") || strings.Contains(string(b), "12.0") {
+		t.Fatalf("expected relative start times, got:\n%s", b)
+	}
+
+	// A later run: other trigger time, dec radix, 2µs of drift.
+	same := writeFile(t, dir, "same.csv", `name,type,start_time,duration,"data"
+"spi","result",3.000100000,0.000002000,60
+"spi","result",3.000302000,0.000002000,255
+`)
+	results, err := Check(goldenDir, []Table{{Export: 2, Path: same, Radix: "dec"}})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(results) != 1 || results[0].Diff.Differs() {
+		t.Fatalf("expected no differences, got %+v", results)
+	}
+
+	changed := writeFile(t, dir, "changed.csv", `name,type,start_time,duration,"data"
+"spi","result",3.000100000,0.000002000,0x3C
+"spi","result",3.000300000,0.000002000,0xFE
+`)
+	results, err = Check(goldenDir, []Table{{Export: 2, Path: changed, Radix: "hex"}})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if !results[0].Diff.Differs() || results[0].Diff.Analyzers[0].Changed != 1 {
+		t.Fatalf("expected one changed frame, got %+v", results[0].Diff.Analyzers)
+	}
+
+	if _, err := Check(goldenDir, []Table{{Export: 3, Path: same}}); err == nil || !strings.Contains(err.Error(), "no longer has") {
+		t.Fatalf("expected missing export error, got %v", err)
+	}
+}
+
+func TestRecord_Errors(t *testing.T) {
+	if _, err := Record(t.TempDir(), nil, Options{}); err == nil {
+		t.Fatalf("expected error without tables")
+	}
+	path := writeFile(t, t.TempDir(), "table.csv", recorded)
+	if _, err := Record(t.TempDir(), []Table{{Path: path}}, Options{Tolerance: "soon"}); err == nil {
+		t.Fatalf("expected tolerance error")
+	}
+}
+
+func TestRecord_FailureKeepsGoldens(t *testing.T) {
+	dir := t.TempDir()
+	goldenDir := filepath.Join(dir, "golden")
+	path := writeFile(t, dir, "table.csv", recorded)
+	if _, err := Record(goldenDir, []Table{{Export: 2, Path: path}}, Options{}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	missing := filepath.Join(dir, "missing.csv")
+	if _, err := Record(goldenDir, []Table{{Export: 0, Path: path}, {Export: 1, Path: missing}}, Options{}); err == nil {
+		t.Fatalf("expected error for a missing table")
+	}
+	results, err := Check(goldenDir, []Table{{Export: 2, Path: path}})
+	if err != nil {
+		t.Fatalf("Check after a failed recording: %v", err)
+	}
+	if results[0].Diff.Differs() {
+		t.Fatalf("expected the old golden to be unchanged, got %+v", results[0].Diff)
+	}
+	entries, _ := os.ReadDir(goldenDir)
+	if len(entries) != 2 {
+		t.Fatalf("expected only the manifest and one table, got %v", entries)
+	}
+
+	// Re-recording under other export indexes removes the stale table.
+	if _, err := Record(goldenDir, []Table{{Export: 0, Path: path}}, Options{}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(goldenDir, "02-table.csv")); !os.IsNotExist(err) {
+		t.Fatalf("expected 02-table.csv to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Package httpjson holds the JSON request/response helpers shared by the HTTP APIs of
+// salad serve (internal/server) and the mock admin interface (internal/mock/saleae):
+// handlers return errors, and an *Error carries the HTTP status to answer it with.
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// MaxBodyBytes bounds request bodies (pipeline and mock configs are the largest).
+const MaxBodyBytes = 1 << 20
+
+// Error is an error with the HTTP status to answer it with.
+type Error struct {
+	Code int
+	Msg  string
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+// Errorf returns an *Error with the given status.
+func Errorf(code int, format string, args ...any) error {
+	return &Error{Code: code, Msg: errors.Errorf(format, args...).Error()}
+}
+
+func BadRequest(format string, args ...any) error {
+	return Errorf(http.StatusBadRequest, format, args...)
+}
+
+func NotFound(format string, args ...any) error {
+	return Errorf(http.StatusNotFound, format, args...)
+}
+
+// StatusOf returns the status carried by err, if it wraps an *Error.
+func StatusOf(err error) (int, bool) {
+	var he *Error
+	if errors.As(err, &he) {
+		return he.Code, true
+	}
+	return 0, false
+}
+
+// Write writes v as indented JSON with the given status.
+func Write(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// WriteError writes err as {"error": "..."} with the given status.
+func WriteError(w http.ResponseWriter, code int, err error) {
+	Write(w, code, map[string]string{"error": err.Error()})
+}
+
+// Read decodes the request body strictly into v.
+func Read(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return BadRequest("decode request body: %v", err)
+	}
+	return nil
+}
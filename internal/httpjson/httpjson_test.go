@@ -0,0 +1,30 @@
+package httpjson
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestReadAndStatusOf(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"a","extra":1}`))
+	err := Read(httptest.NewRecorder(), r, &v)
+	if code, ok := StatusOf(errors.Wrap(err, "submit")); !ok || code != http.StatusBadRequest {
+		t.Fatalf("expected a 400 for an unknown field, got %v (%d)", err, code)
+	}
+	if _, ok := StatusOf(errors.New("plain")); ok {
+		t.Fatalf("expected no status for a plain error")
+	}
+
+	w := httptest.NewRecorder()
+	WriteError(w, http.StatusConflict, NotFound("job %d not found", 3))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"error": "job 3 not found"`) {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,72 @@
+package jobqueue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Artifact is a file written by a job's exports.
+type Artifact struct {
+	Index int `json:"index"`
+	// Name is the path relative to the directory shared by all artifacts of the job,
+	// used in archives.
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// collectArtifacts turns the files written by a job into artifacts. Files that an
+// export did not write (e.g. a channel without data) are skipped; directories are
+// rejected, so a job can never publish more than the files its exports name.
+func collectArtifacts(paths []string) ([]Artifact, error) {
+	var out []Artifact
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		info, err := os.Stat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "stat artifact %s", p)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, errors.Errorf("artifact %s is not a regular file", p)
+		}
+		out = append(out, Artifact{Index: len(out), Path: p, Size: info.Size()})
+	}
+	base := commonDir(out)
+	for i := range out {
+		name, err := filepath.Rel(base, out[i].Path)
+		if err != nil {
+			name = filepath.Base(out[i].Path)
+		}
+		out[i].Name = filepath.ToSlash(name)
+	}
+	return out, nil
+}
+
+// commonDir returns the deepest directory containing every artifact.
+func commonDir(artifacts []Artifact) string {
+	if len(artifacts) == 0 {
+		return ""
+	}
+	dir := filepath.Dir(artifacts[0].Path)
+	for _, a := range artifacts[1:] {
+		for !within(dir, a.Path) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return dir
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
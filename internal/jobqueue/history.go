@@ -0,0 +1,107 @@
+package jobqueue
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The history directory holds <id>.json (the job) and <id>.log (its log) per job.
+
+func (q *Queue) jobPath(id uint64, ext string) string {
+	return filepath.Join(q.opts.Dir, strconv.FormatUint(id, 10)+ext)
+}
+
+// save writes a job atomically. It is a no-op without a history directory.
+func (q *Queue) save(e *entry) error {
+	if q.opts.Dir == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(e.job, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "encode job %d", e.job.ID)
+	}
+	path := q.jobPath(e.job.ID, ".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return errors.Wrapf(err, "write job %d", e.job.ID)
+	}
+	return errors.Wrapf(os.Rename(tmp, path), "write job %d", e.job.ID)
+}
+
+func (q *Queue) appendLog(id uint64, line string) error {
+	if q.opts.Dir == "" {
+		return nil
+	}
+	f, err := os.OpenFile(q.jobPath(id, ".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "open log of job %d", id)
+	}
+	_, err = f.WriteString(line + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return errors.Wrapf(err, "write log of job %d", id)
+}
+
+// load reads the history directory, creating it if needed.
+func (q *Queue) load() error {
+	if err := os.MkdirAll(q.opts.Dir, 0o755); err != nil {
+		return errors.Wrapf(err, "create job history directory %s", q.opts.Dir)
+	}
+	paths, err := filepath.Glob(filepath.Join(q.opts.Dir, "*.json"))
+	if err != nil {
+		return errors.Wrap(err, "list job history")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "read job %s", path)
+		}
+		e := &entry{}
+		if err := json.Unmarshal(b, &e.job); err != nil {
+			return errors.Wrapf(err, "decode job %s", path)
+		}
+		if e.log, err = readLines(q.jobPath(e.job.ID, ".log")); err != nil {
+			return err
+		}
+		q.entries[e.job.ID] = e
+		q.order = append(q.order, e.job.ID)
+		q.next = max(q.next, e.job.ID)
+		if e.job.Status == Running {
+			e.job.Status, e.job.Error, e.job.Finished = Failed, "interrupted: the server stopped while the job was running", now()
+			q.logLocked(e, "%s", e.job.Error)
+			q.saveLocked(e)
+		}
+	}
+	sort.Slice(q.order, func(a, b int) bool { return q.order[a] < q.order[b] })
+	return nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", path)
+	}
+	defer func() { _ = f.Close() }()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		if line := strings.TrimRight(sc.Text(), "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, errors.Wrapf(sc.Err(), "read %s", path)
+}
@@ -0,0 +1,344 @@
+// Package jobqueue runs the pipeline jobs of `salad serve`. Jobs wait in a priority
+// queue and run one at a time, or in parallel with locks on the devices and captures
+// they declare, so that jobs sharing one Logic 2 instance don't interleave on the same
+// hardware. Each job keeps a log, and the queue can persist its history to a directory.
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	Queued    Status = "queued"
+	Running   Status = "running"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+	Cancelled Status = "cancelled"
+)
+
+// Done reports whether the job has finished.
+func (s Status) Done() bool {
+	return s == Succeeded || s == Failed || s == Cancelled
+}
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrDone     = errors.New("job already finished")
+)
+
+// Job is a pipeline run.
+type Job struct {
+	ID     uint64 `json:"id"`
+	Status Status `json:"status"`
+	// Priority orders queued jobs: higher runs first, ties run in submission order.
+	Priority int `json:"priority"`
+	// Devices are the Logic devices the job uses; in parallel mode jobs sharing a device
+	// (or running against the same capture) never run at the same time.
+	Devices []string `json:"devices,omitempty"`
+	// ConfigPath is the submitted config file, empty for inline configs.
+	ConfigPath string           `json:"config_path,omitempty"`
+	Config     *pipeline.Config `json:"config,omitempty"`
+	// CaptureID is the open capture the job runs against (if submitted with one), then
+	// the capture it used.
+	CaptureID uint64            `json:"capture_id,omitempty"`
+	Analyzers map[string]uint64 `json:"analyzers,omitempty"`
+	Artifacts []Artifact        `json:"artifacts,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Created   *time.Time        `json:"created"`
+	Started   *time.Time        `json:"started,omitempty"`
+	Finished  *time.Time        `json:"finished,omitempty"`
+}
+
+// locks returns the keys a job holds while it runs in parallel mode.
+func (j *Job) locks() []string {
+	keys := make([]string, 0, len(j.Devices)+1)
+	for _, d := range j.Devices {
+		keys = append(keys, "device:"+d)
+	}
+	if j.CaptureID != 0 {
+		keys = append(keys, "capture:"+strconv.FormatUint(j.CaptureID, 10))
+	}
+	return keys
+}
+
+// Outcome is what a successful run produced.
+type Outcome struct {
+	CaptureID uint64
+	Analyzers map[string]uint64
+	// Paths are the files written by exports.
+	Paths []string
+}
+
+// RunFunc runs a job. logf appends a line to the job's log.
+type RunFunc func(ctx context.Context, j Job, logf func(format string, args ...any)) (*Outcome, error)
+
+// Options configures a Queue.
+type Options struct {
+	Run RunFunc
+	// Parallel runs jobs concurrently unless they share a device or capture. Otherwise
+	// jobs run one at a time.
+	Parallel bool
+	// Dir, if set, persists every job and its log there and reloads them on start.
+	Dir string
+}
+
+type entry struct {
+	job   Job
+	log   []string
+	locks []string
+	// cancel stops a running job; cancelled records that a user asked for it.
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// Queue schedules and runs jobs.
+type Queue struct {
+	opts    Options
+	mu      sync.Mutex
+	entries map[uint64]*entry
+	order   []uint64
+	next    uint64
+	held    map[string]bool
+	running int
+	closed  bool
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New returns a queue, loading the history in opts.Dir. Jobs that were queued when the
+// previous queue stopped are run again; jobs that were running are marked failed.
+func New(opts Options) (*Queue, error) {
+	if opts.Run == nil {
+		return nil, errors.New("jobqueue: Run is required")
+	}
+	q := &Queue{opts: opts, entries: map[uint64]*entry{}, held: map[string]bool{}}
+	q.ctx, q.stop = context.WithCancel(context.Background())
+	if opts.Dir != "" {
+		if err := q.load(); err != nil {
+			return nil, err
+		}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.schedule()
+	return q, nil
+}
+
+// Submit queues a job. ID, Status and the timestamps are assigned by the queue.
+func (q *Queue) Submit(j Job) (Job, error) {
+	if j.Config == nil {
+		return Job{}, errors.New("job config is required")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return Job{}, errors.New("job queue is closed")
+	}
+	q.next++
+	j.ID, j.Status, j.Created = q.next, Queued, now()
+	j.Started, j.Finished, j.Error, j.Analyzers, j.Artifacts = nil, nil, "", nil, nil
+	e := &entry{job: j}
+	if err := q.save(e); err != nil {
+		q.next--
+		return Job{}, err
+	}
+	q.entries[j.ID] = e
+	q.order = append(q.order, j.ID)
+	q.logLocked(e, "queued with priority %d", j.Priority)
+	q.schedule()
+	return e.job, nil
+}
+
+// Get returns a job.
+func (q *Queue) Get(id uint64) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.entries[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// List returns all jobs in submission order.
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Job, 0, len(q.order))
+	for _, id := range q.order {
+		out = append(out, q.entries[id].job)
+	}
+	return out
+}
+
+// Log returns the log lines of a job from index since on, and the index after them.
+func (q *Queue) Log(id uint64, since int) ([]string, int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.entries[id]
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	since = min(max(since, 0), len(e.log))
+	return append([]string{}, e.log[since:]...), len(e.log), nil
+}
+
+// Cancel removes a queued job from the queue or stops a running one.
+func (q *Queue) Cancel(id uint64) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.entries[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	switch e.job.Status {
+	case Queued:
+		e.job.Status, e.job.Finished = Cancelled, now()
+		q.logLocked(e, "cancelled before it started")
+		q.saveLocked(e)
+	case Running:
+		e.cancelled = true
+		e.cancel()
+		q.logLocked(e, "cancelling")
+	case Succeeded, Failed, Cancelled:
+		return e.job, ErrDone
+	}
+	return e.job, nil
+}
+
+// Close stops scheduling, interrupts running jobs and waits for them. Queued jobs stay
+// queued in the history.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.stop()
+	q.wg.Wait()
+}
+
+// schedule starts the queued jobs that may run now. q.mu must be held.
+func (q *Queue) schedule() {
+	if q.closed {
+		return
+	}
+	var queued []*entry
+	for _, id := range q.order {
+		if e := q.entries[id]; e.job.Status == Queued {
+			queued = append(queued, e)
+		}
+	}
+	sort.SliceStable(queued, func(a, b int) bool {
+		return queued[a].job.Priority > queued[b].job.Priority
+	})
+	for _, e := range queued {
+		if !q.opts.Parallel && q.running > 0 {
+			return
+		}
+		locks := e.job.locks()
+		if q.opts.Parallel && q.anyHeld(locks) {
+			continue
+		}
+		q.start(e, locks)
+	}
+}
+
+func (q *Queue) anyHeld(keys []string) bool {
+	for _, k := range keys {
+		if q.held[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// start runs e in a goroutine. q.mu must be held.
+func (q *Queue) start(e *entry, locks []string) {
+	for _, k := range locks {
+		q.held[k] = true
+	}
+	e.locks = locks
+	q.running++
+	var ctx context.Context
+	ctx, e.cancel = context.WithCancel(q.ctx)
+	e.job.Status, e.job.Started = Running, now()
+	q.logLocked(e, "started")
+	q.saveLocked(e)
+
+	job := e.job
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		out, err := q.opts.Run(ctx, job, func(format string, args ...any) {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.logLocked(e, format, args...)
+		})
+		var artifacts []Artifact
+		if err == nil {
+			artifacts, err = collectArtifacts(out.Paths)
+		}
+		q.finish(e, out, artifacts, err)
+	}()
+}
+
+func (q *Queue) finish(e *entry, out *Outcome, artifacts []Artifact, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e.cancel()
+	for _, k := range e.locks {
+		delete(q.held, k)
+	}
+	q.running--
+	e.job.Finished = now()
+	switch {
+	case err == nil:
+		e.job.Status = Succeeded
+		e.job.CaptureID, e.job.Analyzers, e.job.Artifacts = out.CaptureID, out.Analyzers, artifacts
+		q.logLocked(e, "succeeded with %d artifacts", len(artifacts))
+	case e.cancelled:
+		e.job.Status = Cancelled
+		q.logLocked(e, "cancelled: %v", err)
+	case q.ctx.Err() != nil:
+		e.job.Status, e.job.Error = Failed, "interrupted: the server shut down"
+		q.logLocked(e, "%s", e.job.Error)
+	default:
+		e.job.Status, e.job.Error = Failed, err.Error()
+		q.logLocked(e, "failed: %v", err)
+	}
+	q.saveLocked(e)
+	q.schedule()
+}
+
+// logLocked appends a timestamped line to the job log. q.mu must be held.
+func (q *Queue) logLocked(e *entry, format string, args ...any) {
+	line := time.Now().UTC().Format(time.RFC3339Nano) + " " + fmt.Sprintf(format, args...)
+	e.log = append(e.log, line)
+	if err := q.appendLog(e.job.ID, line); err != nil {
+		log.Warn().Err(err).Uint64("job_id", e.job.ID).Msg("persist job log")
+	}
+}
+
+// saveLocked persists a job, warning on failure (the job itself is not affected).
+func (q *Queue) saveLocked(e *entry) {
+	if err := q.save(e); err != nil {
+		log.Warn().Err(err).Uint64("job_id", e.job.ID).Msg("persist job")
+	}
+}
+
+func now() *time.Time {
+	t := time.Now().UTC()
+	return &t
+}
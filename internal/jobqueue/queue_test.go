@@ -0,0 +1,211 @@
+package jobqueue
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/pipeline"
+)
+
+// gatedRun is a RunFunc whose jobs block until released (or cancelled), reporting the
+// order in which they start.
+type gatedRun struct {
+	mu      sync.Mutex
+	gates   map[uint64]chan struct{}
+	started chan uint64
+}
+
+func newGatedRun() *gatedRun {
+	return &gatedRun{gates: map[uint64]chan struct{}{}, started: make(chan uint64, 16)}
+}
+
+func (g *gatedRun) gate(id uint64) chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.gates[id] == nil {
+		g.gates[id] = make(chan struct{})
+	}
+	return g.gates[id]
+}
+
+func (g *gatedRun) run(ctx context.Context, j Job, logf func(format string, args ...any)) (*Outcome, error) {
+	g.started <- j.ID
+	logf("working on %v", j.Devices)
+	select {
+	case <-g.gate(j.ID):
+		return &Outcome{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *gatedRun) release(id uint64) { close(g.gate(id)) }
+
+// expectStarted waits for exactly the given jobs to start, in any order.
+func (g *gatedRun) expectStarted(t *testing.T, want ...uint64) {
+	t.Helper()
+	pending := map[uint64]bool{}
+	for _, id := range want {
+		pending[id] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case got := <-g.started:
+			if !pending[got] {
+				t.Fatalf("expected jobs %v to start, got %d", want, got)
+			}
+			delete(pending, got)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected jobs %v to start", want)
+		}
+	}
+	select {
+	case got := <-g.started:
+		t.Fatalf("unexpected start of job %d", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func submit(t *testing.T, q *Queue, priority int, devices ...string) uint64 {
+	t.Helper()
+	j, err := q.Submit(Job{Priority: priority, Devices: devices, Config: &pipeline.Config{Version: 1}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	return j.ID
+}
+
+func waitDone(t *testing.T, q *Queue, id uint64) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		j, ok := q.Get(id)
+		if !ok {
+			t.Fatalf("job %d not found", id)
+		}
+		if j.Status.Done() {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d still %s", id, j.Status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueue_SerialRunsByPriority(t *testing.T) {
+	g := newGatedRun()
+	q, err := New(Options{Run: g.run})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer q.Close()
+
+	first := submit(t, q, 0)
+	g.expectStarted(t, first)
+	low, high := submit(t, q, 0), submit(t, q, 5)
+	g.expectStarted(t)
+
+	g.release(first)
+	g.expectStarted(t, high)
+	g.release(high)
+	g.expectStarted(t, low)
+	g.release(low)
+	if j := waitDone(t, q, low); j.Status != Succeeded {
+		t.Fatalf("unexpected job %+v", j)
+	}
+}
+
+func TestQueue_ParallelLocksDevices(t *testing.T) {
+	g := newGatedRun()
+	q, err := New(Options{Run: g.run, Parallel: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer q.Close()
+
+	a := submit(t, q, 0, "DEV1")
+	b := submit(t, q, 0, "DEV1")
+	c := submit(t, q, 0, "DEV2")
+	g.expectStarted(t, a, c)
+	if j, _ := q.Get(b); j.Status != Queued {
+		t.Fatalf("expected job %d to wait for DEV1, got %s", b, j.Status)
+	}
+	g.release(a)
+	g.expectStarted(t, b)
+	g.release(b)
+	g.release(c)
+	waitDone(t, q, b)
+	waitDone(t, q, c)
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	g := newGatedRun()
+	q, err := New(Options{Run: g.run})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer q.Close()
+
+	running, queued := submit(t, q, 0), submit(t, q, 0)
+	g.expectStarted(t, running)
+	if j, err := q.Cancel(queued); err != nil || j.Status != Cancelled {
+		t.Fatalf("cancel queued job: %+v, %v", j, err)
+	}
+	if _, err := q.Cancel(running); err != nil {
+		t.Fatalf("cancel running job: %v", err)
+	}
+	if j := waitDone(t, q, running); j.Status != Cancelled {
+		t.Fatalf("expected running job to be cancelled, got %+v", j)
+	}
+	g.expectStarted(t)
+	if _, err := q.Cancel(running); err != ErrDone {
+		t.Fatalf("expected ErrDone, got %v", err)
+	}
+	if _, err := q.Cancel(99); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_HistorySurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	g := newGatedRun()
+	q, err := New(Options{Run: g.run, Dir: dir})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	done, interrupted, pending := submit(t, q, 0, "DEV1"), submit(t, q, 0), submit(t, q, 0)
+	g.expectStarted(t, done)
+	g.release(done)
+	g.expectStarted(t, interrupted)
+	q.Close()
+
+	g = newGatedRun()
+	q, err = New(Options{Run: g.run, Dir: dir})
+	if err != nil {
+		t.Fatalf("New (restart): %v", err)
+	}
+	defer q.Close()
+	g.expectStarted(t, pending)
+	g.release(pending)
+
+	if j, _ := q.Get(done); j.Status != Succeeded || len(j.Devices) != 1 {
+		t.Fatalf("unexpected reloaded job %+v", j)
+	}
+	if j, _ := q.Get(interrupted); j.Status != Failed || !strings.Contains(j.Error, "interrupted") {
+		t.Fatalf("unexpected interrupted job %+v", j)
+	}
+	if j := waitDone(t, q, pending); j.Status != Succeeded {
+		t.Fatalf("unexpected resumed job %+v", j)
+	}
+	lines, next, err := q.Log(done, 0)
+	if err != nil || next != len(lines) || len(lines) != 4 || !strings.HasSuffix(lines[2], "working on [DEV1]") {
+		t.Fatalf("unexpected reloaded log (%v):\n%s", err, strings.Join(lines, "\n"))
+	}
+	if id := submit(t, q, 0); id != pending+1 {
+		t.Fatalf("expected IDs to continue after the history, got %d", id)
+	}
+}
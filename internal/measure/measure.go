@@ -0,0 +1,248 @@
+// Package measure computes timing statistics from raw digital exports: frequency,
+// period jitter, duty cycle, pulse widths, edge counts, and setup/hold times between a
+// data and a clock channel.
+//
+// All times are seconds in the export's time base. A Window restricts the analysis; a
+// pulse or period counts only if both of its edges fall inside the window.
+package measure
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+// Window bounds a measurement. Use All for the whole capture.
+type Window struct {
+	From float64
+	To   float64
+}
+
+// All is the unbounded window.
+var All = Window{From: math.Inf(-1), To: math.Inf(1)}
+
+// Stats summarizes a set of durations. Fields are NaN when Count is 0 (and Stddev when
+// Count < 2).
+type Stats struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Stddev float64
+	P50    float64
+	P90    float64
+	P99    float64
+}
+
+// Summarize sorts values in place and computes their statistics. Percentiles use the
+// nearest-rank method.
+func Summarize(values []float64) Stats {
+	nan := math.NaN()
+	s := Stats{Count: len(values), Min: nan, Max: nan, Mean: nan, Stddev: nan, P50: nan, P90: nan, P99: nan}
+	if len(values) == 0 {
+		return s
+	}
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	s.Min, s.Max, s.Mean = values[0], values[len(values)-1], sum/float64(len(values))
+	if len(values) > 1 {
+		var sq float64
+		for _, v := range values {
+			sq += (v - s.Mean) * (v - s.Mean)
+		}
+		s.Stddev = math.Sqrt(sq / float64(len(values)-1))
+	}
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(values)))) - 1
+		return values[max(i, 0)]
+	}
+	s.P50, s.P90, s.P99 = rank(0.5), rank(0.9), rank(0.99)
+	return s
+}
+
+// ChannelStats are the per-channel measurements.
+type ChannelStats struct {
+	Channel int
+	Name    string
+	// Start and End are the analysed span: the window clipped to the capture.
+	Start   float64
+	End     float64
+	Rising  int
+	Falling int
+	// Period is measured rising edge to rising edge; Frequency is 1/Period.Mean.
+	Period    Stats
+	Frequency float64
+	// DutyCycle is the fraction of [Start, End] spent high.
+	DutyCycle float64
+	// High and Low are the widths of complete pulses at each level.
+	High Stats
+	Low  Stats
+}
+
+// Channel measures one digital source inside w.
+func Channel(src rawdata.DigitalSource, w Window) (ChannelStats, error) {
+	st := ChannelStats{Channel: src.Channel(), Name: src.Name()}
+	start := math.Max(w.From, src.BeginTime())
+	level := src.InitialState()
+
+	var periods, highs, lows []float64
+	lastRise, lastFall := math.NaN(), math.NaN()
+	cursor := start
+	var highTime float64
+	truncated := false
+	for tr, err := range src.Transitions() {
+		if err != nil {
+			return st, err
+		}
+		if tr.State == level {
+			continue
+		}
+		if tr.Time < start {
+			level = tr.State
+			continue
+		}
+		if tr.Time > w.To {
+			truncated = true
+			break
+		}
+		if level == 1 {
+			highTime += tr.Time - cursor
+		}
+		cursor, level = tr.Time, tr.State
+		if tr.State == 1 {
+			st.Rising++
+			if !math.IsNaN(lastRise) {
+				periods = append(periods, tr.Time-lastRise)
+			}
+			if !math.IsNaN(lastFall) {
+				lows = append(lows, tr.Time-lastFall)
+			}
+			lastRise = tr.Time
+		} else {
+			st.Falling++
+			if !math.IsNaN(lastRise) {
+				highs = append(highs, tr.Time-lastRise)
+			}
+			lastFall = tr.Time
+		}
+	}
+	end := w.To
+	if !truncated {
+		end = math.Min(w.To, src.EndTime())
+	}
+	if end < start {
+		return st, errors.Errorf("%s: window [%g, %g] does not overlap the capture", src.Name(), w.From, w.To)
+	}
+	if level == 1 {
+		highTime += end - cursor
+	}
+
+	st.Start, st.End = start, end
+	st.Period, st.High, st.Low = Summarize(periods), Summarize(highs), Summarize(lows)
+	st.Frequency = 1 / st.Period.Mean
+	st.DutyCycle = math.NaN()
+	if end > start {
+		st.DutyCycle = highTime / (end - start)
+	}
+	return st, nil
+}
+
+// Edge selects the clock edge for setup/hold measurements.
+type Edge int
+
+const (
+	Rising Edge = iota
+	Falling
+)
+
+// ParseEdge parses "rising" or "falling" (empty means rising).
+func ParseEdge(s string) (Edge, error) {
+	switch s {
+	case "", "rising":
+		return Rising, nil
+	case "falling":
+		return Falling, nil
+	default:
+		return Rising, errors.Errorf("unknown clock edge %q (expected rising|falling)", s)
+	}
+}
+
+func (e Edge) String() string {
+	switch e {
+	case Rising:
+		return "rising"
+	case Falling:
+		return "falling"
+	default:
+		return "unknown"
+	}
+}
+
+// SetupHoldStats are setup and hold times of a data line around clock edges.
+type SetupHoldStats struct {
+	Data  string
+	Clock string
+	Edge  Edge
+	// ClockEdges counts clock edges inside the window.
+	ClockEdges int
+	// Setup is, per clock edge, the time since the previous data transition.
+	Setup Stats
+	// Hold is the time from a clock edge to the next data transition, for clock edges
+	// where data changes before the next clock edge.
+	Hold Stats
+}
+
+// SetupHold measures data transitions relative to clock edges inside w. A data edge at
+// the same instant as a clock edge counts as zero setup. data and clock must be
+// different sources: binary sources can be read only once.
+func SetupHold(data, clock rawdata.DigitalSource, edge Edge, w Window) (SetupHoldStats, error) {
+	st := SetupHoldStats{Data: data.Name(), Clock: clock.Name(), Edge: edge}
+	if data == clock {
+		return st, errors.Errorf("setup/hold: data and clock are both %s", data.Name())
+	}
+	levels := []uint8{data.InitialState(), clock.InitialState()}
+	want := uint8(1)
+	if edge == Falling {
+		want = 0
+	}
+	lastData, lastClock := math.NaN(), math.NaN()
+	var setups, holds []float64
+	for e, err := range rawdata.Merge([]rawdata.DigitalSource{data, clock}) {
+		if err != nil {
+			return st, err
+		}
+		if e.State == levels[e.Index] {
+			continue
+		}
+		levels[e.Index] = e.State
+		if e.Time > w.To {
+			break
+		}
+		if e.Time < w.From {
+			continue
+		}
+		if e.Index == 0 {
+			if !math.IsNaN(lastClock) {
+				holds = append(holds, e.Time-lastClock)
+				lastClock = math.NaN()
+			}
+			lastData = e.Time
+			continue
+		}
+		if e.State != want {
+			continue
+		}
+		st.ClockEdges++
+		if !math.IsNaN(lastData) {
+			setups = append(setups, e.Time-lastData)
+		}
+		lastClock = e.Time
+	}
+	st.Setup, st.Hold = Summarize(setups), Summarize(holds)
+	return st, nil
+}
@@ -0,0 +1,97 @@
+package measure
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/salad/internal/rawdata"
+)
+
+// source builds a digital source from one level per 1µs step.
+func source(t *testing.T, levels ...uint8) rawdata.DigitalSource {
+	t.Helper()
+	var toggles []float64
+	for i := 1; i < len(levels); i++ {
+		if levels[i] != levels[i-1] {
+			toggles = append(toggles, float64(i)*1e-6)
+		}
+	}
+	var buf bytes.Buffer
+	if err := rawdata.WriteDigitalBinary(&buf, levels[0], 0, float64(len(levels))*1e-6, toggles); err != nil {
+		t.Fatalf("WriteDigitalBinary: %v", err)
+	}
+	src, err := rawdata.NewDigitalBinary(&buf, 0)
+	if err != nil {
+		t.Fatalf("NewDigitalBinary: %v", err)
+	}
+	return src
+}
+
+// near compares with a relative tolerance (exact for zero).
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b)) || math.Abs(a-b) < 1e-15
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{5, 1, 4, 2, 3})
+	if s.Count != 5 || s.Min != 1 || s.Max != 5 || s.Mean != 3 || s.P50 != 3 || s.P90 != 5 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if !near(s.Stddev, math.Sqrt(2.5)) {
+		t.Fatalf("stddev: expected %v, got %v", math.Sqrt(2.5), s.Stddev)
+	}
+	if empty := Summarize(nil); empty.Count != 0 || !math.IsNaN(empty.Mean) {
+		t.Fatalf("empty: expected NaN stats, got %+v", empty)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	// 1µs high, 3µs low, repeated: 250 kHz, 25% duty.
+	src := source(t, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0)
+	st, err := Channel(src, All)
+	if err != nil {
+		t.Fatalf("Channel: %v", err)
+	}
+	if st.Rising != 3 || st.Falling != 3 {
+		t.Fatalf("edges: expected 3/3, got %d/%d", st.Rising, st.Falling)
+	}
+	if !near(st.Frequency, 250e3) || !near(st.Period.Stddev, 0) {
+		t.Fatalf("frequency: expected 250kHz without jitter, got %v (%v)", st.Frequency, st.Period.Stddev)
+	}
+	if st.High.Count != 3 || !near(st.High.Max, 1e-6) || st.Low.Count != 2 || !near(st.Low.Min, 3e-6) {
+		t.Fatalf("pulses: unexpected high %+v low %+v", st.High, st.Low)
+	}
+	if !near(st.DutyCycle, 3.0/13) {
+		t.Fatalf("duty: expected %v, got %v", 3.0/13, st.DutyCycle)
+	}
+
+	// The window cuts the first pulse and everything after 6µs.
+	st, err = Channel(source(t, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0), Window{From: 1.5e-6, To: 6e-6})
+	if err != nil {
+		t.Fatalf("Channel window: %v", err)
+	}
+	if st.Rising != 1 || st.Falling != 2 || st.High.Count != 1 || !near(st.DutyCycle, 1.5/4.5) {
+		t.Fatalf("window: unexpected %+v", st)
+	}
+}
+
+func TestSetupHold(t *testing.T) {
+	clock := source(t, 0, 0, 1, 0, 0, 1, 0, 0)
+	data := source(t, 0, 1, 1, 1, 0, 0, 0, 0)
+	st, err := SetupHold(data, clock, Rising, All)
+	if err != nil {
+		t.Fatalf("SetupHold: %v", err)
+	}
+	if st.ClockEdges != 2 || st.Setup.Count != 2 || !near(st.Setup.Min, 1e-6) || !near(st.Setup.Max, 1e-6) {
+		t.Fatalf("setup: unexpected %+v", st)
+	}
+	if st.Hold.Count != 1 || !near(st.Hold.Min, 2e-6) {
+		t.Fatalf("hold: unexpected %+v", st.Hold)
+	}
+
+	if _, err := SetupHold(clock, clock, Rising, All); err == nil || !strings.Contains(err.Error(), "both") {
+		t.Fatalf("expected an error for the same data and clock source, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package saleae
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/httpjson"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// AdminHandler returns the HTTP admin interface of the server: JSON endpoints that drive
+// the mock at runtime (see the mock server user guide). It has no authentication; serve
+// it on loopback only.
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	for _, r := range s.adminRoutes() {
+		mux.HandleFunc(r.method+" "+r.pattern, adminHandle(r.handler))
+	}
+	return mux
+}
+
+type adminRoute struct {
+	method  string
+	pattern string
+	handler func(w http.ResponseWriter, r *http.Request) error
+}
+
+func (s *Server) adminRoutes() []adminRoute {
+	return []adminRoute{
+		{http.MethodGet, "/clock", s.adminGetClock},
+		{http.MethodPost, "/clock/advance", s.adminAdvanceClock},
+		{http.MethodPost, "/clock/set", s.adminSetClock},
+		{http.MethodPost, "/clock/scale", s.adminScaleClock},
+		{http.MethodPost, "/clock/freeze", s.adminFreezeClock},
+		{http.MethodGet, "/state", s.adminGetState},
+		{http.MethodGet, "/calls", s.adminGetCalls},
+		{http.MethodGet, "/journal", s.adminGetJournal},
+		{http.MethodPost, "/reset", s.adminReset},
+		{http.MethodPost, "/config", s.adminLoadConfig},
+		{http.MethodGet, "/faults", s.adminListFaults},
+		{http.MethodPost, "/faults", s.adminAddFault},
+		{http.MethodDelete, "/faults", s.adminClearFaults},
+		{http.MethodDelete, "/faults/{id}", s.adminRemoveFault},
+		{http.MethodPost, "/captures/{capture_id}/status", s.adminSetCaptureStatus},
+	}
+}
+
+func adminHandle(fn func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := fn(w, r)
+		if err == nil {
+			return
+		}
+		code, ok := httpjson.StatusOf(err)
+		if !ok {
+			code = http.StatusInternalServerError
+		}
+		httpjson.WriteError(w, code, err)
+	}
+}
+
+// readAdminYAML decodes a config fragment (YAML, or JSON) from the request body strictly
+// into v, with the field names of the mock config.
+func readAdminYAML(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := yaml.NewDecoder(http.MaxBytesReader(w, r.Body, httpjson.MaxBodyBytes))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return httpjson.BadRequest("decode request body: %v", err)
+	}
+	return nil
+}
+
+type clockJSON struct {
+	Virtual bool      `json:"virtual"`
+	Now     time.Time `json:"now"`
+	Scale   *float64  `json:"scale,omitempty"`
+}
+
+func (s *Server) clockJSON() clockJSON {
+	current := s.Clock()
+	out := clockJSON{Now: current.Now()}
+	if clock, ok := current.(*VirtualClock); ok {
+		scale := clock.Scale()
+		out.Virtual, out.Scale = true, &scale
+	}
+	return out
+}
+
+func (s *Server) virtualClock() (*VirtualClock, error) {
+	clock, ok := s.Clock().(*VirtualClock)
+	if !ok {
+		return nil, &httpjson.Error{Code: http.StatusConflict, Msg: "the clock is not virtual (set defaults.timing.clock.mode: virtual)"}
+	}
+	return clock, nil
+}
+
+func (s *Server) adminGetClock(w http.ResponseWriter, r *http.Request) error {
+	httpjson.Write(w, http.StatusOK, s.clockJSON())
+	return nil
+}
+
+func (s *Server) adminAdvanceClock(w http.ResponseWriter, r *http.Request) error {
+	clock, err := s.virtualClock()
+	if err != nil {
+		return err
+	}
+	var req struct {
+		By string `json:"by"`
+	}
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(req.By)
+	if err != nil || d < 0 {
+		return httpjson.BadRequest("invalid duration %q (e.g. \"1.5s\")", req.By)
+	}
+	clock.Advance(d)
+	httpjson.Write(w, http.StatusOK, s.clockJSON())
+	return nil
+}
+
+func (s *Server) adminSetClock(w http.ResponseWriter, r *http.Request) error {
+	clock, err := s.virtualClock()
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Now time.Time `json:"now"`
+	}
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	if req.Now.IsZero() {
+		return httpjson.BadRequest("now (RFC3339) is required")
+	}
+	clock.Set(req.Now)
+	httpjson.Write(w, http.StatusOK, s.clockJSON())
+	return nil
+}
+
+func (s *Server) adminScaleClock(w http.ResponseWriter, r *http.Request) error {
+	clock, err := s.virtualClock()
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Scale *float64 `json:"scale"`
+	}
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	if req.Scale == nil || *req.Scale < 0 {
+		return httpjson.BadRequest("scale must be a number >= 0")
+	}
+	clock.SetScale(*req.Scale)
+	httpjson.Write(w, http.StatusOK, s.clockJSON())
+	return nil
+}
+
+func (s *Server) adminFreezeClock(w http.ResponseWriter, r *http.Request) error {
+	clock, err := s.virtualClock()
+	if err != nil {
+		return err
+	}
+	clock.Freeze()
+	httpjson.Write(w, http.StatusOK, s.clockJSON())
+	return nil
+}
+
+func (s *Server) adminGetState(w http.ResponseWriter, r *http.Request) error {
+	httpjson.Write(w, http.StatusOK, s.Snapshot())
+	return nil
+}
+
+func (s *Server) adminGetCalls(w http.ResponseWriter, r *http.Request) error {
+	httpjson.Write(w, http.StatusOK, map[string]any{"calls": s.CallCounts()})
+	return nil
+}
+
+// adminGetJournal lists the journal, optionally only ?method= calls and those after
+// ?since=<seq>.
+func (s *Server) adminGetJournal(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	since := 0
+	if raw := query.Get("since"); raw != "" {
+		var err error
+		if since, err = strconv.Atoi(raw); err != nil {
+			return httpjson.BadRequest("invalid since %q", raw)
+		}
+	}
+	var method Method
+	if raw := query.Get("method"); raw != "" {
+		var err error
+		if method, err = parseMethod(raw); err != nil {
+			return httpjson.BadRequest("%v", err)
+		}
+	}
+	calls := Journal{}
+	for _, call := range s.Journal() {
+		if call.Seq > since && (method == "" || call.Method == method) {
+			calls = append(calls, call)
+		}
+	}
+	httpjson.Write(w, http.StatusOK, map[string]any{"calls": calls})
+	return nil
+}
+
+func (s *Server) adminReset(w http.ResponseWriter, r *http.Request) error {
+	s.Reset()
+	httpjson.Write(w, http.StatusOK, s.Snapshot())
+	return nil
+}
+
+// adminLoadConfig loads the config in the request body, or the file named by ?path=.
+func (s *Server) adminLoadConfig(w http.ResponseWriter, r *http.Request) error {
+	var cfg Config
+	var err error
+	if path := r.URL.Query().Get("path"); path != "" {
+		cfg, err = LoadConfig(path)
+	} else {
+		cfg, err = LoadConfigFromReader(http.MaxBytesReader(w, r.Body, httpjson.MaxBodyBytes))
+	}
+	if err != nil {
+		return httpjson.BadRequest("%v", err)
+	}
+	plan, err := Compile(cfg)
+	if err != nil {
+		return httpjson.BadRequest("%v", err)
+	}
+	s.LoadPlan(plan)
+	httpjson.Write(w, http.StatusOK, s.Snapshot())
+	return nil
+}
+
+func (s *Server) adminListFaults(w http.ResponseWriter, r *http.Request) error {
+	httpjson.Write(w, http.StatusOK, map[string]any{"faults": s.Faults()})
+	return nil
+}
+
+func (s *Server) adminAddFault(w http.ResponseWriter, r *http.Request) error {
+	var cfg FaultRuleConfig
+	if err := readAdminYAML(w, r, &cfg); err != nil {
+		return err
+	}
+	id, err := s.AddFault(cfg)
+	if err != nil {
+		return httpjson.BadRequest("%v", err)
+	}
+	httpjson.Write(w, http.StatusCreated, InstalledFault{ID: id, Config: cfg})
+	return nil
+}
+
+func (s *Server) adminClearFaults(w http.ResponseWriter, r *http.Request) error {
+	s.ClearFaults()
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) adminRemoveFault(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return httpjson.BadRequest("invalid fault id %q", r.PathValue("id"))
+	}
+	if !s.RemoveFault(id) {
+		return httpjson.NotFound("fault %d not found", id)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) adminSetCaptureStatus(w http.ResponseWriter, r *http.Request) error {
+	captureID, err := strconv.ParseUint(r.PathValue("capture_id"), 10, 64)
+	if err != nil {
+		return httpjson.BadRequest("invalid capture id %q", r.PathValue("capture_id"))
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	if req.Status == "" {
+		return httpjson.BadRequest("status is required")
+	}
+	status, err := parseCaptureStatus(req.Status)
+	if err != nil {
+		return httpjson.BadRequest("%v", err)
+	}
+	if err := s.SetCaptureStatus(captureID, status); err != nil {
+		if errors.Is(err, ErrCaptureNotFound) {
+			return httpjson.NotFound("capture %d not found", captureID)
+		}
+		return err
+	}
+	httpjson.Write(w, http.StatusOK, s.Snapshot())
+	return nil
+}
@@ -0,0 +1,226 @@
+package saleae
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func adminCall(t *testing.T, h http.Handler, method, path, body string, wantCode int, out any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	b, _ := io.ReadAll(rec.Body)
+	if rec.Code != wantCode {
+		t.Fatalf("%s %s: expected HTTP %d, got %d: %s", method, path, wantCode, rec.Code, b)
+	}
+	if out != nil {
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, path, b, err)
+		}
+	}
+}
+
+func TestAdmin_Clock(t *testing.T) {
+	plan, err := Compile(Config{Defaults: DefaultsConfig{Timing: TimingDefaultsConfig{
+		Clock: &ClockConfig{Mode: "virtual", Start: "2025-01-01T00:00:00Z"},
+	}}})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	server := NewServer(plan)
+	h := server.AdminHandler()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var clock clockJSON
+	adminCall(t, h, http.MethodGet, "/clock", "", http.StatusOK, &clock)
+	if !clock.Virtual || !clock.Now.Equal(start) || clock.Scale == nil || *clock.Scale != 0 {
+		t.Fatalf("unexpected clock %+v", clock)
+	}
+	adminCall(t, h, http.MethodPost, "/clock/advance", `{"by": "90s"}`, http.StatusOK, &clock)
+	if !clock.Now.Equal(start.Add(90 * time.Second)) {
+		t.Fatalf("expected the clock to advance by 90s, got %s", clock.Now)
+	}
+	adminCall(t, h, http.MethodPost, "/clock/set", `{"now": "2030-06-01T12:00:00Z"}`, http.StatusOK, &clock)
+	if !server.Clock().Now().Equal(time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the server clock to be set, got %s", server.Clock().Now())
+	}
+	adminCall(t, h, http.MethodPost, "/clock/scale", `{"scale": 2}`, http.StatusOK, &clock)
+	if *clock.Scale != 2 {
+		t.Fatalf("expected scale 2, got %v", *clock.Scale)
+	}
+	adminCall(t, h, http.MethodPost, "/clock/freeze", "", http.StatusOK, &clock)
+	if *clock.Scale != 0 {
+		t.Fatalf("expected a frozen clock, got scale %v", *clock.Scale)
+	}
+	adminCall(t, h, http.MethodPost, "/clock/advance", `{"by": "-1s"}`, http.StatusBadRequest, nil)
+	adminCall(t, h, http.MethodPost, "/clock/scale", `{"scale": -1}`, http.StatusBadRequest, nil)
+
+	realClock := NewServer(plan, WithClock(RealClock{})).AdminHandler()
+	adminCall(t, realClock, http.MethodGet, "/clock", "", http.StatusOK, &clock)
+	if clock.Virtual {
+		t.Fatalf("expected a real clock")
+	}
+	adminCall(t, realClock, http.MethodPost, "/clock/advance", `{"by": "1s"}`, http.StatusConflict, nil)
+}
+
+func TestAdmin_StateCallsFaultsAndReset(t *testing.T) {
+	plan, err := Compile(Config{
+		Fixtures: FixturesConfig{
+			Devices: []DeviceConfig{{DeviceID: "DEV1", DeviceType: "DEVICE_TYPE_LOGIC_PRO_8"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	server, _, listener, cleanup, err := StartMockServer(plan)
+	if err != nil {
+		t.Fatalf("StartMockServer: %v", err)
+	}
+	defer cleanup()
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	manager := pb.NewManagerClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := server.AdminHandler()
+
+	load, err := manager.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: "/tmp/mock.sal"})
+	if err != nil {
+		t.Fatalf("LoadCapture: %v", err)
+	}
+	captureID := load.GetCaptureInfo().GetCaptureId()
+	if _, err := manager.AddAnalyzer(ctx, &pb.AddAnalyzerRequest{
+		CaptureId:     captureID,
+		AnalyzerName:  "SPI",
+		AnalyzerLabel: "spi",
+		Settings:      map[string]*pb.AnalyzerSettingValue{"Bits per Transfer": {Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: 8}}},
+	}); err != nil {
+		t.Fatalf("AddAnalyzer: %v", err)
+	}
+
+	var state StateSnapshot
+	adminCall(t, h, http.MethodGet, "/state", "", http.StatusOK, &state)
+	if len(state.Captures) != 1 || state.Captures[0].Status != "completed" || state.NextCaptureID != captureID+1 {
+		t.Fatalf("unexpected captures %+v", state)
+	}
+	if len(state.Analyzers) != 1 || state.Analyzers[0].Label != "spi" || state.Analyzers[0].Settings["Bits per Transfer"] != float64(8) {
+		t.Fatalf("unexpected analyzers %+v", state.Analyzers)
+	}
+	var calls struct {
+		Calls map[Method]int `json:"calls"`
+	}
+	adminCall(t, h, http.MethodGet, "/calls", "", http.StatusOK, &calls)
+	if calls.Calls[MethodLoadCapture] != 1 || calls.Calls[MethodAddAnalyzer] != 1 || calls.Calls[MethodGetDevices] != 0 {
+		t.Fatalf("unexpected call counts %v", calls.Calls)
+	}
+
+	// Faults can be added and removed at runtime.
+	var fault map[string]any
+	adminCall(t, h, http.MethodPost, "/faults", `{"when": {"method": "GetDevices"}, "respond": {"status": "UNAVAILABLE", "message": "unplugged"}}`, http.StatusCreated, &fault)
+	if _, err := manager.GetDevices(ctx, &pb.GetDevicesRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the runtime fault to fire, got %v", err)
+	}
+	adminCall(t, h, http.MethodPost, "/faults", `{"when": {"method": "Nope"}}`, http.StatusBadRequest, nil)
+	id := int(fault["id"].(float64))
+	adminCall(t, h, http.MethodDelete, "/faults/"+strconv.Itoa(id), "", http.StatusNoContent, nil)
+	adminCall(t, h, http.MethodDelete, "/faults/"+strconv.Itoa(id), "", http.StatusNotFound, nil)
+	if _, err := manager.GetDevices(ctx, &pb.GetDevicesRequest{}); err != nil {
+		t.Fatalf("expected GetDevices to succeed once the fault is removed, got %v", err)
+	}
+
+	// LoadPlan starts over from the plan's fixtures.
+	server.LoadPlan(plan)
+	adminCall(t, h, http.MethodGet, "/state", "", http.StatusOK, &state)
+	if len(state.Captures) != 0 {
+		t.Fatalf("expected LoadPlan to reset the captures, got %+v", state.Captures)
+	}
+	load, err = manager.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: "/tmp/mock.sal"})
+	if err != nil {
+		t.Fatalf("LoadCapture: %v", err)
+	}
+	captureID = load.GetCaptureInfo().GetCaptureId()
+
+	// Captures can be moved between statuses.
+	adminCall(t, h, http.MethodPost, "/captures/"+strconv.FormatUint(captureID, 10)+"/status", `{"status": "closed"}`, http.StatusOK, &state)
+	if state.Captures[0].Status != "closed" {
+		t.Fatalf("expected the capture to be closed, got %+v", state.Captures)
+	}
+	adminCall(t, h, http.MethodPost, "/captures/99/status", `{"status": "completed"}`, http.StatusNotFound, nil)
+
+	adminCall(t, h, http.MethodPost, "/reset", "", http.StatusOK, &state)
+	adminCall(t, h, http.MethodGet, "/calls", "", http.StatusOK, &calls)
+	if len(state.Captures) != 0 || calls.Calls[MethodLoadCapture] != 0 {
+		t.Fatalf("expected an empty state after reset, got %+v and %v", state, calls.Calls)
+	}
+
+	// A new config replaces fixtures and faults.
+	adminCall(t, h, http.MethodPost, "/config", strings.Join([]string{
+		"version: 1",
+		"fixtures:",
+		"  devices:",
+		"    - {device_id: DEV9, device_type: DEVICE_TYPE_LOGIC_8}",
+		"faults:",
+		"  - when: {method: SaveCapture}",
+		"    respond: {status: INTERNAL, message: disk full}",
+	}, "\n"), http.StatusOK, &state)
+	if len(state.Devices) != 1 || state.Devices[0].DeviceID != "DEV9" {
+		t.Fatalf("unexpected devices %+v", state.Devices)
+	}
+	var faults struct {
+		Faults []map[string]any `json:"faults"`
+	}
+	adminCall(t, h, http.MethodGet, "/faults", "", http.StatusOK, &faults)
+	if len(faults.Faults) != 1 || faults.Faults[0]["respond"].(map[string]any)["message"] != "disk full" {
+		t.Fatalf("unexpected faults %+v", faults.Faults)
+	}
+	adminCall(t, h, http.MethodPost, "/config", "version: 2", http.StatusBadRequest, nil)
+}
+
+func TestAdmin_SetCaptureStatusEndsBlockedWait(t *testing.T) {
+	plan, err := Compile(Config{
+		Defaults: DefaultsConfig{Timing: TimingDefaultsConfig{WaitCapturePolicy: "block_until_done"}},
+		Fixtures: FixturesConfig{Captures: []CaptureFixture{{CaptureID: 1, Status: "running", Mode: &CaptureModeConfig{Kind: "trigger"}}}},
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	server := NewServer(plan)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := server.WaitCapture(context.Background(), &pb.WaitCaptureRequest{CaptureId: 1})
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := server.SetCaptureStatus(1, CaptureStatusCompleted); err != nil {
+		t.Fatalf("SetCaptureStatus: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("expected the wait to succeed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the wait to end")
+	}
+	if err := server.SetCaptureStatus(2, CaptureStatusCompleted); !errors.Is(err, ErrCaptureNotFound) {
+		t.Fatalf("expected ErrCaptureNotFound, got %v", err)
+	}
+}
@@ -1,9 +1,21 @@
 package saleae
 
-import "time"
+import (
+	"sort"
+	"sync"
+	"time"
+)
 
 type Clock interface {
 	Now() time.Time
+	// NewTimer returns a timer that fires once d has passed on this clock.
+	NewTimer(d time.Duration) Timer
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if it already fired.
+	Stop() bool
 }
 
 type RealClock struct{}
@@ -11,3 +23,147 @@ type RealClock struct{}
 func (RealClock) Now() time.Time {
 	return time.Now()
 }
+
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.timer.C }
+func (t realTimer) Stop() bool          { return t.timer.Stop() }
+
+// VirtualClock is a Clock controlled by tests: it can be frozen, advanced, set, or run
+// at a multiple of real time. Timers fire as soon as the virtual time reaches them, so a
+// timed capture or blocking wait completes on Advance without sleeping.
+type VirtualClock struct {
+	mu sync.Mutex
+	// The virtual time is base + scale * (real time since anchor).
+	base   time.Time
+	anchor time.Time
+	scale  float64
+	timers []*virtualTimer
+	// wake fires the timers due while the clock runs (scale > 0).
+	wake *time.Timer
+}
+
+// NewVirtualClock returns a frozen clock showing start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{base: start, anchor: time.Now()}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+func (c *VirtualClock) now() time.Time {
+	if c.scale == 0 {
+		return c.base
+	}
+	return c.base.Add(time.Duration(float64(time.Since(c.anchor)) * c.scale))
+}
+
+// Scale returns how fast the clock runs relative to real time (0: frozen).
+func (c *VirtualClock) Scale() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scale
+}
+
+// Advance moves the clock forward by d and fires the timers that are due.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase(c.now().Add(d))
+}
+
+// Set moves the clock to t. Timers due by t fire; moving backwards delays the others.
+func (c *VirtualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebase(t)
+}
+
+// SetScale makes the clock run at scale times real time from now on; 0 freezes it.
+func (c *VirtualClock) SetScale(scale float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.scale = scale
+	c.rebase(now)
+}
+
+// Freeze stops the clock; it is SetScale(0).
+func (c *VirtualClock) Freeze() {
+	c.SetScale(0)
+}
+
+func (c *VirtualClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &virtualTimer{clock: c, at: c.now().Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	c.fire()
+	return t
+}
+
+// rebase makes now the virtual time at the current real time, then fires due timers.
+func (c *VirtualClock) rebase(now time.Time) {
+	c.base, c.anchor = now, time.Now()
+	c.fire()
+}
+
+// fire delivers the timers that are due and, while the clock runs, schedules a real
+// timer for the next one.
+func (c *VirtualClock) fire() {
+	now := c.now()
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if now.Before(t.at) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- now
+	}
+	c.timers = pending
+
+	if c.wake != nil {
+		c.wake.Stop()
+		c.wake = nil
+	}
+	if c.scale <= 0 || len(c.timers) == 0 {
+		return
+	}
+	sort.Slice(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	delay := time.Duration(float64(c.timers[0].at.Sub(now)) / c.scale)
+	c.wake = time.AfterFunc(delay, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.fire()
+	})
+}
+
+type virtualTimer struct {
+	clock *VirtualClock
+	at    time.Time
+	c     chan time.Time
+}
+
+func (t *virtualTimer) C() <-chan time.Time { return t.c }
+
+func (t *virtualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
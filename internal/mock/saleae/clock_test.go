@@ -0,0 +1,69 @@
+package saleae
+
+import (
+	"testing"
+	"time"
+)
+
+func fired(timer Timer) bool {
+	select {
+	case <-timer.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestVirtualClock_AdvanceSetAndStop(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewVirtualClock(start)
+	time.Sleep(5 * time.Millisecond)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Fatalf("expected a frozen clock at %s, got %s", start, got)
+	}
+
+	short, long, stopped := clock.NewTimer(time.Second), clock.NewTimer(time.Minute), clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Fatalf("expected Stop to cancel a pending timer")
+	}
+	clock.Advance(time.Second)
+	if !fired(short) || fired(long) || fired(stopped) {
+		t.Fatalf("expected only the 1s timer to fire")
+	}
+	if short.Stop() {
+		t.Fatalf("expected Stop to report an already fired timer")
+	}
+
+	clock.Set(start.Add(time.Hour))
+	if !fired(long) {
+		t.Fatalf("expected Set past the deadline to fire the timer")
+	}
+	if got := clock.Now(); !got.Equal(start.Add(time.Hour)) {
+		t.Fatalf("expected %s, got %s", start.Add(time.Hour), got)
+	}
+	if timer := clock.NewTimer(0); !fired(timer) {
+		t.Fatalf("expected a zero timer to fire immediately")
+	}
+}
+
+func TestVirtualClock_Scale(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewVirtualClock(start)
+	clock.SetScale(1000)
+	timer := clock.NewTimer(10 * time.Second)
+	select {
+	case <-timer.C():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected 10 virtual seconds to pass in about 10ms")
+	}
+
+	clock.Freeze()
+	frozen := clock.Now()
+	if frozen.Sub(start) < 10*time.Second {
+		t.Fatalf("expected at least 10s of virtual time, got %s", frozen.Sub(start))
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got := clock.Now(); !got.Equal(frozen) {
+		t.Fatalf("expected the frozen clock to stay at %s, got %s", frozen, got)
+	}
+}
@@ -3,24 +3,28 @@ package saleae
 import (
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Version  int               `yaml:"version"`
-	Scenario string            `yaml:"scenario,omitempty"`
-	Defaults DefaultsConfig    `yaml:"defaults,omitempty"`
-	Fixtures FixturesConfig    `yaml:"fixtures,omitempty"`
-	Behavior BehaviorConfig    `yaml:"behavior,omitempty"`
-	Faults   []FaultRuleConfig `yaml:"faults,omitempty"`
+	Version  int                   `yaml:"version"`
+	Scenario string                `yaml:"scenario,omitempty"`
+	Defaults DefaultsConfig        `yaml:"defaults,omitempty"`
+	Fixtures FixturesConfig        `yaml:"fixtures,omitempty"`
+	Behavior BehaviorConfig        `yaml:"behavior,omitempty"`
+	Faults   []FaultRuleConfig     `yaml:"faults,omitempty"`
+	Timeline []TimelineEventConfig `yaml:"timeline,omitempty"`
 }
 
 type DefaultsConfig struct {
 	GRPC   GRPCDefaultsConfig   `yaml:"grpc,omitempty"`
 	IDs    IDsDefaultsConfig    `yaml:"ids,omitempty"`
 	Timing TimingDefaultsConfig `yaml:"timing,omitempty"`
+	// Seed makes fault probabilities and latency jitter reproducible (0: random).
+	Seed uint64 `yaml:"seed,omitempty"`
 }
 
 type GRPCDefaultsConfig struct {
@@ -34,8 +38,24 @@ type IDsDefaultsConfig struct {
 }
 
 type TimingDefaultsConfig struct {
-	WaitCapturePolicy string `yaml:"wait_capture_policy,omitempty"`
-	MaxBlockMs        int    `yaml:"max_block_ms,omitempty"`
+	WaitCapturePolicy string              `yaml:"wait_capture_policy,omitempty"`
+	MaxBlockMs        int                 `yaml:"max_block_ms,omitempty"`
+	Clock             *ClockConfig        `yaml:"clock,omitempty"`
+	Latency           []LatencyRuleConfig `yaml:"latency,omitempty"`
+}
+
+// LatencyRuleConfig slows down every call of a method, whether or not a fault fires.
+type LatencyRuleConfig struct {
+	Method   string `yaml:"method,omitempty"`
+	DelayMs  int    `yaml:"delay_ms,omitempty"`
+	JitterMs int    `yaml:"jitter_ms,omitempty"`
+	Hang     bool   `yaml:"hang,omitempty"`
+}
+
+type ClockConfig struct {
+	Mode  string  `yaml:"mode,omitempty"`
+	Start string  `yaml:"start,omitempty"`
+	Scale float64 `yaml:"scale,omitempty"`
 }
 
 type FixturesConfig struct {
@@ -63,11 +83,13 @@ type DeviceConfig struct {
 }
 
 type CaptureFixture struct {
-	CaptureID uint64             `yaml:"capture_id,omitempty"`
-	Status    string             `yaml:"status,omitempty"`
-	Origin    string             `yaml:"origin,omitempty"`
-	StartedAt string             `yaml:"started_at,omitempty"`
-	Mode      *CaptureModeConfig `yaml:"mode,omitempty"`
+	CaptureID  uint64             `yaml:"capture_id,omitempty"`
+	Status     string             `yaml:"status,omitempty"`
+	Origin     string             `yaml:"origin,omitempty"`
+	StartedAt  string             `yaml:"started_at,omitempty"`
+	Mode       *CaptureModeConfig `yaml:"mode,omitempty"`
+	Signals    *SignalsConfig     `yaml:"signals,omitempty"`
+	DataTables []DataTableConfig  `yaml:"data_tables,omitempty"`
 }
 
 type CaptureModeConfig struct {
@@ -75,6 +97,128 @@ type CaptureModeConfig struct {
 	DurationSeconds float64 `yaml:"duration_seconds,omitempty"`
 }
 
+// SignalsConfig is the recorded content of a capture, written by the raw data exports.
+// Each digital entry has exactly one kind; channels without a signal export as low
+// (digital) or 0 V (analog).
+type SignalsConfig struct {
+	DurationSeconds  float64               `yaml:"duration_seconds,omitempty"`
+	SampleRate       float64               `yaml:"sample_rate,omitempty"`
+	AnalogSampleRate float64               `yaml:"analog_sample_rate,omitempty"`
+	Digital          []DigitalSignalConfig `yaml:"digital,omitempty"`
+	Analog           []AnalogSignalConfig  `yaml:"analog,omitempty"`
+}
+
+type DigitalSignalConfig struct {
+	Clock       *ClockSignalConfig       `yaml:"clock,omitempty"`
+	UART        *UARTSignalConfig        `yaml:"uart,omitempty"`
+	SPI         *SPISignalConfig         `yaml:"spi,omitempty"`
+	I2C         *I2CSignalConfig         `yaml:"i2c,omitempty"`
+	Transitions *TransitionsSignalConfig `yaml:"transitions,omitempty"`
+}
+
+type ClockSignalConfig struct {
+	Channel      uint32  `yaml:"channel,omitempty"`
+	FrequencyHz  float64 `yaml:"frequency_hz,omitempty"`
+	DutyCycle    float64 `yaml:"duty_cycle,omitempty"`
+	StartSeconds float64 `yaml:"start_seconds,omitempty"`
+	Cycles       int     `yaml:"cycles,omitempty"`
+}
+
+type UARTSignalConfig struct {
+	Channel      uint32  `yaml:"channel,omitempty"`
+	BaudRate     float64 `yaml:"baud_rate,omitempty"`
+	Text         string  `yaml:"text,omitempty"`
+	Bytes        []uint8 `yaml:"bytes,omitempty"`
+	BitsPerFrame int     `yaml:"bits_per_frame,omitempty"`
+	Parity       string  `yaml:"parity,omitempty"`
+	StopBits     float64 `yaml:"stop_bits,omitempty"`
+	MSBFirst     bool    `yaml:"msb_first,omitempty"`
+	Inverted     bool    `yaml:"inverted,omitempty"`
+	StartSeconds float64 `yaml:"start_seconds,omitempty"`
+	GapSeconds   float64 `yaml:"gap_seconds,omitempty"`
+}
+
+type SPISignalConfig struct {
+	Clock            uint32              `yaml:"clock,omitempty"`
+	MOSI             *uint32             `yaml:"mosi,omitempty"`
+	MISO             *uint32             `yaml:"miso,omitempty"`
+	Enable           *uint32             `yaml:"enable,omitempty"`
+	FrequencyHz      float64             `yaml:"frequency_hz,omitempty"`
+	Mode             int                 `yaml:"mode,omitempty"`
+	BitsPerTransfer  int                 `yaml:"bits_per_transfer,omitempty"`
+	LSBFirst         bool                `yaml:"lsb_first,omitempty"`
+	EnableActiveHigh bool                `yaml:"enable_active_high,omitempty"`
+	StartSeconds     float64             `yaml:"start_seconds,omitempty"`
+	GapSeconds       float64             `yaml:"gap_seconds,omitempty"`
+	Transfers        []SPITransferConfig `yaml:"transfers,omitempty"`
+}
+
+// SPITransferConfig is one enable assertion; the shorter side is padded with zeros.
+type SPITransferConfig struct {
+	MOSI []uint64 `yaml:"mosi,omitempty"`
+	MISO []uint64 `yaml:"miso,omitempty"`
+}
+
+type I2CSignalConfig struct {
+	SCL          uint32              `yaml:"scl,omitempty"`
+	SDA          uint32              `yaml:"sda,omitempty"`
+	FrequencyHz  float64             `yaml:"frequency_hz,omitempty"`
+	StartSeconds float64             `yaml:"start_seconds,omitempty"`
+	GapSeconds   float64             `yaml:"gap_seconds,omitempty"`
+	Transfers    []I2CTransferConfig `yaml:"transfers,omitempty"`
+}
+
+type I2CTransferConfig struct {
+	Address uint8   `yaml:"address,omitempty"`
+	Read    bool    `yaml:"read,omitempty"`
+	Data    []uint8 `yaml:"data,omitempty"`
+}
+
+// TransitionsSignalConfig replays a channel of an existing raw export: a digital.csv
+// column or a digital_<n>.bin file.
+type TransitionsSignalConfig struct {
+	Channel uint32 `yaml:"channel,omitempty"`
+	File    string `yaml:"file,omitempty"`
+	Column  string `yaml:"column,omitempty"`
+}
+
+type AnalogSignalConfig struct {
+	Sine *SineSignalConfig `yaml:"sine,omitempty"`
+	Step *StepSignalConfig `yaml:"step,omitempty"`
+}
+
+type SineSignalConfig struct {
+	Channel      uint32  `yaml:"channel,omitempty"`
+	FrequencyHz  float64 `yaml:"frequency_hz,omitempty"`
+	Amplitude    float64 `yaml:"amplitude,omitempty"`
+	Offset       float64 `yaml:"offset,omitempty"`
+	PhaseDegrees float64 `yaml:"phase_degrees,omitempty"`
+}
+
+type StepSignalConfig struct {
+	Channel   uint32  `yaml:"channel,omitempty"`
+	From      float64 `yaml:"from,omitempty"`
+	To        float64 `yaml:"to,omitempty"`
+	AtSeconds float64 `yaml:"at_seconds,omitempty"`
+}
+
+// DataTableConfig declares the frames of one analyzer, written by ExportDataTableCsv
+// instead of frames decoded from the signals.
+type DataTableConfig struct {
+	// Analyzer is matched against the analyzer (or HLA) label first, then its name.
+	Analyzer string        `yaml:"analyzer,omitempty"`
+	Frames   []FrameConfig `yaml:"frames,omitempty"`
+}
+
+type FrameConfig struct {
+	Type            string  `yaml:"type,omitempty"`
+	StartSeconds    float64 `yaml:"start_seconds,omitempty"`
+	DurationSeconds float64 `yaml:"duration_seconds,omitempty"`
+	// Bits is the width integer fields are padded to in hex and binary; 0 means 8.
+	Bits   int            `yaml:"bits,omitempty"`
+	Fields map[string]any `yaml:"fields,omitempty"`
+}
+
 type BehaviorConfig struct {
 	GetDevices              GetDevicesBehaviorConfig              `yaml:"GetDevices,omitempty"`
 	StartCapture            StartCaptureBehaviorConfig            `yaml:"StartCapture,omitempty"`
@@ -163,8 +307,10 @@ type LoadCaptureOnCallConfig struct {
 }
 
 type CaptureCreateConfig struct {
-	Status string             `yaml:"status,omitempty"`
-	Mode   *CaptureModeConfig `yaml:"mode,omitempty"`
+	Status     string             `yaml:"status,omitempty"`
+	Mode       *CaptureModeConfig `yaml:"mode,omitempty"`
+	Signals    *SignalsConfig     `yaml:"signals,omitempty"`
+	DataTables []DataTableConfig  `yaml:"data_tables,omitempty"`
 }
 
 type SaveCaptureBehaviorConfig struct {
@@ -269,25 +415,85 @@ type ExportRawBinaryFilenames struct {
 }
 
 type FaultRuleConfig struct {
+	// Name identifies the rule for when.after.
+	Name    string             `yaml:"name,omitempty"`
 	When    FaultWhenConfig    `yaml:"when,omitempty"`
 	Respond FaultRespondConfig `yaml:"respond,omitempty"`
+	// Times expires the rule once it has fired that many times (0: never).
+	Times int `yaml:"times,omitempty"`
 }
 
 type FaultWhenConfig struct {
-	Method  string            `yaml:"method,omitempty"`
-	NthCall *int              `yaml:"nth_call,omitempty"`
-	Match   *FaultMatchConfig `yaml:"match,omitempty"`
+	Method  string `yaml:"method,omitempty"`
+	NthCall *int   `yaml:"nth_call,omitempty"`
+	// FromCall and ToCall bound the call numbers of the method (inclusive, 0: unbounded).
+	FromCall int `yaml:"from_call,omitempty"`
+	ToCall   int `yaml:"to_call,omitempty"`
+	EveryNth int `yaml:"every_nth,omitempty"`
+	// Probability fires the rule on that fraction of the matching calls (default 1).
+	Probability *float64 `yaml:"probability,omitempty"`
+	// After turns the rule on once the named rule has fired.
+	After string            `yaml:"after,omitempty"`
+	Match *FaultMatchConfig `yaml:"match,omitempty"`
 }
 
 type FaultMatchConfig struct {
-	CaptureID    *uint64 `yaml:"capture_id,omitempty"`
-	Filepath     *string `yaml:"filepath,omitempty"`
-	AnalyzerID   *uint64 `yaml:"analyzer_id,omitempty"`
-	AnalyzerName *string `yaml:"analyzer_name,omitempty"`
+	CaptureID         *uint64        `yaml:"capture_id,omitempty"`
+	Filepath          *string        `yaml:"filepath,omitempty"`
+	FilepathGlob      string         `yaml:"filepath_glob,omitempty"`
+	FilepathRegex     string         `yaml:"filepath_regex,omitempty"`
+	AnalyzerID        *uint64        `yaml:"analyzer_id,omitempty"`
+	AnalyzerName      *string        `yaml:"analyzer_name,omitempty"`
+	AnalyzerNameGlob  string         `yaml:"analyzer_name_glob,omitempty"`
+	AnalyzerNameRegex string         `yaml:"analyzer_name_regex,omitempty"`
+	Settings          map[string]any `yaml:"settings,omitempty"`
+	SettingsKeys      []string       `yaml:"settings_keys,omitempty"`
+	DigitalChannels   []uint32       `yaml:"digital_channels,omitempty"`
+	AnalogChannels    []uint32       `yaml:"analog_channels,omitempty"`
+}
+
+// FaultRespondConfig is what a fault does: wait (delay_ms + jitter_ms, or hang until the
+// client gives up), then fail with status, or run the call normally if status is empty.
+type FaultRespondConfig struct {
+	Status   string `yaml:"status,omitempty"`
+	Message  string `yaml:"message,omitempty"`
+	DelayMs  int    `yaml:"delay_ms,omitempty"`
+	JitterMs int    `yaml:"jitter_ms,omitempty"`
+	Hang     bool   `yaml:"hang,omitempty"`
 }
 
-type FaultRespondConfig struct {
-	Status  string `yaml:"status,omitempty"`
+// TimelineEventConfig changes the mock at a point in time (at) or when a call arrives
+// (on_call). Each event has exactly one trigger and one action.
+type TimelineEventConfig struct {
+	At     string              `yaml:"at,omitempty"`
+	OnCall *TimelineCallConfig `yaml:"on_call,omitempty"`
+
+	RemoveDevice  string                       `yaml:"remove_device,omitempty"`
+	AddDevice     *DeviceConfig                `yaml:"add_device,omitempty"`
+	CaptureStatus *TimelineCaptureStatusConfig `yaml:"capture_status,omitempty"`
+	FailCapture   *TimelineFailCaptureConfig   `yaml:"fail_capture,omitempty"`
+	AppInfo       *AppInfoConfig               `yaml:"app_info,omitempty"`
+	Unavailable   *TimelineUnavailableConfig   `yaml:"unavailable,omitempty"`
+}
+
+type TimelineCallConfig struct {
+	Method string `yaml:"method,omitempty"`
+	N      int    `yaml:"n,omitempty"`
+}
+
+type TimelineCaptureStatusConfig struct {
+	CaptureID uint64 `yaml:"capture_id,omitempty"`
+	Status    string `yaml:"status,omitempty"`
+}
+
+type TimelineFailCaptureConfig struct {
+	CaptureID uint64 `yaml:"capture_id,omitempty"`
+	Status    string `yaml:"status,omitempty"`
+	Message   string `yaml:"message,omitempty"`
+}
+
+type TimelineUnavailableConfig struct {
+	For     string `yaml:"for,omitempty"`
 	Message string `yaml:"message,omitempty"`
 }
 
@@ -298,7 +504,38 @@ func LoadConfig(path string) (Config, error) {
 	}
 	defer func() { _ = file.Close() }()
 
-	return LoadConfigFromReader(file)
+	cfg, err := LoadConfigFromReader(file)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.resolvePaths(filepath.Dir(path))
+	return cfg, nil
+}
+
+// resolvePaths makes the relative file paths of cfg relative to dir, the directory of
+// the config file.
+func (cfg *Config) resolvePaths(dir string) {
+	signals := []*SignalsConfig{cfg.Behavior.StartCapture.OnCall.CreateCapture.signals(), cfg.Behavior.LoadCapture.OnCall.CreateCapture.signals()}
+	for _, capture := range cfg.Fixtures.Captures {
+		signals = append(signals, capture.Signals)
+	}
+	for _, s := range signals {
+		if s == nil {
+			continue
+		}
+		for _, signal := range s.Digital {
+			if t := signal.Transitions; t != nil && t.File != "" && !filepath.IsAbs(t.File) {
+				t.File = filepath.Join(dir, t.File)
+			}
+		}
+	}
+}
+
+func (c *CaptureCreateConfig) signals() *SignalsConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Signals
 }
 
 func LoadConfigFromReader(reader io.Reader) (Config, error) {
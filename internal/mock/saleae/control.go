@@ -0,0 +1,180 @@
+package saleae
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrCaptureNotFound is returned by SetCaptureStatus for an unknown capture.
+var ErrCaptureNotFound = errors.New("capture not found")
+
+// activeFault is a fault rule installed on the server.
+type activeFault struct {
+	id   int
+	rule FaultRule
+	// fired counts the calls the rule answered.
+	fired int
+}
+
+// expired reports whether the rule has fired its configured number of times.
+func (f *activeFault) expired() bool {
+	return f.rule.Times > 0 && f.fired >= f.rule.Times
+}
+
+// InstalledFault is a fault rule of the server, with the ID used to remove it and the
+// number of times it fired.
+type InstalledFault struct {
+	ID     int
+	Config FaultRuleConfig
+	Fired  int
+}
+
+// MarshalJSON writes the rule with its config field names, plus "id" and "fired".
+func (f InstalledFault) MarshalJSON() ([]byte, error) {
+	b, err := yaml.Marshal(f.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode fault rule")
+	}
+	out := map[string]any{}
+	if err := yaml.Unmarshal(b, &out); err != nil {
+		return nil, errors.Wrap(err, "encode fault rule")
+	}
+	out["id"] = f.ID
+	out["fired"] = f.Fired
+	return json.Marshal(out)
+}
+
+// Snapshot returns a copy of the current state.
+func (s *Server) Snapshot() StateSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.snapshot()
+}
+
+// CallCounts returns the number of calls received per method since the last reset.
+func (s *Server) CallCounts() map[Method]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[Method]int, len(AllMethods))
+	for _, method := range AllMethods {
+		out[method] = s.calls[method]
+	}
+	return out
+}
+
+// Reset restores the state, call counts and fault rules of the plan, clears the
+// journal, re-seeds the random source and restarts the timeline. The clock keeps
+// running; blocked WaitCapture calls re-evaluate against the new state.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+}
+
+// LoadPlan replaces the plan and resets the server to it. A clock that came from the
+// previous plan is replaced by the one the new plan selects.
+func (s *Server) LoadPlan(plan *Plan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plan = plan
+	if s.clockFromPlan {
+		s.clock = newClock(plan.Defaults.Clock)
+	}
+	if needsFileSideEffects(plan) {
+		s.sideEffects = FileSideEffects{}
+	}
+	s.reset()
+}
+
+func (s *Server) reset() {
+	for _, done := range s.state.waiters {
+		close(done)
+	}
+	s.state = newState(s.plan, s.clock)
+	s.calls = make(map[Method]int)
+	s.journal, s.journalSeq = nil, 0
+	s.rng = newRand(s.plan.Defaults.Seed)
+	s.faults = nil
+	s.installFaults(s.plan.Faults)
+	s.startTimeline()
+}
+
+func (s *Server) installFaults(rules []FaultRule) {
+	for _, rule := range rules {
+		s.nextFaultID++
+		s.faults = append(s.faults, &activeFault{id: s.nextFaultID, rule: rule})
+	}
+}
+
+// Faults returns the active fault rules in the order they are checked.
+func (s *Server) Faults() []InstalledFault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]InstalledFault, 0, len(s.faults))
+	for _, active := range s.faults {
+		out = append(out, InstalledFault{ID: active.id, Config: active.rule.Config, Fired: active.fired})
+	}
+	return out
+}
+
+// AddFault compiles a fault rule and checks it before the existing ones. It returns the
+// ID of the rule.
+func (s *Server) AddFault(cfg FaultRuleConfig) (int, error) {
+	rules, err := compileFaults([]FaultRuleConfig{cfg})
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known := map[string]bool{}
+	for _, active := range s.faults {
+		if active.rule.Name != "" {
+			known[active.rule.Name] = true
+		}
+	}
+	if err := checkFaultNames(rules, known); err != nil {
+		return 0, err
+	}
+	existing := s.faults
+	s.faults = nil
+	s.installFaults(rules)
+	id := s.nextFaultID
+	s.faults = append(s.faults, existing...)
+	return id, nil
+}
+
+// RemoveFault removes a fault rule. It reports whether the rule existed.
+func (s *Server) RemoveFault(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, active := range s.faults {
+		if active.id == id {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearFaults removes all fault rules.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetCaptureStatus moves a capture to status, e.g. to complete a running capture.
+func (s *Server) SetCaptureStatus(captureID uint64, status CaptureStatus) error {
+	_, err := s.withState(context.Background(), func(runtime *RuntimeContext) (any, error) {
+		capture, ok := runtime.State.Captures[captureID]
+		if !ok {
+			return nil, errors.Wrapf(ErrCaptureNotFound, "capture %d", captureID)
+		}
+		capture.Status = status
+		return nil, nil
+	})
+	return err
+}
@@ -0,0 +1,355 @@
+package saleae
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/decode"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DataTablePlan is the declared frames of the analyzers labelled or named Analyzer.
+type DataTablePlan struct {
+	Analyzer string
+	// Frames are in start-time order.
+	Frames []decode.Frame
+}
+
+func compileDataTables(cfgs []DataTableConfig) ([]DataTablePlan, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	out := make([]DataTablePlan, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Analyzer == "" {
+			return nil, errors.Errorf("[%d]: analyzer is required", i)
+		}
+		table := DataTablePlan{Analyzer: cfg.Analyzer}
+		for j, frame := range cfg.Frames {
+			compiled, err := compileFrame(frame)
+			if err != nil {
+				return nil, errors.Wrapf(err, "[%d] (%s): frames[%d]", i, cfg.Analyzer, j)
+			}
+			table.Frames = append(table.Frames, compiled)
+		}
+		sort.SliceStable(table.Frames, func(a, b int) bool { return table.Frames[a].Start < table.Frames[b].Start })
+		out = append(out, table)
+	}
+	return out, nil
+}
+
+func compileFrame(cfg FrameConfig) (decode.Frame, error) {
+	if cfg.Type == "" {
+		return decode.Frame{}, errors.New("type is required")
+	}
+	if cfg.StartSeconds < 0 || cfg.DurationSeconds < 0 {
+		return decode.Frame{}, errors.New("start_seconds and duration_seconds must not be negative")
+	}
+	bits := cfg.Bits
+	if bits == 0 {
+		bits = 8
+	}
+	if bits < 0 || bits > 64 {
+		return decode.Frame{}, errors.Errorf("bits must be between 1 and 64, got %d", bits)
+	}
+	frame := decode.Frame{
+		Type:  cfg.Type,
+		Start: cfg.StartSeconds,
+		End:   cfg.StartSeconds + cfg.DurationSeconds,
+	}
+	names := make([]string, 0, len(cfg.Fields))
+	for name := range cfg.Fields {
+		if isFixedColumn(name) {
+			return decode.Frame{}, errors.Errorf("field %q is a fixed column", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := decode.Field{Name: name, Bits: bits}
+		switch v := cfg.Fields[name].(type) {
+		case int:
+			if v < 0 {
+				return decode.Frame{}, errors.Errorf("field %q: negative integer %d", name, v)
+			}
+			field.Value = uint64(v)
+		case uint64:
+			field.Value = v
+		case bool, string:
+			field.Value = v
+		default:
+			return decode.Frame{}, errors.Errorf("field %q: expected an integer, bool or string, got %v", name, v)
+		}
+		frame.Fields = append(frame.Fields, field)
+	}
+	return frame, nil
+}
+
+// frames returns the declared table of an analyzer, matching its label before its name.
+func (capture *CaptureState) frames(label, name string) (*DataTablePlan, bool) {
+	for _, key := range []string{label, name} {
+		if key == "" {
+			continue
+		}
+		for i := range capture.DataTables {
+			if capture.DataTables[i].Analyzer == key {
+				return &capture.DataTables[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// DataTableExport is the synthetic content of a data table export.
+type DataTableExport struct {
+	// Start is the absolute time of the capture start, for ISO8601 timestamps.
+	Start   time.Time
+	ISO8601 bool
+	// Columns are the field columns written after the fixed ones.
+	Columns []string
+	Rows    []DataTableRow
+}
+
+// DataTableRow is one frame, with its fields formatted in the analyzer's radix.
+type DataTableRow struct {
+	Name     string
+	Type     string
+	Start    float64
+	Duration float64
+	Values   map[string]string
+}
+
+// dataTableExport builds the table ExportDataTableCsv writes for capture. Each analyzer
+// uses its declared frames, or else decodes the capture's signals with its settings.
+// Rows of all analyzers are merged in start-time order, then filtered and projected
+// on export_columns.
+func (state *State) dataTableExport(capture *CaptureState, req *pb.ExportDataTableCsvRequest, now time.Time) (*DataTableExport, error) {
+	out := &DataTableExport{Start: capture.StartedAt, ISO8601: req.GetIso8601Timestamp()}
+	if out.Start.IsZero() {
+		out.Start = now
+	}
+
+	configs := req.GetAnalyzers()
+	if len(configs) == 0 {
+		configs = state.allAnalyzers(capture.ID)
+	}
+	var columns []string
+	for _, config := range configs {
+		name, frames, fields, err := state.analyzerFrames(capture, config.GetAnalyzerId(), now)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			if !slices.Contains(columns, field) {
+				columns = append(columns, field)
+			}
+		}
+		radix := radixName(config.GetRadixType())
+		for _, frame := range frames {
+			row := DataTableRow{
+				Name:     name,
+				Type:     frame.Type,
+				Start:    frame.Start,
+				Duration: frame.End - frame.Start,
+				Values:   make(map[string]string, len(frame.Fields)),
+			}
+			for _, field := range frame.Fields {
+				row.Values[field.Name] = decode.FormatValue(field, radix)
+			}
+			out.Rows = append(out.Rows, row)
+		}
+	}
+	sort.SliceStable(out.Rows, func(a, b int) bool { return out.Rows[a].Start < out.Rows[b].Start })
+
+	if filter := req.GetFilter(); filter != nil && filter.GetQuery() != "" {
+		searched := filter.GetColumns()
+		if len(searched) == 0 {
+			searched = append([]string{datatable.ColumnName, datatable.ColumnType}, columns...)
+		}
+		query := strings.ToLower(filter.GetQuery())
+		rows := out.Rows[:0]
+		for _, row := range out.Rows {
+			if row.matches(query, searched) {
+				rows = append(rows, row)
+			}
+		}
+		out.Rows = rows
+	}
+
+	out.Columns = columns
+	if len(req.GetExportColumns()) > 0 {
+		out.Columns = nil
+		for _, column := range req.GetExportColumns() {
+			if !isFixedColumn(column) && !slices.Contains(out.Columns, column) {
+				out.Columns = append(out.Columns, column)
+			}
+		}
+	}
+	return out, nil
+}
+
+// allAnalyzers lists the analyzers and HLAs of a capture in ID order, in hex.
+func (state *State) allAnalyzers(captureID uint64) []*pb.DataTableAnalyzerConfiguration {
+	var ids []uint64
+	for id := range state.Analyzers[captureID] {
+		ids = append(ids, id)
+	}
+	for id := range state.HighLevelAnalyzers[captureID] {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	out := make([]*pb.DataTableAnalyzerConfiguration, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, &pb.DataTableAnalyzerConfiguration{AnalyzerId: id, RadixType: pb.RadixType_RADIX_TYPE_HEXADECIMAL})
+	}
+	return out
+}
+
+// analyzerFrames returns the table name, frames and field columns of an analyzer or HLA.
+// HLAs only have declared frames; analyzers without declared frames are decoded.
+func (state *State) analyzerFrames(capture *CaptureState, analyzerID uint64, now time.Time) (string, []decode.Frame, []string, error) {
+	if hla, ok := state.HighLevelAnalyzers[capture.ID][analyzerID]; ok {
+		name := labelOr(hla.Label, hla.HLAName)
+		table, ok := capture.frames(hla.Label, hla.HLAName)
+		if !ok {
+			return name, nil, nil, nil
+		}
+		return name, table.Frames, frameColumns(table.Frames), nil
+	}
+	analyzer, ok := state.Analyzers[capture.ID][analyzerID]
+	if !ok {
+		return "", nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("ExportDataTableCsv: analyzer %d not found", analyzerID))
+	}
+	name := labelOr(analyzer.Label, analyzer.Name)
+	if table, ok := capture.frames(analyzer.Label, analyzer.Name); ok {
+		return name, table.Frames, frameColumns(table.Frames), nil
+	}
+	if capture.Signals == nil {
+		return name, nil, nil, nil
+	}
+
+	dec, err := decode.New(analyzer.Name, analyzer.Settings)
+	if err != nil {
+		return "", nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("ExportDataTableCsv: analyzer %d: %v; declare its frames in data_tables", analyzerID, err))
+	}
+	channels := &pb.LogicChannels{}
+	for _, channel := range dec.Channels() {
+		// Mock signals are never renamed; a channel given by name is reported missing.
+		if channel.Name == "" {
+			channels.DigitalChannels = append(channels.DigitalChannels, uint32(channel.Index))
+		}
+	}
+	data := capture.Signals.rawExport(capture, channels, 1, now)
+	export := &rawdata.Export{}
+	for _, channel := range data.Digital {
+		export.Digital = append(export.Digital, &traceSource{channel: channel.Channel, trace: channel.Trace, end: data.End})
+	}
+	var frames []decode.Frame
+	for frame, err := range decode.DecodeExport(dec, export) {
+		if err != nil {
+			return "", nil, nil, status.Error(codes.Internal, fmt.Sprintf("ExportDataTableCsv: decode analyzer %d: %v", analyzerID, err))
+		}
+		frames = append(frames, frame)
+	}
+	return name, frames, decode.FieldColumns(dec), nil
+}
+
+func labelOr(label, name string) string {
+	if label != "" {
+		return label
+	}
+	return name
+}
+
+// frameColumns lists the field names of frames in first-seen order.
+func frameColumns(frames []decode.Frame) []string {
+	var out []string
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			if !slices.Contains(out, field.Name) {
+				out = append(out, field.Name)
+			}
+		}
+	}
+	return out
+}
+
+// radixName maps a request radix to datatable.FormatInt's; unspecified is hex, like
+// Logic 2's default display radix.
+func radixName(radix pb.RadixType) string {
+	switch radix {
+	case pb.RadixType_RADIX_TYPE_BINARY:
+		return "bin"
+	case pb.RadixType_RADIX_TYPE_DECIMAL:
+		return "dec"
+	case pb.RadixType_RADIX_TYPE_ASCII:
+		return "ascii"
+	default:
+		return "hex"
+	}
+}
+
+func isFixedColumn(column string) bool {
+	return slices.Contains(datatable.FixedColumns, column)
+}
+
+// matches reports whether a searched column contains query (lower case), ignoring case.
+// start_time and duration are searched in seconds.
+func (row DataTableRow) matches(query string, columns []string) bool {
+	for _, column := range columns {
+		var cell string
+		switch column {
+		case datatable.ColumnName:
+			cell = row.Name
+		case datatable.ColumnType:
+			cell = row.Type
+		case datatable.ColumnStartTime:
+			cell = strconv.FormatFloat(row.Start, 'f', 9, 64)
+		case datatable.ColumnDuration:
+			cell = strconv.FormatFloat(row.Duration, 'f', 9, 64)
+		default:
+			cell = row.Values[column]
+		}
+		if strings.Contains(strings.ToLower(cell), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// traceSource reads a rendered trace as a raw export channel.
+type traceSource struct {
+	channel uint32
+	trace   DigitalTrace
+	end     float64
+}
+
+func (s *traceSource) Channel() int        { return int(s.channel) }
+func (s *traceSource) Name() string        { return rawdata.ChannelName(int(s.channel)) }
+func (s *traceSource) InitialState() uint8 { return s.trace.Initial }
+func (s *traceSource) BeginTime() float64  { return 0 }
+func (s *traceSource) EndTime() float64    { return s.end }
+
+func (s *traceSource) Transitions() iter.Seq2[rawdata.Transition, error] {
+	return func(yield func(rawdata.Transition, error) bool) {
+		level := s.trace.Initial
+		for _, edge := range s.trace.Edges {
+			level ^= 1
+			if !yield(rawdata.Transition{Time: edge, State: level}, nil) {
+				return
+			}
+		}
+	}
+}
+
+var _ rawdata.DigitalSource = (*traceSource)(nil)
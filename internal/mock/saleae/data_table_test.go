@@ -0,0 +1,177 @@
+package saleae
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/datatable"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newDataTablesServer serves configs/mock/data-tables.yaml with its console (UART,
+// analyzer 100) and flash (SPI, analyzer 101) analyzers added to capture 1.
+func newDataTablesServer(t *testing.T) *Server {
+	t.Helper()
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatalf("runtime.Caller failed")
+	}
+	cfg, err := LoadConfig(filepath.Join(filepath.Dir(thisFile), "..", "..", "..", "configs", "mock", "data-tables.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	plan, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	server := NewServer(plan)
+
+	ctx := context.Background()
+	console := &pb.AddAnalyzerRequest{CaptureId: 1, AnalyzerName: "Async Serial", AnalyzerLabel: "console", Settings: map[string]*pb.AnalyzerSettingValue{
+		"Input Channel":     {Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: 0}},
+		"Bit Rate (Bits/s)": {Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: 115200}},
+	}}
+	flash := &pb.AddAnalyzerRequest{CaptureId: 1, AnalyzerName: "SPI", AnalyzerLabel: "flash"}
+	for _, req := range []*pb.AddAnalyzerRequest{console, flash} {
+		if _, err := server.AddAnalyzer(ctx, req); err != nil {
+			t.Fatalf("AddAnalyzer %s: %v", req.GetAnalyzerLabel(), err)
+		}
+	}
+	return server
+}
+
+// exportTable exports a data table and returns its header and rows as
+// "name type start field=value...".
+func exportTable(t *testing.T, server *Server, req *pb.ExportDataTableCsvRequest) (string, []string) {
+	t.Helper()
+	req.CaptureId = 1
+	req.Filepath = filepath.Join(t.TempDir(), "table.csv")
+	if _, err := server.ExportDataTableCsv(context.Background(), req); err != nil {
+		t.Fatalf("ExportDataTableCsv: %v", err)
+	}
+	table, err := datatable.Open(req.Filepath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	var rows []string
+	for row, err := range table.Rows() {
+		if err != nil {
+			t.Fatalf("Rows: %v", err)
+		}
+		start := row.Values[2]
+		rows = append(rows, strings.Join(append([]string{row.Name, row.Type, start}, table.Fields(row)...), " "))
+	}
+	return strings.Join(table.Header(), ","), rows
+}
+
+func TestDataTable_DecodedAndDeclaredFrames(t *testing.T) {
+	server := newDataTablesServer(t)
+	header, rows := exportTable(t, server, &pb.ExportDataTableCsvRequest{Analyzers: []*pb.DataTableAnalyzerConfiguration{
+		{AnalyzerId: 100, RadixType: pb.RadixType_RADIX_TYPE_ASCII},
+		{AnalyzerId: 101, RadixType: pb.RadixType_RADIX_TYPE_HEXADECIMAL},
+	}})
+	if header != "name,type,start_time,duration,data,error,miso,mosi" {
+		t.Fatalf("unexpected header %s", header)
+	}
+	var got []string
+	for _, row := range rows {
+		fields := strings.Fields(row)
+		got = append(got, strings.Join(append(fields[:2:2], fields[3:]...), " "))
+	}
+	want := []string{
+		"console data data=o",
+		"console data data=k",
+		`console data data=\x0D`,
+		`console data data=\x0A`,
+		"flash result miso=0x00 mosi=0x9F",
+		"flash result miso=0xEF mosi=0x00",
+		"flash result miso=0x40 mosi=0x00",
+		"flash result miso=0x18 mosi=0x00",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected rows:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(rows, "\n"))
+	}
+	if !strings.HasPrefix(rows[4], "flash result 0.001000000 ") {
+		t.Fatalf("expected the declared start time, got %s", rows[4])
+	}
+}
+
+func TestDataTable_FilterColumnsAndISO8601(t *testing.T) {
+	server := newDataTablesServer(t)
+
+	header, rows := exportTable(t, server, &pb.ExportDataTableCsvRequest{
+		Analyzers:        []*pb.DataTableAnalyzerConfiguration{{AnalyzerId: 101, RadixType: pb.RadixType_RADIX_TYPE_HEXADECIMAL}},
+		Iso8601Timestamp: true,
+		ExportColumns:    []string{"miso"},
+		Filter:           &pb.DataTableFilter{Query: "0xef", Columns: []string{"miso"}},
+	})
+	if header != "name,type,start_time,duration,miso" {
+		t.Fatalf("unexpected header %s", header)
+	}
+	if len(rows) != 1 || rows[0] != "flash result 2025-01-01T12:00:00.001010000Z miso=0xEF" {
+		t.Fatalf("expected the JEDEC manufacturer row, got %q", rows)
+	}
+
+	// A filter column that is not exported still filters; mosi=0x00 rows have no 0x9F.
+	_, rows = exportTable(t, server, &pb.ExportDataTableCsvRequest{
+		Analyzers:     []*pb.DataTableAnalyzerConfiguration{{AnalyzerId: 101, RadixType: pb.RadixType_RADIX_TYPE_DECIMAL}},
+		ExportColumns: []string{"miso"},
+		Filter:        &pb.DataTableFilter{Query: "159", Columns: []string{"mosi"}},
+	})
+	if len(rows) != 1 || rows[0] != "flash result 0.001000000 miso=0" {
+		t.Fatalf("expected the command row, got %q", rows)
+	}
+
+	// Without columns the query searches name, type and every field, ignoring case.
+	_, rows = exportTable(t, server, &pb.ExportDataTableCsvRequest{
+		Analyzers: []*pb.DataTableAnalyzerConfiguration{
+			{AnalyzerId: 100, RadixType: pb.RadixType_RADIX_TYPE_BINARY},
+			{AnalyzerId: 101, RadixType: pb.RadixType_RADIX_TYPE_BINARY},
+		},
+		Filter: &pb.DataTableFilter{Query: "CONSOLE"},
+	})
+	if len(rows) != 4 || !strings.HasSuffix(rows[0], " data=0b01101111") {
+		t.Fatalf("expected the 4 console rows in binary, got %q", rows)
+	}
+}
+
+func TestDataTable_Errors(t *testing.T) {
+	server := newDataTablesServer(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "table.csv")
+
+	_, err := server.ExportDataTableCsv(ctx, &pb.ExportDataTableCsvRequest{CaptureId: 1, Filepath: path, Analyzers: []*pb.DataTableAnalyzerConfiguration{{AnalyzerId: 999}}})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "analyzer 999 not found") {
+		t.Fatalf("expected InvalidArgument for an unknown analyzer, got %v", err)
+	}
+
+	reply, err := server.AddAnalyzer(ctx, &pb.AddAnalyzerRequest{CaptureId: 1, AnalyzerName: "CAN"})
+	if err != nil {
+		t.Fatalf("AddAnalyzer: %v", err)
+	}
+	_, err = server.ExportDataTableCsv(ctx, &pb.ExportDataTableCsvRequest{CaptureId: 1, Filepath: path, Analyzers: []*pb.DataTableAnalyzerConfiguration{{AnalyzerId: reply.GetAnalyzerId()}}})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "declare its frames in data_tables") {
+		t.Fatalf("expected InvalidArgument for an analyzer without a decoder, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		table DataTableConfig
+		want  string
+	}{
+		{DataTableConfig{Frames: []FrameConfig{{Type: "data"}}}, "analyzer is required"},
+		{DataTableConfig{Analyzer: "spi", Frames: []FrameConfig{{}}}, "type is required"},
+		{DataTableConfig{Analyzer: "spi", Frames: []FrameConfig{{Type: "result", Fields: map[string]any{"mosi": -1}}}}, "negative integer"},
+		{DataTableConfig{Analyzer: "spi", Frames: []FrameConfig{{Type: "result", Fields: map[string]any{"mosi": 1.5}}}}, "expected an integer"},
+		{DataTableConfig{Analyzer: "spi", Frames: []FrameConfig{{Type: "result", Fields: map[string]any{"type": "x"}}}}, "fixed column"},
+	} {
+		_, err := Compile(Config{Fixtures: FixturesConfig{Captures: []CaptureFixture{{CaptureID: 1, DataTables: []DataTableConfig{tc.table}}}}})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected an error containing %q, got %v", tc.want, err)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package saleae
 
 import (
 	"context"
+	"math/rand/v2"
 	"sync"
+	"time"
 
 	pb "github.com/go-go-golems/salad/gen/saleae/automation"
 )
@@ -64,6 +66,19 @@ type Server struct {
 	mu    sync.Mutex
 	state State
 	calls map[Method]int
+	// journal records every call; journalLimit bounds its length (0: unbounded).
+	journal      []*Call
+	journalSeq   int
+	journalLimit int
+	// faults are the active fault rules, checked in order.
+	faults      []*activeFault
+	nextFaultID int
+	// clockFromPlan is set when the clock comes from the plan rather than WithClock.
+	clockFromPlan bool
+	// rng draws fault probabilities and latency jitter.
+	rng *rand.Rand
+	// timeline tracks the scripted events of the plan.
+	timeline timelineRun
 }
 
 type Option func(*Server)
@@ -76,6 +91,14 @@ func WithClock(clock Clock) Option {
 	}
 }
 
+// WithJournalLimit keeps only the last limit calls in the journal; 0 keeps all of them.
+// The default is DefaultJournalLimit.
+func WithJournalLimit(limit int) Option {
+	return func(server *Server) {
+		server.journalLimit = limit
+	}
+}
+
 func WithSideEffects(sideEffects SideEffects) Option {
 	return func(server *Server) {
 		if sideEffects != nil {
@@ -86,32 +109,86 @@ func WithSideEffects(sideEffects SideEffects) Option {
 
 func NewServer(plan *Plan, opts ...Option) *Server {
 	server := &Server{
-		plan:        plan,
-		clock:       RealClock{},
-		sideEffects: NoopSideEffects{},
-		calls:       make(map[Method]int),
+		plan:         plan,
+		sideEffects:  NoopSideEffects{},
+		calls:        make(map[Method]int),
+		journalLimit: DefaultJournalLimit,
 	}
 	for _, opt := range opts {
 		opt(server)
 	}
+	if server.clock == nil {
+		server.clock = newClock(plan.Defaults.Clock)
+		server.clockFromPlan = true
+	}
 
 	server.state = newState(plan, server.clock)
+	server.rng = newRand(plan.Defaults.Seed)
+	server.installFaults(plan.Faults)
+	server.startTimeline()
 	if needsFileSideEffects(plan) {
 		server.sideEffects = FileSideEffects{}
 	}
 	return server
 }
 
+// exec runs one RPC: it counts and journals the call, fires timeline events, applies
+// UNAVAILABLE windows, latency and faults, runs fn under the server lock, and completes
+// the waits fn asks for (blocking WaitCapture) without it.
 func (s *Server) exec(ctx context.Context, method Method, req any, fn func(*RuntimeContext) (any, error)) (any, error) {
+	started := time.Now()
+	out, call, err := s.execLocked(ctx, method, req, fn)
+	if delayed, ok := out.(*delayedCall); ok {
+		// Sleep without holding s.mu, so that slow calls don't stall the others.
+		out, err = s.runDelayed(ctx, delayed, fn)
+	}
+	if wait, ok := out.(*captureWait); ok {
+		// Block without holding s.mu, so that e.g. StopCapture can end the wait.
+		out, err = s.awaitCapture(ctx, wait)
+	}
+	s.finishCall(call, out, err, time.Since(started))
+	return out, err
+}
+
+func (s *Server) execLocked(ctx context.Context, method Method, req any, fn func(*RuntimeContext) (any, error)) (any, *Call, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.calls[method]++
 	callN := s.calls[method]
-	if err := s.maybeFault(method, req, callN); err != nil {
-		return nil, err
+	call := s.beginCall(method, req, callN)
+	s.fireTimeline(method, callN, call.Seq)
+	if err := s.unavailable(); err != nil {
+		return nil, call, err
+	}
+
+	latency := s.plan.Defaults.Latency[method]
+	var faultErr error
+	if fault := s.matchFault(method, req, callN); fault != nil {
+		latency = latency.add(fault.Latency)
+		faultErr = statusError(fault.Code, fault.Message)
 	}
+	if delay := s.drawDelay(latency); delay > 0 || latency.Hang {
+		return &delayedCall{callN: callN, delay: delay, hang: latency.Hang, err: faultErr}, call, nil
+	}
+	if faultErr != nil {
+		return nil, call, faultErr
+	}
+
+	out, err := s.run(ctx, callN, fn)
+	return out, call, err
+}
 
+// withState runs fn against the current state without counting a call or applying faults.
+func (s *Server) withState(ctx context.Context, fn func(*RuntimeContext) (any, error)) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.run(ctx, 0, fn)
+}
+
+// run calls fn with s.mu held, then wakes the waits on captures that fn finished.
+func (s *Server) run(ctx context.Context, callN int, fn func(*RuntimeContext) (any, error)) (any, error) {
 	runtime := &RuntimeContext{
 		Ctx:         ctx,
 		Plan:        s.plan,
@@ -121,25 +198,73 @@ func (s *Server) exec(ctx context.Context, method Method, req any, fn func(*Runt
 		SideEffects: s.ensureSideEffects(),
 	}
 
-	return fn(runtime)
+	out, err := fn(runtime)
+	s.state.signalCaptures()
+	return out, err
 }
 
-func (s *Server) maybeFault(method Method, req any, callN int) error {
-	for _, fault := range s.plan.Faults {
-		if fault.Method != method {
+// matchFault returns the first active fault rule that fires for the call, if any, and
+// counts the firing. The caller holds s.mu.
+func (s *Server) matchFault(method Method, req any, callN int) *FaultRule {
+	for _, active := range s.faults {
+		fault := &active.rule
+		if fault.Method != method || active.expired() || !fault.matchesCall(callN) {
 			continue
 		}
-		if fault.NthCall != nil && *fault.NthCall != callN {
+		if fault.After != "" && !s.faultFired(fault.After) {
 			continue
 		}
 		if fault.Match != nil && !fault.Match(req) {
 			continue
 		}
-		return statusError(fault.Code, fault.Message)
+		if fault.Probability < 1 && s.rng.Float64() >= fault.Probability {
+			continue
+		}
+		active.fired++
+		return fault
 	}
 	return nil
 }
 
+// faultFired reports whether the named rule has fired since the last reset.
+func (s *Server) faultFired(name string) bool {
+	for _, active := range s.faults {
+		if active.rule.Name == name && active.fired > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Clock returns the clock of the server: the WithClock option, or the one the config
+// selects (a *VirtualClock for defaults.timing.clock.mode: virtual).
+func (s *Server) Clock() Clock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock
+}
+
+func newClock(plan ClockPlan) Clock {
+	if !plan.Virtual {
+		return RealClock{}
+	}
+	start := plan.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	clock := NewVirtualClock(start)
+	clock.SetScale(plan.Scale)
+	return clock
+}
+
+// newRand returns the random source for a seed; 0 picks a random seed.
+func newRand(seed uint64) *rand.Rand {
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return rand.New(rand.NewPCG(seed, 0))
+}
+
 func newState(plan *Plan, clock Clock) State {
 	state := State{
 		AppInfo:            plan.Fixtures.AppInfo,
@@ -149,6 +274,7 @@ func newState(plan *Plan, clock Clock) State {
 		HighLevelAnalyzers: make(map[uint64]map[uint64]*HighLevelAnalyzerState),
 		NextCaptureID:      plan.Defaults.CaptureIDStart,
 		NextAnalyzerID:     plan.Defaults.AnalyzerIDStart,
+		waiters:            make(map[uint64]chan struct{}),
 	}
 
 	var maxCaptureID uint64
@@ -158,11 +284,13 @@ func newState(plan *Plan, clock Clock) State {
 			startedAt = clock.Now()
 		}
 		state.Captures[capture.ID] = &CaptureState{
-			ID:        capture.ID,
-			Status:    capture.Status,
-			Origin:    capture.Origin,
-			StartedAt: startedAt,
-			Mode:      capture.Mode,
+			ID:         capture.ID,
+			Status:     capture.Status,
+			Origin:     capture.Origin,
+			StartedAt:  startedAt,
+			Mode:       capture.Mode,
+			Signals:    capture.Signals,
+			DataTables: capture.DataTables,
 		}
 		if capture.ID > maxCaptureID {
 			maxCaptureID = capture.ID
@@ -189,5 +317,13 @@ func needsFileSideEffects(plan *Plan) bool {
 	if plan.Behavior.ExportDataTableCsv.WritePlaceholderFile {
 		return true
 	}
+	if plan.Behavior.StartCapture.CreateCapture.hasContent() || plan.Behavior.LoadCapture.CreateCapture.hasContent() {
+		return true
+	}
+	for _, capture := range plan.Fixtures.Captures {
+		if capture.hasContent() {
+			return true
+		}
+	}
 	return false
 }
@@ -103,7 +103,9 @@ func TestCLI_ExportTable_AgainstMockServer(t *testing.T) {
 	}
 }
 
-func ptrBool(v bool) *bool { return &v }
+func ptrBool(v bool) *bool        { return &v }
+func ptrInt(v int) *int           { return &v }
+func ptrFloat(v float64) *float64 { return &v }
 
 func buildSaladBinary(t *testing.T, moduleRoot string, outPath string) {
 	t.Helper()
@@ -0,0 +1,263 @@
+package saleae
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/pkg/errors"
+)
+
+// faultRequests holds an empty request of each method. A match field applies to a method
+// when its request has the field (see the request accessors below).
+var faultRequests = map[Method]any{
+	MethodGetAppInfo:              &pb.GetAppInfoRequest{},
+	MethodGetDevices:              &pb.GetDevicesRequest{},
+	MethodStartCapture:            &pb.StartCaptureRequest{},
+	MethodLoadCapture:             &pb.LoadCaptureRequest{},
+	MethodSaveCapture:             &pb.SaveCaptureRequest{},
+	MethodStopCapture:             &pb.StopCaptureRequest{},
+	MethodWaitCapture:             &pb.WaitCaptureRequest{},
+	MethodCloseCapture:            &pb.CloseCaptureRequest{},
+	MethodAddAnalyzer:             &pb.AddAnalyzerRequest{},
+	MethodRemoveAnalyzer:          &pb.RemoveAnalyzerRequest{},
+	MethodAddHighLevelAnalyzer:    &pb.AddHighLevelAnalyzerRequest{},
+	MethodRemoveHighLevelAnalyzer: &pb.RemoveHighLevelAnalyzerRequest{},
+	MethodExportRawDataCsv:        &pb.ExportRawDataCsvRequest{},
+	MethodExportRawDataBinary:     &pb.ExportRawDataBinaryRequest{},
+	MethodExportDataTableCsv:      &pb.ExportDataTableCsvRequest{},
+}
+
+// compileFaultMatcher returns a predicate over the requests of method that holds when all
+// the fields set in match do. Fields the request doesn't have are an error.
+func compileFaultMatcher(method Method, match *FaultMatchConfig) (func(any) bool, error) {
+	if match == nil {
+		return nil, nil
+	}
+	zero := faultRequests[method]
+	var preds []func(any) bool
+	require := func(field string, ok bool) error {
+		if !ok {
+			return errors.Errorf("fault matcher %s not supported for method %s", field, method)
+		}
+		return nil
+	}
+
+	if match.CaptureID != nil {
+		if err := require("capture_id", hasField(zero, requestCaptureID)); err != nil {
+			return nil, err
+		}
+		want := *match.CaptureID
+		preds = append(preds, func(req any) bool {
+			id, ok := requestCaptureID(req)
+			return ok && id == want
+		})
+	}
+
+	if match.AnalyzerID != nil {
+		if err := require("analyzer_id", hasField(zero, requestAnalyzerID)); err != nil {
+			return nil, err
+		}
+		want := *match.AnalyzerID
+		preds = append(preds, func(req any) bool {
+			id, ok := requestAnalyzerID(req)
+			return ok && id == want
+		})
+	}
+
+	pathMatch, err := compileStringMatch("filepath", match.Filepath, match.FilepathGlob, match.FilepathRegex, true)
+	if err != nil {
+		return nil, err
+	}
+	if pathMatch != nil {
+		if err := require("filepath", hasField(zero, requestPath)); err != nil {
+			return nil, err
+		}
+		preds = append(preds, func(req any) bool {
+			p, ok := requestPath(req)
+			return ok && pathMatch(p)
+		})
+	}
+
+	nameMatch, err := compileStringMatch("analyzer_name", match.AnalyzerName, match.AnalyzerNameGlob, match.AnalyzerNameRegex, false)
+	if err != nil {
+		return nil, err
+	}
+	if nameMatch != nil {
+		if err := require("analyzer_name", hasField(zero, requestAnalyzerName)); err != nil {
+			return nil, err
+		}
+		preds = append(preds, func(req any) bool {
+			name, ok := requestAnalyzerName(req)
+			return ok && nameMatch(name)
+		})
+	}
+
+	if len(match.Settings) > 0 || len(match.SettingsKeys) > 0 {
+		if err := require("settings", hasField(zero, requestSettings)); err != nil {
+			return nil, err
+		}
+		want := make(map[string]any, len(match.Settings))
+		for key, value := range match.Settings {
+			want[key] = normalizeSettingValue(value)
+		}
+		keys := match.SettingsKeys
+		preds = append(preds, func(req any) bool {
+			settings, _ := requestSettings(req)
+			for _, key := range keys {
+				if _, ok := settings[key]; !ok {
+					return false
+				}
+			}
+			for key, value := range want {
+				got, ok := settings[key]
+				if !ok || normalizeSettingValue(got) != value {
+					return false
+				}
+			}
+			return true
+		})
+	}
+
+	if len(match.DigitalChannels) > 0 || len(match.AnalogChannels) > 0 {
+		if err := require("digital_channels/analog_channels", hasField(zero, requestChannels)); err != nil {
+			return nil, err
+		}
+		digital, analog := match.DigitalChannels, match.AnalogChannels
+		preds = append(preds, func(req any) bool {
+			channels, _ := requestChannels(req)
+			return containsAll(channels.GetDigitalChannels(), digital) && containsAll(channels.GetAnalogChannels(), analog)
+		})
+	}
+
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	return func(req any) bool {
+		for _, pred := range preds {
+			if !pred(req) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// compileStringMatch combines an exact value, a glob and a regular expression (all that
+// are set must match). With basename, a glob without "/" matches the last path element.
+func compileStringMatch(field string, exact *string, glob, regex string, basename bool) (func(string) bool, error) {
+	var preds []func(string) bool
+	if exact != nil {
+		want := *exact
+		preds = append(preds, func(s string) bool { return s == want })
+	}
+	if glob != "" {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, errors.Wrapf(err, "fault matcher %s_glob %q", field, glob)
+		}
+		preds = append(preds, func(s string) bool {
+			if basename && !strings.Contains(glob, "/") {
+				s = path.Base(s)
+			}
+			ok, _ := path.Match(glob, s)
+			return ok
+		})
+	}
+	if regex != "" {
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fault matcher %s_regex %q", field, regex)
+		}
+		preds = append(preds, re.MatchString)
+	}
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	return func(s string) bool {
+		for _, pred := range preds {
+			if !pred(s) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func hasField[T any](zero any, get func(any) (T, bool)) bool {
+	_, ok := get(zero)
+	return ok
+}
+
+func containsAll(have, want []uint32) bool {
+	for _, channel := range want {
+		if !slices.Contains(have, channel) {
+			return false
+		}
+	}
+	return true
+}
+
+func requestCaptureID(req any) (uint64, bool) {
+	if r, ok := req.(interface{ GetCaptureId() uint64 }); ok {
+		return r.GetCaptureId(), true
+	}
+	return 0, false
+}
+
+func requestAnalyzerID(req any) (uint64, bool) {
+	if r, ok := req.(interface{ GetAnalyzerId() uint64 }); ok {
+		return r.GetAnalyzerId(), true
+	}
+	return 0, false
+}
+
+// requestPath returns the file of a request, or the directory of a raw export.
+func requestPath(req any) (string, bool) {
+	switch r := req.(type) {
+	case interface{ GetFilepath() string }:
+		return r.GetFilepath(), true
+	case interface{ GetDirectory() string }:
+		return r.GetDirectory(), true
+	default:
+		return "", false
+	}
+}
+
+// requestAnalyzerName returns the analyzer name of AddAnalyzer, or the HLA name of
+// AddHighLevelAnalyzer.
+func requestAnalyzerName(req any) (string, bool) {
+	switch r := req.(type) {
+	case *pb.AddAnalyzerRequest:
+		return r.GetAnalyzerName(), true
+	case *pb.AddHighLevelAnalyzerRequest:
+		return r.GetHlaName(), true
+	default:
+		return "", false
+	}
+}
+
+func requestSettings(req any) (map[string]any, bool) {
+	out := map[string]any{}
+	switch r := req.(type) {
+	case *pb.AddAnalyzerRequest:
+		for key, value := range r.GetSettings() {
+			out[key] = analyzerSettingValue(value)
+		}
+	case *pb.AddHighLevelAnalyzerRequest:
+		for key, value := range r.GetSettings() {
+			out[key] = hlaSettingValue(value)
+		}
+	default:
+		return nil, false
+	}
+	return out, true
+}
+
+func requestChannels(req any) (*pb.LogicChannels, bool) {
+	if r, ok := req.(interface{ GetLogicChannels() *pb.LogicChannels }); ok {
+		return r.GetLogicChannels(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,211 @@
+package saleae
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newFaultServer(t *testing.T, seed uint64, faults ...FaultRuleConfig) *Server {
+	t.Helper()
+	cfg := happyPathConfig(t)
+	cfg.Defaults.Seed = seed
+	cfg.Faults = faults
+	plan, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return NewServer(plan)
+}
+
+func unavailable(method string) FaultRespondConfig {
+	return FaultRespondConfig{Status: "UNAVAILABLE", Message: method + " fault"}
+}
+
+// deviceCalls returns the status codes of n GetDevices calls.
+func deviceCalls(server *Server, n int) []codes.Code {
+	out := make([]codes.Code, 0, n)
+	for i := 0; i < n; i++ {
+		_, err := server.GetDevices(context.Background(), &pb.GetDevicesRequest{})
+		out = append(out, status.Code(err))
+	}
+	return out
+}
+
+func TestFaultRules_CallRangeAndEveryNth(t *testing.T) {
+	server := newFaultServer(t, 0, FaultRuleConfig{
+		When:    FaultWhenConfig{Method: "GetDevices", FromCall: 2, ToCall: 6, EveryNth: 2},
+		Respond: unavailable("GetDevices"),
+	})
+	got := deviceCalls(server, 8)
+	for i, code := range got {
+		callN := i + 1
+		want := codes.OK
+		if callN == 2 || callN == 4 || callN == 6 {
+			want = codes.Unavailable
+		}
+		if code != want {
+			t.Fatalf("call %d: expected %s, got %v", callN, want, got)
+		}
+	}
+}
+
+func TestFaultRules_TimesAndAfter(t *testing.T) {
+	server := newFaultServer(t, 0,
+		FaultRuleConfig{
+			Name:    "flaky",
+			When:    FaultWhenConfig{Method: "GetDevices"},
+			Respond: unavailable("GetDevices"),
+			Times:   2,
+		},
+		FaultRuleConfig{
+			When:    FaultWhenConfig{Method: "GetAppInfo", After: "flaky"},
+			Respond: FaultRespondConfig{Status: "INTERNAL", Message: "after flaky"},
+		},
+	)
+	ctx := context.Background()
+	if _, err := server.GetAppInfo(ctx, &pb.GetAppInfoRequest{}); err != nil {
+		t.Fatalf("expected GetAppInfo to succeed before flaky fired, got %v", err)
+	}
+	got := deviceCalls(server, 3)
+	if got[0] != codes.Unavailable || got[1] != codes.Unavailable || got[2] != codes.OK {
+		t.Fatalf("expected two failures then recovery, got %v", got)
+	}
+	if _, err := server.GetAppInfo(ctx, &pb.GetAppInfoRequest{}); status.Code(err) != codes.Internal {
+		t.Fatalf("expected GetAppInfo to fail once flaky fired, got %v", err)
+	}
+	faults := server.Faults()
+	if faults[0].Fired != 2 || faults[1].Fired != 1 {
+		t.Fatalf("unexpected fire counts %+v", faults)
+	}
+
+	server.Reset()
+	if got := deviceCalls(server, 1); got[0] != codes.Unavailable {
+		t.Fatalf("expected Reset to re-arm the rule, got %v", got)
+	}
+}
+
+func TestFaultRules_SeededProbability(t *testing.T) {
+	rule := FaultRuleConfig{
+		When:    FaultWhenConfig{Method: "GetDevices", Probability: ptrFloat(0.5)},
+		Respond: unavailable("GetDevices"),
+	}
+	first := deviceCalls(newFaultServer(t, 42, rule), 200)
+	second := deviceCalls(newFaultServer(t, 42, rule), 200)
+	failures := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected the same seed to give the same calls, differ at call %d", i+1)
+		}
+		if first[i] == codes.Unavailable {
+			failures++
+		}
+	}
+	if failures < 50 || failures > 150 {
+		t.Fatalf("expected about half of the calls to fail, got %d of 200", failures)
+	}
+}
+
+func TestFaultRules_RequestMatchers(t *testing.T) {
+	server := newFaultServer(t, 0,
+		FaultRuleConfig{
+			When:    FaultWhenConfig{Method: "LoadCapture", Match: &FaultMatchConfig{FilepathGlob: "broken-*.sal"}},
+			Respond: unavailable("LoadCapture"),
+		},
+		FaultRuleConfig{
+			When: FaultWhenConfig{Method: "AddAnalyzer", Match: &FaultMatchConfig{
+				AnalyzerNameRegex: "^(SPI|I2C)$",
+				Settings:          map[string]any{"Clock": 0},
+				SettingsKeys:      []string{"Enable"},
+			}},
+			Respond: unavailable("AddAnalyzer"),
+		},
+		FaultRuleConfig{
+			When:    FaultWhenConfig{Method: "ExportRawDataCsv", Match: &FaultMatchConfig{DigitalChannels: []uint32{3}}},
+			Respond: unavailable("ExportRawDataCsv"),
+		},
+	)
+	ctx := context.Background()
+
+	if _, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: "/tmp/captures/broken-1.sal"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the glob to match the base name, got %v", err)
+	}
+	loaded, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: "/tmp/broken/ok.sal"})
+	if err != nil {
+		t.Fatalf("LoadCapture: %v", err)
+	}
+	captureID := loaded.GetCaptureInfo().GetCaptureId()
+
+	setting := func(v int64) *pb.AnalyzerSettingValue {
+		return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: v}}
+	}
+	addAnalyzer := func(name string, settings map[string]*pb.AnalyzerSettingValue) error {
+		_, err := server.AddAnalyzer(ctx, &pb.AddAnalyzerRequest{CaptureId: captureID, AnalyzerName: name, Settings: settings})
+		return err
+	}
+	if err := addAnalyzer("SPI", map[string]*pb.AnalyzerSettingValue{"Clock": setting(0), "Enable": setting(3)}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the settings to match, got %v", err)
+	}
+	if err := addAnalyzer("SPI", map[string]*pb.AnalyzerSettingValue{"Clock": setting(1), "Enable": setting(3)}); err != nil {
+		t.Fatalf("expected another Clock not to match, got %v", err)
+	}
+	if err := addAnalyzer("SPI", map[string]*pb.AnalyzerSettingValue{"Clock": setting(0)}); err != nil {
+		t.Fatalf("expected a missing Enable not to match, got %v", err)
+	}
+	if err := addAnalyzer("Async Serial", map[string]*pb.AnalyzerSettingValue{"Clock": setting(0), "Enable": setting(3)}); err != nil {
+		t.Fatalf("expected another analyzer not to match, got %v", err)
+	}
+
+	export := func(digital ...uint32) error {
+		_, err := server.ExportRawDataCsv(ctx, &pb.ExportRawDataCsvRequest{
+			CaptureId: captureID,
+			Directory: t.TempDir(),
+			Channels:  &pb.ExportRawDataCsvRequest_LogicChannels{LogicChannels: &pb.LogicChannels{DigitalChannels: digital}},
+		})
+		return err
+	}
+	if err := export(0, 3); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected channel 3 to match, got %v", err)
+	}
+	if err := export(0, 1); err != nil {
+		t.Fatalf("expected channels without 3 not to match, got %v", err)
+	}
+}
+
+func TestFaultRules_CompileErrors(t *testing.T) {
+	cases := map[string][]FaultRuleConfig{
+		"analyzer_name not supported for method SaveCapture": {{
+			When:    FaultWhenConfig{Method: "SaveCapture", Match: &FaultMatchConfig{AnalyzerNameGlob: "*"}},
+			Respond: unavailable("SaveCapture"),
+		}},
+		"filepath_regex": {{
+			When:    FaultWhenConfig{Method: "LoadCapture", Match: &FaultMatchConfig{FilepathRegex: "("}},
+			Respond: unavailable("LoadCapture"),
+		}},
+		"unknown fault \"nope\"": {{
+			When:    FaultWhenConfig{Method: "GetDevices", After: "nope"},
+			Respond: unavailable("GetDevices"),
+		}},
+		"duplicate fault name": {
+			{Name: "a", When: FaultWhenConfig{Method: "GetDevices"}, Respond: unavailable("GetDevices")},
+			{Name: "a", When: FaultWhenConfig{Method: "GetAppInfo"}, Respond: unavailable("GetAppInfo")},
+		},
+		"from_call 3 is after to_call 2": {{
+			When:    FaultWhenConfig{Method: "GetDevices", FromCall: 3, ToCall: 2},
+			Respond: unavailable("GetDevices"),
+		}},
+		"probability": {{
+			When:    FaultWhenConfig{Method: "GetDevices", Probability: ptrFloat(1.5)},
+			Respond: unavailable("GetDevices"),
+		}},
+	}
+	for want, faults := range cases {
+		if _, err := Compile(Config{Faults: faults}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
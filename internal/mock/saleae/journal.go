@@ -0,0 +1,362 @@
+package saleae
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultJournalLimit is the number of calls a server journals unless WithJournalLimit
+// says otherwise; older calls are dropped.
+const DefaultJournalLimit = 10000
+
+// Call is one RPC received by the server, as recorded in the journal.
+type Call struct {
+	// Seq numbers the calls of all methods since the last reset, from 1.
+	Seq int
+	// N numbers the calls of Method, as RuntimeContext.CallN and fault rules do.
+	N      int
+	Method Method
+	// Request and Response are copies of the messages; Response is nil for failed or
+	// pending calls.
+	Request  proto.Message
+	Response proto.Message
+	Code     codes.Code
+	Error    string
+	// Time is when the call arrived, on the server clock; Duration is the real time it
+	// took to answer.
+	Time     time.Time
+	Duration time.Duration
+	// Done is false while the call is in progress (e.g. a blocked WaitCapture).
+	Done bool
+}
+
+// CaptureID returns the capture the request names, if any.
+func (c Call) CaptureID() (uint64, bool) {
+	if req, ok := c.Request.(interface{ GetCaptureId() uint64 }); ok {
+		return req.GetCaptureId(), true
+	}
+	return 0, false
+}
+
+// Succeeded reports whether the call completed with OK.
+func (c Call) Succeeded() bool {
+	return c.Done && c.Code == codes.OK
+}
+
+func (c Call) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s", c.Seq, c.Method)
+	if id, ok := c.CaptureID(); ok {
+		fmt.Fprintf(&b, " capture=%d", id)
+	}
+	switch {
+	case !c.Done:
+		b.WriteString(" (pending)")
+	case c.Code != codes.OK:
+		fmt.Fprintf(&b, " -> %s: %s", c.Code, c.Error)
+	}
+	return b.String()
+}
+
+// MarshalJSON writes the messages as protojson with their proto field names.
+func (c Call) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Seq        int             `json:"seq"`
+		N          int             `json:"n"`
+		Method     Method          `json:"method"`
+		Request    json.RawMessage `json:"request,omitempty"`
+		Response   json.RawMessage `json:"response,omitempty"`
+		Code       string          `json:"code"`
+		Error      string          `json:"error,omitempty"`
+		Time       time.Time       `json:"time"`
+		DurationMs float64         `json:"duration_ms"`
+		Done       bool            `json:"done"`
+	}{
+		Seq:        c.Seq,
+		N:          c.N,
+		Method:     c.Method,
+		Code:       c.Code.String(),
+		Error:      c.Error,
+		Time:       c.Time,
+		DurationMs: float64(c.Duration) / float64(time.Millisecond),
+		Done:       c.Done,
+	}
+	var err error
+	if out.Request, err = marshalJournalMessage(c.Request); err != nil {
+		return nil, err
+	}
+	if out.Response, err = marshalJournalMessage(c.Response); err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+func marshalJournalMessage(msg proto.Message) (json.RawMessage, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode journal message")
+	}
+	return b, nil
+}
+
+// Journal returns a copy of the calls received since the last reset, oldest first.
+func (s *Server) Journal() Journal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(Journal, 0, len(s.journal))
+	for _, call := range s.journal {
+		out = append(out, *call)
+	}
+	return out
+}
+
+// beginCall journals a call as it arrives. The caller holds s.mu.
+func (s *Server) beginCall(method Method, req any, callN int) *Call {
+	s.journalSeq++
+	call := &Call{Seq: s.journalSeq, N: callN, Method: method, Time: s.clock.Now()}
+	if msg, ok := req.(proto.Message); ok {
+		call.Request = proto.Clone(msg)
+	}
+	s.journal = append(s.journal, call)
+	if s.journalLimit > 0 && len(s.journal) > s.journalLimit {
+		s.journal = s.journal[len(s.journal)-s.journalLimit:]
+	}
+	return call
+}
+
+// finishCall records the outcome of a journaled call.
+func (s *Server) finishCall(call *Call, out any, err error, took time.Duration) {
+	var response proto.Message
+	if msg, ok := out.(proto.Message); ok && err == nil {
+		response = proto.Clone(msg)
+	}
+	st := status.Convert(err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call.Response = response
+	call.Code, call.Error = st.Code(), st.Message()
+	call.Duration = took
+	call.Done = true
+}
+
+// Journal is a list of calls, with helpers for asserting on what a client sent.
+type Journal []Call
+
+// Filter returns the calls that match m.
+func (j Journal) Filter(m CallMatcher) Journal {
+	var out Journal
+	for _, call := range j {
+		if m.Matches(call) {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+// First returns the first call that matches m.
+func (j Journal) First(m CallMatcher) (Call, bool) {
+	for _, call := range j {
+		if m.Matches(call) {
+			return call, true
+		}
+	}
+	return Call{}, false
+}
+
+// Count returns the number of calls that match m.
+func (j Journal) Count(m CallMatcher) int {
+	return len(j.Filter(m))
+}
+
+// Before checks that a call matching first arrived before every call matching then, and
+// that there is at least one of each.
+func (j Journal) Before(first, then CallMatcher) error {
+	a, ok := j.First(first)
+	if !ok {
+		return errors.Errorf("no call matches %s; journal:\n%s", first, j)
+	}
+	b, ok := j.First(then)
+	if !ok {
+		return errors.Errorf("no call matches %s; journal:\n%s", then, j)
+	}
+	if a.Seq > b.Seq {
+		return errors.Errorf("expected %s before %s, got %s first; journal:\n%s", first, then, b, j)
+	}
+	return nil
+}
+
+// CapturesWithoutCall returns the captures opened by successful StartCapture or
+// LoadCapture calls that never got a successful call of method, e.g. CloseCapture.
+func (j Journal) CapturesWithoutCall(method Method) []uint64 {
+	var opened []uint64
+	handled := map[uint64]bool{}
+	for _, call := range j {
+		if !call.Succeeded() {
+			continue
+		}
+		switch reply := call.Response.(type) {
+		case *pb.StartCaptureReply:
+			opened = append(opened, reply.GetCaptureInfo().GetCaptureId())
+		case *pb.LoadCaptureReply:
+			opened = append(opened, reply.GetCaptureInfo().GetCaptureId())
+		}
+		if call.Method != method {
+			continue
+		}
+		if id, ok := call.CaptureID(); ok {
+			handled[id] = true
+		}
+	}
+	var out []uint64
+	for _, id := range opened {
+		if !handled[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (j Journal) String() string {
+	lines := make([]string, 0, len(j))
+	for _, call := range j {
+		lines = append(lines, "  "+call.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CallMatcher selects journal calls. Build one with CallTo and narrow it with the With*
+// methods; each returns a new matcher.
+type CallMatcher struct {
+	method Method
+	descs  []string
+	preds  []func(Call) bool
+}
+
+// CallTo matches the calls of method.
+func CallTo(method Method) CallMatcher {
+	return CallMatcher{method: method}
+}
+
+// Where narrows the matcher with an arbitrary predicate; desc names it in errors.
+func (m CallMatcher) Where(desc string, fn func(Call) bool) CallMatcher {
+	m.descs = append(slices.Clip(m.descs), desc)
+	m.preds = append(slices.Clip(m.preds), fn)
+	return m
+}
+
+// WithCaptureID matches the calls whose request names the capture.
+func (m CallMatcher) WithCaptureID(captureID uint64) CallMatcher {
+	return m.Where(fmt.Sprintf("capture_id=%d", captureID), func(c Call) bool {
+		id, ok := c.CaptureID()
+		return ok && id == captureID
+	})
+}
+
+// WithAnalyzer matches AddAnalyzer calls for the analyzer name, and AddHighLevelAnalyzer
+// calls for the HLA name.
+func (m CallMatcher) WithAnalyzer(name string) CallMatcher {
+	return m.Where(fmt.Sprintf("analyzer=%q", name), func(c Call) bool {
+		switch req := c.Request.(type) {
+		case *pb.AddAnalyzerRequest:
+			return req.GetAnalyzerName() == name
+		case *pb.AddHighLevelAnalyzerRequest:
+			return req.GetHlaName() == name
+		default:
+			return false
+		}
+	})
+}
+
+// WithSetting matches AddAnalyzer and AddHighLevelAnalyzer calls that set key to value.
+// Numbers compare by value, whatever their type.
+func (m CallMatcher) WithSetting(key string, value any) CallMatcher {
+	want := normalizeSettingValue(value)
+	return m.Where(fmt.Sprintf("settings[%q]=%v", key, value), func(c Call) bool {
+		var got any
+		switch req := c.Request.(type) {
+		case *pb.AddAnalyzerRequest:
+			v, ok := req.GetSettings()[key]
+			if !ok {
+				return false
+			}
+			got = analyzerSettingValue(v)
+		case *pb.AddHighLevelAnalyzerRequest:
+			v, ok := req.GetSettings()[key]
+			if !ok {
+				return false
+			}
+			got = hlaSettingValue(v)
+		default:
+			return false
+		}
+		return normalizeSettingValue(got) == want
+	})
+}
+
+// Succeeded matches the calls that completed with OK.
+func (m CallMatcher) Succeeded() CallMatcher {
+	return m.Where("succeeded", Call.Succeeded)
+}
+
+// Failed matches the calls that completed with code.
+func (m CallMatcher) Failed(code codes.Code) CallMatcher {
+	return m.Where("failed with "+code.String(), func(c Call) bool {
+		return c.Done && c.Code == code
+	})
+}
+
+// Matches reports whether the call matches.
+func (m CallMatcher) Matches(c Call) bool {
+	if c.Method != m.method {
+		return false
+	}
+	for _, pred := range m.preds {
+		if !pred(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m CallMatcher) String() string {
+	if len(m.descs) == 0 {
+		return string(m.method)
+	}
+	return string(m.method) + "(" + strings.Join(m.descs, ", ") + ")"
+}
+
+// normalizeSettingValue maps numbers to float64 so that settings compare by value.
+func normalizeSettingValue(v any) any {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	default:
+		return v
+	}
+}
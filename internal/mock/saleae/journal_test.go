@@ -0,0 +1,129 @@
+package saleae
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc/codes"
+)
+
+func happyPathConfig(t *testing.T) Config {
+	t.Helper()
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatalf("runtime.Caller failed")
+	}
+	cfg, err := LoadConfig(filepath.Join(filepath.Dir(thisFile), "..", "..", "..", "configs", "mock", "happy-path.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+func newHappyPathServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	plan, err := Compile(happyPathConfig(t))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return NewServer(plan, opts...)
+}
+
+func TestJournal_RecordsCallsAndMatches(t *testing.T) {
+	server := newHappyPathServer(t)
+	ctx := context.Background()
+
+	started, err := server.StartCapture(ctx, &pb.StartCaptureRequest{DeviceId: "DEV1", CaptureConfiguration: manualMode()})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	captureID := started.GetCaptureInfo().GetCaptureId()
+	if _, err := server.StartCapture(ctx, &pb.StartCaptureRequest{DeviceId: "NOPE", CaptureConfiguration: manualMode()}); err == nil {
+		t.Fatalf("expected StartCapture to fail for an unknown device")
+	}
+	req := &pb.AddAnalyzerRequest{
+		CaptureId:    captureID,
+		AnalyzerName: "SPI",
+		Settings: map[string]*pb.AnalyzerSettingValue{
+			"MISO": {Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: 2}},
+		},
+	}
+	if _, err := server.AddAnalyzer(ctx, req); err != nil {
+		t.Fatalf("AddAnalyzer: %v", err)
+	}
+	// The journal keeps a copy of the request.
+	req.AnalyzerName = "I2C"
+
+	journal := server.Journal()
+	if len(journal) != 3 {
+		t.Fatalf("expected 3 calls, got:\n%s", journal)
+	}
+	failed := journal[1]
+	if failed.Seq != 2 || failed.N != 2 || failed.Code != codes.NotFound || failed.Response != nil || !failed.Done {
+		t.Fatalf("unexpected failed call %+v", failed)
+	}
+	if journal.Count(CallTo(MethodStartCapture).Succeeded()) != 1 || journal.Count(CallTo(MethodStartCapture).Failed(codes.NotFound)) != 1 {
+		t.Fatalf("unexpected StartCapture calls:\n%s", journal)
+	}
+
+	addSPI := CallTo(MethodAddAnalyzer).WithCaptureID(captureID).WithAnalyzer("SPI").WithSetting("MISO", 2)
+	if _, ok := journal.First(addSPI); !ok {
+		t.Fatalf("expected a call matching %s, got:\n%s", addSPI, journal)
+	}
+	if journal.Count(CallTo(MethodAddAnalyzer).WithSetting("MISO", 3)) != 0 {
+		t.Fatalf("expected no AddAnalyzer call with MISO=3")
+	}
+	if err := journal.Before(CallTo(MethodStartCapture), addSPI); err != nil {
+		t.Fatalf("Before: %v", err)
+	}
+	if err := journal.Before(addSPI, CallTo(MethodStartCapture)); err == nil || !strings.Contains(err.Error(), "expected AddAnalyzer(") {
+		t.Fatalf("expected an ordering error, got %v", err)
+	}
+	if got := journal.CapturesWithoutCall(MethodCloseCapture); len(got) != 1 || got[0] != captureID {
+		t.Fatalf("expected capture %d to be unclosed, got %v", captureID, got)
+	}
+
+	if _, err := server.CloseCapture(ctx, &pb.CloseCaptureRequest{CaptureId: captureID}); err != nil {
+		t.Fatalf("CloseCapture: %v", err)
+	}
+	if got := server.Journal().CapturesWithoutCall(MethodCloseCapture); len(got) != 0 {
+		t.Fatalf("expected every capture to be closed, got %v", got)
+	}
+
+	var body struct {
+		Calls []struct {
+			Seq     int            `json:"seq"`
+			Method  string         `json:"method"`
+			Request map[string]any `json:"request"`
+			Code    string         `json:"code"`
+		} `json:"calls"`
+	}
+	adminCall(t, server.AdminHandler(), http.MethodGet, "/journal?method=AddAnalyzer&since=1", "", http.StatusOK, &body)
+	if len(body.Calls) != 1 || body.Calls[0].Seq != 3 || body.Calls[0].Request["analyzer_name"] != "SPI" || body.Calls[0].Code != "OK" {
+		t.Fatalf("unexpected journal %+v", body)
+	}
+	adminCall(t, server.AdminHandler(), http.MethodGet, "/journal?method=Nope", "", http.StatusBadRequest, nil)
+
+	server.Reset()
+	if len(server.Journal()) != 0 {
+		t.Fatalf("expected Reset to clear the journal")
+	}
+}
+
+func TestJournal_Limit(t *testing.T) {
+	server := newHappyPathServer(t, WithJournalLimit(2))
+	for i := 0; i < 3; i++ {
+		if _, err := server.GetDevices(context.Background(), &pb.GetDevicesRequest{}); err != nil {
+			t.Fatalf("GetDevices: %v", err)
+		}
+	}
+	journal := server.Journal()
+	if len(journal) != 2 || journal[0].Seq != 2 || journal[1].N != 3 {
+		t.Fatalf("expected the last 2 calls, got:\n%s", journal)
+	}
+}
@@ -0,0 +1,67 @@
+package saleae
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// delayedCall is a call that waits before it responds: the server sleeps for delay (or,
+// with hang, until the client gives up) without holding its lock, then fails with err or
+// runs the handler.
+type delayedCall struct {
+	callN int
+	delay time.Duration
+	hang  bool
+	err   error
+}
+
+// add combines the latency of a method with that of a fault rule.
+func (l LatencyPlan) add(other LatencyPlan) LatencyPlan {
+	return LatencyPlan{
+		Delay:  l.Delay + other.Delay,
+		Jitter: l.Jitter + other.Jitter,
+		Hang:   l.Hang || other.Hang,
+	}
+}
+
+// drawDelay returns Delay plus a random part of Jitter. The caller holds s.mu.
+func (s *Server) drawDelay(latency LatencyPlan) time.Duration {
+	delay := latency.Delay
+	if latency.Jitter > 0 {
+		delay += time.Duration(s.rng.Int64N(int64(latency.Jitter)))
+	}
+	return delay
+}
+
+func (s *Server) runDelayed(ctx context.Context, call *delayedCall, fn func(*RuntimeContext) (any, error)) (any, error) {
+	if err := s.sleep(ctx, call.delay, call.hang); err != nil {
+		return nil, err
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.run(ctx, call.callN, fn)
+}
+
+// sleep waits d on the server clock, or forever with hang. It returns the status of the
+// client context if the client cancels the call or its deadline passes first.
+func (s *Server) sleep(ctx context.Context, d time.Duration, hang bool) error {
+	var wake <-chan time.Time
+	if !hang {
+		timer := s.Clock().NewTimer(d)
+		defer timer.Stop()
+		wake = timer.C()
+	}
+
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-wake:
+		return nil
+	}
+}
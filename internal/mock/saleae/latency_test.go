@@ -0,0 +1,132 @@
+package saleae
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func startLatencyServer(t *testing.T, timing TimingDefaultsConfig, faults []FaultRuleConfig, opts ...Option) pb.ManagerClient {
+	t.Helper()
+	plan, err := Compile(Config{
+		Defaults: DefaultsConfig{Timing: timing},
+		Fixtures: FixturesConfig{Devices: []DeviceConfig{{DeviceID: "DEV1", DeviceType: "DEVICE_TYPE_LOGIC_PRO_8"}}},
+		Faults:   faults,
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	_, _, listener, cleanup, err := StartMockServer(plan, opts...)
+	if err != nil {
+		t.Fatalf("StartMockServer: %v", err)
+	}
+	t.Cleanup(cleanup)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewManagerClient(conn)
+}
+
+func TestLatency_FaultDelayThenSucceedOnVirtualClock(t *testing.T) {
+	clock := NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := startLatencyServer(t, TimingDefaultsConfig{}, []FaultRuleConfig{{
+		When:    FaultWhenConfig{Method: "GetDevices", NthCall: ptrInt(1)},
+		Respond: FaultRespondConfig{DelayMs: 60000},
+	}}, WithClock(clock))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		_, err := manager.GetDevices(ctx, &pb.GetDevicesRequest{})
+		errc <- err
+	}()
+
+	// The delayed call holds no lock: the next one answers right away.
+	time.Sleep(20 * time.Millisecond)
+	if _, err := manager.GetAppInfo(ctx, &pb.GetAppInfoRequest{}); err != nil {
+		t.Fatalf("GetAppInfo during a delayed call: %v", err)
+	}
+	clock.Advance(59 * time.Second)
+	select {
+	case err := <-errc:
+		t.Fatalf("expected GetDevices to wait for the full delay, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if err := <-errc; err != nil {
+		t.Fatalf("expected GetDevices to succeed after the delay, got %v", err)
+	}
+}
+
+func TestLatency_DelayThenFailAndGlobalLatency(t *testing.T) {
+	manager := startLatencyServer(t,
+		TimingDefaultsConfig{Latency: []LatencyRuleConfig{{Method: "GetDevices", DelayMs: 20, JitterMs: 10}}},
+		[]FaultRuleConfig{{
+			When:    FaultWhenConfig{Method: "GetDevices", NthCall: ptrInt(2)},
+			Respond: FaultRespondConfig{Status: "UNAVAILABLE", Message: "slow and broken", DelayMs: 30},
+		}},
+	)
+	ctx := context.Background()
+
+	start := time.Now()
+	if _, err := manager.GetDevices(ctx, &pb.GetDevicesRequest{}); err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected the method latency to apply, took %s", elapsed)
+	}
+
+	start = time.Now()
+	_, err := manager.GetDevices(ctx, &pb.GetDevicesRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected the method and fault delays to add up, took %s", elapsed)
+	}
+}
+
+func TestLatency_HangUntilClientDeadline(t *testing.T) {
+	manager := startLatencyServer(t, TimingDefaultsConfig{Latency: []LatencyRuleConfig{{Method: "GetAppInfo", Hang: true}}}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := manager.GetAppInfo(ctx, &pb.GetAppInfoRequest{}); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if _, err := manager.GetDevices(context.Background(), &pb.GetDevicesRequest{}); err != nil {
+		t.Fatalf("expected other methods to answer, got %v", err)
+	}
+}
+
+func TestLatency_CompileErrors(t *testing.T) {
+	cases := map[string]Config{
+		"needs a status, delay_ms": {Faults: []FaultRuleConfig{{When: FaultWhenConfig{Method: "GetDevices"}}}},
+		"hang cannot be combined with status": {Faults: []FaultRuleConfig{{
+			When:    FaultWhenConfig{Method: "GetDevices"},
+			Respond: FaultRespondConfig{Status: "UNAVAILABLE", Message: "x", Hang: true},
+		}}},
+		"cannot be negative": {Defaults: DefaultsConfig{Timing: TimingDefaultsConfig{
+			Latency: []LatencyRuleConfig{{Method: "GetDevices", DelayMs: -1}},
+		}}},
+		"duplicate method": {Defaults: DefaultsConfig{Timing: TimingDefaultsConfig{
+			Latency: []LatencyRuleConfig{{Method: "GetDevices", DelayMs: 1}, {Method: "GetDevices", Hang: true}},
+		}}},
+	}
+	for want, cfg := range cases {
+		if _, err := Compile(cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -33,7 +33,7 @@ func TestCLI_PipelineRun_AgainstMockServer(t *testing.T) {
 		t.Fatalf("Compile(%s): %v", cfgPath, err)
 	}
 
-	_, _, listener, cleanup, err := StartMockServer(plan)
+	server, _, listener, cleanup, err := StartMockServer(plan)
 	if err != nil {
 		t.Fatalf("StartMockServer: %v", err)
 	}
@@ -126,4 +126,16 @@ func TestCLI_PipelineRun_AgainstMockServer(t *testing.T) {
 	if !strings.Contains(string(bTable), "filter.query=0xAA") {
 		t.Fatalf("expected filter marker in %s, got:\n%s", tablePath, string(bTable))
 	}
+
+	// Verify what the client sent: the settings file is applied before the table export,
+	// and the cleanup closes every capture.
+	journal := server.Journal()
+	addSPI := CallTo(MethodAddAnalyzer).WithAnalyzer("SPI").
+		WithSetting("Clock", 0).WithSetting("MOSI", 1).WithSetting("MISO", 2).WithSetting("Enable", 3)
+	if err := journal.Before(addSPI, CallTo(MethodExportDataTableCsv)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if unclosed := journal.CapturesWithoutCall(MethodCloseCapture); len(unclosed) != 0 {
+		t.Fatalf("expected every capture to be closed, got %v; journal:\n%s", unclosed, journal)
+	}
 }
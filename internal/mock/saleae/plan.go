@@ -3,6 +3,7 @@ package saleae
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,6 +20,48 @@ type Plan struct {
 	Fixtures FixturesPlan
 	Behavior BehaviorPlan
 	Faults   []FaultRule
+	Timeline []TimelineEvent
+}
+
+// TimelineEvent is a scripted change of the mock. It fires once OnCall's call arrives or,
+// without OnCall, once At has passed on the server clock since the server started or was
+// reset. Exactly one of the action fields is set.
+type TimelineEvent struct {
+	At     time.Duration
+	OnCall *TimelineCall
+
+	RemoveDevice  string
+	AddDevice     *pb.Device
+	CaptureStatus *TimelineCaptureStatus
+	FailCapture   *TimelineCaptureFailure
+	AppInfo       *pb.AppInfo
+	Unavailable   *TimelineUnavailable
+}
+
+// TimelineCall is the N-th call of Method, or of any method when Method is empty.
+type TimelineCall struct {
+	Method Method
+	N      int
+}
+
+// TimelineCaptureStatus moves a capture, or every running one when CaptureID is 0.
+type TimelineCaptureStatus struct {
+	CaptureID uint64
+	Status    CaptureStatus
+}
+
+// TimelineCaptureFailure stops a capture, or every running one when CaptureID is 0, with
+// an error that WaitCapture and StopCapture return from then on.
+type TimelineCaptureFailure struct {
+	CaptureID uint64
+	Code      codes.Code
+	Message   string
+}
+
+// TimelineUnavailable fails every call with UNAVAILABLE for a while.
+type TimelineUnavailable struct {
+	For     time.Duration
+	Message string
 }
 
 type DefaultsPlan struct {
@@ -27,6 +70,28 @@ type DefaultsPlan struct {
 	AnalyzerIDStart          uint64
 	WaitCapturePolicy        WaitCapturePolicy
 	WaitCaptureMaxBlock      time.Duration
+	Clock                    ClockPlan
+	// Latency applies to every call of a method, before faults respond.
+	Latency map[Method]LatencyPlan
+	// Seed seeds fault probabilities and latency jitter; 0 picks a random seed.
+	Seed uint64
+}
+
+// LatencyPlan delays a call by Delay plus a random duration below Jitter, or, with Hang,
+// until the client cancels it or its deadline passes.
+type LatencyPlan struct {
+	Delay  time.Duration
+	Jitter time.Duration
+	Hang   bool
+}
+
+// ClockPlan selects the server clock when no WithClock option is given.
+type ClockPlan struct {
+	Virtual bool
+	// Start is the initial virtual time; zero means the real time at server creation.
+	Start time.Time
+	// Scale is the speed of the virtual clock relative to real time; 0 freezes it.
+	Scale float64
 }
 
 type FixturesPlan struct {
@@ -41,6 +106,17 @@ type CapturePlan struct {
 	Origin    CaptureOrigin
 	StartedAt time.Time
 	Mode      CaptureMode
+	// Signals is the recorded content raw exports write; nil writes placeholders.
+	Signals *SignalPlan
+	// DataTables are the declared analyzer frames; analyzers without one are decoded
+	// from Signals.
+	DataTables []DataTablePlan
+}
+
+// hasContent reports whether exports of the capture write synthetic data instead of
+// placeholders.
+func (c CapturePlan) hasContent() bool {
+	return c.Signals != nil || len(c.DataTables) > 0
 }
 
 type BehaviorPlan struct {
@@ -78,7 +154,8 @@ type LoadCapturePlan struct {
 type SaveCapturePlan struct {
 	RequireCaptureExists bool
 	WritePlaceholderFile bool
-	PlaceholderBytes     []byte
+	// PlaceholderBytes replaces the .sal archive when set.
+	PlaceholderBytes []byte
 }
 
 type StopCapturePlan struct {
@@ -146,11 +223,25 @@ type RemoveHighLevelAnalyzerPlan struct {
 }
 
 type FaultRule struct {
-	Method  Method
-	NthCall *int
+	Name     string
+	Method   Method
+	NthCall  *int
+	FromCall int
+	ToCall   int
+	EveryNth int
+	// Probability is the chance that a matching call fires the rule (1: always).
+	Probability float64
+	// After names the rule that must have fired before this one turns on.
+	After string
+	// Times is the number of firings after which the rule expires (0: never).
+	Times   int
 	Match   func(any) bool
+	Latency LatencyPlan
+	// Code is the status to fail with; OK runs the call once the latency has passed.
 	Code    codes.Code
 	Message string
+	// Config is the rule as written in the config.
+	Config FaultRuleConfig
 }
 
 func Compile(cfg Config) (*Plan, error) {
@@ -167,6 +258,7 @@ func Compile(cfg Config) (*Plan, error) {
 		AnalyzerIDStart:          10000,
 		WaitCapturePolicy:        WaitCaptureImmediate,
 		WaitCaptureMaxBlock:      0,
+		Seed:                     cfg.Defaults.Seed,
 	}
 
 	if cfg.Defaults.GRPC.StatusOnUnknownCaptureID != "" {
@@ -195,6 +287,18 @@ func Compile(cfg Config) (*Plan, error) {
 	if cfg.Defaults.Timing.MaxBlockMs > 0 {
 		defaults.WaitCaptureMaxBlock = time.Duration(cfg.Defaults.Timing.MaxBlockMs) * time.Millisecond
 	}
+	if cfg.Defaults.Timing.Clock != nil {
+		clock, err := compileClock(*cfg.Defaults.Timing.Clock)
+		if err != nil {
+			return nil, err
+		}
+		defaults.Clock = clock
+	}
+	latency, err := compileLatencyRules(cfg.Defaults.Timing.Latency)
+	if err != nil {
+		return nil, err
+	}
+	defaults.Latency = latency
 
 	fixtures, err := compileFixtures(cfg.Fixtures)
 	if err != nil {
@@ -210,6 +314,14 @@ func Compile(cfg Config) (*Plan, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkFaultNames(faults, nil); err != nil {
+		return nil, err
+	}
+
+	timeline, err := compileTimeline(cfg.Timeline)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Plan{
 		Version:  cfg.Version,
@@ -218,6 +330,205 @@ func Compile(cfg Config) (*Plan, error) {
 		Fixtures: fixtures,
 		Behavior: behavior,
 		Faults:   faults,
+		Timeline: timeline,
+	}, nil
+}
+
+func compileTimeline(cfg []TimelineEventConfig) ([]TimelineEvent, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	events := make([]TimelineEvent, 0, len(cfg))
+	for i, event := range cfg {
+		compiled, err := compileTimelineEvent(event)
+		if err != nil {
+			return nil, errors.Wrapf(err, "timeline[%d]", i)
+		}
+		events = append(events, compiled)
+	}
+	return events, nil
+}
+
+func compileTimelineEvent(cfg TimelineEventConfig) (TimelineEvent, error) {
+	var event TimelineEvent
+	switch {
+	case cfg.At != "" && cfg.OnCall != nil:
+		return TimelineEvent{}, errors.New("at and on_call are exclusive")
+	case cfg.At != "":
+		at, err := time.ParseDuration(cfg.At)
+		if err != nil || at < 0 {
+			return TimelineEvent{}, errors.Errorf("invalid at %q (e.g. \"2s\")", cfg.At)
+		}
+		event.At = at
+	case cfg.OnCall != nil:
+		if cfg.OnCall.N < 1 {
+			return TimelineEvent{}, errors.New("on_call.n must be >= 1")
+		}
+		call := &TimelineCall{N: cfg.OnCall.N}
+		if cfg.OnCall.Method != "" {
+			method, err := parseMethod(cfg.OnCall.Method)
+			if err != nil {
+				return TimelineEvent{}, err
+			}
+			call.Method = method
+		}
+		event.OnCall = call
+	default:
+		return TimelineEvent{}, errors.New("at or on_call is required")
+	}
+
+	actions := 0
+	if cfg.RemoveDevice != "" {
+		actions++
+		event.RemoveDevice = cfg.RemoveDevice
+	}
+	if cfg.AddDevice != nil {
+		actions++
+		device, err := compileDevice("add_device", *cfg.AddDevice)
+		if err != nil {
+			return TimelineEvent{}, err
+		}
+		event.AddDevice = device
+	}
+	if cfg.CaptureStatus != nil {
+		actions++
+		if cfg.CaptureStatus.Status == "" {
+			return TimelineEvent{}, errors.New("capture_status.status is required")
+		}
+		status, err := parseCaptureStatus(cfg.CaptureStatus.Status)
+		if err != nil {
+			return TimelineEvent{}, err
+		}
+		event.CaptureStatus = &TimelineCaptureStatus{CaptureID: cfg.CaptureStatus.CaptureID, Status: status}
+	}
+	if cfg.FailCapture != nil {
+		actions++
+		failure := &TimelineCaptureFailure{
+			CaptureID: cfg.FailCapture.CaptureID,
+			Code:      codes.Aborted,
+			Message:   "ERROR_CODE_DEVICE_ERROR: device error during capture",
+		}
+		if cfg.FailCapture.Status != "" {
+			code, err := parseStatusCode(cfg.FailCapture.Status)
+			if err != nil {
+				return TimelineEvent{}, err
+			}
+			if code == codes.OK {
+				return TimelineEvent{}, errors.New("fail_capture.status cannot be OK")
+			}
+			failure.Code = code
+		}
+		if cfg.FailCapture.Message != "" {
+			failure.Message = cfg.FailCapture.Message
+		}
+		event.FailCapture = failure
+	}
+	if cfg.AppInfo != nil {
+		actions++
+		appInfo, err := compileAppInfo(*cfg.AppInfo)
+		if err != nil {
+			return TimelineEvent{}, err
+		}
+		event.AppInfo = appInfo
+	}
+	if cfg.Unavailable != nil {
+		actions++
+		window, err := time.ParseDuration(cfg.Unavailable.For)
+		if err != nil || window <= 0 {
+			return TimelineEvent{}, errors.Errorf("invalid unavailable.for %q (e.g. \"5s\")", cfg.Unavailable.For)
+		}
+		message := cfg.Unavailable.Message
+		if message == "" {
+			message = "Logic 2 is not responding"
+		}
+		event.Unavailable = &TimelineUnavailable{For: window, Message: message}
+	}
+	if actions != 1 {
+		return TimelineEvent{}, errors.New("exactly one of remove_device, add_device, capture_status, fail_capture, app_info or unavailable is required")
+	}
+	return event, nil
+}
+
+func compileClock(cfg ClockConfig) (ClockPlan, error) {
+	plan := ClockPlan{Scale: cfg.Scale}
+	switch strings.ToLower(strings.TrimSpace(cfg.Mode)) {
+	case "", "real":
+		if cfg.Start != "" || cfg.Scale != 0 {
+			return ClockPlan{}, errors.New("defaults.timing.clock.start/scale require mode: virtual")
+		}
+		return plan, nil
+	case "virtual":
+		plan.Virtual = true
+	default:
+		return ClockPlan{}, errors.Errorf("unknown clock mode %q", cfg.Mode)
+	}
+	if cfg.Scale < 0 {
+		return ClockPlan{}, errors.New("defaults.timing.clock.scale cannot be negative")
+	}
+	if cfg.Start != "" {
+		start, err := time.Parse(time.RFC3339, cfg.Start)
+		if err != nil {
+			return ClockPlan{}, errors.Wrap(err, "parse defaults.timing.clock.start")
+		}
+		plan.Start = start
+	}
+	return plan, nil
+}
+
+func compileLatencyRules(cfg []LatencyRuleConfig) (map[Method]LatencyPlan, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	out := make(map[Method]LatencyPlan, len(cfg))
+	for _, rule := range cfg {
+		if rule.Method == "" {
+			return nil, errors.New("defaults.timing.latency.method is required")
+		}
+		method, err := parseMethod(rule.Method)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := out[method]; ok {
+			return nil, errors.Errorf("defaults.timing.latency: duplicate method %s", method)
+		}
+		latency, err := compileLatency("defaults.timing.latency", rule.DelayMs, rule.JitterMs, rule.Hang)
+		if err != nil {
+			return nil, err
+		}
+		out[method] = latency
+	}
+	return out, nil
+}
+
+func compileLatency(field string, delayMs, jitterMs int, hang bool) (LatencyPlan, error) {
+	if delayMs < 0 || jitterMs < 0 {
+		return LatencyPlan{}, errors.Errorf("%s.delay_ms/jitter_ms cannot be negative", field)
+	}
+	if hang && (delayMs > 0 || jitterMs > 0) {
+		return LatencyPlan{}, errors.Errorf("%s.hang cannot be combined with delay_ms/jitter_ms", field)
+	}
+	return LatencyPlan{
+		Delay:  time.Duration(delayMs) * time.Millisecond,
+		Jitter: time.Duration(jitterMs) * time.Millisecond,
+		Hang:   hang,
+	}, nil
+}
+
+func compileDevice(field string, device DeviceConfig) (*pb.Device, error) {
+	if device.DeviceID == "" {
+		return nil, errors.Errorf("%s.device_id is required", field)
+	}
+	if device.DeviceType == "" {
+		return nil, errors.Errorf("%s.device_type is required", field)
+	}
+	deviceType, err := parseDeviceType(device.DeviceType)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Device{
+		DeviceId:     device.DeviceID,
+		DeviceType:   deviceType,
+		IsSimulation: device.IsSimulation,
 	}, nil
 }
 
@@ -233,21 +544,11 @@ func compileFixtures(cfg FixturesConfig) (FixturesPlan, error) {
 
 	devices := make([]*pb.Device, 0, len(cfg.Devices))
 	for _, device := range cfg.Devices {
-		if device.DeviceID == "" {
-			return FixturesPlan{}, errors.New("fixtures.devices.device_id is required")
-		}
-		if device.DeviceType == "" {
-			return FixturesPlan{}, errors.New("fixtures.devices.device_type is required")
-		}
-		deviceType, err := parseDeviceType(device.DeviceType)
+		compiled, err := compileDevice("fixtures.devices", device)
 		if err != nil {
 			return FixturesPlan{}, err
 		}
-		devices = append(devices, &pb.Device{
-			DeviceId:     device.DeviceID,
-			DeviceType:   deviceType,
-			IsSimulation: device.IsSimulation,
-		})
+		devices = append(devices, compiled)
 	}
 	plan.Devices = devices
 
@@ -275,12 +576,22 @@ func compileFixtures(cfg FixturesConfig) (FixturesPlan, error) {
 				return FixturesPlan{}, errors.Wrapf(err, "parse fixtures.captures.started_at for capture %d", capture.CaptureID)
 			}
 		}
+		signals, err := compileSignals(capture.Signals)
+		if err != nil {
+			return FixturesPlan{}, errors.Wrapf(err, "fixtures.captures.signals for capture %d", capture.CaptureID)
+		}
+		dataTables, err := compileDataTables(capture.DataTables)
+		if err != nil {
+			return FixturesPlan{}, errors.Wrapf(err, "fixtures.captures.data_tables for capture %d", capture.CaptureID)
+		}
 		captures = append(captures, CapturePlan{
-			ID:        capture.CaptureID,
-			Status:    status,
-			Origin:    origin,
-			StartedAt: startedAt,
-			Mode:      mode,
+			ID:         capture.CaptureID,
+			Status:     status,
+			Origin:     origin,
+			StartedAt:  startedAt,
+			Mode:       mode,
+			Signals:    signals,
+			DataTables: dataTables,
 		})
 	}
 	plan.Captures = captures
@@ -405,8 +716,18 @@ func compileBehavior(cfg Config, defaults DefaultsPlan) (BehaviorPlan, error) {
 		if err != nil {
 			return BehaviorPlan{}, err
 		}
+		signals, err := compileSignals(create.Signals)
+		if err != nil {
+			return BehaviorPlan{}, errors.Wrap(err, "behavior.StartCapture.on_call.create_capture.signals")
+		}
+		dataTables, err := compileDataTables(create.DataTables)
+		if err != nil {
+			return BehaviorPlan{}, errors.Wrap(err, "behavior.StartCapture.on_call.create_capture.data_tables")
+		}
 		behavior.StartCapture.CreateCapture.Status = status
 		behavior.StartCapture.CreateCapture.Mode = mode
+		behavior.StartCapture.CreateCapture.Signals = signals
+		behavior.StartCapture.CreateCapture.DataTables = dataTables
 	}
 
 	if cfg.Behavior.LoadCapture.OnCall.CreateCapture != nil {
@@ -419,12 +740,18 @@ func compileBehavior(cfg Config, defaults DefaultsPlan) (BehaviorPlan, error) {
 		if err != nil {
 			return BehaviorPlan{}, err
 		}
+		signals, err := compileSignals(create.Signals)
+		if err != nil {
+			return BehaviorPlan{}, errors.Wrap(err, "behavior.LoadCapture.on_call.create_capture.signals")
+		}
+		dataTables, err := compileDataTables(create.DataTables)
+		if err != nil {
+			return BehaviorPlan{}, errors.Wrap(err, "behavior.LoadCapture.on_call.create_capture.data_tables")
+		}
 		behavior.LoadCapture.CreateCapture.Status = status
 		behavior.LoadCapture.CreateCapture.Mode = mode
-	}
-
-	if cfg.Behavior.SaveCapture.SideEffect.PlaceholderBytes == "" {
-		behavior.SaveCapture.PlaceholderBytes = []byte("SALAD_MOCK_SAL_V1\n")
+		behavior.LoadCapture.CreateCapture.Signals = signals
+		behavior.LoadCapture.CreateCapture.DataTables = dataTables
 	}
 
 	if cfg.Behavior.StopCapture.Transition.From != "" || cfg.Behavior.StopCapture.Transition.To != "" {
@@ -497,256 +824,124 @@ func compileFaults(cfg []FaultRuleConfig) ([]FaultRule, error) {
 		if err != nil {
 			return nil, err
 		}
-		if fault.Respond.Status == "" {
-			return nil, errors.New("faults.respond.status is required")
-		}
-		code, err := parseStatusCode(fault.Respond.Status)
+		respond := fault.Respond
+		latency, err := compileLatency("faults.respond", respond.DelayMs, respond.JitterMs, respond.Hang)
 		if err != nil {
 			return nil, err
 		}
-		if fault.Respond.Message == "" {
-			return nil, errors.New("faults.respond.message is required")
+		code := codes.OK
+		switch {
+		case respond.Status != "":
+			if code, err = parseStatusCode(respond.Status); err != nil {
+				return nil, err
+			}
+			if code != codes.OK && respond.Message == "" {
+				return nil, errors.New("faults.respond.message is required")
+			}
+			if respond.Hang {
+				return nil, errors.New("faults.respond.hang cannot be combined with status")
+			}
+		case latency == LatencyPlan{}:
+			return nil, errors.New("faults.respond needs a status, delay_ms, jitter_ms or hang")
+		}
+
+		when := fault.When
+		if when.NthCall != nil && *when.NthCall < 1 {
+			return nil, errors.New("faults.when.nth_call must be >= 1")
+		}
+		if when.FromCall < 0 || when.ToCall < 0 || when.EveryNth < 0 || fault.Times < 0 {
+			return nil, errors.New("faults.when.from_call/to_call/every_nth and faults.times cannot be negative")
+		}
+		if when.ToCall > 0 && when.FromCall > when.ToCall {
+			return nil, errors.Errorf("faults.when.from_call %d is after to_call %d", when.FromCall, when.ToCall)
+		}
+		probability := 1.0
+		if when.Probability != nil {
+			probability = *when.Probability
+			if probability < 0 || probability > 1 {
+				return nil, errors.Errorf("faults.when.probability %v must be between 0 and 1", probability)
+			}
+		}
+		if when.After != "" && when.After == fault.Name {
+			return nil, errors.Errorf("fault %q cannot come after itself", fault.Name)
 		}
 
-		matcher, err := compileFaultMatcher(method, fault.When.Match)
+		matcher, err := compileFaultMatcher(method, when.Match)
 		if err != nil {
 			return nil, err
 		}
 
 		faults = append(faults, FaultRule{
-			Method:  method,
-			NthCall: fault.When.NthCall,
-			Match:   matcher,
-			Code:    code,
-			Message: fault.Respond.Message,
+			Name:        fault.Name,
+			Method:      method,
+			NthCall:     when.NthCall,
+			FromCall:    when.FromCall,
+			ToCall:      when.ToCall,
+			EveryNth:    when.EveryNth,
+			Probability: probability,
+			After:       when.After,
+			Times:       fault.Times,
+			Match:       matcher,
+			Latency:     latency,
+			Code:        code,
+			Message:     respond.Message,
+			Config:      fault,
 		})
 	}
 	return faults, nil
 }
 
-func compileFaultMatcher(method Method, match *FaultMatchConfig) (func(any) bool, error) {
-	if match == nil {
-		return nil, nil
-	}
-
-	switch method {
-	case MethodGetAppInfo, MethodGetDevices:
-		return nil, errors.Errorf("fault matchers not supported for method %s", method)
-	case MethodStartCapture:
-		if match.CaptureID != nil {
-			// StartCapture doesn't have capture_id in request, so this doesn't make sense
-			return nil, errors.Errorf("fault matcher capture_id not supported for StartCapture")
+// checkFaultNames checks that rule names are unique and that when.after names a rule,
+// either in rules or in known.
+func checkFaultNames(rules []FaultRule, known map[string]bool) error {
+	names := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			continue
 		}
-		if match.AnalyzerID != nil || match.AnalyzerName != nil || match.Filepath != nil {
-			return nil, errors.Errorf("fault matcher fields not supported for StartCapture")
+		if names[rule.Name] || known[rule.Name] {
+			return errors.Errorf("duplicate fault name %q", rule.Name)
 		}
-		// For now, no matchers supported for StartCapture
-		return nil, nil
-	case MethodAddAnalyzer:
-		// Support matching by capture_id and/or analyzer_name
-		var wantCaptureID *uint64
-		if match.CaptureID != nil {
-			wantCaptureID = match.CaptureID
-		}
-		var wantName *string
-		if match.AnalyzerName != nil {
-			wantName = match.AnalyzerName
-		}
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.AddAnalyzerRequest)
-			if !ok {
-				return false
-			}
-			if wantCaptureID != nil && reqTyped.GetCaptureId() != *wantCaptureID {
-				return false
-			}
-			if wantName != nil && reqTyped.GetAnalyzerName() != *wantName {
-				return false
-			}
-			return true
-		}, nil
-	case MethodRemoveAnalyzer:
-		// Support matching by capture_id and/or analyzer_id
-		var wantCaptureID *uint64
-		if match.CaptureID != nil {
-			wantCaptureID = match.CaptureID
-		}
-		var wantAnalyzerID *uint64
-		if match.AnalyzerID != nil {
-			wantAnalyzerID = match.AnalyzerID
-		}
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.RemoveAnalyzerRequest)
-			if !ok {
-				return false
-			}
-			if wantCaptureID != nil && reqTyped.GetCaptureId() != *wantCaptureID {
-				return false
-			}
-			if wantAnalyzerID != nil && reqTyped.GetAnalyzerId() != *wantAnalyzerID {
-				return false
-			}
-			return true
-		}, nil
-	case MethodAddHighLevelAnalyzer:
-		// Support matching by capture_id and/or analyzer_name (HLA name)
-		var wantCaptureID *uint64
-		if match.CaptureID != nil {
-			wantCaptureID = match.CaptureID
-		}
-		var wantName *string
-		if match.AnalyzerName != nil {
-			wantName = match.AnalyzerName
-		}
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.AddHighLevelAnalyzerRequest)
-			if !ok {
-				return false
-			}
-			if wantCaptureID != nil && reqTyped.GetCaptureId() != *wantCaptureID {
-				return false
-			}
-			if wantName != nil && reqTyped.GetHlaName() != *wantName {
-				return false
-			}
-			return true
-		}, nil
-	case MethodRemoveHighLevelAnalyzer:
-		// Support matching by capture_id and/or analyzer_id
-		var wantCaptureID *uint64
-		if match.CaptureID != nil {
-			wantCaptureID = match.CaptureID
-		}
-		var wantAnalyzerID *uint64
-		if match.AnalyzerID != nil {
-			wantAnalyzerID = match.AnalyzerID
-		}
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.RemoveHighLevelAnalyzerRequest)
-			if !ok {
-				return false
-			}
-			if wantCaptureID != nil && reqTyped.GetCaptureId() != *wantCaptureID {
-				return false
-			}
-			if wantAnalyzerID != nil && reqTyped.GetAnalyzerId() != *wantAnalyzerID {
-				return false
-			}
-			return true
-		}, nil
-	case MethodLoadCapture:
-		if match.Filepath == nil {
-			return nil, nil
-		}
-		want := *match.Filepath
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.LoadCaptureRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetFilepath() == want
-		}, nil
-	case MethodSaveCapture:
-		if match.CaptureID == nil {
-			return nil, nil
-		}
-		want := *match.CaptureID
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.SaveCaptureRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetCaptureId() == want
-		}, nil
-	case MethodStopCapture:
-		if match.CaptureID == nil {
-			return nil, nil
-		}
-		want := *match.CaptureID
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.StopCaptureRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetCaptureId() == want
-		}, nil
-	case MethodWaitCapture:
-		if match.CaptureID == nil {
-			return nil, nil
-		}
-		want := *match.CaptureID
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.WaitCaptureRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetCaptureId() == want
-		}, nil
-	case MethodCloseCapture:
-		if match.CaptureID == nil {
-			return nil, nil
-		}
-		want := *match.CaptureID
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.CloseCaptureRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetCaptureId() == want
-		}, nil
-	case MethodExportRawDataCsv:
-		if match.CaptureID == nil {
-			return nil, nil
-		}
-		want := *match.CaptureID
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.ExportRawDataCsvRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetCaptureId() == want
-		}, nil
-	case MethodExportRawDataBinary:
-		if match.CaptureID == nil {
-			return nil, nil
-		}
-		want := *match.CaptureID
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.ExportRawDataBinaryRequest)
-			if !ok {
-				return false
-			}
-			return reqTyped.GetCaptureId() == want
-		}, nil
-	case MethodExportDataTableCsv:
-		// Support matching by capture_id and/or filepath
-		var wantCaptureID *uint64
-		if match.CaptureID != nil {
-			wantCaptureID = match.CaptureID
-		}
-		var wantFilepath *string
-		if match.Filepath != nil {
-			wantFilepath = match.Filepath
-		}
-		if wantCaptureID == nil && wantFilepath == nil {
-			return nil, nil
-		}
-		return func(req any) bool {
-			reqTyped, ok := req.(*pb.ExportDataTableCsvRequest)
-			if !ok {
-				return false
-			}
-			if wantCaptureID != nil && reqTyped.GetCaptureId() != *wantCaptureID {
-				return false
-			}
-			if wantFilepath != nil && reqTyped.GetFilepath() != *wantFilepath {
-				return false
-			}
-			return true
-		}, nil
-	default:
-		return nil, errors.Errorf("fault matchers not supported for method %s", method)
+		names[rule.Name] = true
+	}
+	for _, rule := range rules {
+		if rule.After != "" && !names[rule.After] && !known[rule.After] {
+			return errors.Errorf("faults.when.after: unknown fault %q", rule.After)
+		}
+	}
+	return nil
+}
+
+// matchesCall reports whether the call number of the method is one the rule targets.
+func (rule *FaultRule) matchesCall(callN int) bool {
+	if rule.NthCall != nil && *rule.NthCall != callN {
+		return false
+	}
+	if callN < rule.FromCall || (rule.ToCall > 0 && callN > rule.ToCall) {
+		return false
+	}
+	return rule.EveryNth == 0 || callN%rule.EveryNth == 0
+}
+
+// Names accepted by the config parsers below. They are the canonical (lowercase)
+// spellings and feed the JSON Schema for mock configs.
+var (
+	CaptureStatusNames     = []string{"running", "stopped", "completed", "closed"}
+	CaptureOriginNames     = []string{"loaded", "started"}
+	CaptureModeKindNames   = []string{"timed", "manual", "trigger", "digital_trigger"}
+	WaitCapturePolicyNames = []string{"immediate", "error_if_running", "block_until_done"}
+	CloseCaptureModeNames  = []string{"delete", "mark_closed"}
+	ClockModeNames         = []string{"real", "virtual"}
+)
+
+// GRPCCodeNames returns the accepted gRPC status code names, sorted.
+func GRPCCodeNames() []string {
+	names := make([]string, 0, len(grpcCodeMap))
+	for name := range grpcCodeMap {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
 
 func parseStatusCode(code string) (codes.Code, error) {
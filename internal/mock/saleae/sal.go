@@ -0,0 +1,401 @@
+package saleae
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/pkg/errors"
+)
+
+// salMetaFile is the session description inside a .sal archive.
+const salMetaFile = "meta.json"
+
+// salMeta is the meta.json of a .sal archive. Analyzers use Logic 2's layout (nodeId,
+// type, name and settings rows with a title and a typed setting); capture settings and
+// HLAs use the same conventions.
+type salMeta struct {
+	Version int         `json:"version"`
+	Data    salMetaData `json:"data"`
+}
+
+type salMetaData struct {
+	CaptureSettings    salCaptureSettings     `json:"captureSettings"`
+	Analyzers          []salAnalyzer          `json:"analyzers"`
+	HighLevelAnalyzers []salHighLevelAnalyzer `json:"highLevelAnalyzers"`
+}
+
+type salCaptureSettings struct {
+	// CaptureMode is timed, manual or trigger.
+	CaptureMode     string  `json:"captureMode"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+type salAnalyzer struct {
+	NodeID   uint64          `json:"nodeId"`
+	Type     string          `json:"type"`
+	Name     string          `json:"name"`
+	Settings []salSettingRow `json:"settings"`
+}
+
+type salHighLevelAnalyzer struct {
+	NodeID             uint64          `json:"nodeId"`
+	Type               string          `json:"type"`
+	Name               string          `json:"name"`
+	ExtensionDirectory string          `json:"extensionDirectory"`
+	InputNodeID        uint64          `json:"inputNodeId"`
+	Settings           []salSettingRow `json:"settings"`
+}
+
+type salSettingRow struct {
+	Title   string     `json:"title"`
+	Setting salSetting `json:"setting"`
+}
+
+// salSetting is a typed value. Dropdowns (NumberList) select the option whose value
+// equals Value; the mock writes analyzer strings as single-option dropdowns.
+type salSetting struct {
+	Type    string             `json:"type"`
+	Value   any                `json:"value"`
+	Options []salSettingOption `json:"options,omitempty"`
+}
+
+type salSettingOption struct {
+	DropdownText string `json:"dropdownText"`
+	Value        any    `json:"value"`
+}
+
+// salArchive returns a .sal archive of capture with its mode, analyzers and HLAs.
+func (state *State) salArchive(capture *CaptureState) ([]byte, error) {
+	meta := salMeta{Version: 1}
+	meta.Data.CaptureSettings = salCaptureSettings{CaptureMode: capture.Mode.Kind.String()}
+	if capture.Mode.Kind == CaptureModeTimed {
+		meta.Data.CaptureSettings.DurationSeconds = capture.Mode.Duration.Seconds()
+	}
+	meta.Data.Analyzers = []salAnalyzer{}
+	for _, analyzer := range state.Analyzers[capture.ID] {
+		out := salAnalyzer{NodeID: analyzer.ID, Type: analyzer.Name, Name: labelOr(analyzer.Label, analyzer.Name)}
+		for _, title := range sortedKeys(analyzer.Settings) {
+			out.Settings = append(out.Settings, salSettingRow{Title: title, Setting: analyzerSalSetting(analyzer.Settings[title])})
+		}
+		meta.Data.Analyzers = append(meta.Data.Analyzers, out)
+	}
+	sort.Slice(meta.Data.Analyzers, func(i, j int) bool { return meta.Data.Analyzers[i].NodeID < meta.Data.Analyzers[j].NodeID })
+	meta.Data.HighLevelAnalyzers = []salHighLevelAnalyzer{}
+	for _, hla := range state.HighLevelAnalyzers[capture.ID] {
+		out := salHighLevelAnalyzer{
+			NodeID:             hla.ID,
+			Type:               hla.HLAName,
+			Name:               labelOr(hla.Label, hla.HLAName),
+			ExtensionDirectory: hla.ExtensionDir,
+			InputNodeID:        hla.InputAnalyzerID,
+		}
+		for _, title := range sortedKeys(hla.Settings) {
+			out.Settings = append(out.Settings, salSettingRow{Title: title, Setting: hlaSalSetting(hla.Settings[title])})
+		}
+		meta.Data.HighLevelAnalyzers = append(meta.Data.HighLevelAnalyzers, out)
+	}
+	sort.Slice(meta.Data.HighLevelAnalyzers, func(i, j int) bool {
+		return meta.Data.HighLevelAnalyzers[i].NodeID < meta.Data.HighLevelAnalyzers[j].NodeID
+	})
+
+	body, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "encode meta.json")
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(salMetaFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "create meta.json")
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, errors.Wrap(err, "write meta.json")
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "close .sal archive")
+	}
+	return buf.Bytes(), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func analyzerSalSetting(v *pb.AnalyzerSettingValue) salSetting {
+	switch value := v.GetValue().(type) {
+	case *pb.AnalyzerSettingValue_StringValue:
+		return salSetting{Type: "NumberList", Value: 0, Options: []salSettingOption{{DropdownText: value.StringValue, Value: 0}}}
+	case *pb.AnalyzerSettingValue_Int64Value:
+		return salSetting{Type: "Number", Value: value.Int64Value}
+	case *pb.AnalyzerSettingValue_BoolValue:
+		return salSetting{Type: "Bool", Value: value.BoolValue}
+	case *pb.AnalyzerSettingValue_DoubleValue:
+		return salSetting{Type: "Double", Value: value.DoubleValue}
+	default:
+		return salSetting{Type: "String", Value: ""}
+	}
+}
+
+func hlaSalSetting(v *pb.HighLevelAnalyzerSettingValue) salSetting {
+	switch value := v.GetValue().(type) {
+	case *pb.HighLevelAnalyzerSettingValue_NumberValue:
+		return salSetting{Type: "Double", Value: value.NumberValue}
+	case *pb.HighLevelAnalyzerSettingValue_StringValue:
+		return salSetting{Type: "String", Value: value.StringValue}
+	default:
+		return salSetting{Type: "String", Value: ""}
+	}
+}
+
+// readSalMeta reads the meta.json of the .sal archive at path. It returns false for a
+// missing file or one that is not a zip archive (e.g. a placeholder_bytes file).
+func readSalMeta(path string) (*salMeta, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "read %s", path)
+	}
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return nil, false, nil
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "open .sal archive %s", path)
+	}
+	f, err := zr.Open(salMetaFile)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "open %s in %s", salMetaFile, path)
+	}
+	defer func() { _ = f.Close() }()
+	body, err := io.ReadAll(f)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "read %s in %s", salMetaFile, path)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var meta salMeta
+	if err := decoder.Decode(&meta); err != nil {
+		return nil, false, errors.Wrapf(err, "decode %s in %s", salMetaFile, path)
+	}
+	return &meta, true, nil
+}
+
+// restoreSal gives capture the mode, analyzers and HLAs of meta. Analyzers keep their
+// node IDs unless another analyzer already uses them. State is only changed when the
+// whole meta is valid.
+func (state *State) restoreSal(capture *CaptureState, meta *salMeta, now time.Time) error {
+	mode := capture.Mode
+	if settings := meta.Data.CaptureSettings; settings.CaptureMode != "" {
+		var err error
+		mode, err = parseCaptureMode(&CaptureModeConfig{Kind: settings.CaptureMode, DurationSeconds: settings.DurationSeconds})
+		if err != nil {
+			return err
+		}
+	}
+	analyzers := make([]*AnalyzerState, 0, len(meta.Data.Analyzers))
+	for _, saved := range meta.Data.Analyzers {
+		analyzer := &AnalyzerState{
+			ID:        saved.NodeID,
+			CaptureID: capture.ID,
+			Name:      saved.Type,
+			Label:     savedLabel(saved.Name, saved.Type),
+			Settings:  make(map[string]*pb.AnalyzerSettingValue, len(saved.Settings)),
+			CreatedAt: now,
+		}
+		for _, row := range saved.Settings {
+			value, err := row.Setting.analyzerValue()
+			if err != nil {
+				return errors.Wrapf(err, "analyzer %d setting %q", saved.NodeID, row.Title)
+			}
+			analyzer.Settings[row.Title] = value
+		}
+		analyzers = append(analyzers, analyzer)
+	}
+	hlas := make([]*HighLevelAnalyzerState, 0, len(meta.Data.HighLevelAnalyzers))
+	for _, saved := range meta.Data.HighLevelAnalyzers {
+		hla := &HighLevelAnalyzerState{
+			ID:              saved.NodeID,
+			CaptureID:       capture.ID,
+			ExtensionDir:    saved.ExtensionDirectory,
+			HLAName:         saved.Type,
+			Label:           savedLabel(saved.Name, saved.Type),
+			InputAnalyzerID: saved.InputNodeID,
+			Settings:        make(map[string]*pb.HighLevelAnalyzerSettingValue, len(saved.Settings)),
+			CreatedAt:       now,
+		}
+		for _, row := range saved.Settings {
+			value, err := row.Setting.hlaValue()
+			if err != nil {
+				return errors.Wrapf(err, "HLA %d setting %q", saved.NodeID, row.Title)
+			}
+			hla.Settings[row.Title] = value
+		}
+		hlas = append(hlas, hla)
+	}
+
+	capture.Mode = mode
+	ids := map[uint64]uint64{}
+	for _, analyzer := range analyzers {
+		analyzer.ID = state.restoredAnalyzerID(analyzer.ID, ids)
+		if state.Analyzers[capture.ID] == nil {
+			state.Analyzers[capture.ID] = make(map[uint64]*AnalyzerState)
+		}
+		state.Analyzers[capture.ID][analyzer.ID] = analyzer
+	}
+	for _, hla := range hlas {
+		hla.ID = state.restoredAnalyzerID(hla.ID, ids)
+		if state.HighLevelAnalyzers[capture.ID] == nil {
+			state.HighLevelAnalyzers[capture.ID] = make(map[uint64]*HighLevelAnalyzerState)
+		}
+		state.HighLevelAnalyzers[capture.ID][hla.ID] = hla
+	}
+	for _, hla := range hlas {
+		if id, ok := ids[hla.InputAnalyzerID]; ok {
+			hla.InputAnalyzerID = id
+		}
+	}
+	return nil
+}
+
+// savedLabel is the label of a saved analyzer: its name, unless that is just its type.
+func savedLabel(name, typ string) string {
+	if name == typ {
+		return ""
+	}
+	return name
+}
+
+// restoredAnalyzerID returns nodeID if it is free, or else the next analyzer ID, and
+// records the mapping in ids.
+func (state *State) restoredAnalyzerID(nodeID uint64, ids map[uint64]uint64) uint64 {
+	taken := nodeID == 0 || state.analyzerIDInUse(nodeID)
+	for _, restored := range ids {
+		taken = taken || restored == nodeID
+	}
+	id := nodeID
+	if taken {
+		id = state.NextAnalyzerID
+	}
+	if id >= state.NextAnalyzerID {
+		state.NextAnalyzerID = id + 1
+	}
+	ids[nodeID] = id
+	return id
+}
+
+func (state *State) analyzerIDInUse(id uint64) bool {
+	for _, byCapture := range state.Analyzers {
+		if _, ok := byCapture[id]; ok {
+			return true
+		}
+	}
+	for _, byCapture := range state.HighLevelAnalyzers {
+		if _, ok := byCapture[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzerValue converts a setting back to its AddAnalyzer value. Channel and integral
+// Number settings are ints; dropdowns are their selected dropdownText.
+func (s salSetting) analyzerValue() (*pb.AnalyzerSettingValue, error) {
+	switch s.Type {
+	case "NumberList":
+		for _, option := range s.Options {
+			if jsonEqual(option.Value, s.Value) {
+				return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_StringValue{StringValue: option.DropdownText}}, nil
+			}
+		}
+		return nil, errors.Errorf("no option with value %v", s.Value)
+	case "Channel", "Number":
+		n, ok := s.Value.(json.Number)
+		if !ok {
+			return nil, errors.Errorf("%s value %v is not a number", s.Type, s.Value)
+		}
+		v, err := n.Int64()
+		if err != nil {
+			return nil, errors.Errorf("%s value %v is not an integer", s.Type, s.Value)
+		}
+		return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: v}}, nil
+	case "Double":
+		v, err := s.float()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_DoubleValue{DoubleValue: v}}, nil
+	case "Bool":
+		v, ok := s.Value.(bool)
+		if !ok {
+			return nil, errors.Errorf("Bool value %v is not a bool", s.Value)
+		}
+		return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_BoolValue{BoolValue: v}}, nil
+	case "String":
+		v, ok := s.Value.(string)
+		if !ok {
+			return nil, errors.Errorf("String value %v is not a string", s.Value)
+		}
+		return &pb.AnalyzerSettingValue{Value: &pb.AnalyzerSettingValue_StringValue{StringValue: v}}, nil
+	default:
+		return nil, errors.Errorf("unsupported setting type %q", s.Type)
+	}
+}
+
+// hlaValue converts a setting back to its AddHighLevelAnalyzer value: a string or a
+// number.
+func (s salSetting) hlaValue() (*pb.HighLevelAnalyzerSettingValue, error) {
+	switch s.Type {
+	case "String", "NumberList":
+		value, err := s.analyzerValue()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.HighLevelAnalyzerSettingValue{Value: &pb.HighLevelAnalyzerSettingValue_StringValue{StringValue: value.GetStringValue()}}, nil
+	case "Double", "Number":
+		v, err := s.float()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.HighLevelAnalyzerSettingValue{Value: &pb.HighLevelAnalyzerSettingValue_NumberValue{NumberValue: v}}, nil
+	default:
+		return nil, errors.Errorf("unsupported HLA setting type %q", s.Type)
+	}
+}
+
+func (s salSetting) float() (float64, error) {
+	n, ok := s.Value.(json.Number)
+	if !ok {
+		return 0, errors.Errorf("%s value %v is not a number", s.Type, s.Value)
+	}
+	v, err := n.Float64()
+	if err != nil {
+		return 0, errors.Errorf("%s value %v is not a number", s.Type, s.Value)
+	}
+	return v, nil
+}
+
+// jsonEqual compares decoded option values, numbers by their text.
+func jsonEqual(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,252 @@
+package saleae
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// setUpAnalyzers adds a labelled SPI analyzer and an HLA on top of it to capture.
+func setUpAnalyzers(t *testing.T, server *Server, captureID uint64) (uint64, uint64) {
+	t.Helper()
+	ctx := context.Background()
+	spi, err := server.AddAnalyzer(ctx, &pb.AddAnalyzerRequest{
+		CaptureId:     captureID,
+		AnalyzerName:  "SPI",
+		AnalyzerLabel: "flash",
+		Settings: map[string]*pb.AnalyzerSettingValue{
+			"Clock":              {Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: 0}},
+			"MOSI":               {Value: &pb.AnalyzerSettingValue_Int64Value{Int64Value: 1}},
+			"Bits per Transfer":  {Value: &pb.AnalyzerSettingValue_StringValue{StringValue: "8 Bits per Transfer (Standard)"}},
+			"Enable Line Active": {Value: &pb.AnalyzerSettingValue_BoolValue{BoolValue: true}},
+			"Threshold":          {Value: &pb.AnalyzerSettingValue_DoubleValue{DoubleValue: 1.65}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddAnalyzer: %v", err)
+	}
+	hla, err := server.AddHighLevelAnalyzer(ctx, &pb.AddHighLevelAnalyzerRequest{
+		CaptureId:          captureID,
+		ExtensionDirectory: "/ext/flash-commands",
+		HlaName:            "Flash Commands",
+		InputAnalyzerId:    spi.GetAnalyzerId(),
+		Settings: map[string]*pb.HighLevelAnalyzerSettingValue{
+			"Chip":    {Value: &pb.HighLevelAnalyzerSettingValue_StringValue{StringValue: "W25Q64"}},
+			"Page KB": {Value: &pb.HighLevelAnalyzerSettingValue_NumberValue{NumberValue: 4}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddHighLevelAnalyzer: %v", err)
+	}
+	return spi.GetAnalyzerId(), hla.GetAnalyzerId()
+}
+
+// analyzersOf returns the snapshot of a capture's analyzers and HLAs without capture IDs
+// and creation times.
+func analyzersOf(server *Server, captureID uint64) ([]AnalyzerSnapshot, []HighLevelAnalyzerSnapshot) {
+	snapshot := server.Snapshot()
+	var analyzers []AnalyzerSnapshot
+	for _, a := range snapshot.Analyzers {
+		if a.CaptureID == captureID {
+			a.CaptureID, a.CreatedAt = 0, time.Time{}
+			analyzers = append(analyzers, a)
+		}
+	}
+	var hlas []HighLevelAnalyzerSnapshot
+	for _, h := range snapshot.HighLevelAnalyzers {
+		if h.CaptureID == captureID {
+			h.CaptureID, h.CreatedAt = 0, time.Time{}
+			hlas = append(hlas, h)
+		}
+	}
+	return analyzers, hlas
+}
+
+func TestSal_SaveCloseLoadKeepsAnalyzers(t *testing.T) {
+	server := newHappyPathServer(t)
+	ctx := context.Background()
+	started, err := server.StartCapture(ctx, &pb.StartCaptureRequest{DeviceId: "DEV1", CaptureConfiguration: manualMode()})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	captureID := started.GetCaptureInfo().GetCaptureId()
+	spiID, hlaID := setUpAnalyzers(t, server, captureID)
+	wantAnalyzers, wantHLAs := analyzersOf(server, captureID)
+
+	path := filepath.Join(t.TempDir(), "session.sal")
+	if _, err := server.SaveCapture(ctx, &pb.SaveCaptureRequest{CaptureId: captureID, Filepath: path}); err != nil {
+		t.Fatalf("SaveCapture: %v", err)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+	f, err := zr.Open("meta.json")
+	if err != nil {
+		t.Fatalf("open meta.json: %v", err)
+	}
+	var meta struct {
+		Data struct {
+			Analyzers []struct {
+				NodeID   uint64 `json:"nodeId"`
+				Type     string `json:"type"`
+				Name     string `json:"name"`
+				Settings []struct {
+					Title string `json:"title"`
+				} `json:"settings"`
+			} `json:"analyzers"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(f).Decode(&meta); err != nil {
+		t.Fatalf("decode meta.json: %v", err)
+	}
+	_ = f.Close()
+	_ = zr.Close()
+	if len(meta.Data.Analyzers) != 1 || meta.Data.Analyzers[0].NodeID != spiID || meta.Data.Analyzers[0].Type != "SPI" || meta.Data.Analyzers[0].Name != "flash" || len(meta.Data.Analyzers[0].Settings) != 5 {
+		t.Fatalf("unexpected meta.json analyzers %+v", meta.Data.Analyzers)
+	}
+
+	if _, err := server.CloseCapture(ctx, &pb.CloseCaptureRequest{CaptureId: captureID}); err != nil {
+		t.Fatalf("CloseCapture: %v", err)
+	}
+	loaded, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: path})
+	if err != nil {
+		t.Fatalf("LoadCapture: %v", err)
+	}
+	loadedID := loaded.GetCaptureInfo().GetCaptureId()
+
+	gotAnalyzers, gotHLAs := analyzersOf(server, loadedID)
+	if !reflect.DeepEqual(gotAnalyzers, wantAnalyzers) {
+		t.Fatalf("expected analyzers %+v, got %+v", wantAnalyzers, gotAnalyzers)
+	}
+	if len(gotHLAs) != 1 || gotHLAs[0].ID != hlaID || gotHLAs[0].InputAnalyzerID != spiID {
+		t.Fatalf("expected HLA %d on analyzer %d, got %+v", hlaID, spiID, gotHLAs)
+	}
+	if !reflect.DeepEqual(gotHLAs, wantHLAs) {
+		t.Fatalf("expected HLAs %+v, got %+v", wantHLAs, gotHLAs)
+	}
+	for _, capture := range server.Snapshot().Captures {
+		if capture.ID == loadedID && capture.Mode != "manual" {
+			t.Fatalf("expected the loaded capture to keep manual mode, got %s", capture.Mode)
+		}
+	}
+
+	// New analyzers don't reuse the restored IDs.
+	next, err := server.AddAnalyzer(ctx, &pb.AddAnalyzerRequest{CaptureId: loadedID, AnalyzerName: "I2C"})
+	if err != nil {
+		t.Fatalf("AddAnalyzer: %v", err)
+	}
+	if next.GetAnalyzerId() == spiID || next.GetAnalyzerId() == hlaID {
+		t.Fatalf("expected a fresh analyzer ID, got %d", next.GetAnalyzerId())
+	}
+}
+
+func TestSal_LoadRemapsAnalyzerIDsInUse(t *testing.T) {
+	server := newHappyPathServer(t)
+	ctx := context.Background()
+	started, err := server.StartCapture(ctx, &pb.StartCaptureRequest{DeviceId: "DEV1", CaptureConfiguration: manualMode()})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	captureID := started.GetCaptureInfo().GetCaptureId()
+	spiID, hlaID := setUpAnalyzers(t, server, captureID)
+	path := filepath.Join(t.TempDir(), "session.sal")
+	if _, err := server.SaveCapture(ctx, &pb.SaveCaptureRequest{CaptureId: captureID, Filepath: path}); err != nil {
+		t.Fatalf("SaveCapture: %v", err)
+	}
+
+	// The saved capture is still open, so its analyzer IDs are taken.
+	loaded, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: path})
+	if err != nil {
+		t.Fatalf("LoadCapture: %v", err)
+	}
+	analyzers, hlas := analyzersOf(server, loaded.GetCaptureInfo().GetCaptureId())
+	if len(analyzers) != 1 || len(hlas) != 1 {
+		t.Fatalf("expected 1 analyzer and 1 HLA, got %+v %+v", analyzers, hlas)
+	}
+	if analyzers[0].ID == spiID || hlas[0].ID == hlaID || hlas[0].InputAnalyzerID != analyzers[0].ID {
+		t.Fatalf("expected new IDs with the HLA on the new analyzer, got analyzer %d, HLA %d on %d", analyzers[0].ID, hlas[0].ID, hlas[0].InputAnalyzerID)
+	}
+}
+
+func TestSal_LoadOtherFiles(t *testing.T) {
+	server := newHappyPathServer(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	placeholder := filepath.Join(dir, "placeholder.sal")
+	if err := os.WriteFile(placeholder, []byte("SALAD_MOCK_SAL_V1\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: placeholder}); err != nil {
+		t.Fatalf("expected a placeholder file to load as an empty capture, got %v", err)
+	}
+
+	writeZip := func(name string, files map[string]string) string {
+		path := filepath.Join(dir, name)
+		out, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		zw := zip.NewWriter(out)
+		for file, content := range files {
+			w, err := zw.Create(file)
+			if err != nil {
+				t.Fatalf("zip create: %v", err)
+			}
+			if _, err := w.Write([]byte(content)); err != nil {
+				t.Fatalf("zip write: %v", err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("zip close: %v", err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		return path
+	}
+	next := server.Snapshot().NextCaptureID
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{writeZip("no-meta.sal", map[string]string{"digital-0.bin": ""}), "open meta.json"},
+		{writeZip("bad-json.sal", map[string]string{"meta.json": "{"}), "decode meta.json"},
+		{writeZip("bad-mode.sal", map[string]string{"meta.json": `{"data": {"captureSettings": {"captureMode": "looping"}}}`}), "unknown capture mode kind"},
+		{writeZip("bad-setting.sal", map[string]string{"meta.json": `{"data": {"analyzers": [{"nodeId": 7, "type": "SPI", "name": "SPI", "settings": [{"title": "Clock", "setting": {"type": "Channel", "value": 1.5}}]}]}}`}), `analyzer 7 setting "Clock"`},
+	} {
+		_, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: tc.path})
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected InvalidArgument containing %q, got %v", filepath.Base(tc.path), tc.want, err)
+		}
+	}
+	if got := server.Snapshot().NextCaptureID; got != next {
+		t.Fatalf("expected failed loads to leave next_capture_id at %d, got %d", next, got)
+	}
+
+	// Logic 2's own channel and dropdown settings load as ints and dropdown text.
+	logic := writeZip("logic.sal", map[string]string{"meta.json": `{"version": 14, "data": {"analyzers": [{"nodeId": 10028, "type": "SPI", "name": "SPI: CLK0", "settings": [
+		{"title": "Clock", "setting": {"type": "Channel", "value": 0}},
+		{"title": "Bits per Transfer", "setting": {"type": "NumberList", "value": 8, "options": [{"dropdownText": "7 Bits per Transfer", "value": 7}, {"dropdownText": "8 Bits per Transfer (Standard)", "value": 8}]}}
+	]}]}}`})
+	loaded, err := server.LoadCapture(ctx, &pb.LoadCaptureRequest{Filepath: logic})
+	if err != nil {
+		t.Fatalf("LoadCapture: %v", err)
+	}
+	analyzers, _ := analyzersOf(server, loaded.GetCaptureInfo().GetCaptureId())
+	want := map[string]any{"Clock": int64(0), "Bits per Transfer": "8 Bits per Transfer (Standard)"}
+	if len(analyzers) != 1 || analyzers[0].ID != 10028 || analyzers[0].Label != "SPI: CLK0" || !reflect.DeepEqual(analyzers[0].Settings, want) {
+		t.Fatalf("unexpected analyzers %+v", analyzers)
+	}
+}
@@ -0,0 +1,176 @@
+package saleae
+
+import (
+	"reflect"
+	"sort"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/schema"
+)
+
+// JSONSchema returns the JSON Schema for salad-mock YAML configs.
+func JSONSchema() (*schema.Schema, error) {
+	methods := make([]string, 0, len(AllMethods))
+	for _, m := range AllMethods {
+		methods = append(methods, string(m))
+	}
+
+	codeNames := GRPCCodeNames()
+	nonOKCodes := make([]string, 0, len(codeNames))
+	for _, name := range codeNames {
+		if name != "OK" {
+			nonOKCodes = append(nonOKCodes, name)
+		}
+	}
+
+	deviceTypes := make([]string, 0, len(pb.DeviceType_value))
+	for name := range pb.DeviceType_value {
+		deviceTypes = append(deviceTypes, name)
+	}
+	sort.Strings(deviceTypes)
+
+	return schema.Generate(reflect.TypeOf(Config{}), schema.Options{
+		ID:          "https://github.com/go-go-golems/salad/configs/schema/mock.schema.json",
+		Title:       "salad-mock config",
+		Description: "Scenario config for the salad-mock Saleae Logic 2 automation server (version 1).",
+		Enums: map[schema.FieldRef][]string{
+			schema.Field[GRPCDefaultsConfig]("StatusOnUnknownCaptureID"): nonOKCodes,
+			schema.Field[TimingDefaultsConfig]("WaitCapturePolicy"):      WaitCapturePolicyNames,
+			schema.Field[DeviceConfig]("DeviceType"):                     deviceTypes,
+			schema.Field[CaptureFixture]("Status"):                       CaptureStatusNames,
+			schema.Field[CaptureFixture]("Origin"):                       CaptureOriginNames,
+			schema.Field[CaptureModeConfig]("Kind"):                      CaptureModeKindNames,
+			schema.Field[CaptureCreateConfig]("Status"):                  CaptureStatusNames,
+			schema.Field[TransitionConfig]("From"):                       CaptureStatusNames,
+			schema.Field[TransitionConfig]("To"):                         CaptureStatusNames,
+			schema.Field[CloseCaptureBehaviorConfig]("Mode"):             CloseCaptureModeNames,
+			schema.Field[ClockConfig]("Mode"):                            ClockModeNames,
+			schema.Field[FaultWhenConfig]("Method"):                      methods,
+			schema.Field[LatencyRuleConfig]("Method"):                    methods,
+			schema.Field[FaultRespondConfig]("Status"):                   codeNames,
+			schema.Field[TimelineCallConfig]("Method"):                   methods,
+			schema.Field[TimelineCaptureStatusConfig]("Status"):          CaptureStatusNames,
+			schema.Field[TimelineFailCaptureConfig]("Status"):            nonOKCodes,
+			schema.Field[UARTSignalConfig]("Parity"):                     {"none", "even", "odd"},
+		},
+		Required: []schema.FieldRef{
+			schema.Field[DeviceConfig]("DeviceID"),
+			schema.Field[DeviceConfig]("DeviceType"),
+			schema.Field[CaptureFixture]("CaptureID"),
+			schema.Field[FaultWhenConfig]("Method"),
+			schema.Field[LatencyRuleConfig]("Method"),
+			schema.Field[TimelineCallConfig]("N"),
+			schema.Field[TimelineCaptureStatusConfig]("Status"),
+			schema.Field[TimelineUnavailableConfig]("For"),
+			schema.Field[ClockSignalConfig]("FrequencyHz"),
+			schema.Field[UARTSignalConfig]("BaudRate"),
+			schema.Field[SPISignalConfig]("FrequencyHz"),
+			schema.Field[SPISignalConfig]("Transfers"),
+			schema.Field[I2CSignalConfig]("SCL"),
+			schema.Field[I2CSignalConfig]("SDA"),
+			schema.Field[I2CSignalConfig]("Transfers"),
+			schema.Field[TransitionsSignalConfig]("File"),
+			schema.Field[SineSignalConfig]("FrequencyHz"),
+			schema.Field[DataTableConfig]("Analyzer"),
+			schema.Field[FrameConfig]("Type"),
+		},
+		Descriptions: map[schema.FieldRef]string{
+			schema.Field[CaptureFixture]("StartedAt"):              "RFC3339 timestamp.",
+			schema.Field[TimingDefaultsConfig]("MaxBlockMs"):       "Upper bound for block_until_done waits, in milliseconds (0: until the capture is done or the client deadline).",
+			schema.Field[FaultWhenConfig]("NthCall"):               "Fire only on the n-th call (1-based) of the method.",
+			schema.Field[FaultWhenConfig]("FromCall"):              "Fire only from this call (1-based) of the method on.",
+			schema.Field[FaultWhenConfig]("ToCall"):                "Fire only up to this call (1-based, inclusive) of the method.",
+			schema.Field[FaultWhenConfig]("EveryNth"):              "Fire only on every n-th call of the method (n, 2n, ...).",
+			schema.Field[FaultWhenConfig]("Probability"):           "Chance (0-1) that a matching call fires the rule; see defaults.seed.",
+			schema.Field[FaultWhenConfig]("After"):                 "Name of a fault rule that must have fired before this one turns on.",
+			schema.Field[FaultRuleConfig]("Name"):                  "Rule name, referenced by when.after.",
+			schema.Field[FaultRuleConfig]("Times"):                 "Expire the rule after it fired this many times (0: never).",
+			schema.Field[DefaultsConfig]("Seed"):                   "Seed for fault probabilities and latency jitter (0: random).",
+			schema.Field[FaultMatchConfig]("Filepath"):             "Exact file path (directory for raw exports).",
+			schema.Field[FaultMatchConfig]("FilepathGlob"):         "Glob on the file path; without a slash it matches the base name.",
+			schema.Field[FaultMatchConfig]("FilepathRegex"):        "Regular expression searched in the file path.",
+			schema.Field[FaultMatchConfig]("AnalyzerName"):         "Exact analyzer name (HLA name for AddHighLevelAnalyzer).",
+			schema.Field[FaultMatchConfig]("Settings"):             "Analyzer settings that must have these values.",
+			schema.Field[FaultMatchConfig]("SettingsKeys"):         "Analyzer settings that must be present.",
+			schema.Field[FaultMatchConfig]("DigitalChannels"):      "Digital channels the export must include.",
+			schema.Field[FaultMatchConfig]("AnalogChannels"):       "Analog channels the export must include.",
+			schema.Field[Config]("Timeline"):                       "Scripted events: each has one trigger (at or on_call) and one action.",
+			schema.Field[TimelineEventConfig]("At"):                "Fire once this much time (e.g. \"2s\") has passed on the server clock since start or reset.",
+			schema.Field[TimelineEventConfig]("OnCall"):            "Fire when this call arrives, before it is answered.",
+			schema.Field[TimelineEventConfig]("RemoveDevice"):      "Device ID that disappears from GetDevices.",
+			schema.Field[TimelineEventConfig]("AddDevice"):         "Device that appears in GetDevices (replacing one with the same ID).",
+			schema.Field[TimelineEventConfig]("CaptureStatus"):     "Move a capture to a new status.",
+			schema.Field[TimelineEventConfig]("FailCapture"):       "Stop a capture with a device error that WaitCapture and StopCapture return.",
+			schema.Field[TimelineEventConfig]("AppInfo"):           "New app info, e.g. to simulate a Logic 2 restart.",
+			schema.Field[TimelineEventConfig]("Unavailable"):       "Fail every call with UNAVAILABLE for a while.",
+			schema.Field[TimelineCallConfig]("Method"):             "Method whose n-th call triggers the event; omit it to count the calls of all methods.",
+			schema.Field[TimelineCallConfig]("N"):                  "Call number (1-based).",
+			schema.Field[TimelineCaptureStatusConfig]("CaptureID"): "Capture to change; omit it for every running capture.",
+			schema.Field[TimelineFailCaptureConfig]("CaptureID"):   "Capture to fail; omit it for every running capture.",
+			schema.Field[TimelineFailCaptureConfig]("Status"):      "Status of the error (default ABORTED).",
+			schema.Field[TimelineFailCaptureConfig]("Message"):     "Error message (default \"ERROR_CODE_DEVICE_ERROR: device error during capture\").",
+			schema.Field[TimelineUnavailableConfig]("For"):         "Length of the window on the server clock, e.g. \"5s\".",
+			schema.Field[TimelineUnavailableConfig]("Message"):     "Error message (default \"Logic 2 is not responding\").",
+			schema.Field[CaptureFixture]("Signals"):                "Recorded content; raw data exports of the capture write it instead of placeholders.",
+			schema.Field[CaptureCreateConfig]("Signals"):           "Recorded content of created captures; raw data exports write it instead of placeholders.",
+			schema.Field[SignalsConfig]("DurationSeconds"):         "Length of the recording (default: the timed capture duration, or 1s).",
+			schema.Field[SignalsConfig]("SampleRate"):              "Digital sample rate in Hz; edges snap to its grid (default 10 MHz).",
+			schema.Field[SignalsConfig]("AnalogSampleRate"):        "Analog sample rate in Hz before analog_downsample_ratio (default 100 kHz).",
+			schema.Field[SignalsConfig]("Digital"):                 "Digital signals; each entry has exactly one kind. Channels without one stay low.",
+			schema.Field[SignalsConfig]("Analog"):                  "Analog signals; each entry has exactly one kind. Channels without one stay at 0 V.",
+			schema.Field[ClockSignalConfig]("DutyCycle"):           "Fraction of each period spent high (default 0.5).",
+			schema.Field[ClockSignalConfig]("Cycles"):              "Number of periods (0: until the end of the recording).",
+			schema.Field[UARTSignalConfig]("Text"):                 "Bytes to send, as text; sent before bytes.",
+			schema.Field[UARTSignalConfig]("Bytes"):                "Bytes to send.",
+			schema.Field[UARTSignalConfig]("BitsPerFrame"):         "Data bits per frame, 1-8 (default 8).",
+			schema.Field[UARTSignalConfig]("StopBits"):             "Stop bits (default 1).",
+			schema.Field[UARTSignalConfig]("Inverted"):             "Idle low, with every bit inverted.",
+			schema.Field[UARTSignalConfig]("StartSeconds"):         "Time of the first frame (default: after ten bit periods of idle).",
+			schema.Field[UARTSignalConfig]("GapSeconds"):           "Idle time between frames.",
+			schema.Field[SPISignalConfig]("Mode"):                  "SPI mode 0-3 (CPOL = mode/2, CPHA = mode%2).",
+			schema.Field[SPISignalConfig]("BitsPerTransfer"):       "Bits per word, 1-64 (default 8), MSB first unless lsb_first.",
+			schema.Field[SPISignalConfig]("Enable"):                "Enable channel, active low unless enable_active_high.",
+			schema.Field[SPISignalConfig]("StartSeconds"):          "Time of the first transfer (default: after ten clock periods).",
+			schema.Field[SPISignalConfig]("GapSeconds"):            "Extra idle time between transfers.",
+			schema.Field[SPISignalConfig]("Transfers"):             "Transfers, one enable assertion each.",
+			schema.Field[SPITransferConfig]("MOSI"):                "Words sent by the controller; the shorter side is padded with zeros.",
+			schema.Field[SPITransferConfig]("MISO"):                "Words sent by the peripheral; the shorter side is padded with zeros.",
+			schema.Field[I2CSignalConfig]("FrequencyHz"):           "SCL frequency in Hz (default 100 kHz).",
+			schema.Field[I2CSignalConfig]("StartSeconds"):          "Time of the first transfer (default: after ten clock periods).",
+			schema.Field[I2CSignalConfig]("GapSeconds"):            "Extra bus free time between transfers.",
+			schema.Field[I2CSignalConfig]("Transfers"):             "Transfers from start to stop condition. Every byte is acknowledged, except the last byte read.",
+			schema.Field[I2CTransferConfig]("Address"):             "7-bit device address.",
+			schema.Field[I2CTransferConfig]("Data"):                "Bytes written, or read from the device with read.",
+			schema.Field[TransitionsSignalConfig]("File"):          "digital.csv or digital_<n>.bin of an existing export, relative to the config file.",
+			schema.Field[TransitionsSignalConfig]("Column"):        "Column of a digital.csv (default \"Channel <channel>\").",
+			schema.Field[SineSignalConfig]("Amplitude"):            "Peak amplitude in volts (default 1).",
+			schema.Field[SineSignalConfig]("Offset"):               "DC offset in volts.",
+			schema.Field[StepSignalConfig]("From"):                 "Voltage before at_seconds.",
+			schema.Field[StepSignalConfig]("To"):                   "Voltage from at_seconds on.",
+			schema.Field[CaptureFixture]("DataTables"):             "Declared analyzer frames for data table exports; other analyzers are decoded from signals.",
+			schema.Field[CaptureCreateConfig]("DataTables"):        "Declared analyzer frames of created captures; other analyzers are decoded from signals.",
+			schema.Field[DataTableConfig]("Analyzer"):              "Analyzer or HLA label, or else its name, the frames belong to.",
+			schema.Field[DataTableConfig]("Frames"):                "Rows of the analyzer, in any order.",
+			schema.Field[FrameConfig]("Type"):                      "Frame type, e.g. result, address or data.",
+			schema.Field[FrameConfig]("StartSeconds"):              "Frame start, in seconds from the capture start.",
+			schema.Field[FrameConfig]("DurationSeconds"):           "Frame length, in seconds.",
+			schema.Field[FrameConfig]("Bits"):                      "Width integer fields are padded to in hex and binary (default 8).",
+			schema.Field[FrameConfig]("Fields"):                    "Column values: integers are formatted in the requested radix, bools and strings as is.",
+			schema.Field[ClockConfig]("Mode"):                      "real (default) or virtual: a clock driven through the admin interface.",
+			schema.Field[ClockConfig]("Start"):                     "Initial virtual time (RFC3339); default: the time the server starts.",
+			schema.Field[ClockConfig]("Scale"):                     "Virtual clock speed relative to real time; 0 (default) freezes it.",
+			schema.Field[TimingDefaultsConfig]("Latency"):          "Delays applied to every call of a method, on top of fault delays.",
+			schema.Field[LatencyRuleConfig]("DelayMs"):             "Delay before the call runs, in milliseconds of the server clock.",
+			schema.Field[LatencyRuleConfig]("JitterMs"):            "Random extra delay, between 0 and jitter_ms milliseconds.",
+			schema.Field[LatencyRuleConfig]("Hang"):                "Never respond; the call ends when the client cancels it or its deadline passes.",
+			schema.Field[FaultRespondConfig]("Status"):             "Status to fail with after the delay; omit it to delay the call and then run it normally.",
+			schema.Field[FaultRespondConfig]("Message"):            "Error message; required with a status other than OK.",
+			schema.Field[FaultRespondConfig]("DelayMs"):            "Delay before responding, in milliseconds of the server clock.",
+			schema.Field[FaultRespondConfig]("JitterMs"):           "Random extra delay, between 0 and jitter_ms milliseconds.",
+			schema.Field[FaultRespondConfig]("Hang"):               "Never respond; the call ends when the client cancels it or its deadline passes. Excludes status.",
+
+			schema.Field[SaveCaptureSideEffectConfig]("WritePlaceholderFile"): "Write a .sal archive whose meta.json holds the capture mode, analyzers and HLAs; LoadCapture restores them.",
+			schema.Field[SaveCaptureSideEffectConfig]("PlaceholderBytes"):     "Write these bytes instead of a .sal archive.",
+		},
+	})
+}
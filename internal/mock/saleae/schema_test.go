@@ -0,0 +1,45 @@
+package saleae
+
+import "testing"
+
+// The schema enums must be accepted by the config parsers they describe.
+func TestSchemaEnumsParse(t *testing.T) {
+	for _, name := range CaptureStatusNames {
+		if _, err := parseCaptureStatus(name); err != nil {
+			t.Fatalf("capture status %q: %v", name, err)
+		}
+	}
+	for _, name := range CaptureOriginNames {
+		if _, err := parseCaptureOrigin(name); err != nil {
+			t.Fatalf("capture origin %q: %v", name, err)
+		}
+	}
+	for _, name := range CaptureModeKindNames {
+		if _, err := parseCaptureMode(&CaptureModeConfig{Kind: name}); err != nil {
+			t.Fatalf("capture mode kind %q: %v", name, err)
+		}
+	}
+	for _, name := range WaitCapturePolicyNames {
+		if _, err := parseWaitCapturePolicy(name); err != nil {
+			t.Fatalf("wait capture policy %q: %v", name, err)
+		}
+	}
+	for _, name := range CloseCaptureModeNames {
+		if _, err := parseCloseCaptureMode(name); err != nil {
+			t.Fatalf("close capture mode %q: %v", name, err)
+		}
+	}
+	for _, name := range GRPCCodeNames() {
+		if _, err := parseStatusCode(name); err != nil {
+			t.Fatalf("grpc code %q: %v", name, err)
+		}
+	}
+
+	s, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema: %v", err)
+	}
+	if s.Defs["FaultWhenConfig"].Properties["method"].Enum == nil {
+		t.Fatalf("expected faults.when.method enum in schema")
+	}
+}
@@ -123,11 +123,13 @@ func (s *Server) StartCapture(ctx context.Context, req *pb.StartCaptureRequest)
 
 		capturePlan := runtime.Plan.Behavior.StartCapture.CreateCapture
 		capture := &CaptureState{
-			ID:        captureID,
-			Status:    capturePlan.Status,
-			Origin:    CaptureOriginStarted,
-			StartedAt: runtime.Clock.Now(),
-			Mode:      captureMode,
+			ID:         captureID,
+			Status:     capturePlan.Status,
+			Origin:     CaptureOriginStarted,
+			StartedAt:  runtime.Clock.Now(),
+			Mode:       captureMode,
+			Signals:    capturePlan.Signals,
+			DataTables: capturePlan.DataTables,
 		}
 		runtime.State.Captures[captureID] = capture
 
@@ -151,18 +153,28 @@ func (s *Server) LoadCapture(ctx context.Context, req *pb.LoadCaptureRequest) (*
 				return nil, status.Error(codes.InvalidArgument, "LoadCapture: file does not exist")
 			}
 		}
+		meta, isSal, err := readSalMeta(req.GetFilepath())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("LoadCapture: %v", err))
+		}
 
 		captureID := runtime.State.NextCaptureID
-		runtime.State.NextCaptureID++
-
 		capturePlan := runtime.Plan.Behavior.LoadCapture.CreateCapture
 		capture := &CaptureState{
-			ID:        captureID,
-			Status:    capturePlan.Status,
-			Origin:    CaptureOriginLoaded,
-			StartedAt: runtime.Clock.Now(),
-			Mode:      capturePlan.Mode,
+			ID:         captureID,
+			Status:     capturePlan.Status,
+			Origin:     CaptureOriginLoaded,
+			StartedAt:  runtime.Clock.Now(),
+			Mode:       capturePlan.Mode,
+			Signals:    capturePlan.Signals,
+			DataTables: capturePlan.DataTables,
+		}
+		if isSal {
+			if err := runtime.State.restoreSal(capture, meta, runtime.Clock.Now()); err != nil {
+				return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("LoadCapture: %s: %v", req.GetFilepath(), err))
+			}
 		}
+		runtime.State.NextCaptureID++
 		runtime.State.Captures[captureID] = capture
 
 		return &pb.LoadCaptureReply{
@@ -186,7 +198,14 @@ func (s *Server) SaveCapture(ctx context.Context, req *pb.SaveCaptureRequest) (*
 		}
 
 		if runtime.Plan.Behavior.SaveCapture.WritePlaceholderFile {
-			if err := runtime.SideEffects.SaveCapture(req.GetFilepath(), capture.ID, runtime.Plan.Behavior.SaveCapture.PlaceholderBytes); err != nil {
+			payload := runtime.Plan.Behavior.SaveCapture.PlaceholderBytes
+			if len(payload) == 0 {
+				payload, err = runtime.State.salArchive(capture)
+				if err != nil {
+					return nil, status.Error(codes.Internal, err.Error())
+				}
+			}
+			if err := runtime.SideEffects.SaveCapture(req.GetFilepath(), capture.ID, payload); err != nil {
 				return nil, status.Error(codes.Internal, err.Error())
 			}
 		}
@@ -208,6 +227,9 @@ func (s *Server) StopCapture(ctx context.Context, req *pb.StopCaptureRequest) (*
 			}
 			return nil, err
 		}
+		if capture.Failure != nil {
+			return nil, capture.Failure
+		}
 
 		if capture.Status == runtime.Plan.Behavior.StopCapture.TransitionFrom {
 			capture.Status = runtime.Plan.Behavior.StopCapture.TransitionTo
@@ -223,48 +245,61 @@ func (s *Server) StopCapture(ctx context.Context, req *pb.StopCaptureRequest) (*
 
 func (s *Server) WaitCapture(ctx context.Context, req *pb.WaitCaptureRequest) (*pb.WaitCaptureReply, error) {
 	out, err := s.exec(ctx, MethodWaitCapture, req, func(runtime *RuntimeContext) (any, error) {
-		capture, err := runtime.State.captureFor(req.GetCaptureId(), runtime.Plan.Defaults.StatusOnUnknownCaptureID)
-		if err != nil {
-			if !runtime.Plan.Behavior.WaitCapture.RequireCaptureExists {
-				return &pb.WaitCaptureReply{}, nil
-			}
-			return nil, err
+		var deadline time.Time
+		if maxBlock := runtime.Plan.Behavior.WaitCapture.MaxBlock; maxBlock > 0 {
+			deadline = runtime.Clock.Now().Add(maxBlock)
 		}
+		return runtime.waitCapture(req.GetCaptureId(), deadline)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.(*pb.WaitCaptureReply), nil
+}
 
-		if capture.Mode.Kind == CaptureModeManual && runtime.Plan.Behavior.WaitCapture.ErrorOnManualMode {
-			return nil, status.Error(codes.InvalidArgument, "WaitCapture: manual capture mode does not support waiting")
+// waitCapture evaluates a WaitCapture against the current state. It returns a reply, an
+// error, or a *captureWait when the block_until_done policy has to keep waiting.
+func (runtime *RuntimeContext) waitCapture(captureID uint64, deadline time.Time) (any, error) {
+	capture, err := runtime.State.captureFor(captureID, runtime.Plan.Defaults.StatusOnUnknownCaptureID)
+	if err != nil {
+		if !runtime.Plan.Behavior.WaitCapture.RequireCaptureExists {
+			return &pb.WaitCaptureReply{}, nil
 		}
+		return nil, err
+	}
+	if capture.Failure != nil {
+		return nil, capture.Failure
+	}
 
-		if capture.Mode.Kind == CaptureModeTimed && runtime.Plan.Behavior.WaitCapture.TimedCapturesCompleteAfterDuration {
-			if capture.Mode.Duration <= 0 {
-				capture.Status = CaptureStatusCompleted
-			} else if capture.StartedAt.Add(capture.Mode.Duration).Before(runtime.Clock.Now()) {
-				capture.Status = CaptureStatusCompleted
-			}
-		}
+	if capture.Mode.Kind == CaptureModeManual && runtime.Plan.Behavior.WaitCapture.ErrorOnManualMode {
+		return nil, status.Error(codes.InvalidArgument, "WaitCapture: manual capture mode does not support waiting")
+	}
 
-		if capture.Status == CaptureStatusCompleted {
-			return &pb.WaitCaptureReply{}, nil
+	if capture.Mode.Kind == CaptureModeTimed && runtime.Plan.Behavior.WaitCapture.TimedCapturesCompleteAfterDuration {
+		if capture.Mode.Duration <= 0 {
+			capture.Status = CaptureStatusCompleted
+		} else if !runtime.Clock.Now().Before(capture.StartedAt.Add(capture.Mode.Duration)) {
+			capture.Status = CaptureStatusCompleted
 		}
+	}
+
+	if capture.Status == CaptureStatusCompleted {
+		return &pb.WaitCaptureReply{}, nil
+	}
 
-		switch runtime.Plan.Behavior.WaitCapture.Policy {
-		case WaitCaptureImmediate:
+	switch runtime.Plan.Behavior.WaitCapture.Policy {
+	case WaitCaptureImmediate:
+		return nil, status.Error(codes.DeadlineExceeded, "WaitCapture: capture still running")
+	case WaitCaptureErrorIfRunning:
+		if capture.Status == CaptureStatusRunning {
 			return nil, status.Error(codes.DeadlineExceeded, "WaitCapture: capture still running")
-		case WaitCaptureErrorIfRunning:
-			if capture.Status == CaptureStatusRunning {
-				return nil, status.Error(codes.DeadlineExceeded, "WaitCapture: capture still running")
-			}
-			return &pb.WaitCaptureReply{}, nil
-		case WaitCaptureBlockUntilDone:
-			return runtime.blockUntilDone(capture)
-		default:
-			return nil, status.Error(codes.Internal, "WaitCapture: unknown policy")
 		}
-	})
-	if err != nil {
-		return nil, err
+		return &pb.WaitCaptureReply{}, nil
+	case WaitCaptureBlockUntilDone:
+		return runtime.blockUntilDone(capture, deadline)
+	default:
+		return nil, status.Error(codes.Internal, "WaitCapture: unknown policy")
 	}
-	return out.(*pb.WaitCaptureReply), nil
 }
 
 func (s *Server) CloseCapture(ctx context.Context, req *pb.CloseCaptureRequest) (*pb.CloseCaptureReply, error) {
@@ -301,7 +336,19 @@ func (s *Server) ExportRawDataCsv(ctx context.Context, req *pb.ExportRawDataCsvR
 			}
 		}
 
-		if runtime.Plan.Behavior.ExportRawDataCsv.WriteDigitalCSV || runtime.Plan.Behavior.ExportRawDataCsv.WriteAnalogCSV {
+		if capture := runtime.State.Captures[req.GetCaptureId()]; capture != nil && capture.Signals != nil {
+			data := capture.Signals.rawExport(capture, req.GetLogicChannels(), req.GetAnalogDownsampleRatio(), runtime.Clock.Now())
+			err := runtime.SideEffects.ExportRawCSV(req.GetDirectory(), req, ExportCSVOptions{
+				WriteDigital:    len(data.Digital) > 0,
+				WriteAnalog:     len(data.Analog) > 0,
+				DigitalFilename: runtime.Plan.Behavior.ExportRawDataCsv.DigitalFilename,
+				AnalogFilename:  runtime.Plan.Behavior.ExportRawDataCsv.AnalogFilename,
+				Data:            data,
+			})
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		} else if runtime.Plan.Behavior.ExportRawDataCsv.WriteDigitalCSV || runtime.Plan.Behavior.ExportRawDataCsv.WriteAnalogCSV {
 			err := runtime.SideEffects.ExportRawCSV(req.GetDirectory(), req, ExportCSVOptions{
 				WriteDigital:             runtime.Plan.Behavior.ExportRawDataCsv.WriteDigitalCSV,
 				WriteAnalog:              runtime.Plan.Behavior.ExportRawDataCsv.WriteAnalogCSV,
@@ -330,7 +377,17 @@ func (s *Server) ExportRawDataBinary(ctx context.Context, req *pb.ExportRawDataB
 			}
 		}
 
-		if runtime.Plan.Behavior.ExportRawDataBinary.WriteDigitalBin || runtime.Plan.Behavior.ExportRawDataBinary.WriteAnalogBin {
+		if capture := runtime.State.Captures[req.GetCaptureId()]; capture != nil && capture.Signals != nil {
+			data := capture.Signals.rawExport(capture, req.GetLogicChannels(), req.GetAnalogDownsampleRatio(), runtime.Clock.Now())
+			err := runtime.SideEffects.ExportRawBinary(req.GetDirectory(), req, ExportBinaryOptions{
+				WriteDigital: len(data.Digital) > 0,
+				WriteAnalog:  len(data.Analog) > 0,
+				Data:         data,
+			})
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		} else if runtime.Plan.Behavior.ExportRawDataBinary.WriteDigitalBin || runtime.Plan.Behavior.ExportRawDataBinary.WriteAnalogBin {
 			err := runtime.SideEffects.ExportRawBinary(req.GetDirectory(), req, ExportBinaryOptions{
 				WriteDigital:    runtime.Plan.Behavior.ExportRawDataBinary.WriteDigitalBin,
 				WriteAnalog:     runtime.Plan.Behavior.ExportRawDataBinary.WriteAnalogBin,
@@ -358,7 +415,15 @@ func (s *Server) ExportDataTableCsv(ctx context.Context, req *pb.ExportDataTable
 			}
 		}
 
-		if runtime.Plan.Behavior.ExportDataTableCsv.WritePlaceholderFile {
+		if capture := runtime.State.Captures[req.GetCaptureId()]; capture != nil && (capture.Signals != nil || len(capture.DataTables) > 0) {
+			data, err := runtime.State.dataTableExport(capture, req, runtime.Clock.Now())
+			if err != nil {
+				return nil, err
+			}
+			if err := runtime.SideEffects.ExportDataTableCSV(req.GetFilepath(), req, ExportDataTableCSVOptions{Data: data}); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		} else if runtime.Plan.Behavior.ExportDataTableCsv.WritePlaceholderFile {
 			err := runtime.SideEffects.ExportDataTableCSV(req.GetFilepath(), req, ExportDataTableCSVOptions{
 				IncludeRequest: runtime.Plan.Behavior.ExportDataTableCsv.IncludeRequestInFile,
 			})
@@ -566,21 +631,25 @@ func (s *Server) RemoveHighLevelAnalyzer(ctx context.Context, req *pb.RemoveHigh
 	return out.(*pb.RemoveHighLevelAnalyzerReply), nil
 }
 
-func (runtime *RuntimeContext) blockUntilDone(capture *CaptureState) (*pb.WaitCaptureReply, error) {
-	if capture.Status == CaptureStatusCompleted {
+// blockUntilDone returns a *captureWait for a capture that is still running, unless the
+// max_block_ms deadline has passed.
+func (runtime *RuntimeContext) blockUntilDone(capture *CaptureState, deadline time.Time) (any, error) {
+	if capture.Status != CaptureStatusRunning {
 		return &pb.WaitCaptureReply{}, nil
 	}
-
-	if capture.Mode.Kind == CaptureModeTimed && capture.Mode.Duration > 0 {
-		deadline := runtime.Clock.Now().Add(runtime.Plan.Behavior.WaitCapture.MaxBlock)
-		completion := capture.StartedAt.Add(capture.Mode.Duration)
-		if completion.Before(deadline) || completion.Equal(deadline) {
-			capture.Status = CaptureStatusCompleted
-			return &pb.WaitCaptureReply{}, nil
-		}
+	if !deadline.IsZero() && !runtime.Clock.Now().Before(deadline) {
+		return nil, status.Error(codes.DeadlineExceeded, "WaitCapture: capture still running")
 	}
 
-	return nil, status.Error(codes.DeadlineExceeded, "WaitCapture: capture still running")
+	wait := &captureWait{
+		captureID: capture.ID,
+		done:      runtime.State.captureDone(capture.ID),
+		deadline:  deadline,
+	}
+	if capture.Mode.Kind == CaptureModeTimed && runtime.Plan.Behavior.WaitCapture.TimedCapturesCompleteAfterDuration {
+		wait.completesAt = capture.StartedAt.Add(capture.Mode.Duration)
+	}
+	return wait, nil
 }
 
 func (state *State) captureFor(captureID uint64, missingStatus codes.Code) (*CaptureState, error) {
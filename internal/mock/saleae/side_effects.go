@@ -1,12 +1,19 @@
 package saleae
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/rawdata"
 	"github.com/pkg/errors"
 )
 
@@ -23,6 +30,8 @@ type ExportCSVOptions struct {
 	DigitalFilename          string
 	AnalogFilename           string
 	IncludeRequestedChannels bool
+	// Data is the content to write; nil writes placeholders.
+	Data *RawExport
 }
 
 type ExportBinaryOptions struct {
@@ -30,10 +39,15 @@ type ExportBinaryOptions struct {
 	WriteAnalog     bool
 	DigitalFilename string
 	AnalogFilename  string
+	// Data is the content to write, one digital_<n>.bin or analog_<n>.bin file per
+	// channel; nil writes placeholders.
+	Data *RawExport
 }
 
 type ExportDataTableCSVOptions struct {
 	IncludeRequest bool
+	// Data is the table to write; nil writes a placeholder.
+	Data *DataTableExport
 }
 
 type NoopSideEffects struct{}
@@ -77,6 +91,24 @@ func (FileSideEffects) ExportRawCSV(directory string, req *pb.ExportRawDataCsvRe
 		return errors.Wrapf(err, "create export directory %s", directory)
 	}
 
+	if opts.Data != nil {
+		if opts.WriteDigital {
+			if err := writeFile(filepath.Join(directory, opts.DigitalFilename), func(w io.Writer) error {
+				return writeDigitalCSV(w, opts.Data, req.GetIso8601Timestamp())
+			}); err != nil {
+				return errors.Wrap(err, "write digital csv")
+			}
+		}
+		if opts.WriteAnalog {
+			if err := writeFile(filepath.Join(directory, opts.AnalogFilename), func(w io.Writer) error {
+				return writeAnalogCSV(w, opts.Data, req.GetIso8601Timestamp())
+			}); err != nil {
+				return errors.Wrap(err, "write analog csv")
+			}
+		}
+		return nil
+	}
+
 	if opts.WriteDigital {
 		path := filepath.Join(directory, opts.DigitalFilename)
 		payload := buildCSVPlaceholder("digital", req, opts.IncludeRequestedChannels)
@@ -102,6 +134,26 @@ func (FileSideEffects) ExportRawBinary(directory string, req *pb.ExportRawDataBi
 		return errors.Wrapf(err, "create export directory %s", directory)
 	}
 
+	if data := opts.Data; data != nil {
+		for _, channel := range data.Digital {
+			path := filepath.Join(directory, fmt.Sprintf("digital_%d.bin", channel.Channel))
+			if err := writeFile(path, func(w io.Writer) error {
+				return rawdata.WriteDigitalBinary(w, channel.Trace.Initial, 0, data.End, channel.Trace.Edges)
+			}); err != nil {
+				return err
+			}
+		}
+		for _, channel := range data.Analog {
+			path := filepath.Join(directory, fmt.Sprintf("analog_%d.bin", channel.Channel))
+			if err := writeFile(path, func(w io.Writer) error {
+				return rawdata.WriteAnalogBinary(w, 0, uint64(data.AnalogSampleRate), data.AnalogDownsample, channel.Samples)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if opts.WriteDigital {
 		path := filepath.Join(directory, opts.DigitalFilename)
 		payload := fmt.Sprintf("SALAD_MOCK_DIGITAL_BIN capture_id=%d\n", req.GetCaptureId())
@@ -127,6 +179,9 @@ func (FileSideEffects) ExportDataTableCSV(path string, req *pb.ExportDataTableCs
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return errors.Wrapf(err, "create export directory for %s", path)
 	}
+	if opts.Data != nil {
+		return writeFile(path, func(w io.Writer) error { return writeDataTableCSV(w, opts.Data) })
+	}
 	payload := buildDataTableCSVPlaceholder(req, opts.IncludeRequest)
 	if err := os.WriteFile(path, []byte(payload), 0o644); err != nil {
 		return errors.Wrapf(err, "write data table csv placeholder %s", path)
@@ -184,3 +239,127 @@ func buildDataTableCSVPlaceholder(req *pb.ExportDataTableCsvRequest, includeRequ
 	}
 	return builder.String()
 }
+
+// writeFile creates path and fills it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", path)
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "write %s", path)
+	}
+	return errors.Wrapf(f.Close(), "close %s", path)
+}
+
+// csvTime formats t seconds into the capture like Logic 2: seconds, or an absolute
+// timestamp with iso8601.
+func csvTime(data *RawExport, t float64, iso8601 bool) string {
+	if iso8601 {
+		return data.Start.Add(time.Duration(math.Round(t * float64(time.Second)))).Format("2006-01-02T15:04:05.000000000Z07:00")
+	}
+	return strconv.FormatFloat(t, 'f', 9, 64)
+}
+
+// writeDigitalCSV writes a row with the initial levels, then one per change on any
+// channel.
+func writeDigitalCSV(w io.Writer, data *RawExport, iso8601 bool) error {
+	out := csv.NewWriter(w)
+	header := []string{"Time [s]"}
+	levels := make([]uint8, len(data.Digital))
+	next := make([]int, len(data.Digital))
+	for i, channel := range data.Digital {
+		header = append(header, rawdata.ChannelName(int(channel.Channel)))
+		levels[i] = channel.Trace.Initial
+	}
+	row := make([]string, len(header))
+	writeRow := func(t float64) error {
+		row[0] = csvTime(data, t, iso8601)
+		for i, level := range levels {
+			row[i+1] = strconv.Itoa(int(level))
+		}
+		return out.Write(row)
+	}
+
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	if err := writeRow(0); err != nil {
+		return err
+	}
+	for {
+		t := math.Inf(1)
+		for i, channel := range data.Digital {
+			if next[i] < len(channel.Trace.Edges) {
+				t = min(t, channel.Trace.Edges[next[i]])
+			}
+		}
+		if math.IsInf(t, 1) {
+			break
+		}
+		for i, channel := range data.Digital {
+			if next[i] < len(channel.Trace.Edges) && channel.Trace.Edges[next[i]] == t {
+				levels[i] ^= 1
+				next[i]++
+			}
+		}
+		if err := writeRow(t); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeAnalogCSV writes one row per (downsampled) sample.
+func writeAnalogCSV(w io.Writer, data *RawExport, iso8601 bool) error {
+	out := csv.NewWriter(w)
+	header := []string{"Time [s]"}
+	count := 0
+	for _, channel := range data.Analog {
+		header = append(header, rawdata.ChannelName(int(channel.Channel)))
+		count = max(count, len(channel.Samples))
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	period := float64(data.AnalogDownsample) / data.AnalogSampleRate
+	row := make([]string, len(header))
+	for n := 0; n < count; n++ {
+		row[0] = csvTime(data, float64(n)*period, iso8601)
+		for i, channel := range data.Analog {
+			row[i+1] = strconv.FormatFloat(float64(channel.Samples[n]), 'f', -1, 32)
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeDataTableCSV writes the fixed columns and data.Columns; values of other columns
+// are dropped.
+func writeDataTableCSV(w io.Writer, data *DataTableExport) error {
+	table, err := datatable.NewWriter(w, data.Columns)
+	if err != nil {
+		return err
+	}
+	if data.ISO8601 {
+		table.UseISO8601(data.Start)
+	}
+	values := map[string]string{}
+	for _, row := range data.Rows {
+		clear(values)
+		for _, column := range data.Columns {
+			if v, ok := row.Values[column]; ok {
+				values[column] = v
+			}
+		}
+		if err := table.Write(row.Name, row.Type, row.Start, row.Duration, values); err != nil {
+			return err
+		}
+	}
+	return table.Flush()
+}
@@ -0,0 +1,607 @@
+package saleae
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSignalDuration         = time.Second
+	defaultSignalSampleRate       = 10_000_000
+	defaultSignalAnalogSampleRate = 100_000
+	// signalLeadIn is the idle time, in bit periods, before the first frame of a
+	// protocol signal without start_seconds.
+	signalLeadIn = 10
+)
+
+// SignalPlan is the recorded content of a capture (see SignalsConfig).
+type SignalPlan struct {
+	// Duration is the length of the recording; 0 means the duration of a timed
+	// capture, or one second.
+	Duration time.Duration
+	// SampleRate is the digital sample rate in Hz. Edges fall on its sample grid.
+	SampleRate float64
+	// AnalogSampleRate is the analog sample rate in Hz, before analog_downsample_ratio.
+	AnalogSampleRate float64
+	Digital          []DigitalSignal
+	// Analog holds the voltage of each channel at t seconds.
+	Analog map[uint32]func(t float64) float64
+}
+
+// DigitalSignal drives one or more digital channels.
+type DigitalSignal struct {
+	Channels []uint32
+	// Render draws the channels, in Channels order, on a sample grid of rate Hz up to
+	// end seconds (edges past end are cut later).
+	Render func(rate, end float64) []DigitalTrace
+}
+
+// DigitalTrace is a digital channel: its level at time 0 and the times of its edges,
+// in seconds.
+type DigitalTrace struct {
+	Initial uint8
+	Edges   []float64
+}
+
+func compileSignals(cfg *SignalsConfig) (*SignalPlan, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.DurationSeconds < 0 || cfg.SampleRate < 0 || cfg.AnalogSampleRate < 0 {
+		return nil, errors.New("duration_seconds, sample_rate and analog_sample_rate cannot be negative")
+	}
+	plan := &SignalPlan{
+		Duration:         time.Duration(cfg.DurationSeconds * float64(time.Second)),
+		SampleRate:       cfg.SampleRate,
+		AnalogSampleRate: math.Round(cfg.AnalogSampleRate),
+		Analog:           map[uint32]func(float64) float64{},
+	}
+	if plan.SampleRate == 0 {
+		plan.SampleRate = defaultSignalSampleRate
+	}
+	if plan.AnalogSampleRate == 0 {
+		plan.AnalogSampleRate = defaultSignalAnalogSampleRate
+	}
+
+	driven := map[uint32]string{}
+	for i, signalCfg := range cfg.Digital {
+		field := fmt.Sprintf("digital[%d]", i)
+		signal, err := compileDigitalSignal(signalCfg)
+		if err != nil {
+			return nil, errors.Wrap(err, field)
+		}
+		for _, channel := range signal.Channels {
+			if other, ok := driven[channel]; ok {
+				return nil, errors.Errorf("%s: digital channel %d is already driven by %s", field, channel, other)
+			}
+			driven[channel] = field
+		}
+		plan.Digital = append(plan.Digital, signal)
+	}
+	for i, signalCfg := range cfg.Analog {
+		field := fmt.Sprintf("analog[%d]", i)
+		channel, wave, err := compileAnalogSignal(signalCfg)
+		if err != nil {
+			return nil, errors.Wrap(err, field)
+		}
+		if _, ok := plan.Analog[channel]; ok {
+			return nil, errors.Errorf("%s: analog channel %d already has a signal", field, channel)
+		}
+		plan.Analog[channel] = wave
+	}
+	return plan, nil
+}
+
+func compileDigitalSignal(cfg DigitalSignalConfig) (DigitalSignal, error) {
+	kinds := 0
+	for _, set := range []bool{cfg.Clock != nil, cfg.UART != nil, cfg.SPI != nil, cfg.I2C != nil, cfg.Transitions != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return DigitalSignal{}, errors.New("needs exactly one of clock, uart, spi, i2c, transitions")
+	}
+	switch {
+	case cfg.Clock != nil:
+		return compileClockSignal(*cfg.Clock)
+	case cfg.UART != nil:
+		return compileUARTSignal(*cfg.UART)
+	case cfg.SPI != nil:
+		return compileSPISignal(*cfg.SPI)
+	case cfg.I2C != nil:
+		return compileI2CSignal(*cfg.I2C)
+	default:
+		return compileTransitionsSignal(*cfg.Transitions)
+	}
+}
+
+func compileClockSignal(cfg ClockSignalConfig) (DigitalSignal, error) {
+	if cfg.FrequencyHz <= 0 {
+		return DigitalSignal{}, errors.New("clock.frequency_hz must be positive")
+	}
+	duty := cfg.DutyCycle
+	if duty == 0 {
+		duty = 0.5
+	}
+	if duty <= 0 || duty >= 1 {
+		return DigitalSignal{}, errors.Errorf("clock.duty_cycle must be between 0 and 1, got %v", cfg.DutyCycle)
+	}
+	if cfg.StartSeconds < 0 || cfg.Cycles < 0 {
+		return DigitalSignal{}, errors.New("clock.start_seconds and clock.cycles cannot be negative")
+	}
+	period := 1 / cfg.FrequencyHz
+	return DigitalSignal{
+		Channels: []uint32{cfg.Channel},
+		Render: func(rate, end float64) []DigitalTrace {
+			line := newTraceBuilder(rate, 0)
+			for k := 0; cfg.Cycles == 0 || k < cfg.Cycles; k++ {
+				t := cfg.StartSeconds + float64(k)*period
+				if t >= end {
+					break
+				}
+				line.set(t, 1)
+				line.set(t+duty*period, 0)
+			}
+			return []DigitalTrace{line.trace}
+		},
+	}, nil
+}
+
+func compileUARTSignal(cfg UARTSignalConfig) (DigitalSignal, error) {
+	if cfg.BaudRate <= 0 {
+		return DigitalSignal{}, errors.New("uart.baud_rate must be positive")
+	}
+	frameBits := cfg.BitsPerFrame
+	if frameBits == 0 {
+		frameBits = 8
+	}
+	if frameBits < 1 || frameBits > 8 {
+		return DigitalSignal{}, errors.Errorf("uart.bits_per_frame: expected 1..8, got %d", cfg.BitsPerFrame)
+	}
+	stopBits := cfg.StopBits
+	if stopBits == 0 {
+		stopBits = 1
+	}
+	if stopBits < 0 || cfg.StartSeconds < 0 || cfg.GapSeconds < 0 {
+		return DigitalSignal{}, errors.New("uart.stop_bits, uart.start_seconds and uart.gap_seconds cannot be negative")
+	}
+	parity := strings.ToLower(cfg.Parity)
+	switch parity {
+	case "", "none", "even", "odd":
+	default:
+		return DigitalSignal{}, errors.Errorf("uart.parity: expected none, even or odd, got %q", cfg.Parity)
+	}
+	data := append([]byte(cfg.Text), cfg.Bytes...)
+	if len(data) == 0 {
+		return DigitalSignal{}, errors.New("uart needs text or bytes")
+	}
+
+	bit := 1 / cfg.BaudRate
+	idle := uint8(1)
+	if cfg.Inverted {
+		idle = 0
+	}
+	return DigitalSignal{
+		Channels: []uint32{cfg.Channel},
+		Render: func(rate, end float64) []DigitalTrace {
+			line := newTraceBuilder(rate, idle)
+			// Inverted lines idle low and carry every bit inverted.
+			drive := func(t float64, level uint8) { line.set(t, level^idle^1) }
+			t := leadIn(cfg.StartSeconds, bit)
+			for _, value := range data {
+				if t >= end {
+					break
+				}
+				drive(t, 0)
+				n := 1
+				for i := 0; i < frameBits; i++ {
+					shift := i
+					if cfg.MSBFirst {
+						shift = frameBits - 1 - i
+					}
+					drive(t+float64(n)*bit, value>>shift&1)
+					n++
+				}
+				if parity == "even" || parity == "odd" {
+					p := uint8(bits.OnesCount(uint(value)&(1<<frameBits-1)) & 1)
+					if parity == "odd" {
+						p ^= 1
+					}
+					drive(t+float64(n)*bit, p)
+					n++
+				}
+				drive(t+float64(n)*bit, 1)
+				t += (float64(n)+stopBits)*bit + cfg.GapSeconds
+			}
+			return []DigitalTrace{line.trace}
+		},
+	}, nil
+}
+
+func compileSPISignal(cfg SPISignalConfig) (DigitalSignal, error) {
+	if cfg.FrequencyHz <= 0 {
+		return DigitalSignal{}, errors.New("spi.frequency_hz must be positive")
+	}
+	if cfg.Mode < 0 || cfg.Mode > 3 {
+		return DigitalSignal{}, errors.Errorf("spi.mode: expected 0..3, got %d", cfg.Mode)
+	}
+	wordBits := cfg.BitsPerTransfer
+	if wordBits == 0 {
+		wordBits = 8
+	}
+	if wordBits < 1 || wordBits > 64 {
+		return DigitalSignal{}, errors.Errorf("spi.bits_per_transfer: expected 1..64, got %d", cfg.BitsPerTransfer)
+	}
+	if cfg.MOSI == nil && cfg.MISO == nil {
+		return DigitalSignal{}, errors.New("spi needs mosi or miso")
+	}
+	if cfg.StartSeconds < 0 || cfg.GapSeconds < 0 {
+		return DigitalSignal{}, errors.New("spi.start_seconds and spi.gap_seconds cannot be negative")
+	}
+	if len(cfg.Transfers) == 0 {
+		return DigitalSignal{}, errors.New("spi needs transfers")
+	}
+
+	channels := []uint32{cfg.Clock}
+	for _, line := range []*uint32{cfg.MOSI, cfg.MISO, cfg.Enable} {
+		if line != nil {
+			channels = append(channels, *line)
+		}
+	}
+	cpol, cpha := uint8(cfg.Mode>>1), uint8(cfg.Mode&1)
+	active := uint8(0)
+	if cfg.EnableActiveHigh {
+		active = 1
+	}
+	half := 1 / (2 * cfg.FrequencyHz)
+	return DigitalSignal{
+		Channels: channels,
+		Render: func(rate, end float64) []DigitalTrace {
+			clock := newTraceBuilder(rate, cpol)
+			mosi := newTraceBuilder(rate, 0)
+			miso := newTraceBuilder(rate, 0)
+			enable := newTraceBuilder(rate, active^1)
+			t := leadIn(cfg.StartSeconds, 2*half)
+			for _, transfer := range cfg.Transfers {
+				if t >= end {
+					break
+				}
+				enable.set(t, active)
+				t += half
+				for w := range max(len(transfer.MOSI), len(transfer.MISO)) {
+					for i := 0; i < wordBits; i++ {
+						shift := wordBits - 1 - i
+						if cfg.LSBFirst {
+							shift = i
+						}
+						data := t
+						if cpha == 0 {
+							// Data is valid half a period before the leading (sampling) edge.
+							t += half
+						}
+						mosi.set(data, wordBit(transfer.MOSI, w, shift))
+						miso.set(data, wordBit(transfer.MISO, w, shift))
+						clock.set(t, cpol^1)
+						clock.set(t+half, cpol)
+						t += half
+						if cpha == 1 {
+							t += half
+						}
+					}
+				}
+				enable.set(t+half, active^1)
+				t += 2*half + cfg.GapSeconds
+			}
+
+			traces := []DigitalTrace{clock.trace}
+			for _, line := range []struct {
+				channel *uint32
+				trace   *traceBuilder
+			}{{cfg.MOSI, mosi}, {cfg.MISO, miso}, {cfg.Enable, enable}} {
+				if line.channel != nil {
+					traces = append(traces, line.trace.trace)
+				}
+			}
+			return traces
+		},
+	}, nil
+}
+
+func wordBit(words []uint64, i, shift int) uint8 {
+	if i >= len(words) {
+		return 0
+	}
+	return uint8(words[i] >> shift & 1)
+}
+
+func compileI2CSignal(cfg I2CSignalConfig) (DigitalSignal, error) {
+	frequency := cfg.FrequencyHz
+	if frequency == 0 {
+		frequency = 100_000
+	}
+	if frequency < 0 || cfg.StartSeconds < 0 || cfg.GapSeconds < 0 {
+		return DigitalSignal{}, errors.New("i2c.frequency_hz, i2c.start_seconds and i2c.gap_seconds cannot be negative")
+	}
+	if cfg.SCL == cfg.SDA {
+		return DigitalSignal{}, errors.New("i2c.scl and i2c.sda must be different channels")
+	}
+	if len(cfg.Transfers) == 0 {
+		return DigitalSignal{}, errors.New("i2c needs transfers")
+	}
+	for i, transfer := range cfg.Transfers {
+		if transfer.Address > 0x7f {
+			return DigitalSignal{}, errors.Errorf("i2c.transfers[%d].address: expected a 7-bit address, got %#x", i, transfer.Address)
+		}
+	}
+
+	half := 1 / (2 * frequency)
+	return DigitalSignal{
+		Channels: []uint32{cfg.SCL, cfg.SDA},
+		Render: func(rate, end float64) []DigitalTrace {
+			scl := newTraceBuilder(rate, 1)
+			sda := newTraceBuilder(rate, 1)
+			// clockBit puts level on SDA while SCL is low, then pulses SCL.
+			t := leadIn(cfg.StartSeconds, 2*half)
+			clockBit := func(level uint8) {
+				sda.set(t+half/2, level)
+				scl.set(t+half, 1)
+				scl.set(t+2*half, 0)
+				t += 2 * half
+			}
+			for _, transfer := range cfg.Transfers {
+				if t >= end {
+					break
+				}
+				// Start: SDA falls while SCL is high.
+				sda.set(t, 0)
+				t += half
+				scl.set(t, 0)
+
+				address := transfer.Address << 1
+				if transfer.Read {
+					address |= 1
+				}
+				payload := append([]byte{address}, transfer.Data...)
+				for i, value := range payload {
+					for shift := 7; shift >= 0; shift-- {
+						clockBit(value >> shift & 1)
+					}
+					// The device acknowledges the address and written bytes; when
+					// reading, the controller acknowledges every byte but the last.
+					ack := uint8(0)
+					if transfer.Read && i > 0 && i == len(payload)-1 {
+						ack = 1
+					}
+					clockBit(ack)
+				}
+
+				// Stop: SDA rises while SCL is high, then the bus stays free.
+				sda.set(t+half/2, 0)
+				scl.set(t+half, 1)
+				sda.set(t+2*half, 1)
+				t += 4*half + cfg.GapSeconds
+			}
+			return []DigitalTrace{scl.trace, sda.trace}
+		},
+	}, nil
+}
+
+func compileTransitionsSignal(cfg TransitionsSignalConfig) (DigitalSignal, error) {
+	if cfg.File == "" {
+		return DigitalSignal{}, errors.New("transitions.file is required")
+	}
+	var src rawdata.DigitalSource
+	if strings.EqualFold(filepath.Ext(cfg.File), ".bin") {
+		d, err := rawdata.OpenDigitalBinary(cfg.File)
+		if err != nil {
+			return DigitalSignal{}, errors.Wrap(err, "transitions.file")
+		}
+		defer func() { _ = d.Close() }()
+		src = d
+	} else {
+		column := cfg.Column
+		if column == "" {
+			column = rawdata.ChannelName(int(cfg.Channel))
+		}
+		d, err := rawdata.OpenDigitalCSV(cfg.File, column)
+		if err != nil {
+			return DigitalSignal{}, errors.Wrap(err, "transitions.file")
+		}
+		sources, err := d.Sources()
+		if err != nil {
+			return DigitalSignal{}, errors.Wrap(err, "transitions.file")
+		}
+		src = sources[0]
+	}
+	transitions, err := rawdata.CollectTransitions(src)
+	if err != nil {
+		return DigitalSignal{}, errors.Wrap(err, "transitions.file")
+	}
+	initial, begin := src.InitialState(), src.BeginTime()
+
+	return DigitalSignal{
+		Channels: []uint32{cfg.Channel},
+		Render: func(rate, end float64) []DigitalTrace {
+			line := newTraceBuilder(rate, initial)
+			for _, tr := range transitions {
+				if tr.Time-begin >= end {
+					break
+				}
+				line.set(tr.Time-begin, tr.State)
+			}
+			return []DigitalTrace{line.trace}
+		},
+	}, nil
+}
+
+func compileAnalogSignal(cfg AnalogSignalConfig) (uint32, func(float64) float64, error) {
+	switch {
+	case cfg.Sine != nil && cfg.Step != nil, cfg.Sine == nil && cfg.Step == nil:
+		return 0, nil, errors.New("needs exactly one of sine, step")
+	case cfg.Sine != nil:
+		sine := *cfg.Sine
+		if sine.FrequencyHz <= 0 {
+			return 0, nil, errors.New("sine.frequency_hz must be positive")
+		}
+		amplitude := sine.Amplitude
+		if amplitude == 0 {
+			amplitude = 1
+		}
+		phase := sine.PhaseDegrees * math.Pi / 180
+		return sine.Channel, func(t float64) float64 {
+			return sine.Offset + amplitude*math.Sin(2*math.Pi*sine.FrequencyHz*t+phase)
+		}, nil
+	default:
+		step := *cfg.Step
+		if step.AtSeconds < 0 {
+			return 0, nil, errors.New("step.at_seconds cannot be negative")
+		}
+		return step.Channel, func(t float64) float64 {
+			if t < step.AtSeconds {
+				return step.From
+			}
+			return step.To
+		}, nil
+	}
+}
+
+// leadIn returns start, or signalLeadIn bit periods when start is 0.
+func leadIn(start, bit float64) float64 {
+	if start > 0 {
+		return start
+	}
+	return signalLeadIn * bit
+}
+
+// traceBuilder draws a digital channel. Edges snap to the sample grid; a pulse shorter
+// than one sample disappears.
+type traceBuilder struct {
+	rate  float64
+	level uint8
+	trace DigitalTrace
+}
+
+func newTraceBuilder(rate float64, initial uint8) *traceBuilder {
+	return &traceBuilder{rate: rate, level: initial, trace: DigitalTrace{Initial: initial}}
+}
+
+// set drives the channel to level from t seconds on. Calls come in time order.
+func (b *traceBuilder) set(t float64, level uint8) {
+	if level == b.level {
+		return
+	}
+	b.level = level
+	t = math.Round(t*b.rate) / b.rate
+	edges := b.trace.Edges
+	switch {
+	case len(edges) > 0 && edges[len(edges)-1] >= t:
+		b.trace.Edges = edges[:len(edges)-1]
+	case t <= 0:
+		b.trace.Initial = level
+	default:
+		b.trace.Edges = append(edges, t)
+	}
+}
+
+// end returns the length of the recording of a capture in mode, in seconds.
+func (p *SignalPlan) end(mode CaptureMode) float64 {
+	switch {
+	case p.Duration > 0:
+		return p.Duration.Seconds()
+	case mode.Kind == CaptureModeTimed && mode.Duration > 0:
+		return mode.Duration.Seconds()
+	default:
+		return defaultSignalDuration.Seconds()
+	}
+}
+
+// RawExport is the synthetic content of a raw data export.
+type RawExport struct {
+	// Start is the absolute time of the first sample, for ISO8601 timestamps.
+	Start time.Time
+	// End is the length of the recording, in seconds.
+	End     float64
+	Digital []DigitalChannelData
+	// Analog samples are AnalogDownsample/AnalogSampleRate seconds apart.
+	AnalogSampleRate float64
+	AnalogDownsample uint64
+	Analog           []AnalogChannelData
+}
+
+type DigitalChannelData struct {
+	Channel uint32
+	Trace   DigitalTrace
+}
+
+type AnalogChannelData struct {
+	Channel uint32
+	Samples []float32
+}
+
+// rawExport renders the requested channels of capture; no channels means every channel
+// with a signal. Channels without a signal are low (digital) or 0 V (analog).
+func (p *SignalPlan) rawExport(capture *CaptureState, channels *pb.LogicChannels, downsample uint64, now time.Time) *RawExport {
+	end := p.end(capture.Mode)
+	if downsample == 0 {
+		downsample = 1
+	}
+	out := &RawExport{
+		Start:            capture.StartedAt,
+		End:              end,
+		AnalogSampleRate: p.AnalogSampleRate,
+		AnalogDownsample: downsample,
+	}
+	if out.Start.IsZero() {
+		out.Start = now
+	}
+
+	traces := map[uint32]DigitalTrace{}
+	for _, signal := range p.Digital {
+		for i, trace := range signal.Render(p.SampleRate, end) {
+			trace.Edges = trace.Edges[:sort.SearchFloat64s(trace.Edges, end)]
+			traces[signal.Channels[i]] = trace
+		}
+	}
+	digital, analog := channels.GetDigitalChannels(), channels.GetAnalogChannels()
+	if len(digital) == 0 && len(analog) == 0 {
+		for channel := range traces {
+			digital = append(digital, channel)
+		}
+		for channel := range p.Analog {
+			analog = append(analog, channel)
+		}
+	}
+	for _, channel := range sortedUnique(digital) {
+		out.Digital = append(out.Digital, DigitalChannelData{Channel: channel, Trace: traces[channel]})
+	}
+
+	period := float64(downsample) / p.AnalogSampleRate
+	count := int(math.Ceil(end/period - 1e-9))
+	for _, channel := range sortedUnique(analog) {
+		samples := make([]float32, count)
+		if wave := p.Analog[channel]; wave != nil {
+			for i := range samples {
+				samples[i] = float32(wave(float64(i) * period))
+			}
+		}
+		out.Analog = append(out.Analog, AnalogChannelData{Channel: channel, Samples: samples})
+	}
+	return out
+}
+
+func sortedUnique(channels []uint32) []uint32 {
+	out := slices.Clone(channels)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
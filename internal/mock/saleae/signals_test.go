@@ -0,0 +1,298 @@
+package saleae
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/go-go-golems/salad/internal/decode"
+	"github.com/go-go-golems/salad/internal/rawdata"
+)
+
+func ptrUint32(v uint32) *uint32 { return &v }
+
+func binaryChannels(digital, analog []uint32) *pb.ExportRawDataBinaryRequest_LogicChannels {
+	return &pb.ExportRawDataBinaryRequest_LogicChannels{LogicChannels: &pb.LogicChannels{DigitalChannels: digital, AnalogChannels: analog}}
+}
+
+// labSignals drives UART on 0, SPI on 1-4, I2C on 5-6, a clock on 7, a sine on analog 0
+// and a step on analog 1.
+func labSignals() *SignalsConfig {
+	return &SignalsConfig{
+		DurationSeconds:  0.01,
+		AnalogSampleRate: 10_000,
+		Digital: []DigitalSignalConfig{
+			{UART: &UARTSignalConfig{Channel: 0, BaudRate: 115200, Text: "hi", Bytes: []uint8{0x00, 0xff}, Parity: "even"}},
+			{SPI: &SPISignalConfig{
+				Clock: 1, MOSI: ptrUint32(2), MISO: ptrUint32(3), Enable: ptrUint32(4),
+				FrequencyHz: 1_000_000, Mode: 3,
+				Transfers: []SPITransferConfig{{MOSI: []uint64{0x9f, 0x00}, MISO: []uint64{0x00, 0xef}}},
+			}},
+			{I2C: &I2CSignalConfig{SCL: 5, SDA: 6, Transfers: []I2CTransferConfig{
+				{Address: 0x50, Data: []uint8{0x10}},
+				{Address: 0x50, Read: true, Data: []uint8{0xca, 0xfe}},
+			}}},
+			{Clock: &ClockSignalConfig{Channel: 7, FrequencyHz: 1000}},
+		},
+		Analog: []AnalogSignalConfig{
+			{Sine: &SineSignalConfig{Channel: 0, FrequencyHz: 100, Amplitude: 1.5, Offset: 1.65}},
+			{Step: &StepSignalConfig{Channel: 1, From: 0, To: 3.3, AtSeconds: 0.005}},
+		},
+	}
+}
+
+func newSignalServer(t *testing.T, signals *SignalsConfig) *Server {
+	t.Helper()
+	plan, err := Compile(Config{Fixtures: FixturesConfig{Captures: []CaptureFixture{{
+		CaptureID: 1,
+		Status:    "completed",
+		StartedAt: "2025-01-01T12:00:00Z",
+		Signals:   signals,
+	}}}})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return NewServer(plan)
+}
+
+func decodeExport(t *testing.T, dir string, dec decode.Decoder) []string {
+	t.Helper()
+	export, err := rawdata.OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+	defer func() { _ = export.Close() }()
+
+	var out []string
+	for frame, err := range decode.DecodeExport(dec, export) {
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if frame.Type == "enable" || frame.Type == "disable" {
+			continue
+		}
+		fields := make([]string, 0, len(frame.Fields))
+		for _, field := range frame.Fields {
+			fields = append(fields, fmt.Sprintf("%s=%v", field.Name, field.Value))
+		}
+		out = append(out, strings.TrimSpace(frame.Type+" "+strings.Join(fields, " ")))
+	}
+	return out
+}
+
+func expectFrames(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("%s: expected frames\n%s\ngot\n%s", name, strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestSignals_BinaryExportDecodes(t *testing.T) {
+	server := newSignalServer(t, labSignals())
+	dir := t.TempDir()
+	_, err := server.ExportRawDataBinary(context.Background(), &pb.ExportRawDataBinaryRequest{
+		CaptureId:             1,
+		Directory:             dir,
+		Channels:              binaryChannels([]uint32{0, 1, 2, 3, 4, 5, 6, 7}, []uint32{0, 1}),
+		AnalogDownsampleRatio: 10,
+	})
+	if err != nil {
+		t.Fatalf("ExportRawDataBinary: %v", err)
+	}
+
+	expectFrames(t, "uart", decodeExport(t, dir, &decode.UART{Input: decode.Ch(0), BitRate: 115200, BitsPerFrame: 8, Parity: decode.ParityEven, StopBits: 1}),
+		"data data=104", "data data=105", "data data=0", "data data=255")
+	expectFrames(t, "spi", decodeExport(t, dir, &decode.SPI{Clock: decode.Ch(1), MOSI: decode.Ch(2), MISO: decode.Ch(3), Enable: decode.Ch(4), CPOL: 1, CPHA: 1, BitsPerTransfer: 8, MSBFirst: true}),
+		"result mosi=159 miso=0", "result mosi=0 miso=239")
+	expectFrames(t, "i2c", decodeExport(t, dir, &decode.I2C{SCL: decode.Ch(5), SDA: decode.Ch(6)}),
+		"start", "address address=80 read=false ack=true", "data data=16 ack=true", "stop",
+		"start", "address address=80 read=true ack=true", "data data=202 ack=true", "data data=254 ack=false", "stop")
+
+	clock, err := rawdata.OpenDigitalBinary(filepath.Join(dir, "digital_7.bin"))
+	if err != nil {
+		t.Fatalf("OpenDigitalBinary: %v", err)
+	}
+	defer func() { _ = clock.Close() }()
+	edges, err := rawdata.CollectTransitions(clock)
+	if err != nil {
+		t.Fatalf("CollectTransitions: %v", err)
+	}
+	// 10 cycles of 1 kHz in 10 ms, starting high: the last rising edge would be at 10 ms.
+	if clock.InitialState() != 1 || len(edges) != 19 || edges[0] != (rawdata.Transition{Time: 0.0005, State: 0}) || clock.EndTime() != 0.01 {
+		t.Fatalf("unexpected clock: initial=%d end=%v edges=%v", clock.InitialState(), clock.EndTime(), edges)
+	}
+
+	sine, err := rawdata.OpenAnalogBinary(filepath.Join(dir, "analog_0.bin"))
+	if err != nil {
+		t.Fatalf("OpenAnalogBinary: %v", err)
+	}
+	defer func() { _ = sine.Close() }()
+	samples, err := rawdata.CollectSamples(sine)
+	if err != nil {
+		t.Fatalf("CollectSamples: %v", err)
+	}
+	// 10 kHz downsampled by 10: 10 samples of 1 ms; a quarter period of 100 Hz is 2.5 ms.
+	if len(samples) != 10 || sine.SampleRate() != 1000 || math.Abs(float64(samples[5].Value)-1.65) > 1e-5 {
+		t.Fatalf("unexpected sine: rate=%v samples=%v", sine.SampleRate(), samples)
+	}
+}
+
+func TestSignals_CSVExportISO8601(t *testing.T) {
+	server := newSignalServer(t, labSignals())
+	dir := t.TempDir()
+	_, err := server.ExportRawDataCsv(context.Background(), &pb.ExportRawDataCsvRequest{
+		CaptureId:             1,
+		Directory:             dir,
+		Channels:              &pb.ExportRawDataCsvRequest_LogicChannels{LogicChannels: &pb.LogicChannels{DigitalChannels: []uint32{7, 0}, AnalogChannels: []uint32{1}}},
+		AnalogDownsampleRatio: 100,
+		Iso8601Timestamp:      true,
+	})
+	if err != nil {
+		t.Fatalf("ExportRawDataCsv: %v", err)
+	}
+
+	// digital.csv ends at the last change on any column: the clock keeps the last UART
+	// frame inside the export.
+	expectFrames(t, "uart", decodeExport(t, dir, &decode.UART{Input: decode.Ch(0), BitRate: 115200, BitsPerFrame: 8, Parity: decode.ParityEven, StopBits: 1}),
+		"data data=104", "data data=105", "data data=0", "data data=255")
+
+	f, err := os.Open(filepath.Join(dir, "analog.csv"))
+	if err != nil {
+		t.Fatalf("open analog.csv: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("read analog.csv: %v", err)
+	}
+	// 10 ms at 10 kHz downsampled by 100: one sample per 10 ms.
+	if len(rows) != 2 || strings.Join(rows[0], ",") != "Time [s],Channel 1" || strings.Join(rows[1], ",") != "2025-01-01T12:00:00.000000000Z,0" {
+		t.Fatalf("unexpected analog.csv %v", rows)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "digital_0.bin")); !os.IsNotExist(err) {
+		t.Fatalf("expected no binary files in a csv export, got %v", err)
+	}
+}
+
+func TestSignals_TransitionsFileRelativeToConfig(t *testing.T) {
+	dir := t.TempDir()
+	recorded := "Time [s],Channel 0,Channel 3\n0.000000000,0,1\n0.001000000,1,1\n0.002000000,1,0\n0.003000000,0,0\n"
+	if err := os.WriteFile(filepath.Join(dir, "recorded.csv"), []byte(recorded), 0o644); err != nil {
+		t.Fatalf("write recorded.csv: %v", err)
+	}
+	config := `version: 1
+fixtures:
+  captures:
+    - capture_id: 1
+      status: completed
+      signals:
+        duration_seconds: 0.0025
+        digital:
+          - transitions: {channel: 2, file: recorded.csv, column: Channel 3}
+`
+	configPath := filepath.Join(dir, "mock.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	plan, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	out := t.TempDir()
+	if _, err := NewServer(plan).ExportRawDataCsv(context.Background(), &pb.ExportRawDataCsvRequest{CaptureId: 1, Directory: out}); err != nil {
+		t.Fatalf("ExportRawDataCsv: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(out, "digital.csv"))
+	if err != nil {
+		t.Fatalf("read digital.csv: %v", err)
+	}
+	// The falling edge at 3 ms is past the 2.5 ms recording.
+	want := "Time [s],Channel 2\n0.000000000,1\n0.002000000,0\n"
+	if string(got) != want {
+		t.Fatalf("expected digital.csv\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestSignals_StartedCaptureUsesTimedDuration(t *testing.T) {
+	plan, err := Compile(Config{
+		Fixtures: FixturesConfig{Devices: []DeviceConfig{{DeviceID: "DEV1", DeviceType: "DEVICE_TYPE_LOGIC_PRO_8"}}},
+		Behavior: BehaviorConfig{StartCapture: StartCaptureBehaviorConfig{OnCall: StartCaptureOnCallConfig{
+			CreateCapture: &CaptureCreateConfig{Signals: &SignalsConfig{
+				Digital: []DigitalSignalConfig{{Clock: &ClockSignalConfig{Channel: 0, FrequencyHz: 1000}}},
+			}},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	clock := NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	server := NewServer(plan, WithClock(clock))
+	ctx := context.Background()
+	started, err := server.StartCapture(ctx, &pb.StartCaptureRequest{
+		DeviceId: "DEV1",
+		CaptureConfiguration: &pb.CaptureConfiguration{CaptureMode: &pb.CaptureConfiguration_TimedCaptureMode{
+			TimedCaptureMode: &pb.TimedCaptureMode{DurationSeconds: 0.003},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+
+	dir := t.TempDir()
+	_, err = server.ExportRawDataBinary(ctx, &pb.ExportRawDataBinaryRequest{
+		CaptureId: started.GetCaptureInfo().GetCaptureId(),
+		Directory: dir,
+		Channels:  binaryChannels([]uint32{0, 4}, nil),
+	})
+	if err != nil {
+		t.Fatalf("ExportRawDataBinary: %v", err)
+	}
+	export, err := rawdata.OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+	defer func() { _ = export.Close() }()
+	if len(export.Digital) != 2 || export.Digital[0].EndTime() != 0.003 {
+		t.Fatalf("expected 2 channels of 3 ms, got %d", len(export.Digital))
+	}
+	// Channel 4 has no signal: it stays low.
+	if edges, err := rawdata.CollectTransitions(export.Digital[1]); err != nil || len(edges) != 0 || export.Digital[1].InitialState() != 0 {
+		t.Fatalf("expected a flat channel 4, got %v (%v)", edges, err)
+	}
+}
+
+func TestSignals_CompileErrors(t *testing.T) {
+	cases := map[string]SignalsConfig{
+		"needs exactly one of clock, uart": {Digital: []DigitalSignalConfig{{}}},
+		"digital channel 3 is already driven by digital[0]": {Digital: []DigitalSignalConfig{
+			{Clock: &ClockSignalConfig{Channel: 3, FrequencyHz: 1}},
+			{UART: &UARTSignalConfig{Channel: 3, BaudRate: 9600, Text: "x"}},
+		}},
+		"uart.parity":            {Digital: []DigitalSignalConfig{{UART: &UARTSignalConfig{BaudRate: 9600, Text: "x", Parity: "mark"}}}},
+		"spi needs mosi or miso": {Digital: []DigitalSignalConfig{{SPI: &SPISignalConfig{FrequencyHz: 1e6}}}},
+		"7-bit address":          {Digital: []DigitalSignalConfig{{I2C: &I2CSignalConfig{SCL: 0, SDA: 1, Transfers: []I2CTransferConfig{{Address: 0x80}}}}}},
+		"transitions.file":       {Digital: []DigitalSignalConfig{{Transitions: &TransitionsSignalConfig{File: "does-not-exist.csv"}}}},
+		"analog channel 0 already has a signal": {Analog: []AnalogSignalConfig{
+			{Sine: &SineSignalConfig{FrequencyHz: 1}},
+			{Step: &StepSignalConfig{To: 1}},
+		}},
+	}
+	for want, signals := range cases {
+		_, err := Compile(Config{Fixtures: FixturesConfig{Captures: []CaptureFixture{{CaptureID: 1, Signals: &signals}}}})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
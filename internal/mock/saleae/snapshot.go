@@ -0,0 +1,175 @@
+package saleae
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+)
+
+// StateSnapshot is a copy of the server State, in the JSON form of the admin API.
+type StateSnapshot struct {
+	AppInfo            *AppInfoSnapshot            `json:"appinfo,omitempty"`
+	Devices            []DeviceSnapshot            `json:"devices"`
+	Captures           []CaptureSnapshot           `json:"captures"`
+	Analyzers          []AnalyzerSnapshot          `json:"analyzers"`
+	HighLevelAnalyzers []HighLevelAnalyzerSnapshot `json:"high_level_analyzers"`
+	NextCaptureID      uint64                      `json:"next_capture_id"`
+	NextAnalyzerID     uint64                      `json:"next_analyzer_id"`
+}
+
+type AppInfoSnapshot struct {
+	ApplicationVersion string `json:"application_version"`
+	APIVersion         string `json:"api_version"`
+	LaunchPID          uint64 `json:"launch_pid"`
+}
+
+type DeviceSnapshot struct {
+	DeviceID     string `json:"device_id"`
+	DeviceType   string `json:"device_type"`
+	IsSimulation bool   `json:"is_simulation"`
+}
+
+type CaptureSnapshot struct {
+	ID              uint64    `json:"capture_id"`
+	Status          string    `json:"status"`
+	Origin          string    `json:"origin"`
+	StartedAt       time.Time `json:"started_at"`
+	Mode            string    `json:"mode"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+type AnalyzerSnapshot struct {
+	ID        uint64         `json:"analyzer_id"`
+	CaptureID uint64         `json:"capture_id"`
+	Name      string         `json:"name"`
+	Label     string         `json:"label"`
+	Settings  map[string]any `json:"settings"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type HighLevelAnalyzerSnapshot struct {
+	ID              uint64         `json:"analyzer_id"`
+	CaptureID       uint64         `json:"capture_id"`
+	ExtensionDir    string         `json:"extension_directory"`
+	HLAName         string         `json:"hla_name"`
+	Label           string         `json:"label"`
+	InputAnalyzerID uint64         `json:"input_analyzer_id"`
+	Settings        map[string]any `json:"settings"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+func (state *State) snapshot() StateSnapshot {
+	out := StateSnapshot{
+		Devices:            make([]DeviceSnapshot, 0, len(state.Devices)),
+		Captures:           make([]CaptureSnapshot, 0, len(state.Captures)),
+		Analyzers:          []AnalyzerSnapshot{},
+		HighLevelAnalyzers: []HighLevelAnalyzerSnapshot{},
+		NextCaptureID:      state.NextCaptureID,
+		NextAnalyzerID:     state.NextAnalyzerID,
+	}
+	if info := state.AppInfo; info != nil {
+		v := info.GetApiVersion()
+		out.AppInfo = &AppInfoSnapshot{
+			ApplicationVersion: info.GetApplicationVersion(),
+			APIVersion:         versionString(v),
+			LaunchPID:          info.GetLaunchPid(),
+		}
+	}
+	for _, device := range state.Devices {
+		out.Devices = append(out.Devices, DeviceSnapshot{
+			DeviceID:     device.GetDeviceId(),
+			DeviceType:   device.GetDeviceType().String(),
+			IsSimulation: device.GetIsSimulation(),
+		})
+	}
+	for _, capture := range state.Captures {
+		snapshot := CaptureSnapshot{
+			ID:              capture.ID,
+			Status:          capture.Status.String(),
+			Origin:          capture.Origin.String(),
+			StartedAt:       capture.StartedAt,
+			Mode:            capture.Mode.Kind.String(),
+			DurationSeconds: capture.Mode.Duration.Seconds(),
+		}
+		if capture.Failure != nil {
+			snapshot.Error = capture.Failure.Error()
+		}
+		out.Captures = append(out.Captures, snapshot)
+	}
+	sort.Slice(out.Captures, func(i, j int) bool { return out.Captures[i].ID < out.Captures[j].ID })
+
+	for _, byCapture := range state.Analyzers {
+		for _, analyzer := range byCapture {
+			settings := make(map[string]any, len(analyzer.Settings))
+			for k, v := range analyzer.Settings {
+				settings[k] = analyzerSettingValue(v)
+			}
+			out.Analyzers = append(out.Analyzers, AnalyzerSnapshot{
+				ID:        analyzer.ID,
+				CaptureID: analyzer.CaptureID,
+				Name:      analyzer.Name,
+				Label:     analyzer.Label,
+				Settings:  settings,
+				CreatedAt: analyzer.CreatedAt,
+			})
+		}
+	}
+	sort.Slice(out.Analyzers, func(i, j int) bool { return out.Analyzers[i].ID < out.Analyzers[j].ID })
+
+	for _, byCapture := range state.HighLevelAnalyzers {
+		for _, hla := range byCapture {
+			settings := make(map[string]any, len(hla.Settings))
+			for k, v := range hla.Settings {
+				settings[k] = hlaSettingValue(v)
+			}
+			out.HighLevelAnalyzers = append(out.HighLevelAnalyzers, HighLevelAnalyzerSnapshot{
+				ID:              hla.ID,
+				CaptureID:       hla.CaptureID,
+				ExtensionDir:    hla.ExtensionDir,
+				HLAName:         hla.HLAName,
+				Label:           hla.Label,
+				InputAnalyzerID: hla.InputAnalyzerID,
+				Settings:        settings,
+				CreatedAt:       hla.CreatedAt,
+			})
+		}
+	}
+	sort.Slice(out.HighLevelAnalyzers, func(i, j int) bool { return out.HighLevelAnalyzers[i].ID < out.HighLevelAnalyzers[j].ID })
+	return out
+}
+
+func versionString(v *pb.Version) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", v.GetMajor(), v.GetMinor(), v.GetPatch())
+}
+
+func analyzerSettingValue(v *pb.AnalyzerSettingValue) any {
+	switch value := v.GetValue().(type) {
+	case *pb.AnalyzerSettingValue_StringValue:
+		return value.StringValue
+	case *pb.AnalyzerSettingValue_Int64Value:
+		return value.Int64Value
+	case *pb.AnalyzerSettingValue_BoolValue:
+		return value.BoolValue
+	case *pb.AnalyzerSettingValue_DoubleValue:
+		return value.DoubleValue
+	default:
+		return nil
+	}
+}
+
+func hlaSettingValue(v *pb.HighLevelAnalyzerSettingValue) any {
+	switch value := v.GetValue().(type) {
+	case *pb.HighLevelAnalyzerSettingValue_StringValue:
+		return value.StringValue
+	case *pb.HighLevelAnalyzerSettingValue_NumberValue:
+		return value.NumberValue
+	default:
+		return nil
+	}
+}
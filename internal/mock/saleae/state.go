@@ -56,6 +56,12 @@ type CaptureState struct {
 	Origin    CaptureOrigin
 	StartedAt time.Time
 	Mode      CaptureMode
+	// Failure is the error WaitCapture and StopCapture return after a device error.
+	Failure error
+	// Signals is the recorded content raw exports write; nil writes placeholders.
+	Signals *SignalPlan
+	// DataTables are the declared analyzer frames data table exports write.
+	DataTables []DataTablePlan
 }
 
 type AnalyzerState struct {
@@ -86,4 +92,46 @@ type State struct {
 	HighLevelAnalyzers map[uint64]map[uint64]*HighLevelAnalyzerState // capture_id -> analyzer_id -> state
 	NextCaptureID      uint64
 	NextAnalyzerID     uint64
+
+	// waiters holds the completion signal of each capture a WaitCapture is blocked on.
+	waiters map[uint64]chan struct{}
+}
+
+func (status CaptureStatus) String() string {
+	switch status {
+	case CaptureStatusRunning:
+		return "running"
+	case CaptureStatusStopped:
+		return "stopped"
+	case CaptureStatusCompleted:
+		return "completed"
+	case CaptureStatusClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+func (origin CaptureOrigin) String() string {
+	switch origin {
+	case CaptureOriginLoaded:
+		return "loaded"
+	case CaptureOriginStarted:
+		return "started"
+	default:
+		return "unknown"
+	}
+}
+
+func (kind CaptureModeKind) String() string {
+	switch kind {
+	case CaptureModeTimed:
+		return "timed"
+	case CaptureModeManual:
+		return "manual"
+	case CaptureModeTrigger:
+		return "trigger"
+	default:
+		return "unknown"
+	}
 }
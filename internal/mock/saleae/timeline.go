@@ -0,0 +1,167 @@
+package saleae
+
+import (
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// timelineRun is the progress of the plan's timeline since the server started or was
+// reset.
+type timelineRun struct {
+	// start is when the timeline started, on the server clock; At counts from it.
+	start time.Time
+	fired []bool
+	// stop ends the goroutine waiting for the next timed event.
+	stop chan struct{}
+	// unavailableUntil ends the current UNAVAILABLE window.
+	unavailableUntil   time.Time
+	unavailableMessage string
+}
+
+// startTimeline (re)starts the timeline of the plan. The caller holds s.mu.
+func (s *Server) startTimeline() {
+	if s.timeline.stop != nil {
+		close(s.timeline.stop)
+	}
+	s.timeline = timelineRun{
+		start: s.clock.Now(),
+		fired: make([]bool, len(s.plan.Timeline)),
+	}
+	s.fireTimeline("", 0, 0)
+}
+
+// fireTimeline applies the timed events that are due and the events triggered by the
+// call (callN-th of method, seq-th overall; 0 for none). If any fired, or no timed event
+// is scheduled, it schedules the next one. The caller holds s.mu.
+func (s *Server) fireTimeline(method Method, callN, seq int) {
+	now := s.clock.Now()
+	elapsed := now.Sub(s.timeline.start)
+	fired := false
+	for i := range s.plan.Timeline {
+		event := &s.plan.Timeline[i]
+		if s.timeline.fired[i] {
+			continue
+		}
+		if call := event.OnCall; call != nil {
+			overall := call.Method == "" && call.N == seq
+			ofMethod := call.Method != "" && call.Method == method && call.N == callN
+			if !overall && !ofMethod {
+				continue
+			}
+		} else if event.At > elapsed {
+			continue
+		}
+		s.timeline.fired[i] = true
+		s.applyTimelineEvent(event, now)
+		fired = true
+	}
+	if !fired && s.timeline.stop != nil {
+		return
+	}
+	s.state.signalCaptures()
+	s.scheduleTimeline(elapsed)
+}
+
+// scheduleTimeline starts a goroutine that fires the next timed event on time, replacing
+// the previous one. The caller holds s.mu.
+func (s *Server) scheduleTimeline(elapsed time.Duration) {
+	if s.timeline.stop != nil {
+		close(s.timeline.stop)
+		s.timeline.stop = nil
+	}
+	next := time.Duration(-1)
+	for i, event := range s.plan.Timeline {
+		if s.timeline.fired[i] || event.OnCall != nil {
+			continue
+		}
+		if next < 0 || event.At < next {
+			next = event.At
+		}
+	}
+	if next < 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	s.timeline.stop = stop
+	timer := s.clock.NewTimer(next - elapsed)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-stop:
+		case <-timer.C():
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.timeline.stop == stop {
+				s.timeline.stop = nil
+				s.fireTimeline("", 0, 0)
+			}
+		}
+	}()
+}
+
+func (s *Server) applyTimelineEvent(event *TimelineEvent, now time.Time) {
+	state := &s.state
+	switch {
+	case event.RemoveDevice != "":
+		devices := make([]*pb.Device, 0, len(state.Devices))
+		for _, device := range state.Devices {
+			if device.GetDeviceId() != event.RemoveDevice {
+				devices = append(devices, device)
+			}
+		}
+		state.Devices = devices
+	case event.AddDevice != nil:
+		devices := make([]*pb.Device, 0, len(state.Devices)+1)
+		for _, device := range state.Devices {
+			if device.GetDeviceId() != event.AddDevice.GetDeviceId() {
+				devices = append(devices, device)
+			}
+		}
+		state.Devices = append(devices, event.AddDevice)
+	case event.CaptureStatus != nil:
+		for _, capture := range state.timelineCaptures(event.CaptureStatus.CaptureID) {
+			capture.Status = event.CaptureStatus.Status
+		}
+	case event.FailCapture != nil:
+		failure := status.Error(event.FailCapture.Code, event.FailCapture.Message)
+		for _, capture := range state.timelineCaptures(event.FailCapture.CaptureID) {
+			capture.Status = CaptureStatusStopped
+			capture.Failure = failure
+		}
+	case event.AppInfo != nil:
+		state.AppInfo = event.AppInfo
+	case event.Unavailable != nil:
+		s.timeline.unavailableUntil = now.Add(event.Unavailable.For)
+		s.timeline.unavailableMessage = event.Unavailable.Message
+	}
+}
+
+// unavailable returns the UNAVAILABLE error of the current window, if any. The caller
+// holds s.mu.
+func (s *Server) unavailable() error {
+	if !s.clock.Now().Before(s.timeline.unavailableUntil) {
+		return nil
+	}
+	return status.Error(codes.Unavailable, s.timeline.unavailableMessage)
+}
+
+// timelineCaptures returns the capture with the ID, or every running capture for 0.
+func (state *State) timelineCaptures(captureID uint64) []*CaptureState {
+	if captureID != 0 {
+		if capture, ok := state.Captures[captureID]; ok {
+			return []*CaptureState{capture}
+		}
+		return nil
+	}
+	var out []*CaptureState
+	for _, capture := range state.Captures {
+		if capture.Status == CaptureStatusRunning {
+			out = append(out, capture)
+		}
+	}
+	return out
+}
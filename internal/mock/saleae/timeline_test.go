@@ -0,0 +1,157 @@
+package saleae
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTimelineServer(t *testing.T, timeline ...TimelineEventConfig) (*Server, *VirtualClock) {
+	t.Helper()
+	cfg := happyPathConfig(t)
+	cfg.Defaults.Timing.WaitCapturePolicy = "block_until_done"
+	cfg.Behavior.WaitCapture.Validate.ErrorOnManualMode = ptrBool(false)
+	cfg.Timeline = timeline
+	plan, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	clock := NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	return NewServer(plan, WithClock(clock)), clock
+}
+
+func deviceIDs(t *testing.T, server *Server) []string {
+	t.Helper()
+	reply, err := server.GetDevices(context.Background(), &pb.GetDevicesRequest{})
+	if err != nil {
+		t.Fatalf("GetDevices: %v", err)
+	}
+	var out []string
+	for _, device := range reply.GetDevices() {
+		out = append(out, device.GetDeviceId())
+	}
+	return out
+}
+
+func TestTimeline_TimedEvents(t *testing.T) {
+	server, clock := newTimelineServer(t,
+		TimelineEventConfig{At: "2s", RemoveDevice: "DEV1"},
+		TimelineEventConfig{At: "5s", AppInfo: &AppInfoConfig{ApplicationVersion: "2.4.0", LaunchPID: 9999}},
+		TimelineEventConfig{At: "5s", AddDevice: &DeviceConfig{DeviceID: "DEV1", DeviceType: "DEVICE_TYPE_LOGIC_PRO_8"}},
+		TimelineEventConfig{At: "10s", Unavailable: &TimelineUnavailableConfig{For: "5s", Message: "restarting"}},
+	)
+	ctx := context.Background()
+
+	if got := deviceIDs(t, server); len(got) != 1 {
+		t.Fatalf("expected DEV1 before 2s, got %v", got)
+	}
+	clock.Advance(2 * time.Second)
+	if got := deviceIDs(t, server); len(got) != 0 {
+		t.Fatalf("expected DEV1 to disappear at 2s, got %v", got)
+	}
+	clock.Advance(3 * time.Second)
+	if got := deviceIDs(t, server); len(got) != 1 || got[0] != "DEV1" {
+		t.Fatalf("expected DEV1 to come back at 5s, got %v", got)
+	}
+	info, err := server.GetAppInfo(ctx, &pb.GetAppInfoRequest{})
+	if err != nil || info.GetAppInfo().GetLaunchPid() != 9999 || info.GetAppInfo().GetApplicationVersion() != "2.4.0" {
+		t.Fatalf("expected the restarted app info, got %v, %v", info, err)
+	}
+
+	clock.Advance(5 * time.Second)
+	if _, err := server.GetAppInfo(ctx, &pb.GetAppInfoRequest{}); status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "restarting") {
+		t.Fatalf("expected Unavailable during the window, got %v", err)
+	}
+	clock.Advance(5 * time.Second)
+	if _, err := server.GetAppInfo(ctx, &pb.GetAppInfoRequest{}); err != nil {
+		t.Fatalf("expected the window to end after 5s, got %v", err)
+	}
+
+	server.Reset()
+	if got := deviceIDs(t, server); len(got) != 1 {
+		t.Fatalf("expected Reset to restart the timeline, got %v", got)
+	}
+}
+
+func TestTimeline_FailCaptureEndsBlockedWait(t *testing.T) {
+	server, clock := newTimelineServer(t, TimelineEventConfig{At: "30s", FailCapture: &TimelineFailCaptureConfig{}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started, err := server.StartCapture(ctx, &pb.StartCaptureRequest{DeviceId: "DEV1", CaptureConfiguration: manualMode()})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	captureID := started.GetCaptureInfo().GetCaptureId()
+	errc := make(chan error, 1)
+	go func() {
+		_, err := server.WaitCapture(ctx, &pb.WaitCaptureRequest{CaptureId: captureID})
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	clock.Advance(30 * time.Second)
+
+	err = <-errc
+	if status.Code(err) != codes.Aborted || !strings.Contains(err.Error(), "ERROR_CODE_DEVICE_ERROR") {
+		t.Fatalf("expected the device error, got %v", err)
+	}
+	if _, err := server.StopCapture(ctx, &pb.StopCaptureRequest{CaptureId: captureID}); status.Code(err) != codes.Aborted {
+		t.Fatalf("expected StopCapture to report the device error, got %v", err)
+	}
+	if snapshot := server.Snapshot(); snapshot.Captures[0].Status != "stopped" || snapshot.Captures[0].Error == "" {
+		t.Fatalf("unexpected capture %+v", snapshot.Captures[0])
+	}
+}
+
+func TestTimeline_CallEvents(t *testing.T) {
+	server, _ := newTimelineServer(t,
+		TimelineEventConfig{OnCall: &TimelineCallConfig{Method: "GetDevices", N: 2}, RemoveDevice: "DEV1"},
+		TimelineEventConfig{OnCall: &TimelineCallConfig{N: 4}, CaptureStatus: &TimelineCaptureStatusConfig{Status: "completed"}},
+	)
+	ctx := context.Background()
+
+	if got := deviceIDs(t, server); len(got) != 1 {
+		t.Fatalf("expected DEV1 on the first call, got %v", got)
+	}
+	if got := deviceIDs(t, server); len(got) != 0 {
+		t.Fatalf("expected DEV1 to be gone on the second call, got %v", got)
+	}
+
+	// After a reset, call 1 starts a capture and call 4 (whatever the method) completes it.
+	server.Reset()
+	started, err := server.StartCapture(ctx, &pb.StartCaptureRequest{DeviceId: "DEV1", CaptureConfiguration: manualMode()})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	deviceIDs(t, server)
+	deviceIDs(t, server)
+	if snapshot := server.Snapshot(); snapshot.Captures[0].Status != "running" {
+		t.Fatalf("expected the capture to run until call 4, got %+v", snapshot.Captures[0])
+	}
+	if _, err := server.WaitCapture(ctx, &pb.WaitCaptureRequest{CaptureId: started.GetCaptureInfo().GetCaptureId()}); err != nil {
+		t.Fatalf("expected call 4 to complete the capture first, got %v", err)
+	}
+}
+
+func TestTimeline_CompileErrors(t *testing.T) {
+	cases := map[string]TimelineEventConfig{
+		"at or on_call is required":        {RemoveDevice: "DEV1"},
+		"at and on_call are exclusive":     {At: "1s", OnCall: &TimelineCallConfig{N: 1}, RemoveDevice: "DEV1"},
+		"exactly one of":                   {At: "1s", RemoveDevice: "DEV1", AppInfo: &AppInfoConfig{}},
+		"invalid at":                       {At: "soon", RemoveDevice: "DEV1"},
+		"on_call.n must be >= 1":           {OnCall: &TimelineCallConfig{Method: "GetDevices"}, RemoveDevice: "DEV1"},
+		"invalid unavailable.for":          {At: "1s", Unavailable: &TimelineUnavailableConfig{}},
+		"capture_status.status":            {At: "1s", CaptureStatus: &TimelineCaptureStatusConfig{CaptureID: 1}},
+		"fail_capture.status cannot be OK": {At: "1s", FailCapture: &TimelineFailCaptureConfig{Status: "OK"}},
+	}
+	for want, event := range cases {
+		if _, err := Compile(Config{Timeline: []TimelineEventConfig{event}}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package saleae
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// captureWait is a blocking WaitCapture in progress. The server waits for it without
+// holding its lock and re-evaluates the capture whenever one of the events below happens.
+type captureWait struct {
+	captureID uint64
+	// done is closed once the capture stops running or disappears.
+	done <-chan struct{}
+	// completesAt is when a timed capture completes; deadline is when max_block_ms
+	// expires. Zero means never.
+	completesAt time.Time
+	deadline    time.Time
+}
+
+// wakeAt returns the earliest of completesAt and deadline, or zero if neither is set.
+func (wait *captureWait) wakeAt() time.Time {
+	at := wait.completesAt
+	if !wait.deadline.IsZero() && (at.IsZero() || wait.deadline.Before(at)) {
+		at = wait.deadline
+	}
+	return at
+}
+
+// awaitCapture blocks until the wait ends and returns the WaitCapture reply or error.
+func (s *Server) awaitCapture(ctx context.Context, wait *captureWait) (any, error) {
+	for {
+		if err := s.block(ctx, wait); err != nil {
+			return nil, err
+		}
+		out, err := s.withState(ctx, func(runtime *RuntimeContext) (any, error) {
+			return runtime.waitCapture(wait.captureID, wait.deadline)
+		})
+		if err != nil {
+			return nil, err
+		}
+		next, ok := out.(*captureWait)
+		if !ok {
+			return out, nil
+		}
+		wait = next
+	}
+}
+
+// block returns once the wait should be re-evaluated, or with the status of the client
+// context once it is cancelled or past its deadline.
+func (s *Server) block(ctx context.Context, wait *captureWait) error {
+	var wake <-chan time.Time
+	if at := wait.wakeAt(); !at.IsZero() {
+		clock := s.Clock()
+		timer := clock.NewTimer(at.Sub(clock.Now()))
+		defer timer.Stop()
+		wake = timer.C()
+	}
+
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-wait.done:
+	case <-wake:
+	}
+	return nil
+}
+
+// captureDone returns a channel that is closed once the capture is no longer running.
+func (state *State) captureDone(captureID uint64) <-chan struct{} {
+	done, ok := state.waiters[captureID]
+	if !ok {
+		done = make(chan struct{})
+		state.waiters[captureID] = done
+	}
+	return done
+}
+
+// signalCaptures closes the completion signals of captures that stopped running or were
+// deleted. The server calls it after every state change.
+func (state *State) signalCaptures() {
+	for id, done := range state.waiters {
+		if capture, ok := state.Captures[id]; ok && capture.Status == CaptureStatusRunning {
+			continue
+		}
+		close(done)
+		delete(state.waiters, id)
+	}
+}
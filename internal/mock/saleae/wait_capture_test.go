@@ -0,0 +1,184 @@
+package saleae
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func startBlockingWaitServer(t *testing.T, maxBlockMs int, opts ...Option) pb.ManagerClient {
+	t.Helper()
+	cfg := Config{
+		Version: 1,
+		Defaults: DefaultsConfig{
+			Timing: TimingDefaultsConfig{WaitCapturePolicy: "block_until_done", MaxBlockMs: maxBlockMs},
+		},
+		Fixtures: FixturesConfig{
+			Devices: []DeviceConfig{{DeviceID: "DEV1", DeviceType: "DEVICE_TYPE_LOGIC_PRO_8"}},
+		},
+		Behavior: BehaviorConfig{
+			WaitCapture: WaitCaptureBehaviorConfig{
+				Validate: WaitCaptureValidateConfig{ErrorOnManualMode: ptrBool(false)},
+			},
+		},
+	}
+	plan, err := Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	_, _, listener, cleanup, err := StartMockServer(plan, opts...)
+	if err != nil {
+		t.Fatalf("StartMockServer: %v", err)
+	}
+	t.Cleanup(cleanup)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewManagerClient(conn)
+}
+
+func startCapture(t *testing.T, manager pb.ManagerClient, mode *pb.CaptureConfiguration) uint64 {
+	t.Helper()
+	reply, err := manager.StartCapture(context.Background(), &pb.StartCaptureRequest{
+		DeviceId:             "DEV1",
+		CaptureConfiguration: mode,
+	})
+	if err != nil {
+		t.Fatalf("StartCapture: %v", err)
+	}
+	return reply.GetCaptureInfo().GetCaptureId()
+}
+
+func manualMode() *pb.CaptureConfiguration {
+	return &pb.CaptureConfiguration{CaptureMode: &pb.CaptureConfiguration_ManualCaptureMode{ManualCaptureMode: &pb.ManualCaptureMode{}}}
+}
+
+// waitAsync runs WaitCapture in the background and returns its result channel.
+func waitAsync(ctx context.Context, manager pb.ManagerClient, captureID uint64) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		_, err := manager.WaitCapture(ctx, &pb.WaitCaptureRequest{CaptureId: captureID})
+		errc <- err
+	}()
+	return errc
+}
+
+func TestWaitCapture_BlockingWaitDoesNotBlockOtherRPCs(t *testing.T) {
+	manager := startBlockingWaitServer(t, 0)
+	captureID := startCapture(t, manager, manualMode())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errc := waitAsync(ctx, manager, captureID)
+
+	// Other RPCs go through while the wait is blocked.
+	rpcCtx, rpcCancel := context.WithTimeout(ctx, time.Second)
+	defer rpcCancel()
+	if _, err := manager.GetDevices(rpcCtx, &pb.GetDevicesRequest{}); err != nil {
+		t.Fatalf("GetDevices during WaitCapture: %v", err)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("expected WaitCapture to block on a running capture, got %v", err)
+	default:
+	}
+
+	if _, err := manager.StopCapture(rpcCtx, &pb.StopCaptureRequest{CaptureId: captureID}); err != nil {
+		t.Fatalf("StopCapture during WaitCapture: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("expected WaitCapture to return once stopped, got %v", err)
+	}
+}
+
+func TestWaitCapture_ClosedCaptureEndsWait(t *testing.T) {
+	manager := startBlockingWaitServer(t, 0)
+	captureID := startCapture(t, manager, manualMode())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errc := waitAsync(ctx, manager, captureID)
+	time.Sleep(20 * time.Millisecond)
+	if _, err := manager.CloseCapture(ctx, &pb.CloseCaptureRequest{CaptureId: captureID}); err != nil {
+		t.Fatalf("CloseCapture: %v", err)
+	}
+	if err := <-errc; status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for the closed capture, got %v", err)
+	}
+}
+
+func TestWaitCapture_HonorsClientDeadlineAndMaxBlock(t *testing.T) {
+	manager := startBlockingWaitServer(t, 0)
+	captureID := startCapture(t, manager, manualMode())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := <-waitAsync(ctx, manager, captureID); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the wait to end at the client deadline, took %s", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	errc := waitAsync(ctx, manager, captureID)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-errc; status.Code(err) != codes.Canceled {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+
+	manager = startBlockingWaitServer(t, 50)
+	captureID = startCapture(t, manager, manualMode())
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := <-waitAsync(ctx, manager, captureID); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded after max_block_ms, got %v", err)
+	}
+}
+
+func TestWaitCapture_TimedCaptureCompletes(t *testing.T) {
+	manager := startBlockingWaitServer(t, 0)
+	captureID := startCapture(t, manager, &pb.CaptureConfiguration{
+		CaptureMode: &pb.CaptureConfiguration_TimedCaptureMode{TimedCaptureMode: &pb.TimedCaptureMode{DurationSeconds: 0.05}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := <-waitAsync(ctx, manager, captureID); err != nil {
+		t.Fatalf("expected the timed capture to complete, got %v", err)
+	}
+}
+
+func TestWaitCapture_VirtualClockCompletesTimedCapture(t *testing.T) {
+	clock := NewVirtualClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := startBlockingWaitServer(t, 0, WithClock(clock))
+	captureID := startCapture(t, manager, &pb.CaptureConfiguration{
+		CaptureMode: &pb.CaptureConfiguration_TimedCaptureMode{TimedCaptureMode: &pb.TimedCaptureMode{DurationSeconds: 3600}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errc := waitAsync(ctx, manager, captureID)
+	time.Sleep(20 * time.Millisecond)
+	clock.Advance(59 * time.Minute)
+	select {
+	case err := <-errc:
+		t.Fatalf("expected the wait to block until the hour has passed, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Minute)
+	if err := <-errc; err != nil {
+		t.Fatalf("expected the timed capture to complete, got %v", err)
+	}
+}
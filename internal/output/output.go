@@ -0,0 +1,155 @@
+// Package output writes command results either as the greppable key=value lines salad
+// has always printed, or as JSON for scripts.
+//
+// Text mode prints one line per record (fields separated by spaces, values quoted when
+// they contain spaces) and a final "ok". JSON mode prints one object with a "records"
+// array (each record an object with keys in field order) and "ok": true.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format selects the output encoding.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// Field is one key/value of a record.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Record is an ordered list of fields, printed as one line in text mode.
+type Record []Field
+
+// Add appends a field. NaN floats are skipped, so "not measurable" values disappear
+// from both encodings instead of printing NaN.
+func (r Record) Add(key string, value any) Record {
+	if f, ok := value.(float64); ok && math.IsNaN(f) {
+		return r
+	}
+	return append(r, Field{Key: key, Value: value})
+}
+
+// Writer collects or prints records.
+type Writer struct {
+	w       io.Writer
+	format  Format
+	records []Record
+}
+
+// New returns a writer for the given format.
+func New(w io.Writer, format Format) *Writer {
+	return &Writer{w: w, format: format}
+}
+
+// Write prints a record (text) or buffers it until Close (JSON).
+func (w *Writer) Write(r Record) error {
+	if w.format == FormatJSON {
+		w.records = append(w.records, r)
+		return nil
+	}
+	parts := make([]string, len(r))
+	for i, f := range r {
+		parts[i] = f.Key + "=" + textValue(f.Value)
+	}
+	_, err := io.WriteString(w.w, strings.Join(parts, " ")+"\n")
+	return errors.Wrap(err, "write output")
+}
+
+// Close finishes a successful run: "ok" in text mode, the JSON document in JSON mode.
+func (w *Writer) Close() error { return w.Finish(true) }
+
+// Finish ends the output. When ok is false (e.g. a check found differences) text mode
+// omits the final "ok" and JSON mode writes "ok": false; the command then returns an
+// error so the exit status is non-zero.
+func (w *Writer) Finish(ok bool) error {
+	if w.format != FormatJSON {
+		if !ok {
+			return nil
+		}
+		_, err := io.WriteString(w.w, "ok\n")
+		return errors.Wrap(err, "write output")
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`{"records":[`)
+	for i, r := range w.records {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		b, err := r.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	buf.WriteString("],\"ok\":" + strconv.FormatBool(ok) + "}\n")
+	_, err := w.w.Write(buf.Bytes())
+	return errors.Wrap(err, "write output")
+}
+
+// MarshalJSON encodes the record as an object, keeping field order.
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encode key %q", f.Key)
+		}
+		v, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encode %s", f.Key)
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func textValue(v any) string {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case float64:
+		// Nine significant digits hide float noise (499999.99999999994) in text mode;
+		// JSON keeps full precision.
+		s = strconv.FormatFloat(x, 'g', 9, 64)
+	case bool:
+		s = strconv.FormatBool(x)
+	case int:
+		s = strconv.Itoa(x)
+	case int64:
+		s = strconv.FormatInt(x, 10)
+	case uint64:
+		s = strconv.FormatUint(x, 10)
+	default:
+		b, err := json.Marshal(x)
+		if err != nil {
+			return strconv.Quote(err.Error())
+		}
+		s = string(b)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package output
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestWriter_Text(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, FormatText)
+	r := Record{}.Add("name", "Channel 0").Add("rising", 3).Add("frequency_hz", 499999.99999999994).Add("duty", math.NaN())
+	if err := w.Write(r); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	want := "name=\"Channel 0\" rising=3 frequency_hz=500000\nok\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestWriter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, FormatJSON)
+	for _, r := range []Record{Record{}.Add("b", 1).Add("a", "x"), Record{}.Add("ok", true)} {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	want := `{"records":[{"b":1,"a":"x"},{"ok":true}],"ok":true}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("expected %s, got %s", want, buf.String())
+	}
+}
@@ -0,0 +1,353 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Composition directives. They are consumed while loading and never reach Config.
+const (
+	keyExtends   = "extends"
+	keyListMerge = "list_merge"
+	keyInclude   = "include"
+)
+
+// ListMergeStrategy controls how a list in a file is combined with the same list
+// from the file(s) it extends.
+type ListMergeStrategy string
+
+const (
+	ListMergeReplace ListMergeStrategy = "replace"
+	ListMergeAppend  ListMergeStrategy = "append"
+	ListMergePrepend ListMergeStrategy = "prepend"
+)
+
+// includableLists are the top-level lists whose items may be `{include: fragment.yaml}`.
+var includableLists = []string{"analyzers", "exports"}
+
+// readFileFunc reads a config file; LoadIn confines it to a directory.
+type readFileFunc func(path string) ([]byte, error)
+
+// composeFile loads path and resolves `extends`, `list_merge` and `include` directives,
+// returning the fully merged (but not yet decoded) document.
+//
+// Paths in directives are resolved relative to the file that declares them. Paths
+// inside the config itself (settings files, export targets) are left untouched.
+func composeFile(path string, stack []string, read readFileFunc) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolve pipeline config path %s", path)
+	}
+	for _, seen := range stack {
+		if seen == abs {
+			return nil, errors.Errorf("pipeline config cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	raw, err := readDocument(abs, read)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := raw.(map[string]any)
+	if !ok {
+		if raw == nil {
+			doc = map[string]any{}
+		} else {
+			return nil, errors.Errorf("pipeline config %s: expected mapping at top-level, got %T", path, raw)
+		}
+	}
+
+	if err := checkVersion(doc, path); err != nil {
+		return nil, err
+	}
+
+	bases, err := popStringList(doc, keyExtends, path)
+	if err != nil {
+		return nil, err
+	}
+	strategies, err := popListMerge(doc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(abs)
+	for _, key := range includableLists {
+		if err := expandIncludes(doc, key, dir, stack, read); err != nil {
+			return nil, errors.Wrapf(err, "pipeline config %s", path)
+		}
+	}
+
+	if len(bases) == 0 {
+		return doc, nil
+	}
+
+	var merged map[string]any
+	for _, base := range bases {
+		baseDoc, err := composeFile(resolveRelative(dir, base), stack, read)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = baseDoc
+			continue
+		}
+		merged = deepMerge(merged, baseDoc, strategies, "")
+	}
+	return deepMerge(merged, doc, strategies, ""), nil
+}
+
+func readDocument(path string, read readFileFunc) (any, error) {
+	b, err := read(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read pipeline config %s", path)
+	}
+
+	var doc any
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, errors.Wrapf(err, "decode pipeline config yaml %s", path)
+		}
+	case ".json":
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, errors.Wrapf(err, "decode pipeline config json %s", path)
+		}
+	default:
+		return nil, errors.Errorf("unsupported pipeline config extension %q (expected .yaml/.yml/.json)", ext)
+	}
+	return doc, nil
+}
+
+// checkVersion rejects files that explicitly declare a version other than 1, so a
+// v1 file can never silently extend or include a future format.
+func checkVersion(doc map[string]any, path string) error {
+	raw, ok := doc["version"]
+	if !ok || raw == nil {
+		return nil
+	}
+	switch v := raw.(type) {
+	case int:
+		if v == 0 || v == 1 {
+			return nil
+		}
+	case float64:
+		if v == 0 || v == 1 {
+			return nil
+		}
+	}
+	return errors.Errorf("pipeline config %s: unsupported pipeline config version %v", path, raw)
+}
+
+func popStringList(doc map[string]any, key string, path string) ([]string, error) {
+	raw, ok := doc[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(doc, key)
+
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, errors.Errorf("pipeline config %s: %s must not be empty", path, key)
+		}
+		return []string{v}, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return nil, errors.Errorf("pipeline config %s: %s[%d] must be a non-empty string", path, key, i)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, errors.Errorf("pipeline config %s: %s must be a string or list of strings, got %T", path, key, raw)
+	}
+}
+
+func popListMerge(doc map[string]any, path string) (map[string]ListMergeStrategy, error) {
+	raw, ok := doc[keyListMerge]
+	if !ok {
+		return nil, nil
+	}
+	delete(doc, keyListMerge)
+
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.Errorf("pipeline config %s: %s must be a mapping of list path to strategy, got %T", path, keyListMerge, raw)
+	}
+	out := make(map[string]ListMergeStrategy, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("pipeline config %s: %s[%q] must be a string", path, keyListMerge, k)
+		}
+		strategy, err := parseListMergeStrategy(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "pipeline config %s: %s[%q]", path, keyListMerge, k)
+		}
+		out[k] = strategy
+	}
+	return out, nil
+}
+
+func parseListMergeStrategy(s string) (ListMergeStrategy, error) {
+	switch ListMergeStrategy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ListMergeReplace:
+		return ListMergeReplace, nil
+	case ListMergeAppend:
+		return ListMergeAppend, nil
+	case ListMergePrepend:
+		return ListMergePrepend, nil
+	default:
+		return ListMergeReplace, errors.Errorf("unknown list merge strategy %q (expected replace|append|prepend)", s)
+	}
+}
+
+// expandIncludes replaces `{include: path}` items of doc[key] with the fragment's items.
+// A fragment is either a single mapping or a list of mappings; fragments may include
+// further fragments.
+func expandIncludes(doc map[string]any, key string, dir string, stack []string, read readFileFunc) error {
+	raw, ok := doc[key]
+	if !ok || raw == nil {
+		return nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return errors.Errorf("%s must be a list, got %T", key, raw)
+	}
+	expanded, err := expandIncludeItems(items, key, dir, stack, read)
+	if err != nil {
+		return err
+	}
+	doc[key] = expanded
+	return nil
+}
+
+func expandIncludeItems(items []any, key string, dir string, stack []string, read readFileFunc) ([]any, error) {
+	out := make([]any, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			out = append(out, item)
+			continue
+		}
+		target, ok := m[keyInclude]
+		if !ok {
+			out = append(out, item)
+			continue
+		}
+		if len(m) != 1 {
+			return nil, errors.Errorf("%s[%d]: include items must not have other keys", key, i)
+		}
+		rel, ok := target.(string)
+		if !ok || strings.TrimSpace(rel) == "" {
+			return nil, errors.Errorf("%s[%d].include must be a non-empty string", key, i)
+		}
+
+		fragment, err := loadFragment(resolveRelative(dir, rel), key, stack, read)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s[%d]", key, i)
+		}
+		out = append(out, fragment...)
+	}
+	return out, nil
+}
+
+func loadFragment(path string, key string, stack []string, read readFileFunc) ([]any, error) {
+	for _, seen := range stack {
+		if seen == path {
+			return nil, errors.Errorf("pipeline config cycle: %s", strings.Join(append(stack, path), " -> "))
+		}
+	}
+
+	raw, err := readDocument(path, read)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []any
+	switch v := raw.(type) {
+	case map[string]any:
+		items = []any{v}
+	case []any:
+		items = v
+	default:
+		return nil, errors.Errorf("include %s: expected mapping or list of mappings, got %T", path, raw)
+	}
+	return expandIncludeItems(items, key, filepath.Dir(path), append(stack, path), read)
+}
+
+// deepMerge merges over onto base. Mappings merge key by key, scalars in over win,
+// and lists follow the strategy registered for their dotted path (default: replace).
+func deepMerge(base map[string]any, over map[string]any, strategies map[string]ListMergeStrategy, prefix string) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+
+	keys := make([]string, 0, len(over))
+	for k := range over {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := over[k]
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		existing, ok := out[k]
+		if !ok {
+			out[k] = v
+			continue
+		}
+
+		switch ov := v.(type) {
+		case map[string]any:
+			if bv, ok := existing.(map[string]any); ok {
+				out[k] = deepMerge(bv, ov, strategies, path)
+				continue
+			}
+		case []any:
+			if bv, ok := existing.([]any); ok {
+				out[k] = mergeLists(bv, ov, strategies[path])
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func mergeLists(base []any, over []any, strategy ListMergeStrategy) []any {
+	switch strategy {
+	case ListMergeAppend:
+		return append(append(make([]any, 0, len(base)+len(over)), base...), over...)
+	case ListMergePrepend:
+		return append(append(make([]any, 0, len(base)+len(over)), over...), base...)
+	case ListMergeReplace:
+		return over
+	default:
+		return over
+	}
+}
+
+func resolveRelative(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
@@ -1,10 +1,10 @@
 package pipeline
 
 import (
-	"encoding/json"
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -17,6 +17,9 @@ import (
 // - add LLA analyzers
 // - export raw-csv/raw-binary/table-csv
 // - close capture
+//
+// Files may also use the composition directives `extends`, `list_merge` and
+// list-item `include` (see compose.go); they are resolved by Load before decoding.
 type Config struct {
 	Version int `json:"version" yaml:"version"`
 
@@ -58,6 +61,19 @@ type CleanupConfig struct {
 	CloseCapture *bool `json:"close_capture,omitempty" yaml:"close_capture,omitempty"`
 }
 
+// Export types understood by the runner.
+const (
+	ExportTypeRawCSV    = "raw-csv"
+	ExportTypeRawBinary = "raw-binary"
+	ExportTypeTableCSV  = "table-csv"
+)
+
+// ExportTypes lists all export types (used for validation messages and schemas).
+var ExportTypes = []string{ExportTypeRawCSV, ExportTypeRawBinary, ExportTypeTableCSV}
+
+// RadixNames lists the radix values accepted by table-csv analyzer refs.
+var RadixNames = []string{"hex", "dec", "bin", "ascii"}
+
 type ExportConfig struct {
 	// Type is one of: raw-csv, raw-binary, table-csv
 	Type string `json:"type" yaml:"type"`
@@ -88,28 +104,68 @@ type TableFilterConfig struct {
 	Columns []string `json:"columns,omitempty" yaml:"columns,omitempty"`
 }
 
+// Load reads a pipeline config, resolves `extends` / `include` composition and
+// decodes the merged result strictly (unknown fields are rejected).
 func Load(path string) (*Config, error) {
 	if path == "" {
 		return nil, errors.New("pipeline config path is required")
 	}
-	b, err := os.ReadFile(path)
+	return load(path, os.ReadFile)
+}
+
+// LoadIn is Load for a path relative to dir, which must contain the config and every
+// file it extends or includes.
+func LoadIn(dir, path string) (*Config, error) {
+	if path == "" {
+		return nil, errors.New("pipeline config path is required")
+	}
+	root, err := os.OpenRoot(dir)
 	if err != nil {
-		return nil, errors.Wrapf(err, "read pipeline config %s", path)
+		return nil, errors.Wrapf(err, "open pipeline config directory %s", dir)
+	}
+	defer func() { _ = root.Close() }()
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolve pipeline config directory %s", dir)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return load(path, func(name string) ([]byte, error) {
+		rel, err := filepath.Rel(base, name)
+		if err != nil || !filepath.IsLocal(rel) {
+			return nil, errors.Errorf("%s is outside %s", name, dir)
+		}
+		return root.ReadFile(rel)
+	})
+}
+
+func load(path string, read readFileFunc) (*Config, error) {
+	doc, err := composeFile(path, nil, read)
+	if err != nil {
+		return nil, err
 	}
 
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encode merged pipeline config %s", path)
+	}
+	return decode(b, path)
+}
+
+// Parse decodes a single pipeline config document (YAML or JSON) strictly. Composition
+// directives are not supported since there is no file to resolve them against.
+func Parse(b []byte) (*Config, error) {
+	return decode(b, "<inline>")
+}
+
+func decode(b []byte, name string) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+
 	cfg := &Config{}
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(b, cfg); err != nil {
-			return nil, errors.Wrapf(err, "decode pipeline config yaml %s", path)
-		}
-	case ".json":
-		if err := json.Unmarshal(b, cfg); err != nil {
-			return nil, errors.Wrapf(err, "decode pipeline config json %s", path)
-		}
-	default:
-		return nil, errors.Errorf("unsupported pipeline config extension %q (expected .yaml/.yml/.json)", ext)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrapf(err, "decode pipeline config %s", name)
 	}
 
 	if cfg.Version == 0 {
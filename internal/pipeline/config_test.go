@@ -0,0 +1,173 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestLoad_ExtendsDeepMergeAndListStrategies(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base", "base.yaml"), `
+version: 1
+capture:
+  load:
+    filepath: /tmp/base.sal
+analyzers:
+  - include: fragments/spi.yaml
+exports:
+  - type: raw-csv
+    directory: /tmp/base/raw
+    digital: [0, 1]
+cleanup:
+  close_capture: false
+`)
+	writeFile(t, filepath.Join(dir, "base", "fragments", "spi.yaml"), `
+name: SPI
+label: spi
+set_int: ["Clock=0"]
+`)
+	writeFile(t, filepath.Join(dir, "fragments", "i2c.yaml"), `
+- name: I2C
+  label: i2c
+`)
+	writeFile(t, filepath.Join(dir, "child.yaml"), `
+extends: base/base.yaml
+list_merge:
+  analyzers: append
+capture:
+  load:
+    filepath: /tmp/child.sal
+analyzers:
+  - include: fragments/i2c.yaml
+exports:
+  - type: table-csv
+    filepath: /tmp/child/table.csv
+    analyzers:
+      - ref: spi
+        radix: hex
+`)
+
+	cfg, err := Load(filepath.Join(dir, "child.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Version != 1 {
+		t.Fatalf("version: expected 1, got %d", cfg.Version)
+	}
+	if got := cfg.Capture.Load.Filepath; got != "/tmp/child.sal" {
+		t.Fatalf("capture.load.filepath: expected child override, got %q", got)
+	}
+	if len(cfg.Analyzers) != 2 || cfg.Analyzers[0].Label != "spi" || cfg.Analyzers[1].Label != "i2c" {
+		t.Fatalf("analyzers: expected [spi i2c] (append), got %+v", cfg.Analyzers)
+	}
+	if len(cfg.Analyzers[0].SetInt) != 1 || cfg.Analyzers[0].SetInt[0] != "Clock=0" {
+		t.Fatalf("analyzers[0].set_int: expected fragment content, got %+v", cfg.Analyzers[0].SetInt)
+	}
+	if len(cfg.Exports) != 1 || cfg.Exports[0].Type != "table-csv" {
+		t.Fatalf("exports: expected child list to replace base list, got %+v", cfg.Exports)
+	}
+	if cfg.Cleanup.CloseCapture == nil || *cfg.Cleanup.CloseCapture {
+		t.Fatalf("cleanup.close_capture: expected inherited false, got %v", cfg.Cleanup.CloseCapture)
+	}
+}
+
+func TestLoad_RejectsUnknownFieldsAfterMerge(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yaml"), `
+version: 1
+capture:
+  load:
+    filepath: /tmp/base.sal
+`)
+	writeFile(t, filepath.Join(dir, "frag.yaml"), `
+name: SPI
+lable: typo
+`)
+	writeFile(t, filepath.Join(dir, "child.yaml"), `
+extends: base.yaml
+analyzers:
+  - include: frag.yaml
+`)
+
+	_, err := Load(filepath.Join(dir, "child.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "lable") {
+		t.Fatalf("expected unknown field error mentioning %q, got %v", "lable", err)
+	}
+}
+
+func TestLoad_ExtendsCycleAndVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), "extends: b.yaml\n")
+	writeFile(t, filepath.Join(dir, "b.yaml"), "extends: a.yaml\n")
+
+	_, err := Load(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "v2.yaml"), "version: 2\n")
+	writeFile(t, filepath.Join(dir, "c.yaml"), "extends: v2.yaml\n")
+	_, err = Load(filepath.Join(dir, "c.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "version") {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func TestLoadIn_StaysInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "configs")
+	writeFile(t, filepath.Join(dir, "outside.yaml"), "capture: {load: {filepath: /tmp/x.sal}}\n")
+	writeFile(t, filepath.Join(root, "base.yaml"), "capture: {load: {filepath: /tmp/base.sal}}\n")
+	writeFile(t, filepath.Join(root, "child.yaml"), "extends: base.yaml\n")
+	writeFile(t, filepath.Join(root, "escape.yaml"), "extends: ../outside.yaml\n")
+	if err := os.Symlink(filepath.Join(dir, "outside.yaml"), filepath.Join(root, "link.yaml")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	cfg, err := LoadIn(root, "child.yaml")
+	if err != nil || cfg.Capture.Load.Filepath != "/tmp/base.sal" {
+		t.Fatalf("expected child.yaml to load, got %+v, %v", cfg, err)
+	}
+	for _, path := range []string{"escape.yaml", "../outside.yaml", filepath.Join(dir, "outside.yaml"), "link.yaml"} {
+		if _, err := LoadIn(root, path); err == nil {
+			t.Fatalf("expected %s to be refused", path)
+		}
+	}
+}
+
+func TestSchemaEnumsParse(t *testing.T) {
+	for _, name := range RadixNames {
+		if _, err := ParseRadixType(name); err != nil {
+			t.Fatalf("radix %q: %v", name, err)
+		}
+	}
+	s, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema: %v", err)
+	}
+	if got := s.Defs["ExportConfig"].Properties["type"].Enum; len(got) != len(ExportTypes) {
+		t.Fatalf("exports[].type enum: expected %v, got %v", ExportTypes, got)
+	}
+}
+
+func TestUniformRadix(t *testing.T) {
+	if got := UniformRadix([]string{"HEX", " hex"}); got != "hex" {
+		t.Fatalf("expected hex, got %q", got)
+	}
+	if got := UniformRadix([]string{"hex", "dec"}); got != "" {
+		t.Fatalf("expected no radix for mixed radixes, got %q", got)
+	}
+}
@@ -2,6 +2,8 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 	"strings"
 
 	pb "github.com/go-go-golems/salad/gen/saleae/automation"
@@ -12,42 +14,77 @@ import (
 
 type Runner struct {
 	SaleaeConfig saleae.Config
+
+	// CaptureID, if set, runs the pipeline against this already open capture (e.g. a
+	// live capture in the Logic 2 UI) instead of capture.load. The capture is left open.
+	CaptureID uint64
+
+	// Client, if set, is used instead of dialing SaleaeConfig and is left open.
+	Client *saleae.Client
+
+	// Logf, if set, receives a line per pipeline step (e.g. for job logs).
+	Logf func(format string, args ...any)
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
 }
 
 type Result struct {
 	CaptureID uint64
 	Analyzers map[string]uint64 // label -> analyzer_id
-	Artifacts []string          // file/directory paths written by exports (best-effort tracking)
+	Artifacts []string          // file paths written by exports (best-effort tracking)
+	Tables    []TableArtifact   // table-csv exports, in config order
+}
+
+// TableArtifact is a data table written by a table-csv export.
+type TableArtifact struct {
+	// Export is the index of the export in the pipeline config.
+	Export   int
+	Filepath string
+	// Radix is the radix shared by all analyzers of the export, or "" if they differ.
+	Radix string
 }
 
 func (r *Runner) Run(ctx context.Context, cfg *Config) (*Result, error) {
 	if cfg == nil {
 		return nil, errors.New("pipeline config is nil")
 	}
-	if cfg.Capture.Load == nil || strings.TrimSpace(cfg.Capture.Load.Filepath) == "" {
+	if r.CaptureID == 0 && (cfg.Capture.Load == nil || strings.TrimSpace(cfg.Capture.Load.Filepath) == "") {
 		return nil, errors.New("pipeline.capture.load.filepath is required (StartCapture is not implemented yet)")
 	}
 
-	c, err := saleae.New(ctx, r.SaleaeConfig)
-	if err != nil {
-		return nil, err
+	c := r.Client
+	var err error
+	if c == nil {
+		c, err = saleae.New(ctx, r.SaleaeConfig)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = c.Close() }()
 	}
-	defer func() { _ = c.Close() }()
 
 	res := &Result{
 		Analyzers: make(map[string]uint64),
 	}
 
-	captureID, err := c.LoadCapture(ctx, cfg.Capture.Load.Filepath)
-	if err != nil {
-		return nil, err
+	res.CaptureID = r.CaptureID
+	if res.CaptureID == 0 {
+		captureID, err := c.LoadCapture(ctx, cfg.Capture.Load.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		res.CaptureID = captureID
+		r.logf("loaded %s as capture %d", cfg.Capture.Load.Filepath, captureID)
 	}
-	res.CaptureID = captureID
 
 	// Best-effort cleanup.
 	defer func() {
-		if pickBool(cfg.Cleanup.CloseCapture, true) && res.CaptureID != 0 {
+		if r.CaptureID == 0 && pickBool(cfg.Cleanup.CloseCapture, true) && res.CaptureID != 0 {
 			_ = c.CloseCapture(ctx, res.CaptureID)
+			r.logf("closed capture %d", res.CaptureID)
 		}
 	}()
 
@@ -94,12 +131,13 @@ func (r *Runner) Run(ctx context.Context, cfg *Config) (*Result, error) {
 			return nil, errors.Errorf("duplicate analyzer ref %q (labels must be unique)", ref)
 		}
 		res.Analyzers[ref] = analyzerID
+		r.logf("added analyzer %q (%s) as %d", ref, name, analyzerID)
 	}
 
 	// 2) Exports.
 	for i, e := range cfg.Exports {
 		switch strings.ToLower(strings.TrimSpace(e.Type)) {
-		case "raw-csv":
+		case ExportTypeRawCSV:
 			ch := &pb.LogicChannels{
 				DigitalChannels: e.DigitalChannels,
 				AnalogChannels:  e.AnalogChannels,
@@ -110,9 +148,10 @@ func (r *Runner) Run(ctx context.Context, cfg *Config) (*Result, error) {
 			if err := c.ExportRawDataCsv(ctx, res.CaptureID, e.Directory, ch, e.AnalogDownsampleRatio, e.Iso8601Timestamp); err != nil {
 				return nil, err
 			}
-			res.Artifacts = append(res.Artifacts, e.Directory)
+			res.Artifacts = append(res.Artifacts, rawCSVFiles(e.Directory, ch)...)
+			r.logf("exported raw-csv to %s", e.Directory)
 
-		case "raw-binary":
+		case ExportTypeRawBinary:
 			ch := &pb.LogicChannels{
 				DigitalChannels: e.DigitalChannels,
 				AnalogChannels:  e.AnalogChannels,
@@ -123,9 +162,10 @@ func (r *Runner) Run(ctx context.Context, cfg *Config) (*Result, error) {
 			if err := c.ExportRawDataBinary(ctx, res.CaptureID, e.Directory, ch, e.AnalogDownsampleRatio); err != nil {
 				return nil, err
 			}
-			res.Artifacts = append(res.Artifacts, e.Directory)
+			res.Artifacts = append(res.Artifacts, rawBinaryFiles(e.Directory, ch)...)
+			r.logf("exported raw-binary to %s", e.Directory)
 
-		case "table-csv":
+		case ExportTypeTableCSV:
 			if strings.TrimSpace(e.Filepath) == "" {
 				return nil, errors.Errorf("pipeline.exports[%d] table-csv: filepath is required", i)
 			}
@@ -150,6 +190,8 @@ func (r *Runner) Run(ctx context.Context, cfg *Config) (*Result, error) {
 				return nil, err
 			}
 			res.Artifacts = append(res.Artifacts, e.Filepath)
+			res.Tables = append(res.Tables, TableArtifact{Export: i, Filepath: e.Filepath, Radix: tableRadix(e.Analyzers)})
+			r.logf("exported table-csv to %s", e.Filepath)
 
 		default:
 			return nil, errors.Errorf("pipeline.exports[%d]: unknown type %q (expected raw-csv|raw-binary|table-csv)", i, e.Type)
@@ -170,7 +212,7 @@ func resolveTableAnalyzers(specs []TableAnalyzerRef, created map[string]uint64)
 		if !ok {
 			return nil, errors.Errorf("table analyzers[%d]: unknown ref %q (no such analyzer label)", i, ref)
 		}
-		radix, err := parseRadixType(strings.TrimSpace(s.Radix))
+		radix, err := ParseRadixType(strings.TrimSpace(s.Radix))
 		if err != nil {
 			return nil, err
 		}
@@ -182,7 +224,54 @@ func resolveTableAnalyzers(specs []TableAnalyzerRef, created map[string]uint64)
 	return out, nil
 }
 
-func parseRadixType(s string) (pb.RadixType, error) {
+// rawCSVFiles returns the files a raw CSV export of ch writes to dir.
+func rawCSVFiles(dir string, ch *pb.LogicChannels) []string {
+	var files []string
+	if len(ch.GetDigitalChannels()) > 0 {
+		files = append(files, filepath.Join(dir, "digital.csv"))
+	}
+	if len(ch.GetAnalogChannels()) > 0 {
+		files = append(files, filepath.Join(dir, "analog.csv"))
+	}
+	return files
+}
+
+// rawBinaryFiles returns the files a raw binary export of ch writes to dir.
+func rawBinaryFiles(dir string, ch *pb.LogicChannels) []string {
+	var files []string
+	for _, n := range ch.GetDigitalChannels() {
+		files = append(files, filepath.Join(dir, fmt.Sprintf("digital_%d.bin", n)))
+	}
+	for _, n := range ch.GetAnalogChannels() {
+		files = append(files, filepath.Join(dir, fmt.Sprintf("analog_%d.bin", n)))
+	}
+	return files
+}
+
+// UniformRadix returns the radix shared by all radixes (lower-cased), or "" if they differ.
+func UniformRadix(radixes []string) string {
+	radix := ""
+	for _, r := range radixes {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if radix != "" && r != radix {
+			return ""
+		}
+		radix = r
+	}
+	return radix
+}
+
+// tableRadix returns the radix shared by all analyzers of a table export, or "".
+func tableRadix(refs []TableAnalyzerRef) string {
+	radixes := make([]string, len(refs))
+	for i, ref := range refs {
+		radixes[i] = ref.Radix
+	}
+	return UniformRadix(radixes)
+}
+
+// ParseRadixType parses one of RadixNames.
+func ParseRadixType(s string) (pb.RadixType, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "hex":
 		return pb.RadixType_RADIX_TYPE_HEXADECIMAL, nil
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"reflect"
+
+	"github.com/go-go-golems/salad/internal/schema"
+)
+
+// JSONSchema returns the JSON Schema for pipeline config files, including the
+// composition directives handled by Load (`extends`, `list_merge`, `include`).
+func JSONSchema() (*schema.Schema, error) {
+	s, err := schema.Generate(reflect.TypeOf(Config{}), schema.Options{
+		ID:          "https://github.com/go-go-golems/salad/configs/schema/pipeline.schema.json",
+		Title:       "salad pipeline config",
+		Description: "Pipeline config for `salad run --config` (version 1).",
+		Enums: map[schema.FieldRef][]string{
+			schema.Field[ExportConfig]("Type"):      ExportTypes,
+			schema.Field[TableAnalyzerRef]("Radix"): RadixNames,
+		},
+		Required: []schema.FieldRef{
+			schema.Field[CaptureLoadConfig]("Filepath"),
+			schema.Field[AnalyzerConfig]("Name"),
+			schema.Field[ExportConfig]("Type"),
+			schema.Field[TableAnalyzerRef]("Ref"),
+			schema.Field[TableAnalyzerRef]("Radix"),
+			schema.Field[TableFilterConfig]("Query"),
+		},
+		Descriptions: map[schema.FieldRef]string{
+			schema.Field[Config]("Version"):             "Config format version. Defaults to 1.",
+			schema.Field[AnalyzerConfig]("Name"):        "Logic 2 analyzer UI name, e.g. \"SPI\", \"I2C\", \"Async Serial\".",
+			schema.Field[AnalyzerConfig]("Label"):       "User-facing analyzer label; also the ref used by table-csv exports.",
+			schema.Field[ExportConfig]("Type"):          "Export kind.",
+			schema.Field[TableAnalyzerRef]("Ref"):       "Label (or name) of an analyzer created earlier in the pipeline.",
+			schema.Field[CleanupConfig]("CloseCapture"): "Close the capture at the end of the run. Defaults to true.",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Properties["version"].Enum = []any{1}
+	s.Properties[keyExtends] = &schema.Schema{
+		Description: "Base config file(s) to deep-merge under this file, relative to this file.",
+		AnyOf: []*schema.Schema{
+			{Type: "string"},
+			{Type: "array", Items: &schema.Schema{Type: "string"}},
+		},
+	}
+	s.Properties[keyListMerge] = &schema.Schema{
+		Description: "List merge strategy per list path when extending (default: replace).",
+		Type:        "object",
+		AdditionalProperties: &schema.Schema{
+			Type: "string",
+			Enum: []any{string(ListMergeReplace), string(ListMergeAppend), string(ListMergePrepend)},
+		},
+	}
+
+	s.Defs["Include"] = &schema.Schema{
+		Description:          "Replaced by the items of the referenced fragment file (relative to this file).",
+		Type:                 "object",
+		Properties:           map[string]*schema.Schema{keyInclude: {Type: "string"}},
+		Required:             []string{keyInclude},
+		AdditionalProperties: false,
+	}
+	for _, key := range includableLists {
+		items := s.Properties[key].Items
+		s.Properties[key].Items = &schema.Schema{
+			AnyOf: []*schema.Schema{items, {Ref: "#/$defs/Include"}},
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,533 @@
+package rawdata
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Logic 2 binary export layout (little-endian):
+//
+//	byte[8] identifier "<SALEAE>"
+//	int32   version    (0 or 1)
+//	int32   type       (0 = digital, 1 = analog)
+//
+// Digital, version 0:
+//
+//	uint32 initial_state; float64 begin_time; float64 end_time
+//	uint64 num_transitions; float64 transition_times[num_transitions]
+//
+// Digital, version 1: uint64 chunk_count, then per chunk:
+//
+//	uint32 initial_state; float64 sample_rate; float64 begin_time; float64 end_time
+//	uint64 num_transitions; float64 transition_times[num_transitions]
+//
+// Analog, version 0:
+//
+//	float64 begin_time; uint64 sample_rate; uint64 downsample
+//	uint64 num_samples; float32 samples[num_samples]
+//
+// Analog, version 1: uint64 waveform_count, then per waveform:
+//
+//	float64 begin_time; float64 trigger_time; float64 sample_rate; int64 downsample
+//	uint64 num_samples; float32 samples[num_samples]
+//
+// Sample n of an analog waveform is at begin_time + n*downsample/sample_rate.
+const binaryIdentifier = "<SALEAE>"
+
+// BinaryType is the type field of a binary export header.
+type BinaryType int32
+
+const (
+	BinaryTypeDigital BinaryType = 0
+	BinaryTypeAnalog  BinaryType = 1
+)
+
+var binaryFilenameRe = regexp.MustCompile(`^(digital|analog)_(\d+)\.bin$`)
+
+// BinaryHeader is the common file header.
+type BinaryHeader struct {
+	Version int32
+	Type    BinaryType
+}
+
+// DigitalChunk describes one contiguous recording range in a digital file.
+// Version 0 files have exactly one chunk and no sample rate.
+type DigitalChunk struct {
+	InitialState   uint8
+	SampleRate     float64
+	BeginTime      float64
+	EndTime        float64
+	NumTransitions uint64
+}
+
+// AnalogWaveform describes one contiguous waveform in an analog file.
+// Version 0 files have exactly one waveform and no trigger time.
+type AnalogWaveform struct {
+	BeginTime   float64
+	TriggerTime float64
+	SampleRate  float64
+	Downsample  uint64
+	NumSamples  uint64
+}
+
+// FindBinaryFiles returns the digital_<n>.bin and analog_<n>.bin files in dir, keyed by channel.
+func FindBinaryFiles(dir string) (map[int]string, map[int]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "read export directory %s", dir)
+	}
+	digital := map[int]string{}
+	analog := map[int]string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := binaryFilenameRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		ch, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if m[1] == "digital" {
+			digital[ch] = path
+		} else {
+			analog[ch] = path
+		}
+	}
+	return digital, analog, nil
+}
+
+// SortedChannels returns the keys of a channel map in ascending order.
+func SortedChannels[V any](m map[int]V) []int {
+	out := make([]int, 0, len(m))
+	for ch := range m {
+		out = append(out, ch)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// channelFromFilename returns n for digital_<n>.bin / analog_<n>.bin, or -1.
+func channelFromFilename(path string) int {
+	m := binaryFilenameRe.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return -1
+	}
+	ch, err := strconv.Atoi(m[2])
+	if err != nil {
+		return -1
+	}
+	return ch
+}
+
+func readBinaryHeader(r io.Reader) (BinaryHeader, error) {
+	var ident [8]byte
+	if _, err := io.ReadFull(r, ident[:]); err != nil {
+		return BinaryHeader{}, errors.Wrap(err, "read binary export identifier")
+	}
+	if string(ident[:]) != binaryIdentifier {
+		return BinaryHeader{}, errors.Errorf("not a Logic 2 binary export (identifier %q)", string(ident[:]))
+	}
+	var h BinaryHeader
+	if err := binary.Read(r, binary.LittleEndian, &h.Version); err != nil {
+		return BinaryHeader{}, errors.Wrap(err, "read binary export version")
+	}
+	if err := binary.Read(r, binary.LittleEndian, &h.Type); err != nil {
+		return BinaryHeader{}, errors.Wrap(err, "read binary export type")
+	}
+	if h.Version != 0 && h.Version != 1 {
+		return BinaryHeader{}, errors.Errorf("unsupported binary export version %d", h.Version)
+	}
+	return h, nil
+}
+
+// DigitalBinary streams a digital binary export. Transitions can be iterated once.
+type DigitalBinary struct {
+	header  BinaryHeader
+	channel int
+	r       *bufio.Reader
+	closer  io.Closer
+
+	chunkCount uint64
+	chunkIndex uint64
+	chunk      DigitalChunk
+	firstChunk DigitalChunk
+
+	initialState uint8
+	beginTime    float64
+	endTime      float64
+	consumed     bool
+}
+
+var (
+	_ DigitalSource = &DigitalBinary{}
+	_ SampleRater   = &DigitalBinary{}
+)
+
+// OpenDigitalBinary opens a digital_<n>.bin file. The channel is taken from the
+// filename (-1 if it doesn't follow the Logic 2 naming).
+func OpenDigitalBinary(path string) (*DigitalBinary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open digital binary export %s", path)
+	}
+	d, err := NewDigitalBinary(f, channelFromFilename(path))
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "read digital binary export %s", path)
+	}
+	d.closer = f
+	return d, nil
+}
+
+// NewDigitalBinary reads the header and first chunk header from r.
+func NewDigitalBinary(r io.Reader, channel int) (*DigitalBinary, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	h, err := readBinaryHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if h.Type != BinaryTypeDigital {
+		return nil, errors.Errorf("expected digital binary export, got type %d", h.Type)
+	}
+
+	d := &DigitalBinary{header: h, channel: channel, r: br, chunkCount: 1}
+	if h.Version == 1 {
+		if d.chunkCount, err = readU64(br); err != nil {
+			return nil, errors.Wrap(err, "read chunk count")
+		}
+	}
+	if d.chunkCount == 0 {
+		d.consumed = true
+		return d, nil
+	}
+	if err := d.readChunkHeader(); err != nil {
+		return nil, err
+	}
+	d.firstChunk = d.chunk
+	d.initialState = d.chunk.InitialState
+	d.beginTime = d.chunk.BeginTime
+	d.endTime = d.chunk.EndTime
+	return d, nil
+}
+
+func (d *DigitalBinary) readChunkHeader() error {
+	var c DigitalChunk
+	state, err := readU32(d.r)
+	if err != nil {
+		return errors.Wrapf(err, "read chunk %d initial state", d.chunkIndex)
+	}
+	c.InitialState = uint8(state & 1)
+	if d.header.Version == 1 {
+		if c.SampleRate, err = readF64(d.r); err != nil {
+			return errors.Wrapf(err, "read chunk %d sample rate", d.chunkIndex)
+		}
+	}
+	if c.BeginTime, err = readF64(d.r); err != nil {
+		return errors.Wrapf(err, "read chunk %d begin time", d.chunkIndex)
+	}
+	if c.EndTime, err = readF64(d.r); err != nil {
+		return errors.Wrapf(err, "read chunk %d end time", d.chunkIndex)
+	}
+	if c.NumTransitions, err = readU64(d.r); err != nil {
+		return errors.Wrapf(err, "read chunk %d transition count", d.chunkIndex)
+	}
+	d.chunk = c
+	return nil
+}
+
+func (d *DigitalBinary) Header() BinaryHeader { return d.header }
+func (d *DigitalBinary) Channel() int         { return d.channel }
+func (d *DigitalBinary) Name() string         { return ChannelName(d.channel) }
+func (d *DigitalBinary) InitialState() uint8  { return d.initialState }
+func (d *DigitalBinary) BeginTime() float64   { return d.beginTime }
+
+// EndTime is the end of the first chunk until Transitions has been fully iterated,
+// then the end of the last chunk.
+func (d *DigitalBinary) EndTime() float64 { return d.endTime }
+
+// SampleRate is the sample rate of the first chunk in Hz (version 1 files only, 0
+// otherwise). It doesn't change while Transitions advances through later chunks.
+func (d *DigitalBinary) SampleRate() float64 { return d.firstChunk.SampleRate }
+
+// FirstChunk returns the header of the first chunk, read when the file was opened.
+func (d *DigitalBinary) FirstChunk() DigitalChunk { return d.firstChunk }
+
+func (d *DigitalBinary) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+func (d *DigitalBinary) Transitions() iter.Seq2[Transition, error] {
+	return func(yield func(Transition, error) bool) {
+		if d.consumed {
+			return
+		}
+		d.consumed = true
+
+		state := d.initialState
+		for {
+			for i := uint64(0); i < d.chunk.NumTransitions; i++ {
+				t, err := readF64(d.r)
+				if err != nil {
+					yield(Transition{}, errors.Wrapf(err, "read chunk %d transition %d", d.chunkIndex, i))
+					return
+				}
+				state ^= 1
+				if !yield(Transition{Time: t, State: state}, nil) {
+					return
+				}
+			}
+			d.endTime = d.chunk.EndTime
+
+			d.chunkIndex++
+			if d.chunkIndex >= d.chunkCount {
+				return
+			}
+			if err := d.readChunkHeader(); err != nil {
+				yield(Transition{}, err)
+				return
+			}
+			// A chunk may start at a different level than the previous one ended.
+			if d.chunk.InitialState != state {
+				state = d.chunk.InitialState
+				if !yield(Transition{Time: d.chunk.BeginTime, State: state}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// AnalogBinary streams an analog binary export. Samples can be iterated once.
+type AnalogBinary struct {
+	header  BinaryHeader
+	channel int
+	r       *bufio.Reader
+	closer  io.Closer
+
+	waveformCount uint64
+	waveformIndex uint64
+	waveform      AnalogWaveform
+	firstWaveform AnalogWaveform
+	consumed      bool
+}
+
+var (
+	_ AnalogSource = &AnalogBinary{}
+	_ SampleRater  = &AnalogBinary{}
+)
+
+// OpenAnalogBinary opens an analog_<n>.bin file. The channel is taken from the
+// filename (-1 if it doesn't follow the Logic 2 naming).
+func OpenAnalogBinary(path string) (*AnalogBinary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open analog binary export %s", path)
+	}
+	a, err := NewAnalogBinary(f, channelFromFilename(path))
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "read analog binary export %s", path)
+	}
+	a.closer = f
+	return a, nil
+}
+
+// NewAnalogBinary reads the header and first waveform header from r.
+func NewAnalogBinary(r io.Reader, channel int) (*AnalogBinary, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	h, err := readBinaryHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if h.Type != BinaryTypeAnalog {
+		return nil, errors.Errorf("expected analog binary export, got type %d", h.Type)
+	}
+
+	a := &AnalogBinary{header: h, channel: channel, r: br, waveformCount: 1}
+	if h.Version == 1 {
+		if a.waveformCount, err = readU64(br); err != nil {
+			return nil, errors.Wrap(err, "read waveform count")
+		}
+	}
+	if a.waveformCount == 0 {
+		a.consumed = true
+		return a, nil
+	}
+	if err := a.readWaveformHeader(); err != nil {
+		return nil, err
+	}
+	a.firstWaveform = a.waveform
+	return a, nil
+}
+
+func (a *AnalogBinary) readWaveformHeader() error {
+	var w AnalogWaveform
+	var err error
+	if w.BeginTime, err = readF64(a.r); err != nil {
+		return errors.Wrapf(err, "read waveform %d begin time", a.waveformIndex)
+	}
+	if a.header.Version == 1 {
+		if w.TriggerTime, err = readF64(a.r); err != nil {
+			return errors.Wrapf(err, "read waveform %d trigger time", a.waveformIndex)
+		}
+		if w.SampleRate, err = readF64(a.r); err != nil {
+			return errors.Wrapf(err, "read waveform %d sample rate", a.waveformIndex)
+		}
+		ds, err := readU64(a.r)
+		if err != nil {
+			return errors.Wrapf(err, "read waveform %d downsample", a.waveformIndex)
+		}
+		w.Downsample = ds
+	} else {
+		rate, err := readU64(a.r)
+		if err != nil {
+			return errors.Wrapf(err, "read waveform %d sample rate", a.waveformIndex)
+		}
+		w.SampleRate = float64(rate)
+		if w.Downsample, err = readU64(a.r); err != nil {
+			return errors.Wrapf(err, "read waveform %d downsample", a.waveformIndex)
+		}
+	}
+	if w.NumSamples, err = readU64(a.r); err != nil {
+		return errors.Wrapf(err, "read waveform %d sample count", a.waveformIndex)
+	}
+	if w.SampleRate <= 0 {
+		return errors.Errorf("waveform %d: invalid sample rate %v", a.waveformIndex, w.SampleRate)
+	}
+	if w.Downsample == 0 {
+		w.Downsample = 1
+	}
+	a.waveform = w
+	return nil
+}
+
+func (a *AnalogBinary) Header() BinaryHeader { return a.header }
+func (a *AnalogBinary) Channel() int         { return a.channel }
+func (a *AnalogBinary) Name() string         { return ChannelName(a.channel) }
+
+// SampleRate is the effective (downsampled) sample rate of the first waveform in Hz,
+// or 0 for a file without waveforms. It doesn't change while Samples advances
+// through later waveforms.
+func (a *AnalogBinary) SampleRate() float64 {
+	w := a.firstWaveform
+	if w.SampleRate == 0 || w.Downsample == 0 {
+		return 0
+	}
+	return w.SampleRate / float64(w.Downsample)
+}
+
+// FirstWaveform returns the header of the first waveform, read when the file was opened.
+func (a *AnalogBinary) FirstWaveform() AnalogWaveform { return a.firstWaveform }
+
+func (a *AnalogBinary) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+func (a *AnalogBinary) Samples() iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		if a.consumed {
+			return
+		}
+		a.consumed = true
+
+		for {
+			w := a.waveform
+			period := float64(w.Downsample) / w.SampleRate
+			for i := uint64(0); i < w.NumSamples; i++ {
+				v, err := readF32(a.r)
+				if err != nil {
+					yield(Sample{}, errors.Wrapf(err, "read waveform %d sample %d", a.waveformIndex, i))
+					return
+				}
+				if !yield(Sample{Time: w.BeginTime + float64(i)*period, Value: v}, nil) {
+					return
+				}
+			}
+
+			a.waveformIndex++
+			if a.waveformIndex >= a.waveformCount {
+				return
+			}
+			if err := a.readWaveformHeader(); err != nil {
+				yield(Sample{}, err)
+				return
+			}
+		}
+	}
+}
+
+// WriteDigitalBinary writes a version 0 digital binary export.
+func WriteDigitalBinary(w io.Writer, initialState uint8, beginTime float64, endTime float64, transitions []float64) error {
+	bw := bufio.NewWriter(w)
+	fields := []any{
+		[]byte(binaryIdentifier), int32(0), int32(BinaryTypeDigital),
+		uint32(initialState & 1), beginTime, endTime, uint64(len(transitions)), transitions,
+	}
+	for _, f := range fields {
+		if err := binary.Write(bw, binary.LittleEndian, f); err != nil {
+			return errors.Wrap(err, "write digital binary export")
+		}
+	}
+	return errors.Wrap(bw.Flush(), "write digital binary export")
+}
+
+// WriteAnalogBinary writes a version 0 analog binary export.
+func WriteAnalogBinary(w io.Writer, beginTime float64, sampleRate uint64, downsample uint64, samples []float32) error {
+	bw := bufio.NewWriter(w)
+	fields := []any{
+		[]byte(binaryIdentifier), int32(0), int32(BinaryTypeAnalog),
+		beginTime, sampleRate, downsample, uint64(len(samples)), samples,
+	}
+	for _, f := range fields {
+		if err := binary.Write(bw, binary.LittleEndian, f); err != nil {
+			return errors.Wrap(err, "write analog binary export")
+		}
+	}
+	return errors.Wrap(bw.Flush(), "write analog binary export")
+}
+
+func readU32(r *bufio.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func readU64(r *bufio.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+func readF64(r *bufio.Reader) (float64, error) {
+	v, err := readU64(r)
+	return math.Float64frombits(v), err
+}
+
+func readF32(r *bufio.Reader) (float32, error) {
+	v, err := readU32(r)
+	return math.Float32frombits(v), err
+}
@@ -0,0 +1,187 @@
+package rawdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDigitalBinary_V0RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "digital_3.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := WriteDigitalBinary(f, 1, 0, 2.5, []float64{0.5, 1.0, 1.5}); err != nil {
+		t.Fatalf("WriteDigitalBinary: %v", err)
+	}
+	_ = f.Close()
+
+	d, err := OpenDigitalBinary(path)
+	if err != nil {
+		t.Fatalf("OpenDigitalBinary: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+
+	if d.Channel() != 3 || d.InitialState() != 1 || d.EndTime() != 2.5 {
+		t.Fatalf("header: expected channel=3 initial=1 end=2.5, got channel=%d initial=%d end=%v", d.Channel(), d.InitialState(), d.EndTime())
+	}
+	trs, err := CollectTransitions(d)
+	if err != nil {
+		t.Fatalf("CollectTransitions: %v", err)
+	}
+	want := []Transition{{0.5, 0}, {1.0, 1}, {1.5, 0}}
+	if len(trs) != len(want) {
+		t.Fatalf("transitions: expected %v, got %v", want, trs)
+	}
+	for i := range want {
+		if trs[i] != want[i] {
+			t.Fatalf("transition %d: expected %v, got %v", i, want[i], trs[i])
+		}
+	}
+}
+
+func TestDigitalBinary_V1ChunksInsertLevelChange(t *testing.T) {
+	var buf bytes.Buffer
+	w := func(v any) {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	w([]byte(binaryIdentifier))
+	w(int32(1))
+	w(int32(BinaryTypeDigital))
+	w(uint64(2))
+	// chunk 0: starts low, one rising edge, ends high
+	w(uint32(0))
+	w(float64(1e6))
+	w(float64(0))
+	w(float64(1))
+	w(uint64(1))
+	w(float64(0.25))
+	// chunk 1: starts low again, no edges, different rate
+	w(uint32(0))
+	w(float64(5e5))
+	w(float64(2))
+	w(float64(3))
+	w(uint64(0))
+
+	d, err := NewDigitalBinary(&buf, 0)
+	if err != nil {
+		t.Fatalf("NewDigitalBinary: %v", err)
+	}
+	if d.FirstChunk().SampleRate != 1e6 {
+		t.Fatalf("sample rate: expected 1e6, got %v", d.FirstChunk().SampleRate)
+	}
+	trs, err := CollectTransitions(d)
+	if err != nil {
+		t.Fatalf("CollectTransitions: %v", err)
+	}
+	want := []Transition{{0.25, 1}, {2, 0}}
+	if len(trs) != 2 || trs[0] != want[0] || trs[1] != want[1] {
+		t.Fatalf("transitions: expected %v, got %v", want, trs)
+	}
+	if d.EndTime() != 3 {
+		t.Fatalf("end time after iteration: expected 3, got %v", d.EndTime())
+	}
+	if d.SampleRate() != 1e6 || d.FirstChunk().BeginTime != 0 {
+		t.Fatalf("after iteration: expected the first chunk (1e6 Hz at 0), got %+v", d.FirstChunk())
+	}
+}
+
+func TestAnalogBinary_V0RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteAnalogBinary(&buf, 1.0, 1000, 10, []float32{0.1, 0.2, 3.3}); err != nil {
+		t.Fatalf("WriteAnalogBinary: %v", err)
+	}
+	a, err := NewAnalogBinary(&buf, 2)
+	if err != nil {
+		t.Fatalf("NewAnalogBinary: %v", err)
+	}
+	samples, err := CollectSamples(a)
+	if err != nil {
+		t.Fatalf("CollectSamples: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("samples: expected 3, got %d", len(samples))
+	}
+	if samples[2].Time != 1.02 || samples[2].Value != 3.3 {
+		t.Fatalf("sample 2: expected {1.02 3.3}, got %v", samples[2])
+	}
+}
+
+func TestAnalogBinary_V1WaveformsKeepFirstRate(t *testing.T) {
+	var buf bytes.Buffer
+	w := func(v any) {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	w([]byte(binaryIdentifier))
+	w(int32(1))
+	w(int32(BinaryTypeAnalog))
+	w(uint64(2))
+	// waveform 0: 1 kHz downsampled by 10, two samples
+	w(float64(0))
+	w(float64(0))
+	w(float64(1000))
+	w(uint64(10))
+	w(uint64(2))
+	w([]float32{0.5, 1.5})
+	// waveform 1: 500 Hz, no downsampling, one sample
+	w(float64(1))
+	w(float64(0))
+	w(float64(500))
+	w(uint64(1))
+	w(uint64(1))
+	w(float32(2.5))
+
+	a, err := NewAnalogBinary(&buf, 0)
+	if err != nil {
+		t.Fatalf("NewAnalogBinary: %v", err)
+	}
+	if a.SampleRate() != 100 {
+		t.Fatalf("sample rate: expected 100, got %v", a.SampleRate())
+	}
+	samples, err := CollectSamples(a)
+	if err != nil {
+		t.Fatalf("CollectSamples: %v", err)
+	}
+	if len(samples) != 3 || samples[1].Time != 0.01 || samples[2] != (Sample{Time: 1, Value: 2.5}) {
+		t.Fatalf("samples: unexpected %v", samples)
+	}
+	if a.SampleRate() != 100 || a.FirstWaveform().BeginTime != 0 {
+		t.Fatalf("after iteration: expected the first waveform (100 Hz at 0), got %+v", a.FirstWaveform())
+	}
+}
+
+func TestBinary_RejectsBadInput(t *testing.T) {
+	if _, err := NewDigitalBinary(strings.NewReader("NOTSALEAE......."), 0); err == nil || !strings.Contains(err.Error(), "identifier") {
+		t.Fatalf("expected identifier error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAnalogBinary(&buf, 0, 1000, 1, nil); err != nil {
+		t.Fatalf("WriteAnalogBinary: %v", err)
+	}
+	if _, err := NewDigitalBinary(&buf, 0); err == nil || !strings.Contains(err.Error(), "expected digital") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+
+	buf.Reset()
+	if err := WriteDigitalBinary(&buf, 0, 0, 1, []float64{0.1, 0.2}); err != nil {
+		t.Fatalf("WriteDigitalBinary: %v", err)
+	}
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-4])
+	d, err := NewDigitalBinary(truncated, 0)
+	if err != nil {
+		t.Fatalf("NewDigitalBinary: %v", err)
+	}
+	if _, err := CollectTransitions(d); err == nil {
+		t.Fatalf("expected truncation error")
+	}
+}
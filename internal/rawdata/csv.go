@@ -0,0 +1,420 @@
+package rawdata
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"iter"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Logic 2 raw CSV exports have a time column followed by one column per channel:
+//
+//	Time [s],Channel 0,Channel 1
+//	0.000000000,1,0
+//	0.000125000,0,0
+//
+// digital.csv has one row for the initial state and one per change on any exported
+// channel; analog.csv has one row per (downsampled) sample. With iso8601_timestamp the
+// time column holds absolute RFC 3339 timestamps instead of seconds; the readers below
+// convert those to seconds relative to the first row and expose the absolute start via
+// StartTime. Channel columns carry the channel name shown in Logic 2 ("Channel 3" by
+// default, or the user's rename).
+
+var channelHeaderRe = regexp.MustCompile(`^Channel (\d+)$`)
+
+// CSVColumn is one channel column of a raw CSV export.
+type CSVColumn struct {
+	// Header is the column header as written by Logic 2.
+	Header string
+	// Channel is parsed from a default "Channel <n>" header, or -1 for renamed channels.
+	Channel int
+	// index is the field index in each record.
+	index int
+}
+
+// csvTable is the shared header/row handling for digital.csv and analog.csv. Every
+// scan re-opens the file, so independent per-channel iterators can stream
+// the same export concurrently with bounded memory.
+type csvTable struct {
+	path      string
+	columns   []CSVColumn
+	iso       bool
+	startTime time.Time
+}
+
+func openCSVTable(path string, selected []string) (*csvTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open raw csv export %s", path)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := newCSVReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "read raw csv header %s", path)
+	}
+	if len(header) < 2 {
+		return nil, errors.Errorf("raw csv export %s: expected a time column and at least one channel column, got %v", path, header)
+	}
+
+	all := make([]CSVColumn, 0, len(header)-1)
+	for i, h := range header[1:] {
+		h = strings.TrimSpace(h)
+		ch := -1
+		if m := channelHeaderRe.FindStringSubmatch(h); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				ch = n
+			}
+		}
+		all = append(all, CSVColumn{Header: h, Channel: ch, index: i + 1})
+	}
+
+	t := &csvTable{path: path, columns: all}
+	if len(selected) > 0 {
+		t.columns = make([]CSVColumn, 0, len(selected))
+		for _, name := range selected {
+			col, ok := findColumn(all, name)
+			if !ok {
+				return nil, errors.Errorf("raw csv export %s: no column %q (have %s)", path, name, strings.Join(header[1:], ", "))
+			}
+			t.columns = append(t.columns, col)
+		}
+	}
+
+	first, err := r.Read()
+	if err == io.EOF {
+		return t, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read raw csv export %s", path)
+	}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(first[0]), 64); err != nil {
+		ts, perr := time.Parse(time.RFC3339Nano, strings.TrimSpace(first[0]))
+		if perr != nil {
+			return nil, errors.Errorf("raw csv export %s: unrecognized timestamp %q", path, first[0])
+		}
+		t.iso = true
+		t.startTime = ts
+	}
+	return t, nil
+}
+
+func findColumn(columns []CSVColumn, name string) (CSVColumn, bool) {
+	name = strings.TrimSpace(name)
+	for _, c := range columns {
+		if c.Header == name {
+			return c, true
+		}
+	}
+	for _, c := range columns {
+		if strings.EqualFold(c.Header, name) {
+			return c, true
+		}
+	}
+	return CSVColumn{}, false
+}
+
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(bufio.NewReaderSize(r, 64*1024))
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+	return cr
+}
+
+func (t *csvTable) parseTime(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if !t.iso {
+		v, err := strconv.ParseFloat(s, 64)
+		return v, errors.Wrapf(err, "parse time %q", s)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse ISO8601 time %q", s)
+	}
+	return ts.Sub(t.startTime).Seconds(), nil
+}
+
+// scan streams data rows, calling fn with the row time and record (reused between
+// calls). It stops early when fn returns false.
+func (t *csvTable) scan(fn func(tm float64, rec []string) (bool, error)) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return errors.Wrapf(err, "open raw csv export %s", t.path)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := newCSVReader(f)
+	if _, err := r.Read(); err != nil {
+		return errors.Wrapf(err, "read raw csv header %s", t.path)
+	}
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "%s:%d", t.path, line)
+		}
+		if len(rec) == 0 || (len(rec) == 1 && strings.TrimSpace(rec[0]) == "") {
+			continue
+		}
+		tm, err := t.parseTime(rec[0])
+		if err != nil {
+			return errors.Wrapf(err, "%s:%d", t.path, line)
+		}
+		for _, c := range t.columns {
+			if c.index >= len(rec) {
+				return errors.Errorf("%s:%d: missing column %q", t.path, line, c.Header)
+			}
+		}
+		more, err := fn(tm, rec)
+		if err != nil {
+			return errors.Wrapf(err, "%s:%d", t.path, line)
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// DigitalRow is one row of digital.csv restricted to the selected columns.
+type DigitalRow struct {
+	Time float64
+	// States holds one level per selected column, in Columns order. The slice is
+	// reused between rows.
+	States []uint8
+}
+
+// DigitalCSV reads a Logic 2 digital.csv export.
+type DigitalCSV struct {
+	table *csvTable
+}
+
+// OpenDigitalCSV reads the header of a digital.csv export. columns selects channels by
+// column header (exact match first, then case-insensitive); no columns selects all.
+func OpenDigitalCSV(path string, columns ...string) (*DigitalCSV, error) {
+	t, err := openCSVTable(path, columns)
+	if err != nil {
+		return nil, err
+	}
+	return &DigitalCSV{table: t}, nil
+}
+
+// Columns returns the selected channel columns.
+func (d *DigitalCSV) Columns() []CSVColumn { return d.table.columns }
+
+// ISO8601 reports whether the export uses absolute timestamps.
+func (d *DigitalCSV) ISO8601() bool { return d.table.iso }
+
+// StartTime is the absolute time of the first row for ISO8601 exports (zero otherwise).
+func (d *DigitalCSV) StartTime() time.Time { return d.table.startTime }
+
+// Rows streams all rows for the selected columns. Each call re-reads the file.
+func (d *DigitalCSV) Rows() iter.Seq2[DigitalRow, error] {
+	return func(yield func(DigitalRow, error) bool) {
+		row := DigitalRow{States: make([]uint8, len(d.table.columns))}
+		err := d.table.scan(func(tm float64, rec []string) (bool, error) {
+			row.Time = tm
+			for i, c := range d.table.columns {
+				v, err := parseLevel(rec[c.index])
+				if err != nil {
+					return false, errors.Wrapf(err, "column %q", c.Header)
+				}
+				row.States[i] = v
+			}
+			return yield(row, nil), nil
+		})
+		if err != nil {
+			yield(DigitalRow{}, err)
+		}
+	}
+}
+
+// Sources returns one DigitalSource per selected column. Each source streams the file
+// independently.
+func (d *DigitalCSV) Sources() ([]DigitalSource, error) {
+	out := make([]DigitalSource, 0, len(d.table.columns))
+	for _, c := range d.table.columns {
+		src := &digitalCSVSource{table: d.table, column: c}
+		if err := src.readFirstRow(); err != nil {
+			return nil, err
+		}
+		out = append(out, src)
+	}
+	return out, nil
+}
+
+func parseLevel(s string) (uint8, error) {
+	switch strings.TrimSpace(s) {
+	case "0":
+		return 0, nil
+	case "1":
+		return 1, nil
+	default:
+		return 0, errors.Errorf("invalid digital level %q", s)
+	}
+}
+
+type digitalCSVSource struct {
+	table  *csvTable
+	column CSVColumn
+
+	initialState uint8
+	beginTime    float64
+	endTime      float64
+}
+
+var _ DigitalSource = &digitalCSVSource{}
+
+func (s *digitalCSVSource) readFirstRow() error {
+	return s.table.scan(func(tm float64, rec []string) (bool, error) {
+		v, err := parseLevel(rec[s.column.index])
+		if err != nil {
+			return false, errors.Wrapf(err, "column %q", s.column.Header)
+		}
+		s.initialState = v
+		s.beginTime = tm
+		s.endTime = tm
+		return false, nil
+	})
+}
+
+func (s *digitalCSVSource) Channel() int        { return s.column.Channel }
+func (s *digitalCSVSource) Name() string        { return s.column.Header }
+func (s *digitalCSVSource) InitialState() uint8 { return s.initialState }
+func (s *digitalCSVSource) BeginTime() float64  { return s.beginTime }
+
+// EndTime is the time of the last row once Transitions has been exhausted; digital.csv
+// does not record the capture end.
+func (s *digitalCSVSource) EndTime() float64 { return s.endTime }
+
+func (s *digitalCSVSource) Transitions() iter.Seq2[Transition, error] {
+	return func(yield func(Transition, error) bool) {
+		first := true
+		state := s.initialState
+		err := s.table.scan(func(tm float64, rec []string) (bool, error) {
+			v, err := parseLevel(rec[s.column.index])
+			if err != nil {
+				return false, errors.Wrapf(err, "column %q", s.column.Header)
+			}
+			s.endTime = tm
+			if first {
+				first = false
+				state = v
+				return true, nil
+			}
+			if v == state {
+				return true, nil
+			}
+			state = v
+			return yield(Transition{Time: tm, State: v}, nil), nil
+		})
+		if err != nil {
+			yield(Transition{}, err)
+		}
+	}
+}
+
+// AnalogRow is one row of analog.csv restricted to the selected columns.
+type AnalogRow struct {
+	Time float64
+	// Values holds one sample per selected column, in Columns order. The slice is
+	// reused between rows.
+	Values []float32
+}
+
+// AnalogCSV reads a Logic 2 analog.csv export.
+type AnalogCSV struct {
+	table *csvTable
+}
+
+// OpenAnalogCSV reads the header of an analog.csv export. columns selects channels by
+// column header; no columns selects all.
+func OpenAnalogCSV(path string, columns ...string) (*AnalogCSV, error) {
+	t, err := openCSVTable(path, columns)
+	if err != nil {
+		return nil, err
+	}
+	return &AnalogCSV{table: t}, nil
+}
+
+// Columns returns the selected channel columns.
+func (a *AnalogCSV) Columns() []CSVColumn { return a.table.columns }
+
+// ISO8601 reports whether the export uses absolute timestamps.
+func (a *AnalogCSV) ISO8601() bool { return a.table.iso }
+
+// StartTime is the absolute time of the first row for ISO8601 exports (zero otherwise).
+func (a *AnalogCSV) StartTime() time.Time { return a.table.startTime }
+
+// Rows streams all rows for the selected columns. Each call re-reads the file.
+func (a *AnalogCSV) Rows() iter.Seq2[AnalogRow, error] {
+	return func(yield func(AnalogRow, error) bool) {
+		row := AnalogRow{Values: make([]float32, len(a.table.columns))}
+		err := a.table.scan(func(tm float64, rec []string) (bool, error) {
+			row.Time = tm
+			for i, c := range a.table.columns {
+				v, err := parseVoltage(rec[c.index])
+				if err != nil {
+					return false, errors.Wrapf(err, "column %q", c.Header)
+				}
+				row.Values[i] = v
+			}
+			return yield(row, nil), nil
+		})
+		if err != nil {
+			yield(AnalogRow{}, err)
+		}
+	}
+}
+
+// Sources returns one AnalogSource per selected column. Each source streams the file
+// independently.
+func (a *AnalogCSV) Sources() []AnalogSource {
+	out := make([]AnalogSource, 0, len(a.table.columns))
+	for _, c := range a.table.columns {
+		out = append(out, &analogCSVSource{table: a.table, column: c})
+	}
+	return out
+}
+
+func parseVoltage(s string) (float32, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid analog value %q", s)
+	}
+	return float32(v), nil
+}
+
+type analogCSVSource struct {
+	table  *csvTable
+	column CSVColumn
+}
+
+var _ AnalogSource = &analogCSVSource{}
+
+func (s *analogCSVSource) Channel() int { return s.column.Channel }
+func (s *analogCSVSource) Name() string { return s.column.Header }
+
+func (s *analogCSVSource) Samples() iter.Seq2[Sample, error] {
+	return func(yield func(Sample, error) bool) {
+		err := s.table.scan(func(tm float64, rec []string) (bool, error) {
+			v, err := parseVoltage(rec[s.column.index])
+			if err != nil {
+				return false, errors.Wrapf(err, "column %q", s.column.Header)
+			}
+			return yield(Sample{Time: tm, Value: v}, nil), nil
+		})
+		if err != nil {
+			yield(Sample{}, err)
+		}
+	}
+}
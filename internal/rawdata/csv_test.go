@@ -0,0 +1,223 @@
+package rawdata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestDigitalCSV_SelectColumnAndTransitions(t *testing.T) {
+	path := writeCSV(t, `Time [s],Channel 0,CLK,Channel 2
+0.000000000,1,0,0
+0.000100000,1,1,0
+0.000200000,0,1,0
+0.000300000,0,0,1
+`)
+	d, err := OpenDigitalCSV(path, "clk", "Channel 0")
+	if err != nil {
+		t.Fatalf("OpenDigitalCSV: %v", err)
+	}
+	cols := d.Columns()
+	if len(cols) != 2 || cols[0].Header != "CLK" || cols[0].Channel != -1 || cols[1].Channel != 0 {
+		t.Fatalf("columns: expected [CLK(-1) Channel 0(0)], got %+v", cols)
+	}
+
+	srcs, err := d.Sources()
+	if err != nil {
+		t.Fatalf("Sources: %v", err)
+	}
+	clk := srcs[0]
+	if clk.InitialState() != 0 {
+		t.Fatalf("CLK initial state: expected 0, got %d", clk.InitialState())
+	}
+	trs, err := CollectTransitions(clk)
+	if err != nil {
+		t.Fatalf("CollectTransitions: %v", err)
+	}
+	want := []Transition{{0.0001, 1}, {0.0003, 0}}
+	if len(trs) != 2 || trs[0] != want[0] || trs[1] != want[1] {
+		t.Fatalf("CLK transitions: expected %v, got %v", want, trs)
+	}
+	if clk.EndTime() != 0.0003 {
+		t.Fatalf("CLK end time: expected 0.0003, got %v", clk.EndTime())
+	}
+
+	rows := 0
+	for row, err := range d.Rows() {
+		if err != nil {
+			t.Fatalf("Rows: %v", err)
+		}
+		if len(row.States) != 2 {
+			t.Fatalf("row states: expected 2, got %v", row.States)
+		}
+		rows++
+	}
+	if rows != 4 {
+		t.Fatalf("rows: expected 4, got %d", rows)
+	}
+
+	if _, err := OpenDigitalCSV(path, "MOSI"); err == nil || !strings.Contains(err.Error(), "MOSI") {
+		t.Fatalf("expected missing column error, got %v", err)
+	}
+}
+
+func TestAnalogCSV_ISO8601Timestamps(t *testing.T) {
+	path := writeCSV(t, `Time [s],Channel 0
+2025-01-02T03:04:05.000000000+00:00,0.5
+2025-01-02T03:04:05.000250000+00:00,1.25
+2025-01-02T03:04:06.000000000+00:00,3.3
+`)
+	a, err := OpenAnalogCSV(path)
+	if err != nil {
+		t.Fatalf("OpenAnalogCSV: %v", err)
+	}
+	if !a.ISO8601() || a.StartTime().Second() != 5 {
+		t.Fatalf("expected ISO8601 export starting at :05, got iso=%v start=%v", a.ISO8601(), a.StartTime())
+	}
+	samples, err := CollectSamples(a.Sources()[0])
+	if err != nil {
+		t.Fatalf("CollectSamples: %v", err)
+	}
+	want := []Sample{{0, 0.5}, {0.00025, 1.25}, {1, 3.3}}
+	if len(samples) != len(want) {
+		t.Fatalf("samples: expected %v, got %v", want, samples)
+	}
+	for i := range want {
+		if samples[i] != want[i] {
+			t.Fatalf("sample %d: expected %v, got %v", i, want[i], samples[i])
+		}
+	}
+}
+
+func TestDigitalCSV_ReportsBadRows(t *testing.T) {
+	path := writeCSV(t, "Time [s],Channel 0\n0.0,1\n0.1,x\n")
+	d, err := OpenDigitalCSV(path)
+	if err != nil {
+		t.Fatalf("OpenDigitalCSV: %v", err)
+	}
+	srcs, err := d.Sources()
+	if err != nil {
+		t.Fatalf("Sources: %v", err)
+	}
+	if _, err := CollectTransitions(srcs[0]); err == nil || !strings.Contains(err.Error(), ":3") {
+		t.Fatalf("expected error pointing at line 3, got %v", err)
+	}
+}
+
+func TestOpenExport_PrefersBinaryAndFallsBackToCSV(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, DigitalCSVFilename), []byte("Time [s],Channel 0,Channel 1\n0.0,0,1\n0.5,1,1\n"), 0o644); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	e, err := OpenExport(dir, "Channel 1")
+	if err != nil {
+		t.Fatalf("OpenExport (csv): %v", err)
+	}
+	if len(e.Digital) != 1 || e.Digital[0].Channel() != 1 || e.Digital[0].InitialState() != 1 {
+		t.Fatalf("csv export: expected Channel 1 high, got %+v", e.Digital)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "digital_1.bin"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := WriteDigitalBinary(f, 0, 0, 1, []float64{0.25}); err != nil {
+		t.Fatalf("WriteDigitalBinary: %v", err)
+	}
+	_ = f.Close()
+
+	e, err = OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport (binary): %v", err)
+	}
+	defer func() { _ = e.Close() }()
+	if _, ok := e.Digital[0].(*DigitalBinary); !ok || len(e.Digital) != 1 {
+		t.Fatalf("binary export: expected one binary source, got %+v", e.Digital)
+	}
+
+	// Channel 1 has a digital and an analog file, but no binary file answers to
+	// Channel 0: the CSV has both columns.
+	f, err = os.Create(filepath.Join(dir, "analog_1.bin"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := WriteAnalogBinary(f, 0, 10, 1, []float32{0, 1}); err != nil {
+		t.Fatalf("WriteAnalogBinary: %v", err)
+	}
+	_ = f.Close()
+	e, err = OpenExport(dir, "Channel 1", "Channel 0")
+	if err != nil {
+		t.Fatalf("OpenExport (fallback): %v", err)
+	}
+	if len(e.Digital) != 2 || len(e.Analog) != 0 || e.Digital[1].Channel() != 0 {
+		t.Fatalf("expected the two csv columns, got %+v %+v", e.Digital, e.Analog)
+	}
+	if _, ok := e.Digital[0].(*DigitalBinary); ok {
+		t.Fatalf("expected csv sources, got %+v", e.Digital)
+	}
+
+	if err := os.Remove(filepath.Join(dir, DigitalCSVFilename)); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := OpenExport(dir, "Channel 1", "Channel 0"); err == nil || !strings.Contains(err.Error(), `"Channel 0"`) {
+		t.Fatalf("expected an error naming Channel 0, got %v", err)
+	}
+}
+
+func TestOpenExport_BinaryMatchesCSVSelection(t *testing.T) {
+	csvDir := t.TempDir()
+	for name, content := range map[string]string{
+		DigitalCSVFilename: "Time [s],Channel 0,Channel 2\n0.0,0,1\n",
+		AnalogCSVFilename:  "Time [s],Channel 1,Channel 2\n0.0,0.5,1.5\n",
+	} {
+		if err := os.WriteFile(filepath.Join(csvDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	binDir := t.TempDir()
+	for _, name := range []string{"digital_0.bin", "digital_2.bin", "analog_1.bin", "analog_2.bin"} {
+		f, err := os.Create(filepath.Join(binDir, name))
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if strings.HasPrefix(name, "digital") {
+			err = WriteDigitalBinary(f, 0, 0, 1, nil)
+		} else {
+			err = WriteAnalogBinary(f, 0, 10, 1, []float32{0})
+		}
+		if err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		_ = f.Close()
+	}
+
+	// Requested order is kept, and Channel 2 is the digital channel in both formats.
+	columns := []string{"Channel 2", "Channel 1", "Channel 0"}
+	for _, dir := range []string{csvDir, binDir} {
+		e, err := OpenExport(dir, columns...)
+		if err != nil {
+			t.Fatalf("OpenExport(%s): %v", dir, err)
+		}
+		var digital, analog []int
+		for _, d := range e.Digital {
+			digital = append(digital, d.Channel())
+		}
+		for _, a := range e.Analog {
+			analog = append(analog, a.Channel())
+		}
+		_ = e.Close()
+		if len(digital) != 2 || digital[0] != 2 || digital[1] != 0 || len(analog) != 1 || analog[0] != 1 {
+			t.Fatalf("%s: expected digital [2 0] and analog [1], got %v %v", dir, digital, analog)
+		}
+	}
+}
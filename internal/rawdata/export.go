@@ -0,0 +1,195 @@
+package rawdata
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DigitalCSVFilename = "digital.csv"
+	AnalogCSVFilename  = "analog.csv"
+)
+
+// Export is the set of channel sources found in a raw export directory, whichever
+// format (binary or CSV) it was written in.
+type Export struct {
+	Digital []DigitalSource
+	Analog  []AnalogSource
+
+	closers []io.Closer
+}
+
+// OpenExport opens the raw export in dir. Binary files (digital_<n>.bin, analog_<n>.bin)
+// are preferred; otherwise digital.csv / analog.csv are used. columns selects channels
+// by CSV column header; binary channels answer to their default "Channel <n>" name.
+// A column that no binary file answers to (e.g. a renamed CSV header) selects the CSV
+// files when the directory has them. Either way, sources follow the order of columns
+// and a column naming both a digital and an analog channel selects the digital one.
+// Binary sources can be iterated once; CSV sources re-read the file on each iteration.
+func OpenExport(dir string, columns ...string) (*Export, error) {
+	digital, analog, err := FindBinaryFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	digitalPath := filepath.Join(dir, DigitalCSVFilename)
+	analogPath := filepath.Join(dir, AnalogCSVFilename)
+	haveDigital := fileExists(digitalPath)
+	haveAnalog := fileExists(analogPath)
+	if len(digital) > 0 || len(analog) > 0 {
+		missing := missingBinaryColumn(digital, analog, columns)
+		if missing == "" {
+			return openBinaryExport(digital, analog, columns)
+		}
+		if !haveDigital && !haveAnalog {
+			return nil, errors.Errorf("no binary file for column %q in %s", missing, dir)
+		}
+	}
+
+	e := &Export{}
+	if !haveDigital && !haveAnalog {
+		return nil, errors.Errorf("no raw export found in %s (expected *.bin or %s/%s)", dir, DigitalCSVFilename, AnalogCSVFilename)
+	}
+
+	// A column may live in either file; split the selection by what each header has.
+	digitalCols, analogCols, err := splitCSVColumns(digitalPath, haveDigital, analogPath, haveAnalog, columns)
+	if err != nil {
+		return nil, err
+	}
+	if haveDigital && (len(columns) == 0 || len(digitalCols) > 0) {
+		d, err := OpenDigitalCSV(digitalPath, digitalCols...)
+		if err != nil {
+			return nil, err
+		}
+		if e.Digital, err = d.Sources(); err != nil {
+			return nil, err
+		}
+	}
+	if haveAnalog && (len(columns) == 0 || len(analogCols) > 0) {
+		a, err := OpenAnalogCSV(analogPath, analogCols...)
+		if err != nil {
+			return nil, err
+		}
+		e.Analog = a.Sources()
+	}
+	return e, nil
+}
+
+func splitCSVColumns(digitalPath string, haveDigital bool, analogPath string, haveAnalog bool, columns []string) ([]string, []string, error) {
+	if len(columns) == 0 {
+		return nil, nil, nil
+	}
+	var digitalAll, analogAll []CSVColumn
+	if haveDigital {
+		d, err := OpenDigitalCSV(digitalPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		digitalAll = d.Columns()
+	}
+	if haveAnalog {
+		a, err := OpenAnalogCSV(analogPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		analogAll = a.Columns()
+	}
+	var digitalCols, analogCols []string
+	for _, name := range columns {
+		_, inDigital := findColumn(digitalAll, name)
+		_, inAnalog := findColumn(analogAll, name)
+		switch {
+		case inDigital:
+			digitalCols = append(digitalCols, name)
+		case inAnalog:
+			analogCols = append(analogCols, name)
+		default:
+			return nil, nil, errors.Errorf("no column %q in raw csv export", name)
+		}
+	}
+	return digitalCols, analogCols, nil
+}
+
+// binaryColumn reports whether column selects binary channel ch.
+func binaryColumn(ch int, column string) bool {
+	_, ok := findColumn([]CSVColumn{{Header: ChannelName(ch)}}, column)
+	return ok
+}
+
+// missingBinaryColumn returns the first of columns that no binary file answers to.
+func missingBinaryColumn(digital map[int]string, analog map[int]string, columns []string) string {
+	for _, name := range columns {
+		_, inDigital := findBinaryChannel(digital, name)
+		_, inAnalog := findBinaryChannel(analog, name)
+		if !inDigital && !inAnalog {
+			return name
+		}
+	}
+	return ""
+}
+
+// openBinaryExport opens the binary files selected by columns, in the order requested
+// and with the same rule as the CSV path: a "Channel <n>" column is the digital channel
+// when there is one, the analog channel otherwise. No columns selects every file.
+func openBinaryExport(digital map[int]string, analog map[int]string, columns []string) (*Export, error) {
+	var digitalChs, analogChs []int
+	if len(columns) == 0 {
+		digitalChs, analogChs = SortedChannels(digital), SortedChannels(analog)
+	}
+	for _, name := range columns {
+		if ch, ok := findBinaryChannel(digital, name); ok {
+			digitalChs = append(digitalChs, ch)
+		} else if ch, ok := findBinaryChannel(analog, name); ok {
+			analogChs = append(analogChs, ch)
+		}
+	}
+
+	e := &Export{}
+	for _, ch := range digitalChs {
+		d, err := OpenDigitalBinary(digital[ch])
+		if err != nil {
+			_ = e.Close()
+			return nil, err
+		}
+		e.closers = append(e.closers, d)
+		e.Digital = append(e.Digital, d)
+	}
+	for _, ch := range analogChs {
+		a, err := OpenAnalogBinary(analog[ch])
+		if err != nil {
+			_ = e.Close()
+			return nil, err
+		}
+		e.closers = append(e.closers, a)
+		e.Analog = append(e.Analog, a)
+	}
+	return e, nil
+}
+
+func findBinaryChannel(files map[int]string, column string) (int, bool) {
+	for ch := range files {
+		if binaryColumn(ch, column) {
+			return ch, true
+		}
+	}
+	return 0, false
+}
+
+// Close releases any open binary files.
+func (e *Export) Close() error {
+	var first error
+	for _, c := range e.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	e.closers = nil
+	return first
+}
+
+func fileExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir()
+}
@@ -0,0 +1,154 @@
+// Package rawdata reads Logic 2 raw data exports (ExportRawDataBinary / ExportRawDataCsv)
+// into a streaming transition/sample model.
+//
+// Digital channels are modeled as an initial level followed by transitions; analog
+// channels as a sequence of timestamped samples. All times are in seconds relative to
+// the capture start, as written by Logic 2.
+package rawdata
+
+import (
+	"iter"
+	"strconv"
+)
+
+// Transition is a level change on a digital channel.
+type Transition struct {
+	// Time of the edge, in seconds.
+	Time float64
+	// State is the level after the edge (0 or 1).
+	State uint8
+}
+
+// Sample is one analog sample.
+type Sample struct {
+	// Time of the sample, in seconds.
+	Time float64
+	// Value in volts.
+	Value float32
+}
+
+// DigitalSource streams one digital channel.
+type DigitalSource interface {
+	// Channel is the Logic 2 channel index, or -1 if unknown.
+	Channel() int
+	// Name is the channel name ("Channel <n>" unless renamed in a CSV export).
+	Name() string
+	// InitialState is the level at BeginTime.
+	InitialState() uint8
+	// BeginTime is the start of the recorded range, in seconds.
+	BeginTime() float64
+	// EndTime is the end of the recorded range, in seconds. Sources that only learn the
+	// end while streaming report the final value once Transitions is exhausted.
+	EndTime() float64
+	// Transitions yields edges in time order. Iteration stops at the first error.
+	Transitions() iter.Seq2[Transition, error]
+}
+
+// AnalogSource streams one analog channel.
+type AnalogSource interface {
+	// Channel is the Logic 2 channel index, or -1 if unknown.
+	Channel() int
+	// Name is the channel name ("Channel <n>" unless renamed in a CSV export).
+	Name() string
+	// Samples yields samples in time order. Iteration stops at the first error.
+	Samples() iter.Seq2[Sample, error]
+}
+
+// CollectTransitions drains a digital source into memory. Intended for small captures
+// and tests; prefer iterating Transitions for large exports.
+func CollectTransitions(src DigitalSource) ([]Transition, error) {
+	var out []Transition
+	for tr, err := range src.Transitions() {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tr)
+	}
+	return out, nil
+}
+
+// CollectSamples drains an analog source into memory.
+func CollectSamples(src AnalogSource) ([]Sample, error) {
+	var out []Sample
+	for s, err := range src.Samples() {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// ChannelName is the default Logic 2 name for a channel index.
+func ChannelName(channel int) string {
+	if channel < 0 {
+		return "Channel ?"
+	}
+	return "Channel " + strconv.Itoa(channel)
+}
+
+// SampleRater is implemented by sources that know their sample rate (binary exports).
+// SampleRate returns 0 when the file doesn't record it.
+type SampleRater interface {
+	SampleRate() float64
+}
+
+// Edge is a transition on one of several merged digital sources.
+type Edge struct {
+	Time float64
+	// Index is the position of the source in the slice passed to Merge.
+	Index int
+	// State is the level after the edge.
+	State uint8
+}
+
+// Merge streams the transitions of several sources in time order. Edges at the same
+// time are yielded in source order.
+func Merge(sources []DigitalSource) iter.Seq2[Edge, error] {
+	return func(yield func(Edge, error) bool) {
+		type cursor struct {
+			next func() (Transition, error, bool)
+			head Transition
+			ok   bool
+		}
+		cursors := make([]*cursor, len(sources))
+		for i, src := range sources {
+			next, stop := iter.Pull2(src.Transitions())
+			defer stop()
+			cursors[i] = &cursor{next: next}
+		}
+		advance := func(c *cursor) error {
+			tr, err, ok := c.next()
+			if err != nil {
+				return err
+			}
+			c.head, c.ok = tr, ok
+			return nil
+		}
+		for _, c := range cursors {
+			if err := advance(c); err != nil {
+				yield(Edge{}, err)
+				return
+			}
+		}
+		for {
+			best := -1
+			for i, c := range cursors {
+				if c.ok && (best < 0 || c.head.Time < cursors[best].head.Time) {
+					best = i
+				}
+			}
+			if best < 0 {
+				return
+			}
+			c := cursors[best]
+			if !yield(Edge{Time: c.head.Time, Index: best, State: c.head.State}, nil) {
+				return
+			}
+			if err := advance(c); err != nil {
+				yield(Edge{}, err)
+				return
+			}
+		}
+	}
+}
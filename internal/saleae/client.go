@@ -28,10 +28,8 @@ func New(ctx context.Context, cfg Config) (*Client, error) {
 		defer cancel()
 	}
 
-	conn, err := grpc.NewClient(
-		cfg.Addr(),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, cfg.DialOptions...)
+	conn, err := grpc.NewClient(cfg.Addr(), opts...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "dial saleae automation grpc at %s", cfg.Addr())
 	}
@@ -99,6 +97,29 @@ func (c *Client) GetDevices(ctx context.Context, includeSimulationDevices bool)
 	return reply.GetDevices(), nil
 }
 
+// StartCapture starts a capture on deviceID (the first physical device if empty) and
+// returns its capture id.
+func (c *Client) StartCapture(ctx context.Context, deviceID string, device *pb.LogicDeviceConfiguration, capture *pb.CaptureConfiguration) (uint64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req := &pb.StartCaptureRequest{DeviceId: deviceID, CaptureConfiguration: capture}
+	if device != nil {
+		req.DeviceConfiguration = &pb.StartCaptureRequest_LogicDeviceConfiguration{LogicDeviceConfiguration: device}
+	}
+	reply, err := c.manager.StartCapture(ctx, req)
+	if err != nil {
+		return 0, errors.Wrap(err, "StartCapture RPC")
+	}
+
+	if reply.GetCaptureInfo() == nil {
+		return 0, errors.New("StartCapture: reply.capture_info is nil")
+	}
+
+	return reply.GetCaptureInfo().GetCaptureId(), nil
+}
+
 func (c *Client) LoadCapture(ctx context.Context, filepath string) (uint64, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -4,12 +4,17 @@ import (
 	"net"
 	"strconv"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type Config struct {
 	Host    string
 	Port    int
 	Timeout time.Duration
+
+	// DialOptions are appended to the client's own options (e.g. interceptors).
+	DialOptions []grpc.DialOption
 }
 
 func (c Config) Addr() string {
@@ -0,0 +1,50 @@
+package schema_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	saladconfig "github.com/go-go-golems/salad/internal/config"
+	mock "github.com/go-go-golems/salad/internal/mock/saleae"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/schema"
+)
+
+// TestCommittedSchemasInSync fails when configs/schema/*.schema.json drift from the Go types.
+// Regenerate with: go run ./cmd/salad schema <format> > configs/schema/<format>.schema.json
+func TestCommittedSchemasInSync(t *testing.T) {
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatalf("runtime.Caller failed")
+	}
+	moduleRoot := filepath.Clean(filepath.Join(filepath.Dir(thisFile), "..", ".."))
+
+	generators := map[string]func() (*schema.Schema, error){
+		"pipeline": pipeline.JSONSchema,
+		"mock":     mock.JSONSchema,
+		"analyzer-settings": func() (*schema.Schema, error) {
+			return saladconfig.AnalyzerSettingsJSONSchema(), nil
+		},
+	}
+
+	for name, gen := range generators {
+		s, err := gen()
+		if err != nil {
+			t.Fatalf("%s: generate: %v", name, err)
+		}
+		want, err := schema.MarshalIndent(s)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		path := filepath.Join(moduleRoot, "configs", "schema", name+".schema.json")
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if string(got) != string(want) {
+			t.Fatalf("%s is out of date; regenerate with: go run ./cmd/salad schema %s > configs/schema/%s.schema.json", path, name, name)
+		}
+	}
+}
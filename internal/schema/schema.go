@@ -0,0 +1,249 @@
+// Package schema generates JSON Schema (draft 2020-12) documents from the Go types
+// that back salad's YAML/JSON config formats.
+//
+// Field names come from `yaml` struct tags (the formats are decoded with yaml.v3).
+// Enum values and required fields are registered by the package that owns the type,
+// so they come from the same lists the parsers use.
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema that salad emits.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type                 any                `json:"type,omitempty"` // string or []string
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // bool or *Schema
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+	OneOf []*Schema `json:"oneOf,omitempty"`
+	Not   *Schema   `json:"not,omitempty"`
+
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// FieldRef identifies a struct field by its Go type and Go field name.
+type FieldRef struct {
+	Type  reflect.Type
+	Field string
+}
+
+// Field is a convenience constructor: Field[ExportConfig]("Type").
+func Field[T any](name string) FieldRef {
+	return FieldRef{Type: reflect.TypeOf((*T)(nil)).Elem(), Field: name}
+}
+
+// Options carries the hints that cannot be derived from Go types alone.
+type Options struct {
+	ID          string
+	Title       string
+	Description string
+
+	// Enums restricts string fields (or string list items) to the given values.
+	Enums map[FieldRef][]string
+	// Required lists fields that must be present.
+	Required []FieldRef
+	// Descriptions attaches a description to a field.
+	Descriptions map[FieldRef]string
+	// AllowAdditional keeps additionalProperties open for the given struct types.
+	// Structs are closed by default because salad decodes them strictly.
+	AllowAdditional []reflect.Type
+}
+
+// Generate builds a schema for root (a struct type). Named struct types are
+// emitted once under $defs and referenced with $ref.
+func Generate(root reflect.Type, opts Options) (*Schema, error) {
+	for root.Kind() == reflect.Pointer {
+		root = root.Elem()
+	}
+	if root.Kind() != reflect.Struct {
+		return nil, errors.Errorf("schema root must be a struct, got %s", root)
+	}
+
+	g := &generator{
+		opts:     opts,
+		defs:     map[string]*Schema{},
+		seen:     map[reflect.Type]string{},
+		required: map[FieldRef]bool{},
+		open:     map[reflect.Type]bool{},
+	}
+	for _, r := range opts.Required {
+		g.required[r] = true
+	}
+	for _, t := range opts.AllowAdditional {
+		g.open[t] = true
+	}
+
+	body, err := g.structSchema(root)
+	if err != nil {
+		return nil, err
+	}
+
+	body.Schema = Draft
+	body.ID = opts.ID
+	body.Title = opts.Title
+	body.Description = opts.Description
+	if len(g.defs) > 0 {
+		body.Defs = g.defs
+	}
+	return body, nil
+}
+
+// Def returns the $defs entry for the Go type t (nil if absent).
+func (s *Schema) Def(t reflect.Type) *Schema {
+	if s == nil || s.Defs == nil {
+		return nil
+	}
+	return s.Defs[t.Name()]
+}
+
+// RefTo returns a $ref schema pointing at the $defs entry for t.
+func RefTo(t reflect.Type) *Schema {
+	return &Schema{Ref: "#/$defs/" + t.Name()}
+}
+
+// MarshalIndent renders the schema the way salad writes it to disk.
+func MarshalIndent(s *Schema) ([]byte, error) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "encode json schema")
+	}
+	return append(b, '\n'), nil
+}
+
+type generator struct {
+	opts     Options
+	defs     map[string]*Schema
+	seen     map[reflect.Type]string
+	required map[FieldRef]bool
+	open     map[reflect.Type]bool
+}
+
+func (g *generator) structSchema(t reflect.Type) (*Schema, error) {
+	out := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+	if !g.open[t] {
+		out.AdditionalProperties = false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, ok := yamlName(f)
+		if !ok {
+			continue
+		}
+
+		ref := FieldRef{Type: t, Field: f.Name}
+		prop, err := g.typeSchema(f.Type, ref)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s.%s", t.Name(), f.Name)
+		}
+		if desc, ok := g.opts.Descriptions[ref]; ok {
+			prop.Description = desc
+		}
+		out.Properties[name] = prop
+		if g.required[ref] {
+			out.Required = append(out.Required, name)
+		}
+	}
+	return out, nil
+}
+
+func (g *generator) typeSchema(t reflect.Type, ref FieldRef) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		s := &Schema{Type: "string"}
+		if values, ok := g.opts.Enums[ref]; ok {
+			s.Enum = toAny(values)
+		}
+		return s, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.typeSchema(t.Elem(), ref)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, errors.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.typeSchema(t.Elem(), FieldRef{})
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.seen[t]; !ok {
+			g.seen[t] = t.Name()
+			def, err := g.structSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			g.defs[t.Name()] = def
+		}
+		return RefTo(t), nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	default:
+		return nil, errors.Errorf("unsupported kind %s", t.Kind())
+	}
+}
+
+func yamlName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		// yaml.v3 default: lowercased field name.
+		name = strings.ToLower(f.Name)
+	}
+	return name, true
+}
+
+func toAny(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
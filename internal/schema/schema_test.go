@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testInner struct {
+	Kind string `yaml:"kind,omitempty"`
+}
+
+type testRoot struct {
+	Name    string            `yaml:"name"`
+	Count   uint32            `yaml:"count,omitempty"`
+	Ratio   float64           `yaml:"ratio,omitempty"`
+	Tags    []string          `yaml:"tags,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	Inner   *testInner        `yaml:"inner,omitempty"`
+	Inners  []testInner       `yaml:"inners,omitempty"`
+	Skipped string            `yaml:"-"`
+}
+
+func TestGenerate_StructsEnumsAndRequired(t *testing.T) {
+	s, err := Generate(reflect.TypeOf(testRoot{}), Options{
+		Title: "test",
+		Enums: map[FieldRef][]string{
+			Field[testInner]("Kind"): {"a", "b"},
+			Field[testRoot]("Tags"):  {"x"},
+		},
+		Required: []FieldRef{Field[testRoot]("Name")},
+	})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if s.Schema != Draft || s.Title != "test" {
+		t.Fatalf("expected root metadata, got $schema=%q title=%q", s.Schema, s.Title)
+	}
+	if s.AdditionalProperties != false {
+		t.Fatalf("expected closed root object, got %v", s.AdditionalProperties)
+	}
+	if len(s.Required) != 1 || s.Required[0] != "name" {
+		t.Fatalf("required: expected [name], got %v", s.Required)
+	}
+	if _, ok := s.Properties["Skipped"]; ok {
+		t.Fatalf("expected yaml:\"-\" field to be skipped")
+	}
+	if got := s.Properties["count"]; got.Type != "integer" || got.Minimum == nil || *got.Minimum != 0 {
+		t.Fatalf("count: expected non-negative integer, got %+v", got)
+	}
+	if got := s.Properties["ratio"].Type; got != "number" {
+		t.Fatalf("ratio: expected number, got %v", got)
+	}
+	if got := s.Properties["tags"].Items.Enum; len(got) != 1 || got[0] != "x" {
+		t.Fatalf("tags: expected item enum [x], got %v", got)
+	}
+	if got := s.Properties["labels"].AdditionalProperties.(*Schema).Type; got != "string" {
+		t.Fatalf("labels: expected string values, got %v", got)
+	}
+	if got := s.Properties["inner"].Ref; got != "#/$defs/testInner" {
+		t.Fatalf("inner: expected $ref, got %q", got)
+	}
+	if got := s.Properties["inners"].Items.Ref; got != "#/$defs/testInner" {
+		t.Fatalf("inners: expected shared $ref, got %q", got)
+	}
+	def := s.Def(reflect.TypeOf(testInner{}))
+	if def == nil {
+		t.Fatalf("expected $defs entry for testInner")
+	}
+	if got := def.Properties["kind"].Enum; len(got) != 2 {
+		t.Fatalf("testInner.kind: expected enum, got %v", got)
+	}
+}
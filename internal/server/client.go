@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/jobqueue"
+	"github.com/pkg/errors"
+)
+
+// Client calls the job endpoints of a `salad serve` instance.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for the server at baseURL (e.g. "http://127.0.0.1:8080").
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: http.DefaultClient}
+}
+
+// SubmitJob queues a pipeline job.
+func (c *Client) SubmitJob(ctx context.Context, req SubmitJobRequest) (jobqueue.Job, error) {
+	var j jobqueue.Job
+	err := c.do(ctx, http.MethodPost, "/v1/jobs", req, &j)
+	return j, err
+}
+
+// Job returns a job.
+func (c *Client) Job(ctx context.Context, id uint64) (jobqueue.Job, error) {
+	var j jobqueue.Job
+	err := c.do(ctx, http.MethodGet, "/v1/jobs/"+strconv.FormatUint(id, 10), nil, &j)
+	return j, err
+}
+
+// Jobs returns all jobs in submission order.
+func (c *Client) Jobs(ctx context.Context) ([]jobqueue.Job, error) {
+	var out struct {
+		Jobs []jobqueue.Job `json:"jobs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/v1/jobs", nil, &out)
+	return out.Jobs, err
+}
+
+// CancelJob cancels a queued or running job.
+func (c *Client) CancelJob(ctx context.Context, id uint64) (jobqueue.Job, error) {
+	var j jobqueue.Job
+	err := c.do(ctx, http.MethodPost, "/v1/jobs/"+strconv.FormatUint(id, 10)+"/cancel", nil, &j)
+	return j, err
+}
+
+// JobLog returns the log lines of a job from index since on.
+func (c *Client) JobLog(ctx context.Context, id uint64, since int) (*JobLog, error) {
+	var l JobLog
+	q := url.Values{"since": {strconv.Itoa(since)}}
+	err := c.do(ctx, http.MethodGet, "/v1/jobs/"+strconv.FormatUint(id, 10)+"/log?"+q.Encode(), nil, &l)
+	return &l, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrapf(err, "encode %s %s", method, path)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, r)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "read %s %s", method, path)
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(b, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(b))
+		}
+		return errors.Errorf("%s %s: %s (HTTP %d)", method, path, e.Error, resp.StatusCode)
+	}
+	return errors.Wrapf(json.Unmarshal(b, out), "decode %s %s", method, path)
+}
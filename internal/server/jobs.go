@@ -0,0 +1,275 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-go-golems/salad/internal/httpjson"
+	"github.com/go-go-golems/salad/internal/jobqueue"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// SubmitJobRequest is the JSON body of POST /v1/jobs.
+type SubmitJobRequest struct {
+	// Config is an inline pipeline config; ConfigPath a config file under Options.ConfigDir.
+	Config     json.RawMessage `json:"config"`
+	ConfigPath string          `json:"config_path"`
+	// CaptureID runs the pipeline against an open capture instead of capture.load.
+	CaptureID uint64   `json:"capture_id"`
+	Priority  int      `json:"priority"`
+	Devices   []string `json:"devices"`
+}
+
+// isYAML reports whether the request body is a pipeline config in YAML.
+func isYAML(r *http.Request) bool {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == "application/yaml" || mt == "application/x-yaml" || mt == "text/yaml"
+}
+
+// readJobRequest decodes a job submission: JSON, or a YAML pipeline config with the
+// other fields as query parameters.
+func (s *Server) readJobRequest(w http.ResponseWriter, r *http.Request) (*SubmitJobRequest, *pipeline.Config, error) {
+	req := &SubmitJobRequest{}
+	if isYAML(r) {
+		q := r.URL.Query()
+		var err error
+		if v := q.Get("priority"); v != "" {
+			if req.Priority, err = strconv.Atoi(v); err != nil {
+				return nil, nil, httpjson.BadRequest("invalid priority %q", v)
+			}
+		}
+		if v := q.Get("capture_id"); v != "" {
+			if req.CaptureID, err = strconv.ParseUint(v, 10, 64); err != nil {
+				return nil, nil, httpjson.BadRequest("invalid capture_id %q", v)
+			}
+		}
+		req.Devices = q["device"]
+		b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, httpjson.MaxBodyBytes))
+		if err != nil {
+			return nil, nil, httpjson.BadRequest("read request body: %v", err)
+		}
+		cfg, err := pipeline.Parse(b)
+		if err != nil {
+			return nil, nil, httpjson.BadRequest("%v", err)
+		}
+		return req, cfg, nil
+	}
+
+	if err := httpjson.Read(w, r, req); err != nil {
+		return nil, nil, err
+	}
+	var cfg *pipeline.Config
+	var err error
+	switch {
+	case len(req.Config) > 0 && req.ConfigPath != "":
+		return nil, nil, httpjson.BadRequest("only one of config/config_path may be set")
+	case len(req.Config) > 0:
+		cfg, err = pipeline.Parse(req.Config)
+	case req.ConfigPath != "" && s.opts.ConfigDir == "":
+		return nil, nil, httpjson.BadRequest("config_path is disabled (start the server with --config-dir)")
+	case req.ConfigPath != "":
+		cfg, err = pipeline.LoadIn(s.opts.ConfigDir, req.ConfigPath)
+	default:
+		return nil, nil, httpjson.BadRequest("one of config/config_path is required")
+	}
+	if err != nil {
+		return nil, nil, httpjson.BadRequest("%v", err)
+	}
+	return req, cfg, nil
+}
+
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) error {
+	req, cfg, err := s.readJobRequest(w, r)
+	if err != nil {
+		return err
+	}
+	j, err := s.queue.Submit(jobqueue.Job{
+		Priority:   req.Priority,
+		Devices:    req.Devices,
+		ConfigPath: req.ConfigPath,
+		Config:     cfg,
+		CaptureID:  req.CaptureID,
+	})
+	if err != nil {
+		return &httpjson.Error{Code: http.StatusServiceUnavailable, Msg: err.Error()}
+	}
+	w.Header().Set("Location", "/v1/jobs/"+strconv.FormatUint(j.ID, 10))
+	httpjson.Write(w, http.StatusAccepted, j)
+	return nil
+}
+
+// runJob is the queue's RunFunc: it runs the pipeline over the server's connection.
+func (s *Server) runJob(ctx context.Context, j jobqueue.Job, logf func(format string, args ...any)) (*jobqueue.Outcome, error) {
+	if s.opts.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.JobTimeout)
+		defer cancel()
+	}
+	runner := &pipeline.Runner{Client: s.opts.Client, CaptureID: j.CaptureID, Logf: logf}
+	res, err := runner.Run(ctx, j.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &jobqueue.Outcome{CaptureID: res.CaptureID, Analyzers: res.Analyzers, Paths: res.Artifacts}, nil
+}
+
+func (s *Server) job(r *http.Request) (jobqueue.Job, error) {
+	id, err := pathID(r, "job_id")
+	if err != nil {
+		return jobqueue.Job{}, err
+	}
+	j, ok := s.queue.Get(id)
+	if !ok {
+		return jobqueue.Job{}, httpjson.NotFound("job %d not found", id)
+	}
+	return j, nil
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) error {
+	httpjson.Write(w, http.StatusOK, map[string]any{"jobs": s.queue.List()})
+	return nil
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) error {
+	j, err := s.job(r)
+	if err != nil {
+		return err
+	}
+	httpjson.Write(w, http.StatusOK, j)
+	return nil
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r, "job_id")
+	if err != nil {
+		return err
+	}
+	j, err := s.queue.Cancel(id)
+	switch {
+	case errors.Is(err, jobqueue.ErrNotFound):
+		return httpjson.NotFound("job %d not found", id)
+	case errors.Is(err, jobqueue.ErrDone):
+		return &httpjson.Error{Code: http.StatusConflict, Msg: "job " + strconv.FormatUint(id, 10) + " already " + string(j.Status)}
+	case err != nil:
+		return err
+	}
+	httpjson.Write(w, http.StatusOK, j)
+	return nil
+}
+
+// JobLog is the response of GET /v1/jobs/{job_id}/log.
+type JobLog struct {
+	JobID  uint64          `json:"job_id"`
+	Status jobqueue.Status `json:"status"`
+	Lines  []string        `json:"lines"`
+	// Next is the value of `since` that returns the lines after these.
+	Next int `json:"next"`
+}
+
+func (s *Server) getJobLog(w http.ResponseWriter, r *http.Request) error {
+	j, err := s.job(r)
+	if err != nil {
+		return err
+	}
+	since := 0
+	if v := r.URL.Query().Get("since"); v != "" {
+		if since, err = strconv.Atoi(v); err != nil || since < 0 {
+			return httpjson.BadRequest("invalid since %q", v)
+		}
+	}
+	lines, next, err := s.queue.Log(j.ID, since)
+	if err != nil {
+		return err
+	}
+	// The status is read before the lines, so a finished status means no line is missing.
+	httpjson.Write(w, http.StatusOK, JobLog{JobID: j.ID, Status: j.Status, Lines: lines, Next: next})
+	return nil
+}
+
+func (s *Server) listArtifacts(w http.ResponseWriter, r *http.Request) error {
+	j, err := s.job(r)
+	if err != nil {
+		return err
+	}
+	artifacts := j.Artifacts
+	if artifacts == nil {
+		artifacts = []jobqueue.Artifact{}
+	}
+	httpjson.Write(w, http.StatusOK, map[string]any{"job_id": j.ID, "status": j.Status, "artifacts": artifacts})
+	return nil
+}
+
+// downloadArtifact serves one artifact. Only files recorded by the job can be read.
+func (s *Server) downloadArtifact(w http.ResponseWriter, r *http.Request) error {
+	j, err := s.job(r)
+	if err != nil {
+		return err
+	}
+	i, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || i < 0 || i >= len(j.Artifacts) {
+		return httpjson.NotFound("job %d has no artifact %q", j.ID, r.PathValue("index"))
+	}
+	a := j.Artifacts[i]
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return httpjson.NotFound("open artifact: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "stat artifact %s", a.Path)
+	}
+	w.Header().Set("Content-Disposition", attachment(filepath.Base(a.Path)))
+	http.ServeContent(w, r, a.Path, info.ModTime(), f)
+	return nil
+}
+
+// downloadArtifactsZip streams all artifacts of a job as one zip archive.
+func (s *Server) downloadArtifactsZip(w http.ResponseWriter, r *http.Request) error {
+	j, err := s.job(r)
+	if err != nil {
+		return err
+	}
+	if j.Status != jobqueue.Succeeded {
+		return &httpjson.Error{Code: http.StatusConflict, Msg: "job " + strconv.FormatUint(j.ID, 10) + " is " + string(j.Status)}
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", attachment("job-"+strconv.FormatUint(j.ID, 10)+"-artifacts.zip"))
+	zw := zip.NewWriter(w)
+	for _, a := range j.Artifacts {
+		// Headers are sent by now: a failure can only truncate the archive.
+		if err := addToZip(zw, a); err != nil {
+			log.Warn().Err(err).Uint64("job_id", j.ID).Str("artifact", a.Path).Msg("zip artifacts")
+			return nil
+		}
+	}
+	_ = zw.Close()
+	return nil
+}
+
+func attachment(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+func addToZip(zw *zip.Writer, a jobqueue.Artifact) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	dst, err := zw.Create(a.Name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Listen opens TCP listeners for addr, refusing anything reachable from outside the
+// machine or its private networks. A wildcard host ("", "0.0.0.0" or "::", as in
+// ":8080") listens on each loopback and private address of the machine instead of on
+// all interfaces. Other hosts must be loopback or private addresses, or names that
+// only resolve to such addresses.
+func Listen(ctx context.Context, addr string) ([]net.Listener, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid listen address %q", addr)
+	}
+
+	var lc net.ListenConfig
+	if host != "" && host != "0.0.0.0" && host != "::" {
+		resolved, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve listen host %q", host)
+		}
+		for _, a := range resolved {
+			if !isLocal(a.IP) {
+				return nil, errors.Errorf("refusing to listen on %s: %s is not a loopback or private address", addr, a.IP)
+			}
+		}
+		l, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listen on %s", addr)
+		}
+		return []net.Listener{l}, nil
+	}
+
+	ips, err := localAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var listeners []net.Listener
+	for _, ip := range ips {
+		a := net.JoinHostPort(ip.String(), port)
+		l, err := lc.Listen(ctx, "tcp", a)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, errors.Wrapf(err, "listen on %s", a)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func isLocal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate()
+}
+
+// localAddrs returns the loopback and private addresses of the machine's interfaces.
+func localAddrs() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, errors.Wrap(err, "list interface addresses")
+	}
+	var ips []net.IP
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && isLocal(n.IP) {
+			ips = append(ips, n.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, errors.New("no loopback or private interface address to listen on")
+	}
+	return ips, nil
+}
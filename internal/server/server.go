@@ -0,0 +1,530 @@
+// Package server implements `salad serve`: a JSON REST gateway over one Logic 2
+// connection for captures, analyzers, exports and pipeline jobs. The API is described
+// by the OpenAPI document served at /openapi.json.
+package server
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	saladconfig "github.com/go-go-golems/salad/internal/config"
+	"github.com/go-go-golems/salad/internal/httpjson"
+	"github.com/go-go-golems/salad/internal/jobqueue"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Options configures a Server.
+type Options struct {
+	Client *saleae.Client
+	// State tracks the captures and analyzers opened through Client; its Interceptor
+	// must be installed on Client.
+	State *shell.State
+	// Timeout bounds each RPC made for a request (0: no limit).
+	Timeout time.Duration
+	// JobTimeout bounds each pipeline job (0: no limit).
+	JobTimeout time.Duration
+	// ParallelJobs runs pipeline jobs concurrently unless they share a device or
+	// capture; otherwise jobs run one at a time.
+	ParallelJobs bool
+	// JobHistory, if set, is the directory where jobs and their logs are persisted.
+	JobHistory string
+	// ConfigDir, if set, is the directory that job config_path values are relative to;
+	// configs and the files they extend or include must stay inside it. Without it,
+	// config_path is refused.
+	ConfigDir string
+}
+
+// Server is an http.Handler serving the REST API.
+type Server struct {
+	opts  Options
+	mux   *http.ServeMux
+	queue *jobqueue.Queue
+}
+
+type route struct {
+	method, pattern string
+	handler         func(w http.ResponseWriter, r *http.Request) error
+}
+
+// New returns a Server for opts, loading the job history. Call Close to stop running
+// jobs.
+func New(opts Options) (*Server, error) {
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	queue, err := jobqueue.New(jobqueue.Options{Run: s.runJob, Parallel: opts.ParallelJobs, Dir: opts.JobHistory})
+	if err != nil {
+		return nil, err
+	}
+	s.queue = queue
+	for _, rt := range s.routes() {
+		s.mux.HandleFunc(rt.method+" "+rt.pattern, s.handle(rt.handler))
+	}
+	s.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPI)
+	})
+	return s, nil
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{"GET", "/v1/info", s.getInfo},
+		{"GET", "/v1/devices", s.getDevices},
+		{"GET", "/v1/captures", s.listCaptures},
+		{"POST", "/v1/captures/load", s.loadCapture},
+		{"POST", "/v1/captures/start", s.startCapture},
+		{"GET", "/v1/captures/{capture_id}", s.getCapture},
+		{"DELETE", "/v1/captures/{capture_id}", s.closeCapture},
+		{"POST", "/v1/captures/{capture_id}/stop", s.stopCapture},
+		{"POST", "/v1/captures/{capture_id}/wait", s.waitCapture},
+		{"POST", "/v1/captures/{capture_id}/save", s.saveCapture},
+		{"GET", "/v1/captures/{capture_id}/analyzers", s.listAnalyzers},
+		{"POST", "/v1/captures/{capture_id}/analyzers", s.addAnalyzer},
+		{"DELETE", "/v1/captures/{capture_id}/analyzers/{analyzer_id}", s.removeAnalyzer},
+		{"POST", "/v1/captures/{capture_id}/exports/raw-csv", s.exportRawCSV},
+		{"POST", "/v1/captures/{capture_id}/exports/raw-binary", s.exportRawBinary},
+		{"POST", "/v1/captures/{capture_id}/exports/table-csv", s.exportTableCSV},
+		{"GET", "/v1/jobs", s.listJobs},
+		{"POST", "/v1/jobs", s.submitJob},
+		{"GET", "/v1/jobs/{job_id}", s.getJob},
+		{"POST", "/v1/jobs/{job_id}/cancel", s.cancelJob},
+		{"GET", "/v1/jobs/{job_id}/log", s.getJobLog},
+		{"GET", "/v1/jobs/{job_id}/artifacts", s.listArtifacts},
+		{"GET", "/v1/jobs/{job_id}/artifacts/{index}", s.downloadArtifact},
+		{"GET", "/v1/jobs/{job_id}/artifacts.zip", s.downloadArtifactsZip},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close interrupts running pipeline jobs and waits for them to finish. Queued jobs stay
+// in the history and run when a server is started on it again.
+func (s *Server) Close() {
+	s.queue.Close()
+}
+
+// httpStatusByCode maps gRPC status codes from Logic 2 to HTTP statuses.
+var httpStatusByCode = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// statusOf returns the HTTP status for a handler error. Errors that carry no gRPC
+// status come from request validation in the client and are the caller's fault.
+func statusOf(err error) int {
+	if code, ok := httpjson.StatusOf(err); ok {
+		return code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return http.StatusBadRequest
+	}
+	if code, ok := httpStatusByCode[st.Code()]; ok {
+		return code
+	}
+	return http.StatusBadGateway
+}
+
+func (s *Server) handle(h func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			httpjson.WriteError(w, statusOf(err), err)
+		}
+	}
+}
+
+// rpcContext bounds the RPCs of one request by Options.Timeout.
+func (s *Server) rpcContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.opts.Timeout > 0 {
+		return context.WithTimeout(r.Context(), s.opts.Timeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
+func pathID(r *http.Request, name string) (uint64, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, httpjson.BadRequest("invalid %s %q", name, r.PathValue(name))
+	}
+	return id, nil
+}
+
+type appInfo struct {
+	ApplicationVersion string `json:"application_version"`
+	APIVersion         string `json:"api_version"`
+	LaunchPID          uint64 `json:"launch_pid"`
+}
+
+func (s *Server) getInfo(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := s.rpcContext(r)
+	defer cancel()
+	info, err := s.opts.Client.GetAppInfo(ctx)
+	if err != nil {
+		return err
+	}
+	api := info.GetApiVersion()
+	httpjson.Write(w, http.StatusOK, appInfo{
+		ApplicationVersion: info.GetApplicationVersion(),
+		APIVersion:         fmt.Sprintf("%d.%d.%d", api.GetMajor(), api.GetMinor(), api.GetPatch()),
+		LaunchPID:          info.GetLaunchPid(),
+	})
+	return nil
+}
+
+type device struct {
+	DeviceID     string `json:"device_id"`
+	DeviceType   string `json:"device_type"`
+	IsSimulation bool   `json:"is_simulation"`
+}
+
+func (s *Server) getDevices(w http.ResponseWriter, r *http.Request) error {
+	includeSim := false
+	if v := r.URL.Query().Get("include_simulation"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return httpjson.BadRequest("invalid include_simulation %q", v)
+		}
+		includeSim = b
+	}
+	ctx, cancel := s.rpcContext(r)
+	defer cancel()
+	devices, err := s.opts.Client.GetDevices(ctx, includeSim)
+	if err != nil {
+		return err
+	}
+	out := make([]device, 0, len(devices))
+	for _, d := range devices {
+		out = append(out, device{
+			DeviceID:     d.GetDeviceId(),
+			DeviceType:   strings.TrimPrefix(d.GetDeviceType().String(), "DEVICE_TYPE_"),
+			IsSimulation: d.GetIsSimulation(),
+		})
+	}
+	httpjson.Write(w, http.StatusOK, map[string]any{"devices": out})
+	return nil
+}
+
+type capture struct {
+	CaptureID uint64 `json:"capture_id"`
+	Name      string `json:"name,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+func (s *Server) captureJSON(id uint64) capture {
+	for _, c := range s.opts.State.Captures() {
+		if c.ID == id {
+			return capture{CaptureID: c.ID, Name: c.Name, Source: c.Source}
+		}
+	}
+	return capture{CaptureID: id}
+}
+
+func (s *Server) listCaptures(w http.ResponseWriter, r *http.Request) error {
+	out := []capture{}
+	for _, c := range s.opts.State.Captures() {
+		out = append(out, capture{CaptureID: c.ID, Name: c.Name, Source: c.Source})
+	}
+	httpjson.Write(w, http.StatusOK, map[string]any{"captures": out})
+	return nil
+}
+
+func (s *Server) getCapture(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r, "capture_id")
+	if err != nil {
+		return err
+	}
+	c := s.captureJSON(id)
+	if c.Name == "" {
+		return httpjson.NotFound("capture %d was not opened through this server", id)
+	}
+	httpjson.Write(w, http.StatusOK, c)
+	return nil
+}
+
+type filepathRequest struct {
+	Filepath string `json:"filepath"`
+}
+
+func (s *Server) loadCapture(w http.ResponseWriter, r *http.Request) error {
+	var req filepathRequest
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	ctx, cancel := s.rpcContext(r)
+	defer cancel()
+	id, err := s.opts.Client.LoadCapture(ctx, req.Filepath)
+	if err != nil {
+		return err
+	}
+	httpjson.Write(w, http.StatusCreated, s.captureJSON(id))
+	return nil
+}
+
+type startCaptureRequest struct {
+	DeviceID              string   `json:"device_id"`
+	Digital               []uint32 `json:"digital"`
+	Analog                []uint32 `json:"analog"`
+	DigitalSampleRate     uint32   `json:"digital_sample_rate"`
+	AnalogSampleRate      uint32   `json:"analog_sample_rate"`
+	DigitalThresholdVolts float64  `json:"digital_threshold_volts"`
+	BufferSizeMegabytes   uint32   `json:"buffer_size_megabytes"`
+	DurationSeconds       float64  `json:"duration_seconds"`
+}
+
+func (s *Server) startCapture(w http.ResponseWriter, r *http.Request) error {
+	var req startCaptureRequest
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	if len(req.Digital) == 0 && len(req.Analog) == 0 {
+		return httpjson.BadRequest("at least one of digital/analog must be set")
+	}
+	if req.DurationSeconds < 0 {
+		return httpjson.BadRequest("duration_seconds must not be negative")
+	}
+	deviceConfig := &pb.LogicDeviceConfiguration{
+		EnabledChannels:       &pb.LogicDeviceConfiguration_LogicChannels{LogicChannels: &pb.LogicChannels{DigitalChannels: req.Digital, AnalogChannels: req.Analog}},
+		DigitalSampleRate:     req.DigitalSampleRate,
+		AnalogSampleRate:      req.AnalogSampleRate,
+		DigitalThresholdVolts: req.DigitalThresholdVolts,
+	}
+	captureConfig := &pb.CaptureConfiguration{
+		BufferSizeMegabytes: req.BufferSizeMegabytes,
+		CaptureMode:         &pb.CaptureConfiguration_ManualCaptureMode{ManualCaptureMode: &pb.ManualCaptureMode{}},
+	}
+	if req.DurationSeconds > 0 {
+		captureConfig.CaptureMode = &pb.CaptureConfiguration_TimedCaptureMode{TimedCaptureMode: &pb.TimedCaptureMode{DurationSeconds: req.DurationSeconds}}
+	}
+
+	ctx, cancel := s.rpcContext(r)
+	defer cancel()
+	id, err := s.opts.Client.StartCapture(ctx, req.DeviceID, deviceConfig, captureConfig)
+	if err != nil {
+		return err
+	}
+	httpjson.Write(w, http.StatusCreated, s.captureJSON(id))
+	return nil
+}
+
+// captureAction runs fn for the capture in the path and answers 204 No Content.
+func (s *Server) captureAction(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, id uint64) error) error {
+	id, err := pathID(r, "capture_id")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.rpcContext(r)
+	defer cancel()
+	if err := fn(ctx, id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) closeCapture(w http.ResponseWriter, r *http.Request) error {
+	return s.captureAction(w, r, s.opts.Client.CloseCapture)
+}
+
+func (s *Server) stopCapture(w http.ResponseWriter, r *http.Request) error {
+	return s.captureAction(w, r, s.opts.Client.StopCapture)
+}
+
+func (s *Server) waitCapture(w http.ResponseWriter, r *http.Request) error {
+	return s.captureAction(w, r, s.opts.Client.WaitCapture)
+}
+
+func (s *Server) saveCapture(w http.ResponseWriter, r *http.Request) error {
+	var req filepathRequest
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	return s.captureAction(w, r, func(ctx context.Context, id uint64) error {
+		return s.opts.Client.SaveCapture(ctx, id, req.Filepath)
+	})
+}
+
+type analyzer struct {
+	AnalyzerID uint64 `json:"analyzer_id"`
+	CaptureID  uint64 `json:"capture_id"`
+	Name       string `json:"name,omitempty"`
+	Analyzer   string `json:"analyzer"`
+	Label      string `json:"label,omitempty"`
+}
+
+func toAnalyzerJSON(a shell.Analyzer) analyzer {
+	return analyzer{AnalyzerID: a.ID, CaptureID: a.CaptureID, Name: a.Name, Analyzer: a.Analyzer, Label: a.Label}
+}
+
+func (s *Server) listAnalyzers(w http.ResponseWriter, r *http.Request) error {
+	id, err := pathID(r, "capture_id")
+	if err != nil {
+		return err
+	}
+	out := []analyzer{}
+	for _, a := range s.opts.State.Analyzers() {
+		if a.CaptureID == id {
+			out = append(out, toAnalyzerJSON(a))
+		}
+	}
+	httpjson.Write(w, http.StatusOK, map[string]any{"analyzers": out})
+	return nil
+}
+
+type addAnalyzerRequest struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Settings uses the shapes of analyzer settings JSON files.
+	Settings json.RawMessage `json:"settings"`
+}
+
+func (s *Server) addAnalyzer(w http.ResponseWriter, r *http.Request) error {
+	captureID, err := pathID(r, "capture_id")
+	if err != nil {
+		return err
+	}
+	var req addAnalyzerRequest
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return httpjson.BadRequest("name is required")
+	}
+	settings := map[string]*pb.AnalyzerSettingValue{}
+	if len(req.Settings) > 0 && string(req.Settings) != "null" {
+		settings, err = saladconfig.LoadAnalyzerSettingsFromReader(bytes.NewReader(req.Settings), "json")
+		if err != nil {
+			return httpjson.BadRequest("settings: %v", err)
+		}
+	}
+
+	ctx, cancel := s.rpcContext(r)
+	defer cancel()
+	id, err := s.opts.Client.AddAnalyzer(ctx, captureID, req.Name, req.Label, settings)
+	if err != nil {
+		return err
+	}
+	out := analyzer{AnalyzerID: id, CaptureID: captureID, Analyzer: req.Name, Label: req.Label}
+	for _, a := range s.opts.State.Analyzers() {
+		if a.ID == id && a.CaptureID == captureID {
+			out = toAnalyzerJSON(a)
+		}
+	}
+	httpjson.Write(w, http.StatusCreated, out)
+	return nil
+}
+
+func (s *Server) removeAnalyzer(w http.ResponseWriter, r *http.Request) error {
+	analyzerID, err := pathID(r, "analyzer_id")
+	if err != nil {
+		return err
+	}
+	return s.captureAction(w, r, func(ctx context.Context, id uint64) error {
+		return s.opts.Client.RemoveAnalyzer(ctx, id, analyzerID)
+	})
+}
+
+type rawExportRequest struct {
+	Directory             string   `json:"directory"`
+	Digital               []uint32 `json:"digital"`
+	Analog                []uint32 `json:"analog"`
+	AnalogDownsampleRatio uint64   `json:"analog_downsample_ratio"`
+	Iso8601Timestamp      bool     `json:"iso8601_timestamp"`
+}
+
+func (s *Server) readRawExport(w http.ResponseWriter, r *http.Request) (*rawExportRequest, *pb.LogicChannels, error) {
+	var req rawExportRequest
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return nil, nil, err
+	}
+	if req.Directory == "" {
+		return nil, nil, httpjson.BadRequest("directory is required")
+	}
+	if len(req.Digital) == 0 && len(req.Analog) == 0 {
+		return nil, nil, httpjson.BadRequest("at least one of digital/analog must be set")
+	}
+	return &req, &pb.LogicChannels{DigitalChannels: req.Digital, AnalogChannels: req.Analog}, nil
+}
+
+func (s *Server) exportRawCSV(w http.ResponseWriter, r *http.Request) error {
+	req, ch, err := s.readRawExport(w, r)
+	if err != nil {
+		return err
+	}
+	return s.captureAction(w, r, func(ctx context.Context, id uint64) error {
+		return s.opts.Client.ExportRawDataCsv(ctx, id, req.Directory, ch, req.AnalogDownsampleRatio, req.Iso8601Timestamp)
+	})
+}
+
+func (s *Server) exportRawBinary(w http.ResponseWriter, r *http.Request) error {
+	req, ch, err := s.readRawExport(w, r)
+	if err != nil {
+		return err
+	}
+	return s.captureAction(w, r, func(ctx context.Context, id uint64) error {
+		return s.opts.Client.ExportRawDataBinary(ctx, id, req.Directory, ch, req.AnalogDownsampleRatio)
+	})
+}
+
+type tableExportRequest struct {
+	Filepath  string `json:"filepath"`
+	Analyzers []struct {
+		AnalyzerID uint64 `json:"analyzer_id"`
+		Radix      string `json:"radix"`
+	} `json:"analyzers"`
+	Columns          []string                    `json:"columns"`
+	Iso8601Timestamp bool                        `json:"iso8601_timestamp"`
+	Filter           *pipeline.TableFilterConfig `json:"filter"`
+}
+
+func (s *Server) exportTableCSV(w http.ResponseWriter, r *http.Request) error {
+	var req tableExportRequest
+	if err := httpjson.Read(w, r, &req); err != nil {
+		return err
+	}
+	analyzers := make([]*pb.DataTableAnalyzerConfiguration, 0, len(req.Analyzers))
+	for i, a := range req.Analyzers {
+		radix, err := pipeline.ParseRadixType(a.Radix)
+		if err != nil {
+			return httpjson.BadRequest("analyzers[%d]: %v", i, err)
+		}
+		analyzers = append(analyzers, &pb.DataTableAnalyzerConfiguration{AnalyzerId: a.AnalyzerID, RadixType: radix})
+	}
+	var filter *pb.DataTableFilter
+	if req.Filter != nil && strings.TrimSpace(req.Filter.Query) != "" {
+		filter = &pb.DataTableFilter{Query: req.Filter.Query, Columns: req.Filter.Columns}
+	}
+	return s.captureAction(w, r, func(ctx context.Context, id uint64) error {
+		return s.opts.Client.ExportDataTableCsv(ctx, id, req.Filepath, analyzers, req.Iso8601Timestamp, req.Columns, filter)
+	})
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-go-golems/salad/internal/httpjson"
+	"github.com/go-go-golems/salad/internal/jobqueue"
+	mock "github.com/go-go-golems/salad/internal/mock/saleae"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestServer serves the REST API over a connection to an in-process mock Logic 2.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg, err := mock.LoadConfig("../../configs/mock/happy-path.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	writeTable := true
+	cfg.Behavior.ExportDataTableCsv.SideEffect.WritePlaceholderFile = &writeTable
+	plan, err := mock.Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	_, _, listener, cleanup, err := mock.StartMockServer(plan)
+	if err != nil {
+		t.Fatalf("StartMockServer: %v", err)
+	}
+	t.Cleanup(cleanup)
+	_, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	state := shell.NewState()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := saleae.New(ctx, saleae.Config{
+		Host:        "127.0.0.1",
+		Port:        port,
+		Timeout:     5 * time.Second,
+		DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(state.Interceptor())},
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	srv, err := New(Options{Client: c, State: state, Timeout: 5 * time.Second, JobTimeout: 10 * time.Second, JobHistory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Close()
+	})
+	return ts
+}
+
+// call sends a JSON request and decodes a JSON response into out (if non-nil).
+func call(t *testing.T, ts *httptest.Server, method, path string, body any, wantStatus int, out any) {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, r)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, wantStatus, resp.StatusCode, b)
+	}
+	if out != nil {
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, path, b, err)
+		}
+	}
+}
+
+func TestServer_CapturesAnalyzersExports(t *testing.T) {
+	ts := newTestServer(t)
+
+	var info appInfo
+	call(t, ts, "GET", "/v1/info", nil, http.StatusOK, &info)
+	if info.ApplicationVersion != "2.3.56-mock" || info.APIVersion != "1.0.0" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	var devices struct{ Devices []device }
+	call(t, ts, "GET", "/v1/devices", nil, http.StatusOK, &devices)
+	if len(devices.Devices) != 1 || devices.Devices[0].DeviceID != "DEV1" || devices.Devices[0].DeviceType != "LOGIC_PRO_8" {
+		t.Fatalf("unexpected devices %+v", devices)
+	}
+
+	var c capture
+	call(t, ts, "POST", "/v1/captures/load", map[string]any{"filepath": "/tmp/mock.sal"}, http.StatusCreated, &c)
+	if c.CaptureID == 0 || c.Name != "c1" || c.Source != "/tmp/mock.sal" {
+		t.Fatalf("unexpected capture %+v", c)
+	}
+	capturePath := "/v1/captures/" + strconv.FormatUint(c.CaptureID, 10)
+
+	var a analyzer
+	call(t, ts, "POST", capturePath+"/analyzers", map[string]any{
+		"name":     "SPI",
+		"label":    "spi",
+		"settings": map[string]any{"Bits per Transfer": "8 Bits per Transfer (Standard)", "Enable": 0},
+	}, http.StatusCreated, &a)
+	if a.AnalyzerID == 0 || a.Name != "a1" || a.Analyzer != "SPI" || a.Label != "spi" {
+		t.Fatalf("unexpected analyzer %+v", a)
+	}
+	var analyzers struct{ Analyzers []analyzer }
+	call(t, ts, "GET", capturePath+"/analyzers", nil, http.StatusOK, &analyzers)
+	if len(analyzers.Analyzers) != 1 {
+		t.Fatalf("expected 1 analyzer, got %+v", analyzers)
+	}
+
+	rawDir := filepath.Join(t.TempDir(), "raw")
+	call(t, ts, "POST", capturePath+"/exports/raw-csv", map[string]any{"directory": rawDir, "digital": []int{0, 1}}, http.StatusNoContent, nil)
+	if _, err := os.Stat(filepath.Join(rawDir, "digital.csv")); err != nil {
+		t.Fatalf("expected digital.csv: %v", err)
+	}
+	tablePath := filepath.Join(t.TempDir(), "table.csv")
+	call(t, ts, "POST", capturePath+"/exports/table-csv", map[string]any{
+		"filepath":  tablePath,
+		"analyzers": []any{map[string]any{"analyzer_id": a.AnalyzerID, "radix": "hex"}},
+	}, http.StatusNoContent, nil)
+	if _, err := os.Stat(tablePath); err != nil {
+		t.Fatalf("expected table.csv: %v", err)
+	}
+
+	// Request validation.
+	call(t, ts, "POST", "/v1/captures/load", map[string]any{"file": "/tmp/mock.sal"}, http.StatusBadRequest, nil)
+	call(t, ts, "POST", capturePath+"/exports/table-csv", map[string]any{
+		"filepath":  tablePath,
+		"analyzers": []any{map[string]any{"analyzer_id": a.AnalyzerID, "radix": "octal"}},
+	}, http.StatusBadRequest, nil)
+	call(t, ts, "GET", "/v1/captures/x", nil, http.StatusBadRequest, nil)
+
+	call(t, ts, "DELETE", capturePath+"/analyzers/"+strconv.FormatUint(a.AnalyzerID, 10), nil, http.StatusNoContent, nil)
+	call(t, ts, "DELETE", capturePath, nil, http.StatusNoContent, nil)
+	call(t, ts, "GET", capturePath, nil, http.StatusNotFound, nil)
+	var captures struct{ Captures []capture }
+	call(t, ts, "GET", "/v1/captures", nil, http.StatusOK, &captures)
+	if len(captures.Captures) != 0 {
+		t.Fatalf("expected no captures, got %+v", captures)
+	}
+}
+
+func TestServer_PipelineJobAndArtifacts(t *testing.T) {
+	ts := newTestServer(t)
+	out := t.TempDir()
+	config := map[string]any{
+		"capture":   map[string]any{"load": map[string]any{"filepath": "/tmp/mock.sal"}},
+		"analyzers": []any{map[string]any{"name": "SPI", "label": "spi"}},
+		"exports": []any{
+			map[string]any{"type": "raw-csv", "directory": filepath.Join(out, "raw"), "digital": []int{0}},
+			map[string]any{"type": "table-csv", "filepath": filepath.Join(out, "table.csv"), "analyzers": []any{map[string]any{"ref": "spi", "radix": "hex"}}},
+		},
+	}
+
+	// Files that the export did not write are never published.
+	if err := os.MkdirAll(filepath.Join(out, "raw"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "raw", "id_rsa"), []byte("secret"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var j jobqueue.Job
+	call(t, ts, "POST", "/v1/jobs", map[string]any{"config": config, "priority": 2, "devices": []string{"DEV1"}}, http.StatusAccepted, &j)
+	jobPath := "/v1/jobs/" + strconv.FormatUint(j.ID, 10)
+	j = waitJob(t, ts, j.ID)
+	if j.Status != jobqueue.Succeeded || j.Analyzers["spi"] == 0 || j.Finished == nil || j.Priority != 2 {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	names := map[string]bool{}
+	for _, a := range j.Artifacts {
+		names[a.Name] = true
+	}
+	if len(j.Artifacts) != 2 || !names["raw/digital.csv"] || !names["table.csv"] {
+		t.Fatalf("unexpected artifacts %+v", j.Artifacts)
+	}
+
+	resp, err := ts.Client().Get(ts.URL + jobPath + "/artifacts/0")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	want, _ := os.ReadFile(j.Artifacts[0].Path)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, want) {
+		t.Fatalf("download: status %d, expected %q, got %q", resp.StatusCode, want, got)
+	}
+	call(t, ts, "GET", jobPath+"/artifacts/99", nil, http.StatusNotFound, nil)
+
+	resp, err = ts.Client().Get(ts.URL + jobPath + "/artifacts.zip")
+	if err != nil {
+		t.Fatalf("download zip: %v", err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	if len(zr.File) != len(j.Artifacts) {
+		t.Fatalf("expected %d zip entries, got %d", len(j.Artifacts), len(zr.File))
+	}
+
+	// A failing pipeline reports its error; invalid configs are rejected up front, and
+	// config_path is refused without a config directory.
+	call(t, ts, "POST", "/v1/jobs", map[string]any{"config": map[string]any{"bogus": 1}}, http.StatusBadRequest, nil)
+	call(t, ts, "POST", "/v1/jobs", map[string]any{"config_path": "/etc/pipeline.yaml"}, http.StatusBadRequest, nil)
+	call(t, ts, "POST", "/v1/jobs", map[string]any{"config": map[string]any{
+		"capture":   map[string]any{"load": map[string]any{"filepath": "/tmp/mock.sal"}},
+		"analyzers": []any{map[string]any{"label": "nameless"}},
+	}}, http.StatusAccepted, &j)
+	jobPath = "/v1/jobs/" + strconv.FormatUint(j.ID, 10)
+	j = waitJob(t, ts, j.ID)
+	if j.Status != jobqueue.Failed || !strings.Contains(j.Error, "name is required") {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	call(t, ts, "GET", jobPath+"/artifacts.zip", nil, http.StatusConflict, nil)
+	call(t, ts, "POST", jobPath+"/cancel", nil, http.StatusConflict, nil)
+}
+
+// waitJob follows a job's log through the Go client until the job is done.
+func waitJob(t *testing.T, ts *httptest.Server, id uint64) jobqueue.Job {
+	t.Helper()
+	c := NewClient(ts.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var lines []string
+	for since := 0; ; {
+		l, err := c.JobLog(ctx, id, since)
+		if err != nil {
+			t.Fatalf("JobLog: %v", err)
+		}
+		lines, since = append(lines, l.Lines...), l.Next
+		if l.Status.Done() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	j, err := c.Job(ctx, id)
+	if err != nil {
+		t.Fatalf("Job: %v", err)
+	}
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], "queued with priority "+strconv.Itoa(j.Priority)) || !strings.Contains(lines[len(lines)-1], string(j.Status)) {
+		t.Fatalf("unexpected log of job %d:\n%s", id, strings.Join(lines, "\n"))
+	}
+	return j
+}
+
+func TestOpenAPI_DescribesEveryRoute(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatalf("decode openapi.json: %v", err)
+	}
+	routes := (&Server{}).routes()
+	ops := 0
+	for _, rt := range routes {
+		if _, ok := doc.Paths[rt.pattern][strings.ToLower(rt.method)]; !ok {
+			t.Fatalf("openapi.json does not describe %s %s", rt.method, rt.pattern)
+		}
+	}
+	for _, item := range doc.Paths {
+		for method := range item {
+			if method != "parameters" {
+				ops++
+			}
+		}
+	}
+	if ops != len(routes)+1 { // + GET /openapi.json
+		t.Fatalf("openapi.json describes %d operations, expected %d", ops, len(routes)+1)
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	for err, want := range map[error]int{
+		errors.Wrap(status.Error(codes.NotFound, "no capture"), "CloseCapture RPC"): http.StatusNotFound,
+		status.Error(codes.Unavailable, "down"):                                     http.StatusServiceUnavailable,
+		status.Error(codes.Internal, "boom"):                                        http.StatusBadGateway,
+		errors.New("LoadCapture: filepath is required"):                             http.StatusBadRequest,
+		httpjson.NotFound("job %d not found", 3):                                    http.StatusNotFound,
+	} {
+		if got := statusOf(err); got != want {
+			t.Fatalf("statusOf(%v): expected %d, got %d", err, want, got)
+		}
+	}
+}
+
+func TestListen_RefusesPublicAddresses(t *testing.T) {
+	ctx := context.Background()
+	for _, addr := range []string{"8.8.8.8:0", "[2001:4860:4860::8888]:0"} {
+		if _, err := Listen(ctx, addr); err == nil || !strings.Contains(err.Error(), "refusing") {
+			t.Fatalf("Listen(%s): expected refusal, got %v", addr, err)
+		}
+	}
+	listeners, err := Listen(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen(127.0.0.1:0): %v", err)
+	}
+	for _, l := range listeners {
+		_ = l.Close()
+	}
+}
@@ -0,0 +1,281 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	saladconfig "github.com/go-go-golems/salad/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// KnownAnalyzers are Logic 2's built-in low level analyzer names, offered when
+// completing `analyzer add --name`.
+var KnownAnalyzers = []string{
+	"1-Wire",
+	"Async Serial",
+	"CAN",
+	"DMX-512",
+	"HDLC",
+	"I2C",
+	"I2S / PCM",
+	"JTAG",
+	"LIN",
+	"MDIO",
+	"MIDI",
+	"Manchester",
+	"Modbus",
+	"SMBus",
+	"SPI",
+	"SWD",
+	"Simple Parallel",
+	"USB LS and FS",
+}
+
+// Completer completes shell lines against a cobra command tree.
+type Completer struct {
+	Root  *cobra.Command
+	State *State
+	// Builtins are the shell's own commands, completed as the first word.
+	Builtins []string
+	// Templates is a directory of analyzer settings templates; their keys complete
+	// --set* values when the line names no --settings-yaml/--settings-json file.
+	Templates string
+}
+
+// Complete has the signature of liner's WordCompleter: the word under the cursor is
+// replaced by one of the completions, head and tail are kept.
+func (c *Completer) Complete(line string, pos int) (head string, completions []string, tail string) {
+	if pos > len(line) {
+		pos = len(line)
+	}
+	before, tail := line[:pos], line[pos:]
+	words, open, err := lex(before, nil)
+	if err != nil {
+		return before, nil, tail
+	}
+
+	partial, start := "", len(before)
+	if n := len(words); n > 0 && (open != 0 || !endsInSpace(before)) {
+		partial, start = words[n-1].text, words[n-1].start
+		words = words[:n-1]
+	}
+	head = before[:start]
+
+	args := make([]string, len(words))
+	for i, w := range words {
+		args[i] = w.text
+	}
+	cmd, pending, flags := c.resolve(args)
+
+	var candidates []string
+	prefix := ""
+	switch {
+	case pending != nil:
+		candidates = c.values(cmd, pending, flags, partial)
+	case strings.HasPrefix(partial, "--") && strings.Contains(partial, "="):
+		name, value, _ := strings.Cut(partial[2:], "=")
+		if f := lookupFlag(cmd, name); f != nil {
+			prefix, partial = "--"+name+"=", value
+			candidates = c.values(cmd, f, flags, value)
+		}
+	case strings.HasPrefix(partial, "-"):
+		candidates = flagNames(cmd)
+	case len(args) == 0 || cmd.HasAvailableSubCommands():
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() {
+				candidates = append(candidates, sub.Name())
+			}
+		}
+		if cmd == c.Root {
+			candidates = append(candidates, c.Builtins...)
+		}
+	default:
+		candidates = paths(partial)
+	}
+
+	sort.Strings(candidates)
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, partial) {
+			if !strings.HasPrefix(cand, "$") { // $name references are generated unquoted
+				cand = Quote(cand)
+			}
+			completions = append(completions, prefix+cand)
+		}
+	}
+	return head, completions, tail
+}
+
+func endsInSpace(s string) bool {
+	n := len(s)
+	if n == 0 {
+		return true
+	}
+	if !strings.ContainsRune(" \t", rune(s[n-1])) {
+		return false
+	}
+	return n < 2 || s[n-2] != '\\'
+}
+
+// resolve walks args down the command tree. It returns the command, the flag still
+// waiting for its value (if the last word was such a flag), and the flag values seen.
+func (c *Completer) resolve(args []string) (cmd *cobra.Command, pending *pflag.Flag, flags map[string]string) {
+	cmd, flags = c.Root, map[string]string{}
+	for _, a := range args {
+		if pending != nil {
+			flags[pending.Name] = a
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(a, "-") {
+			name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+			f := lookupFlag(cmd, name)
+			switch {
+			case f == nil:
+			case hasValue:
+				flags[f.Name] = value
+			case f.NoOptDefVal == "":
+				pending = f
+			}
+			continue
+		}
+		for _, sub := range cmd.Commands() {
+			if sub.Name() == a || sub.HasAlias(a) {
+				cmd = sub
+				break
+			}
+		}
+	}
+	return cmd, pending, flags
+}
+
+func lookupFlag(cmd *cobra.Command, name string) *pflag.Flag {
+	for c := cmd; c != nil; c = c.Parent() {
+		fs := []*pflag.FlagSet{c.PersistentFlags()}
+		if c == cmd {
+			fs = append(fs, c.Flags())
+		}
+		for _, set := range fs {
+			if f := set.Lookup(name); f != nil {
+				return f
+			}
+			if len(name) == 1 {
+				if f := set.ShorthandLookup(name); f != nil {
+					return f
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func flagNames(cmd *cobra.Command) []string {
+	var out []string
+	add := func(f *pflag.Flag) {
+		if !f.Hidden {
+			out = append(out, "--"+f.Name)
+		}
+	}
+	cmd.LocalFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+	return out
+}
+
+// values returns the candidate values of flag f.
+func (c *Completer) values(cmd *cobra.Command, f *pflag.Flag, flags map[string]string, partial string) []string {
+	if fn, ok := cmd.GetFlagCompletionFunc(f.Name); ok {
+		comps, _ := fn(cmd, nil, partial)
+		out := make([]string, 0, len(comps))
+		for _, comp := range comps {
+			value, _, _ := strings.Cut(string(comp), "\t")
+			out = append(out, value)
+		}
+		return out
+	}
+
+	switch f.Name {
+	case "capture-id":
+		return refs(c.State.CaptureNames())
+	case "analyzer-id":
+		return refs(c.State.AnalyzerNames())
+	case "analyzer":
+		// export table selectors: <id>:<radix>
+		var out []string
+		for _, name := range refs(c.State.AnalyzerNames()) {
+			for _, radix := range []string{"hex", "dec", "bin", "ascii"} {
+				out = append(out, name+":"+radix)
+			}
+		}
+		return out
+	case "set", "set-bool", "set-int", "set-float":
+		keys := c.templateKeys(flags)
+		out := make([]string, len(keys))
+		for i, k := range keys {
+			out[i] = k + "="
+		}
+		return out
+	}
+	if f.Value.Type() == "string" {
+		return paths(partial)
+	}
+	return nil
+}
+
+func refs(names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = "$" + n
+	}
+	return out
+}
+
+// templateKeys returns the setting keys of the settings file on the line, or of all
+// templates if there is none.
+func (c *Completer) templateKeys(flags map[string]string) []string {
+	files := []string{flags["settings-yaml"], flags["settings-json"]}
+	if files[0] == "" && files[1] == "" && c.Templates != "" {
+		files, _ = filepath.Glob(filepath.Join(c.Templates, "*.yaml"))
+	}
+	seen := map[string]bool{}
+	var keys []string
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		settings, err := saladconfig.LoadAnalyzerSettings(path)
+		if err != nil {
+			continue
+		}
+		for k := range settings {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paths completes file names; directories end in a slash.
+func paths(partial string) []string {
+	matches, _ := filepath.Glob(globEscape(partial) + "*")
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if strings.HasPrefix(filepath.Base(m), ".") && !strings.HasPrefix(filepath.Base(partial), ".") {
+			continue
+		}
+		if st, err := os.Stat(m); err == nil && st.IsDir() {
+			m += string(filepath.Separator)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func globEscape(s string) string {
+	r := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`)
+	return r.Replace(s)
+}
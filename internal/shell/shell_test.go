@@ -0,0 +1,154 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/spf13/cobra"
+)
+
+func TestSplit(t *testing.T) {
+	vars := map[string]string{"c1": "7", "spi": "10001"}
+	expand := func(name string) (string, error) {
+		if v, ok := vars[name]; ok {
+			return v, nil
+		}
+		return "", os.ErrNotExist
+	}
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{`analyzer add --name "Async Serial"  --capture-id $c1`, []string{"analyzer", "add", "--name", "Async Serial", "--capture-id", "7"}},
+		{`export table --analyzer ${spi}:hex`, []string{"export", "table", "--analyzer", "10001:hex"}},
+		{`echo '$c1' "$c1" \$c1 a\ b ""`, []string{"echo", "$c1", "7", "$c1", "a b", ""}},
+		{`echo "say \"hi\" \n" $ $:`, []string{"echo", `say "hi" \n`, "$", "$:"}},
+	}
+	for _, tc := range cases {
+		got, err := Split(tc.line, expand)
+		if err != nil {
+			t.Fatalf("Split(%q): %v", tc.line, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Split(%q): expected %q, got %q", tc.line, tc.want, got)
+		}
+	}
+
+	if _, err := Split(`echo "open`, expand); err == nil {
+		t.Fatalf("expected an error for an unterminated quote")
+	}
+	if _, err := Split(`echo $nope`, expand); err == nil {
+		t.Fatalf("expected an error for an unknown name")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{"SPI", "Async Serial", `a"b`, "$x", ""} {
+		got, err := Split(Quote(s), nil)
+		if err != nil || len(got) != 1 || got[0] != s {
+			t.Fatalf("Quote(%q)=%q splits to %q (%v)", s, Quote(s), got, err)
+		}
+	}
+}
+
+func newTestState() *State {
+	s := NewState()
+	s.Observe(&pb.LoadCaptureRequest{Filepath: "/tmp/a.sal"}, &pb.LoadCaptureReply{CaptureInfo: &pb.CaptureInfo{CaptureId: 7}})
+	s.Observe(&pb.StartCaptureRequest{}, &pb.StartCaptureReply{CaptureInfo: &pb.CaptureInfo{CaptureId: 9}})
+	s.Observe(&pb.AddAnalyzerRequest{CaptureId: 7, AnalyzerName: "SPI", AnalyzerLabel: "spi"}, &pb.AddAnalyzerReply{AnalyzerId: 100})
+	s.Observe(&pb.AddAnalyzerRequest{CaptureId: 9, AnalyzerName: "I2C"}, &pb.AddAnalyzerReply{AnalyzerId: 101})
+	return s
+}
+
+func TestState(t *testing.T) {
+	s := newTestState()
+	for name, want := range map[string]uint64{"c": 9, "c1": 7, "c2": 9, "a1": 100, "spi": 100, "a2": 101, "42": 42} {
+		if got, ok := s.Lookup(name); !ok || got != want {
+			t.Fatalf("Lookup(%q): expected %d, got %d (%v)", name, want, got, ok)
+		}
+	}
+	if _, ok := s.Lookup("a3"); ok {
+		t.Fatalf("expected a3 to be unknown")
+	}
+	if got := s.AnalyzerNames(); !reflect.DeepEqual(got, []string{"a1", "a2", "spi"}) {
+		t.Fatalf("unexpected analyzer names %q", got)
+	}
+
+	if err := s.Use("c1"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if s.Current() != 7 {
+		t.Fatalf("expected current capture 7, got %d", s.Current())
+	}
+	if err := s.Use("a1"); err == nil {
+		t.Fatalf("expected Use of an analyzer to fail")
+	}
+
+	// Closing a capture drops its analyzers; names are not reused.
+	s.Observe(&pb.CloseCaptureRequest{CaptureId: 7}, &pb.CloseCaptureReply{})
+	if s.Current() != 9 {
+		t.Fatalf("expected current capture 9 after close, got %d", s.Current())
+	}
+	if got := s.Analyzers(); len(got) != 1 || got[0].Name != "a2" {
+		t.Fatalf("unexpected analyzers after close: %+v", got)
+	}
+	s.Observe(&pb.LoadCaptureRequest{Filepath: "/tmp/b.sal"}, &pb.LoadCaptureReply{CaptureInfo: &pb.CaptureInfo{CaptureId: 11}})
+	if got := s.CaptureNames(); !reflect.DeepEqual(got, []string{"c", "c2", "c3"}) {
+		t.Fatalf("unexpected capture names %q", got)
+	}
+	s.Observe(&pb.RemoveAnalyzerRequest{CaptureId: 9, AnalyzerId: 101}, &pb.RemoveAnalyzerReply{})
+	if got := s.Analyzers(); len(got) != 0 {
+		t.Fatalf("expected no analyzers, got %+v", got)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "spi.yaml"), []byte("settings:\n  Clock: 0\n  MOSI: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "uart.yaml"), []byte("Bit Rate (Bits/s): 115200\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := &cobra.Command{Use: "salad"}
+	root.PersistentFlags().Int("port", 0, "")
+	analyzer := &cobra.Command{Use: "analyzer"}
+	add := &cobra.Command{Use: "add", Run: func(*cobra.Command, []string) {}}
+	add.Flags().Uint64("capture-id", 0, "")
+	add.Flags().String("name", "", "")
+	add.Flags().String("settings-yaml", "", "")
+	add.Flags().StringArray("set-int", nil, "")
+	add.Flags().Bool("verbose", false, "")
+	_ = add.RegisterFlagCompletionFunc("name", cobra.FixedCompletions(KnownAnalyzers, cobra.ShellCompDirectiveNoFileComp))
+	analyzer.AddCommand(add)
+	root.AddCommand(analyzer, &cobra.Command{Use: "appinfo", Run: func(*cobra.Command, []string) {}})
+
+	c := &Completer{Root: root, State: newTestState(), Builtins: []string{"analyzers", "captures", "exit"}, Templates: dir}
+	cases := []struct {
+		line     string
+		wantHead string
+		want     []string
+	}{
+		{"a", "", []string{"analyzer", "analyzers", "appinfo"}},
+		{"analyzer ", "analyzer ", []string{"add"}},
+		{"analyzer add --c", "analyzer add ", []string{"--capture-id"}},
+		{"analyzer add --p", "analyzer add ", []string{"--port"}},
+		{"analyzer add --capture-id ", "analyzer add --capture-id ", []string{"$c", "$c1", "$c2"}},
+		{"analyzer add --capture-id=$c", "analyzer add ", []string{"--capture-id=$c", "--capture-id=$c1", "--capture-id=$c2"}},
+		{"analyzer add --verbose --name As", "analyzer add --verbose --name ", []string{`"Async Serial"`}},
+		{`analyzer add --name "Asy`, "analyzer add --name ", []string{`"Async Serial"`}},
+		{"analyzer add --set-int ", "analyzer add --set-int ", []string{`"Bit Rate (Bits/s)="`, "Clock=", "MOSI="}},
+		{"analyzer add --settings-yaml " + filepath.Join(dir, "spi.yaml") + " --set-int ", "analyzer add --settings-yaml " + filepath.Join(dir, "spi.yaml") + " --set-int ", []string{"Clock=", "MOSI="}},
+		{"analyzer add --settings-yaml " + filepath.Join(dir, "sp"), "analyzer add --settings-yaml ", []string{filepath.Join(dir, "spi.yaml")}},
+	}
+	for _, tc := range cases {
+		head, got, tail := c.Complete(tc.line+"|tail", len(tc.line))
+		if head != tc.wantHead || tail != "|tail" || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Complete(%q): expected head %q and %q, got head %q, %q, tail %q", tc.line, tc.wantHead, tc.want, head, got, tail)
+		}
+	}
+}
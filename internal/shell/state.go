@@ -0,0 +1,239 @@
+// Package shell holds the pieces of `salad shell` that do not depend on the terminal:
+// tracking of the captures and analyzers opened through the shell's connection, shell
+// word splitting with $name expansion, and tab completion over the cobra command tree.
+package shell
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// Capture is a capture opened through the shell.
+type Capture struct {
+	Name string // short name: c1, c2, ...
+	ID   uint64
+	// Source is the loaded .sal file, or "live" for StartCapture.
+	Source string
+}
+
+// Analyzer is an analyzer added through the shell.
+type Analyzer struct {
+	Name      string // short name: a1, a2, ...
+	ID        uint64
+	CaptureID uint64
+	Analyzer  string // analyzer (or HLA) name, e.g. "SPI"
+	Label     string
+}
+
+// State tracks captures and analyzers by short names. It learns about them by
+// observing RPCs (see Interceptor), so every command run in the shell contributes,
+// including pipelines.
+type State struct {
+	mu        sync.Mutex
+	captures  []Capture
+	analyzers []Analyzer
+	current   uint64
+	nextC     int
+	nextA     int
+}
+
+// NewState returns an empty State.
+func NewState() *State {
+	return &State{}
+}
+
+// Interceptor returns a gRPC client interceptor that records successful capture and
+// analyzer RPCs.
+func (s *State) Interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		s.Observe(req, reply)
+		return nil
+	}
+}
+
+// Observe records the effect of a successful RPC.
+func (s *State) Observe(req, reply any) {
+	switch r := req.(type) {
+	case *pb.LoadCaptureRequest:
+		if rep, ok := reply.(*pb.LoadCaptureReply); ok {
+			s.addCapture(rep.GetCaptureInfo().GetCaptureId(), r.GetFilepath())
+		}
+	case *pb.StartCaptureRequest:
+		if rep, ok := reply.(*pb.StartCaptureReply); ok {
+			s.addCapture(rep.GetCaptureInfo().GetCaptureId(), "live")
+		}
+	case *pb.CloseCaptureRequest:
+		s.removeCapture(r.GetCaptureId())
+	case *pb.AddAnalyzerRequest:
+		if rep, ok := reply.(*pb.AddAnalyzerReply); ok {
+			s.addAnalyzer(rep.GetAnalyzerId(), r.GetCaptureId(), r.GetAnalyzerName(), r.GetAnalyzerLabel())
+		}
+	case *pb.AddHighLevelAnalyzerRequest:
+		if rep, ok := reply.(*pb.AddHighLevelAnalyzerReply); ok {
+			s.addAnalyzer(rep.GetAnalyzerId(), r.GetCaptureId(), r.GetHlaName(), r.GetHlaLabel())
+		}
+	case *pb.RemoveAnalyzerRequest:
+		s.removeAnalyzer(r.GetAnalyzerId())
+	case *pb.RemoveHighLevelAnalyzerRequest:
+		s.removeAnalyzer(r.GetAnalyzerId())
+	}
+}
+
+func (s *State) addCapture(id uint64, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextC++
+	s.captures = append(s.captures, Capture{Name: fmt.Sprintf("c%d", s.nextC), ID: id, Source: source})
+	s.current = id
+}
+
+func (s *State) removeCapture(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.captures = slices.DeleteFunc(s.captures, func(c Capture) bool { return c.ID == id })
+	s.analyzers = slices.DeleteFunc(s.analyzers, func(a Analyzer) bool { return a.CaptureID == id })
+	if s.current == id {
+		s.current = 0
+		if n := len(s.captures); n > 0 {
+			s.current = s.captures[n-1].ID
+		}
+	}
+}
+
+func (s *State) addAnalyzer(id, captureID uint64, name, label string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextA++
+	s.analyzers = append(s.analyzers, Analyzer{
+		Name:      fmt.Sprintf("a%d", s.nextA),
+		ID:        id,
+		CaptureID: captureID,
+		Analyzer:  name,
+		Label:     label,
+	})
+}
+
+func (s *State) removeAnalyzer(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.analyzers = slices.DeleteFunc(s.analyzers, func(a Analyzer) bool { return a.ID == id })
+}
+
+// Captures returns the open captures in the order they were opened.
+func (s *State) Captures() []Capture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Capture(nil), s.captures...)
+}
+
+// Analyzers returns the analyzers in the order they were added.
+func (s *State) Analyzers() []Analyzer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Analyzer(nil), s.analyzers...)
+}
+
+// Current returns the current capture ID (the most recently opened one unless
+// changed with Use), or 0 if no capture is open.
+func (s *State) Current() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// Use makes the capture named ref (short name or ID) current.
+func (s *State) Use(ref string) error {
+	id, ok := s.Lookup(ref)
+	if !ok {
+		return errors.Errorf("unknown capture %q", ref)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.captures {
+		if c.ID == id {
+			s.current = id
+			return nil
+		}
+	}
+	return errors.Errorf("%q is not an open capture", ref)
+}
+
+// Lookup resolves a name to an ID: "c" is the current capture, cN and aN are short
+// names, analyzer labels refer to their analyzer, and a number is taken as is.
+func (s *State) Lookup(name string) (uint64, bool) {
+	if id, err := strconv.ParseUint(name, 10, 64); err == nil {
+		return id, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "c" {
+		return s.current, s.current != 0
+	}
+	for _, c := range s.captures {
+		if c.Name == name {
+			return c.ID, true
+		}
+	}
+	for _, a := range s.analyzers {
+		if a.Name == name {
+			return a.ID, true
+		}
+	}
+	for _, a := range s.analyzers {
+		if a.Label != "" && a.Label == name {
+			return a.ID, true
+		}
+	}
+	return 0, false
+}
+
+// CaptureNames returns the names Lookup accepts for captures, sorted.
+func (s *State) CaptureNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	if s.current != 0 {
+		out = append(out, "c")
+	}
+	for _, c := range s.captures {
+		out = append(out, c.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// AnalyzerNames returns the names Lookup accepts for analyzers (short names and
+// labels that need no quoting), sorted.
+func (s *State) AnalyzerNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, a := range s.analyzers {
+		out = append(out, a.Name)
+		if a.Label != "" && isName(a.Label) {
+			out = append(out, a.Label)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Expand resolves a $name reference for Split.
+func (s *State) Expand(name string) (string, error) {
+	id, ok := s.Lookup(name)
+	if !ok {
+		return "", errors.Errorf("unknown name $%s (see `captures` and `analyzers`)", name)
+	}
+	return strconv.FormatUint(id, 10), nil
+}
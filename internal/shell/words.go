@@ -0,0 +1,154 @@
+package shell
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// word is a shell word: its text after quote removal (and expansion, if enabled) and
+// where it starts in the line.
+type word struct {
+	text  string
+	start int
+}
+
+// Split splits line into words like a POSIX shell: whitespace separates words, single
+// quotes are literal, double quotes and backslashes escape, and $name or ${name}
+// outside single quotes is replaced by expand(name). A nil expand keeps references
+// as typed.
+func Split(line string, expand func(string) (string, error)) ([]string, error) {
+	words, open, err := lex(line, expand)
+	if err != nil {
+		return nil, err
+	}
+	if open != 0 {
+		return nil, errors.Errorf("unterminated %c quote", open)
+	}
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = w.text
+	}
+	return out, nil
+}
+
+// lex splits line into words. open is the quote character still open at the end of
+// the line (0 if none); a trailing unterminated word is returned as is, which is what
+// completion wants.
+func lex(line string, expand func(string) (string, error)) (words []word, open rune, err error) {
+	var (
+		cur     strings.Builder
+		inWord  bool
+		start   int
+		escaped bool
+	)
+	flush := func() {
+		if inWord {
+			words = append(words, word{text: cur.String(), start: start})
+		}
+		cur.Reset()
+		inWord = false
+	}
+	begin := func(i int) {
+		if !inWord {
+			inWord, start = true, i
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case escaped:
+			// Inside double quotes a backslash only escapes ", \ and $.
+			if open == '"' && ch != '"' && ch != '\\' && ch != '$' {
+				cur.WriteByte('\\')
+			}
+			cur.WriteByte(ch)
+			escaped = false
+		case open == '\'':
+			if ch == '\'' {
+				open = 0
+			} else {
+				cur.WriteByte(ch)
+			}
+		case ch == '\\':
+			begin(i)
+			escaped = true
+		case ch == '$' && expand != nil:
+			name, n := refName(line[i+1:])
+			if n == 0 {
+				begin(i)
+				cur.WriteByte(ch)
+				continue
+			}
+			begin(i)
+			v, err := expand(name)
+			if err != nil {
+				return nil, 0, err
+			}
+			cur.WriteString(v)
+			i += n
+		case open == '"':
+			if ch == '"' {
+				open = 0
+			} else {
+				cur.WriteByte(ch)
+			}
+		case ch == '"' || ch == '\'':
+			begin(i)
+			open = rune(ch)
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			flush()
+		default:
+			begin(i)
+			cur.WriteByte(ch)
+		}
+	}
+	if escaped {
+		cur.WriteByte('\\')
+	}
+	flush()
+	return words, open, nil
+}
+
+// refName parses the name after a '$' (either "name" or "{name}") and returns it with
+// the number of bytes consumed, or n=0 if s does not start with a name.
+func refName(s string) (string, int) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end <= 1 || !isName(s[1:end]) {
+			return "", 0
+		}
+		return s[1:end], end + 1
+	}
+	n := 0
+	for n < len(s) && isNameByte(s[n]) {
+		n++
+	}
+	return s[:n], n
+}
+
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Quote returns s as a single shell word, quoting it only if needed.
+func Quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\"'\\$") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + r.Replace(s) + `"`
+}
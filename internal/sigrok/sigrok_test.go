@@ -0,0 +1,120 @@
+package sigrok
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/salad/internal/rawdata"
+)
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) []byte {
+	t.Helper()
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer func() { _ = f.Close() }()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return b
+}
+
+func TestConvert_LogicAndAnalog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, rawdata.DigitalCSVFilename), []byte(`Time [s],Channel 0,Channel 1
+0.0,0,1
+0.000002,1,1
+0.000003,1,0
+0.000004,0,0
+`), 0o644); err != nil {
+		t.Fatalf("write digital.csv: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, rawdata.AnalogCSVFilename), []byte(`Time [s],Channel 2
+0.0,0.5
+0.000002,1.5
+`), 0o644); err != nil {
+		t.Fatalf("write analog.csv: %v", err)
+	}
+	export, err := rawdata.OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+
+	if _, err := Convert(&bytes.Buffer{}, ConvertOptions{Digital: export.Digital}); err == nil || !strings.Contains(err.Error(), "sample rate") {
+		t.Fatalf("expected sample rate error for CSV export, got %v", err)
+	}
+
+	var out bytes.Buffer
+	res, err := Convert(&out, ConvertOptions{
+		SampleRate: 1_000_000,
+		Digital:    export.Digital,
+		Analog:     export.Analog,
+		Names:      map[string]string{"Channel 0": "CLK"},
+	})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	if res.LogicSamples != 4 || len(res.AnalogSamples) != 1 || res.AnalogSamples[0] != 3 {
+		t.Fatalf("result: expected 4 logic / [3] analog samples, got %+v", res)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	if got := string(readZipFile(t, zr, "version")); got != "2" {
+		t.Fatalf("version: expected 2, got %q", got)
+	}
+	meta := string(readZipFile(t, zr, "metadata"))
+	for _, want := range []string{
+		"capturefile=logic-1\n",
+		"total probes=2\n",
+		"samplerate=1 MHz\n",
+		"total analog=1\n",
+		"probe1=CLK\n",
+		"probe2=Channel 1\n",
+		"analog3=Channel 2\n",
+		"unitsize=1\n",
+	} {
+		if !strings.Contains(meta, want) {
+			t.Fatalf("metadata: expected %q in:\n%s", want, meta)
+		}
+	}
+
+	// Samples at 0,1,2,3 us: CLK 0,0,1,1; Channel 1 1,1,1,0.
+	if got := readZipFile(t, zr, "logic-1-1"); !bytes.Equal(got, []byte{0b10, 0b10, 0b11, 0b01}) {
+		t.Fatalf("logic-1-1: expected [2 2 3 1], got %v", got)
+	}
+	analog := readZipFile(t, zr, "analog-1-3-1")
+	var values []float32
+	for i := 0; i+4 <= len(analog); i += 4 {
+		values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(analog[i:])))
+	}
+	if len(values) != 3 || values[0] != 0.5 || values[1] != 0.5 || values[2] != 1.5 {
+		t.Fatalf("analog-1-3-1: expected [0.5 0.5 1.5], got %v", values)
+	}
+}
+
+func TestFormatSampleRate(t *testing.T) {
+	for rate, want := range map[uint64]string{
+		500_000_000:   "500 MHz",
+		2_000_000:     "2 MHz",
+		1_000_000:     "1 MHz",
+		3_125_000:     "3125 kHz",
+		12345:         "12345 Hz",
+		1_000_000_000: "1 GHz",
+	} {
+		if got := FormatSampleRate(rate); got != want {
+			t.Fatalf("FormatSampleRate(%d): expected %q, got %q", rate, want, got)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package tableconv
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+)
+
+// Kind is the inferred type of a data table column.
+type Kind string
+
+const (
+	// KindName is the analyzer label column.
+	KindName Kind = "name"
+	// KindTime holds seconds (start_time, duration).
+	KindTime    Kind = "time"
+	KindInteger Kind = "integer"
+	KindFloat   Kind = "float"
+	KindBool    Kind = "bool"
+	KindText    Kind = "text"
+)
+
+// Radix names match the pipeline/CLI radix names.
+const (
+	RadixHex   = "hex"
+	RadixDec   = "dec"
+	RadixBin   = "bin"
+	RadixASCII = "ascii"
+)
+
+// StartTimeISOColumn holds the original timestamp for ISO8601 tables, whose start_time
+// column is converted to seconds relative to the first row.
+const StartTimeISOColumn = "start_time_iso"
+
+// Column describes one output column.
+type Column struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+	// Radix is the display radix integer values were written in (hex, dec, bin), or
+	// ascii for text columns exported with the ascii radix.
+	Radix string `json:"radix,omitempty"`
+	// index is the position in the CSV header, or -1 for derived columns.
+	index int
+}
+
+// candidate tracks which kinds are still consistent with every value seen.
+type candidate struct {
+	seen  bool
+	hex   bool
+	bin   bool
+	dec   bool
+	float bool
+	bool  bool
+}
+
+func newCandidate() *candidate {
+	return &candidate{hex: true, bin: true, dec: true, float: true, bool: true}
+}
+
+func (c *candidate) observe(v string) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return
+	}
+	c.seen = true
+	if c.hex {
+		_, ok := parseRadix(v, RadixHex)
+		c.hex = ok
+	}
+	if c.bin {
+		_, ok := parseRadix(v, RadixBin)
+		c.bin = ok
+	}
+	if c.dec {
+		_, ok := parseRadix(v, RadixDec)
+		c.dec = ok
+	}
+	if c.float {
+		_, err := strconv.ParseFloat(v, 64)
+		c.float = err == nil
+	}
+	if c.bool {
+		c.bool = v == "true" || v == "false"
+	}
+}
+
+func (c *candidate) column(name string, index int, radixHint string) Column {
+	col := Column{Name: name, index: index, Kind: KindText}
+	switch {
+	case !c.seen:
+	case c.bool:
+		col.Kind = KindBool
+	case c.hex:
+		col.Kind, col.Radix = KindInteger, RadixHex
+	case c.bin:
+		col.Kind, col.Radix = KindInteger, RadixBin
+	case c.dec:
+		col.Kind, col.Radix = KindInteger, RadixDec
+	case c.float:
+		col.Kind = KindFloat
+	}
+	if col.Kind == KindText && radixHint == RadixASCII {
+		col.Radix = RadixASCII
+	}
+	return col
+}
+
+// parseRadix parses an integer written in radix ("0x" / "0b" prefixed for hex / bin).
+// Values must fit in an int64 so they can be stored natively.
+func parseRadix(v string, radix string) (int64, bool) {
+	var u uint64
+	var err error
+	switch radix {
+	case RadixHex:
+		if !strings.HasPrefix(v, "0x") && !strings.HasPrefix(v, "0X") {
+			return 0, false
+		}
+		u, err = strconv.ParseUint(v[2:], 16, 64)
+	case RadixBin:
+		if !strings.HasPrefix(v, "0b") && !strings.HasPrefix(v, "0B") {
+			return 0, false
+		}
+		u, err = strconv.ParseUint(v[2:], 2, 64)
+	case RadixDec:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+	if err != nil || u > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(u), true
+}
+
+// Infer scans the table once and returns the typed output columns: the fixed columns,
+// start_time_iso for ISO8601 tables, then every analyzer field column.
+// radixHint is the radix the table was exported with, if known and uniform.
+func Infer(table *datatable.Table, radixHint string) ([]Column, int, error) {
+	fields := table.FieldColumns()
+	cands := make([]*candidate, len(fields))
+	idx := make([]int, len(fields))
+	for i, f := range fields {
+		cands[i] = newCandidate()
+		idx[i], _ = table.Index(f)
+	}
+
+	rows := 0
+	for row, err := range table.Rows() {
+		if err != nil {
+			return nil, 0, err
+		}
+		rows++
+		for i := range fields {
+			cands[i].observe(row.Values[idx[i]])
+		}
+	}
+
+	cols := make([]Column, 0, len(fields)+5)
+	for _, name := range datatable.FixedColumns {
+		i, _ := table.Index(name)
+		kind := KindText
+		switch name {
+		case datatable.ColumnName:
+			kind = KindName
+		case datatable.ColumnStartTime, datatable.ColumnDuration:
+			kind = KindTime
+		}
+		cols = append(cols, Column{Name: name, Kind: kind, index: i})
+	}
+	if table.ISO8601() {
+		i, _ := table.Index(datatable.ColumnStartTime)
+		cols = append(cols, Column{Name: StartTimeISOColumn, Kind: KindText, index: i})
+	}
+	for i, f := range fields {
+		cols = append(cols, cands[i].column(f, idx[i], radixHint))
+	}
+	return cols, rows, nil
+}
+
+// Value converts a row's cell to the column's Go type: nil for empty cells,
+// otherwise string, int64, float64 or bool.
+func Value(col Column, row datatable.Row) any {
+	switch col.Name {
+	case datatable.ColumnStartTime:
+		return row.Start
+	case datatable.ColumnDuration:
+		return row.Duration
+	}
+	v := strings.TrimSpace(row.Values[col.index])
+	if v == "" {
+		return nil
+	}
+	switch col.Kind {
+	case KindInteger:
+		n, _ := parseRadix(v, col.Radix)
+		return n
+	case KindFloat:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case KindBool:
+		return v == "true"
+	case KindName, KindTime, KindText:
+		return row.Values[col.index]
+	}
+	return row.Values[col.index]
+}
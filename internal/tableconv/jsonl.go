@@ -0,0 +1,67 @@
+package tableconv
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/pkg/errors"
+)
+
+// writeJSONL writes one JSON object per row with keys in column order. Empty cells are
+// omitted. Hex and binary integers keep their original spelling ("0x3C") since JSON has
+// no radix; decimal integers, floats and booleans are JSON numbers/booleans.
+func writeJSONL(table *datatable.Table, cols []Column, opts Options) error {
+	f, err := os.Create(opts.Output)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", opts.Output)
+	}
+	w := bufio.NewWriterSize(f, 64*1024)
+
+	keys := make([][]byte, len(cols))
+	for i, c := range cols {
+		k, _ := json.Marshal(c.Name)
+		keys[i] = append(k, ':')
+	}
+
+	var line []byte
+	for row, err := range table.Rows() {
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		line = append(line[:0], '{')
+		first := true
+		for i, c := range cols {
+			v := Value(c, row)
+			if v == nil {
+				continue
+			}
+			if c.Kind == KindInteger && c.Radix != RadixDec {
+				v = row.Values[c.index]
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				_ = f.Close()
+				return errors.Wrapf(err, "%s:%d: encode %s", table.Path(), row.Line, c.Name)
+			}
+			if !first {
+				line = append(line, ',')
+			}
+			first = false
+			line = append(line, keys[i]...)
+			line = append(line, b...)
+		}
+		line = append(line, '}', '\n')
+		if _, err := w.Write(line); err != nil {
+			_ = f.Close()
+			return errors.Wrapf(err, "write %s", opts.Output)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "write %s", opts.Output)
+	}
+	return errors.Wrapf(f.Close(), "close %s", opts.Output)
+}
@@ -0,0 +1,139 @@
+package tableconv
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+)
+
+// ParquetColumnsKey is the file key/value metadata entry holding the column
+// descriptions (JSON array of {name, kind, radix}).
+const ParquetColumnsKey = "salad.columns"
+
+// parquetRowGroupRows bounds memory: rows are flushed to a row group this often.
+const parquetRowGroupRows = 64 * 1024
+
+func parquetNode(k Kind) parquet.Node {
+	var n parquet.Node
+	switch k {
+	case KindTime, KindFloat:
+		n = parquet.Leaf(parquet.DoubleType)
+	case KindInteger:
+		n = parquet.Int(64)
+	case KindBool:
+		n = parquet.Leaf(parquet.BooleanType)
+	case KindName, KindText:
+		n = parquet.String()
+	}
+	if n == nil {
+		n = parquet.String()
+	}
+	return parquet.Optional(n)
+}
+
+// writeParquet writes a zstd-compressed Parquet file with one optional column per
+// output column.
+func writeParquet(table *datatable.Table, cols []Column, opts Options) error {
+	group := parquet.Group{}
+	for _, c := range cols {
+		group[c.Name] = parquetNode(c.Kind)
+	}
+	schema := parquet.NewSchema(opts.TableName, group)
+
+	// parquet.Group orders leaves by name; map each output column to its leaf index.
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	leaf := map[string]int{}
+	for i, n := range sorted {
+		leaf[n] = i
+	}
+
+	meta, err := json.Marshal(cols)
+	if err != nil {
+		return errors.Wrap(err, "encode parquet column metadata")
+	}
+
+	f, err := os.Create(opts.Output)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", opts.Output)
+	}
+	w := parquet.NewWriter(f,
+		schema,
+		parquet.Compression(&parquet.Zstd),
+		parquet.KeyValueMetadata(ParquetColumnsKey, string(meta)),
+	)
+
+	fail := func(err error) error {
+		_ = f.Close()
+		return err
+	}
+
+	batch := make([]parquet.Row, 0, 1024)
+	buffered := 0
+	flush := func() error {
+		if len(batch) > 0 {
+			if _, err := w.WriteRows(batch); err != nil {
+				return errors.Wrapf(err, "write %s", opts.Output)
+			}
+			batch = batch[:0]
+		}
+		if buffered >= parquetRowGroupRows {
+			buffered = 0
+			return errors.Wrapf(w.Flush(), "write %s", opts.Output)
+		}
+		return nil
+	}
+
+	for row, err := range table.Rows() {
+		if err != nil {
+			return fail(err)
+		}
+		out := make(parquet.Row, len(cols))
+		for _, c := range cols {
+			j := leaf[c.Name]
+			v := Value(c, row)
+			if v == nil {
+				out[j] = parquet.NullValue().Level(0, 0, j)
+				continue
+			}
+			out[j] = parquetValue(v).Level(0, 1, j)
+		}
+		batch = append(batch, out)
+		buffered++
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return fail(err)
+	}
+	if err := w.Close(); err != nil {
+		return fail(errors.Wrapf(err, "write %s", opts.Output))
+	}
+	return errors.Wrapf(f.Close(), "close %s", opts.Output)
+}
+
+func parquetValue(v any) parquet.Value {
+	switch x := v.(type) {
+	case string:
+		return parquet.ByteArrayValue([]byte(x))
+	case int64:
+		return parquet.Int64Value(x)
+	case float64:
+		return parquet.DoubleValue(x)
+	case bool:
+		return parquet.BooleanValue(x)
+	default:
+		return parquet.NullValue()
+	}
+}
@@ -0,0 +1,119 @@
+package tableconv
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/pkg/errors"
+
+	// Pure-Go SQLite driver, registered as "sqlite".
+	_ "modernc.org/sqlite"
+)
+
+// ColumnsTableSuffix names the table describing column kinds and radixes
+// (e.g. frames_columns).
+const ColumnsTableSuffix = "_columns"
+
+func sqliteType(k Kind) string {
+	switch k {
+	case KindTime, KindFloat:
+		return "REAL"
+	case KindInteger, KindBool:
+		return "INTEGER"
+	case KindName, KindText:
+		return "TEXT"
+	}
+	return "TEXT"
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// writeSQLite replaces opts.Output with a database holding the frames table, indexes on
+// start_time and (name, start_time), and a <table>_columns description table.
+func writeSQLite(table *datatable.Table, cols []Column, opts Options) error {
+	if err := os.Remove(opts.Output); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "replace %s", opts.Output)
+	}
+	db, err := sql.Open("sqlite", opts.Output)
+	if err != nil {
+		return errors.Wrapf(err, "open sqlite %s", opts.Output)
+	}
+	defer func() { _ = db.Close() }()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "begin sqlite transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	name := quoteIdent(opts.TableName)
+	defs := make([]string, 0, len(cols)+1)
+	names := make([]string, 0, len(cols))
+	placeholders := make([]string, 0, len(cols))
+	defs = append(defs, "row INTEGER PRIMARY KEY")
+	for _, c := range cols {
+		def := quoteIdent(c.Name) + " " + sqliteType(c.Kind)
+		if c.Kind == KindName {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+		names = append(names, quoteIdent(c.Name))
+		placeholders = append(placeholders, "?")
+	}
+
+	stmts := []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", name, strings.Join(defs, ", ")),
+		fmt.Sprintf("CREATE TABLE %s (name TEXT PRIMARY KEY, position INTEGER, kind TEXT, radix TEXT)", quoteIdent(opts.TableName+ColumnsTableSuffix)),
+	}
+	for _, s := range stmts {
+		if _, err := tx.Exec(s); err != nil {
+			return errors.Wrapf(err, "sqlite: %s", s)
+		}
+	}
+
+	colStmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?, ?)", quoteIdent(opts.TableName+ColumnsTableSuffix)))
+	if err != nil {
+		return errors.Wrap(err, "prepare sqlite column insert")
+	}
+	for i, c := range cols {
+		if _, err := colStmt.Exec(c.Name, i, string(c.Kind), c.Radix); err != nil {
+			return errors.Wrapf(err, "insert column %s", c.Name)
+		}
+	}
+	_ = colStmt.Close()
+
+	rowStmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", name, strings.Join(names, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return errors.Wrap(err, "prepare sqlite row insert")
+	}
+	args := make([]any, len(cols))
+	for row, err := range table.Rows() {
+		if err != nil {
+			return err
+		}
+		for i, c := range cols {
+			args[i] = Value(c, row)
+		}
+		if _, err := rowStmt.Exec(args...); err != nil {
+			return errors.Wrapf(err, "%s:%d: insert row", table.Path(), row.Line)
+		}
+	}
+	_ = rowStmt.Close()
+
+	// Indexes are built after the bulk insert; it is much faster than maintaining them.
+	indexes := []string{
+		fmt.Sprintf("CREATE INDEX %s ON %s (start_time)", quoteIdent(opts.TableName+"_start_time"), name),
+		fmt.Sprintf("CREATE INDEX %s ON %s (name, start_time)", quoteIdent(opts.TableName+"_name_start_time"), name),
+	}
+	for _, s := range indexes {
+		if _, err := tx.Exec(s); err != nil {
+			return errors.Wrapf(err, "sqlite: %s", s)
+		}
+	}
+	return errors.Wrap(tx.Commit(), "commit sqlite transaction")
+}
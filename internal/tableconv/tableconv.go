@@ -0,0 +1,84 @@
+// Package tableconv converts Logic 2 data table CSV exports into typed SQLite, Parquet
+// and JSONL files.
+//
+// Analyzer field columns are typed by inspecting every value: hex ("0x3C"), binary
+// ("0b0011") and decimal integers become integers with their radix recorded, then
+// floats, booleans and finally text. start_time and duration are seconds.
+package tableconv
+
+import (
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/pkg/errors"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	FormatSQLite  Format = "sqlite"
+	FormatParquet Format = "parquet"
+	FormatJSONL   Format = "jsonl"
+)
+
+// FormatNames lists the accepted --to values.
+var FormatNames = []string{string(FormatSQLite), string(FormatParquet), string(FormatJSONL)}
+
+// DefaultTableName is the SQLite table holding the frames.
+const DefaultTableName = "frames"
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case FormatSQLite:
+		return FormatSQLite, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	case FormatJSONL:
+		return FormatJSONL, nil
+	default:
+		return "", errors.Errorf("unknown format %q (expected: %s)", s, strings.Join(FormatNames, "|"))
+	}
+}
+
+// Options configures Convert.
+type Options struct {
+	Format Format
+	Output string
+	// TableName is the SQLite table name (default DefaultTableName).
+	TableName string
+	// RadixHint is the radix the table was exported with, if all analyzers used the same one.
+	RadixHint string
+}
+
+// Result summarizes a conversion.
+type Result struct {
+	Rows    int
+	Columns []Column
+}
+
+// Convert infers column types from table and writes it to opts.Output.
+func Convert(table *datatable.Table, opts Options) (Result, error) {
+	cols, rows, err := Infer(table, opts.RadixHint)
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.TableName == "" {
+		opts.TableName = DefaultTableName
+	}
+
+	switch opts.Format {
+	case FormatSQLite:
+		err = writeSQLite(table, cols, opts)
+	case FormatParquet:
+		err = writeParquet(table, cols, opts)
+	case FormatJSONL:
+		err = writeJSONL(table, cols, opts)
+	default:
+		err = errors.Errorf("unknown format %q", opts.Format)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Rows: rows, Columns: cols}, nil
+}
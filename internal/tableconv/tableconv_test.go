@@ -0,0 +1,141 @@
+package tableconv
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/parquet-go/parquet-go"
+)
+
+const testTable = `name,type,start_time,duration,"data","address","read","ack"
+"spi","result",0.000100000,0.000002000,0x3C,,,
+"i2c","address",0.000200000,0.000010000,,0x50,true,true
+"spi","result",0.000300000,0.000002000,0xFF,,,
+`
+
+func openTestTable(t *testing.T) (*datatable.Table, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "table.csv")
+	if err := os.WriteFile(path, []byte(testTable), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	table, err := datatable.Open(path)
+	if err != nil {
+		t.Fatalf("datatable.Open: %v", err)
+	}
+	return table, dir
+}
+
+func TestInfer(t *testing.T) {
+	table, _ := openTestTable(t)
+	cols, rows, err := Infer(table, "")
+	if err != nil {
+		t.Fatalf("Infer: %v", err)
+	}
+	if rows != 3 {
+		t.Fatalf("rows: expected 3, got %d", rows)
+	}
+	got := map[string]Column{}
+	for _, c := range cols {
+		got[c.Name] = c
+	}
+	checks := map[string][2]string{
+		"name":       {string(KindName), ""},
+		"start_time": {string(KindTime), ""},
+		"data":       {string(KindInteger), RadixHex},
+		"address":    {string(KindInteger), RadixHex},
+		"read":       {string(KindBool), ""},
+	}
+	for name, want := range checks {
+		if string(got[name].Kind) != want[0] || got[name].Radix != want[1] {
+			t.Fatalf("column %s: expected kind=%s radix=%s, got %+v", name, want[0], want[1], got[name])
+		}
+	}
+}
+
+func TestConvert_SQLite(t *testing.T) {
+	table, dir := openTestTable(t)
+	out := filepath.Join(dir, "table.db")
+	if _, err := Convert(table, Options{Format: FormatSQLite, Output: out}); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", out)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var sum int64
+	if err := db.QueryRow(`SELECT SUM(data) FROM frames WHERE name = 'spi' AND start_time > 0`).Scan(&sum); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if sum != 0x3C+0xFF {
+		t.Fatalf("SUM(data): expected %d, got %d", 0x3C+0xFF, sum)
+	}
+	var radix string
+	if err := db.QueryRow(`SELECT radix FROM frames_columns WHERE name = 'data'`).Scan(&radix); err != nil {
+		t.Fatalf("query columns: %v", err)
+	}
+	if radix != RadixHex {
+		t.Fatalf("data radix: expected hex, got %q", radix)
+	}
+	var indexes string
+	if err := db.QueryRow(`SELECT group_concat(name) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'frames'`).Scan(&indexes); err != nil {
+		t.Fatalf("query indexes: %v", err)
+	}
+	if !strings.Contains(indexes, "frames_start_time") || !strings.Contains(indexes, "frames_name_start_time") {
+		t.Fatalf("expected start_time and name indexes, got %q", indexes)
+	}
+}
+
+func TestConvert_JSONL(t *testing.T) {
+	table, dir := openTestTable(t)
+	out := filepath.Join(dir, "table.jsonl")
+	if _, err := Convert(table, Options{Format: FormatJSONL, Output: out}); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	want := `{"name":"i2c","type":"address","start_time":0.0002,"duration":0.00001,"address":"0x50","read":true,"ack":true}`
+	if len(lines) != 3 || lines[1] != want {
+		t.Fatalf("line 2: expected %s, got %v", want, lines)
+	}
+}
+
+func TestConvert_Parquet(t *testing.T) {
+	table, dir := openTestTable(t)
+	out := filepath.Join(dir, "table.parquet")
+	if _, err := Convert(table, Options{Format: FormatParquet, Output: out}); err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	st, _ := f.Stat()
+	pf, err := parquet.OpenFile(f, st.Size())
+	if err != nil {
+		t.Fatalf("parquet.OpenFile: %v", err)
+	}
+	if pf.NumRows() != 3 {
+		t.Fatalf("rows: expected 3, got %d", pf.NumRows())
+	}
+	meta, ok := pf.Lookup(ParquetColumnsKey)
+	if !ok || !strings.Contains(meta, `"radix":"hex"`) {
+		t.Fatalf("metadata %s: expected radix info, got %q", ParquetColumnsKey, meta)
+	}
+	col, ok := pf.Schema().Lookup("data")
+	if !ok || col.Node.Type().Kind() != parquet.Int64 {
+		t.Fatalf("data column: expected INT64 leaf, got %+v", col)
+	}
+}
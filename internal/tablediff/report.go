@@ -0,0 +1,80 @@
+package tablediff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WriteUnified prints the differing analyzers in a unified-diff-like layout: one hunk
+// per group of changes with up to context equal frames around it, "-" for frames of
+// table A and "+" for frames of table B. A changed frame prints as a -/+ pair.
+func WriteUnified(w io.Writer, res Result, nameA, nameB string, context int) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", nameA, nameB)
+	for _, d := range res.Analyzers {
+		for _, h := range hunks(d.Ops, context) {
+			first := d.Ops[h[0]]
+			fmt.Fprintf(&b, "@@ %s a:%s b:%s @@\n", d.Analyzer, lineOf(first.A), lineOf(first.B))
+			for _, op := range d.Ops[h[0]:h[1]] {
+				switch op.Kind {
+				case Equal:
+					b.WriteString("  " + FormatFrame(op.A) + "\n")
+				case Removed:
+					b.WriteString("- " + FormatFrame(op.A) + "\n")
+				case Inserted:
+					b.WriteString("+ " + FormatFrame(op.B) + "\n")
+				case Changed:
+					b.WriteString("- " + FormatFrame(op.A) + "\n")
+					b.WriteString("+ " + FormatFrame(op.B) + "\n")
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return errors.Wrap(err, "write diff")
+}
+
+// hunks returns [start, end) op ranges around non-equal ops, merged when their context
+// overlaps.
+func hunks(ops []Op, context int) [][2]int {
+	var out [][2]int
+	for i, op := range ops {
+		if op.Kind == Equal {
+			continue
+		}
+		start, end := max(i-context, 0), min(i+context+1, len(ops))
+		if n := len(out); n > 0 && start <= out[n-1][1] {
+			out[n-1][1] = end
+			continue
+		}
+		out = append(out, [2]int{start, end})
+	}
+	return out
+}
+
+func lineOf(f *Frame) string {
+	if f == nil {
+		return "-"
+	}
+	return strconv.Itoa(f.Line)
+}
+
+// FormatFrame renders a frame as "<type> t=<start> field=value ...", fields sorted,
+// values as written in the file.
+func FormatFrame(f *Frame) string {
+	keys := make([]string, 0, len(f.Fields))
+	for k := range f.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{f.Type, "t=" + strconv.FormatFloat(f.Start, 'f', 9, 64)}
+	for _, k := range keys {
+		parts = append(parts, k+"="+f.Fields[k])
+	}
+	return strings.Join(parts, " ")
+}
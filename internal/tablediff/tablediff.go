@@ -0,0 +1,524 @@
+// Package tablediff compares two data table exports frame by frame, per analyzer.
+//
+// Values are compared after type inference (internal/tableconv), so a table exported
+// with --radix hex matches one exported with dec or bin: 0x3C, 60 and 0b00111100 are the
+// same frame field. Frames are aligned either by order (an edit script over each
+// analyzer's frame sequence; timestamps are ignored) or by start time within a
+// tolerance.
+package tablediff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/tableconv"
+	"github.com/pkg/errors"
+)
+
+// Align selects how frames of the two tables are paired.
+type Align int
+
+const (
+	// AlignOrder pairs frames by sequence (longest common subsequence), ignoring time.
+	AlignOrder Align = iota
+	// AlignTime pairs frames whose start times, relative to each table's first row,
+	// differ by at most the tolerance.
+	AlignTime
+)
+
+// ParseAlign parses "order" or "time".
+func ParseAlign(s string) (Align, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "order":
+		return AlignOrder, nil
+	case "time":
+		return AlignTime, nil
+	default:
+		return AlignOrder, errors.Errorf("unknown alignment %q (expected order|time)", s)
+	}
+}
+
+func (a Align) String() string {
+	switch a {
+	case AlignOrder:
+		return "order"
+	case AlignTime:
+		return "time"
+	default:
+		return "unknown"
+	}
+}
+
+// Options configure Diff.
+type Options struct {
+	Align Align
+	// Tolerance is the maximum start time difference for AlignTime, in seconds.
+	Tolerance float64
+	// RadixA and RadixB are the radixes the tables were exported with, when known.
+	// Only needed for ascii, where single characters are compared as their codes.
+	RadixA string
+	RadixB string
+	// Ignore lists field columns to leave out of the comparison.
+	Ignore []string
+}
+
+// Frame is one table row.
+type Frame struct {
+	Line     int
+	Type     string
+	Start    float64
+	Duration float64
+	// Fields are the non-empty field cells as written in the file.
+	Fields map[string]string
+
+	// norm holds comparable values; key identifies the frame content.
+	norm map[string]string
+	key  string
+}
+
+// Kind is the kind of an edit operation.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Removed
+	Inserted
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Equal:
+		return "equal"
+	case Removed:
+		return "removed"
+	case Inserted:
+		return "inserted"
+	case Changed:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// Op is one step of the diff. A is nil for Inserted, B is nil for Removed.
+type Op struct {
+	Kind Kind
+	A    *Frame
+	B    *Frame
+	// Fields lists the differing field names of a Changed op ("type" if the type differs).
+	Fields []string
+}
+
+// AnalyzerDiff is the diff of one analyzer's frames.
+type AnalyzerDiff struct {
+	Analyzer string
+	Ops      []Op
+	Equal    int
+	Removed  int
+	Inserted int
+	Changed  int
+}
+
+// Differs reports whether the analyzer's frames differ.
+func (d AnalyzerDiff) Differs() bool { return d.Removed+d.Inserted+d.Changed > 0 }
+
+// Result holds one AnalyzerDiff per analyzer found in either table, sorted by name.
+type Result struct {
+	Analyzers []AnalyzerDiff
+}
+
+// Differs reports whether any analyzer differs.
+func (r Result) Differs() bool {
+	for _, a := range r.Analyzers {
+		if a.Differs() {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff loads both tables and compares them.
+func Diff(a, b *datatable.Table, opts Options) (Result, error) {
+	ignore := map[string]bool{}
+	for _, f := range opts.Ignore {
+		ignore[f] = true
+	}
+	framesA, err := load(a, opts.RadixA, ignore)
+	if err != nil {
+		return Result{}, err
+	}
+	framesB, err := load(b, opts.RadixB, ignore)
+	if err != nil {
+		return Result{}, err
+	}
+
+	names := map[string]bool{}
+	for n := range framesA {
+		names[n] = true
+	}
+	for n := range framesB {
+		names[n] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	var res Result
+	for _, name := range sorted {
+		var ops []Op
+		switch opts.Align {
+		case AlignTime:
+			ops = alignTime(framesA[name], framesB[name], opts.Tolerance)
+		case AlignOrder:
+			ops = alignOrder(framesA[name], framesB[name])
+		}
+		d := AnalyzerDiff{Analyzer: name, Ops: ops}
+		for _, op := range ops {
+			switch op.Kind {
+			case Equal:
+				d.Equal++
+			case Removed:
+				d.Removed++
+			case Inserted:
+				d.Inserted++
+			case Changed:
+				d.Changed++
+			}
+		}
+		res.Analyzers = append(res.Analyzers, d)
+	}
+	return res, nil
+}
+
+// load reads a table into per-analyzer frame lists. Start times are made relative to
+// the first row so captures triggered at different times line up.
+func load(table *datatable.Table, radix string, ignore map[string]bool) (map[string][]*Frame, error) {
+	cols, _, err := tableconv.Infer(table, radix)
+	if err != nil {
+		return nil, err
+	}
+	var fieldCols []tableconv.Column
+	fields := map[string]bool{}
+	// An ascii export prints digits as characters; don't let them pass for decimals.
+	for i, c := range cols {
+		if radix == tableconv.RadixASCII && c.Kind == tableconv.KindInteger && c.Radix == tableconv.RadixDec {
+			cols[i].Kind, cols[i].Radix = tableconv.KindText, tableconv.RadixASCII
+		}
+	}
+	for _, f := range table.FieldColumns() {
+		fields[f] = true
+	}
+	for _, c := range cols {
+		if fields[c.Name] && !ignore[c.Name] {
+			fieldCols = append(fieldCols, c)
+		}
+	}
+
+	out := map[string][]*Frame{}
+	origin := math.NaN()
+	for row, err := range table.Rows() {
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(origin) {
+			origin = row.Start
+		}
+		f := &Frame{
+			Line:     row.Line,
+			Type:     row.Type,
+			Start:    row.Start - origin,
+			Duration: row.Duration,
+			Fields:   map[string]string{},
+			norm:     map[string]string{},
+		}
+		for _, c := range fieldCols {
+			i, _ := table.Index(c.Name)
+			raw := row.Values[i]
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			f.Fields[c.Name] = raw
+			f.norm[c.Name] = normalize(c, tableconv.Value(c, row))
+		}
+		f.key = frameKey(f.Type, f.norm)
+		out[row.Name] = append(out[row.Name], f)
+	}
+	return out, nil
+}
+
+// asciiEscapes are the non-printable characters Logic 2 writes as escapes.
+var asciiEscapes = map[string]int64{`\0`: 0, `\t`: 9, `\n`: 10, `\r`: 13}
+
+// normalize renders a typed value so equal values compare equal across radixes.
+func normalize(c tableconv.Column, v any) string {
+	switch x := v.(type) {
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case string:
+		if c.Radix == tableconv.RadixASCII {
+			if code, ok := asciiCode(x); ok {
+				return strconv.FormatInt(code, 10)
+			}
+		}
+		return x
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func asciiCode(s string) (int64, bool) {
+	if len(s) == 1 {
+		return int64(s[0]), true
+	}
+	if code, ok := asciiEscapes[s]; ok {
+		return code, true
+	}
+	if strings.HasPrefix(s, `\x`) && len(s) == 4 {
+		n, err := strconv.ParseUint(s[2:], 16, 8)
+		return int64(n), err == nil
+	}
+	return 0, false
+}
+
+func frameKey(typ string, norm map[string]string) string {
+	keys := make([]string, 0, len(norm))
+	for k := range norm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(typ)
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(norm[k])
+	}
+	return b.String()
+}
+
+// changedFields lists the fields that differ between two frames.
+func changedFields(a, b *Frame) []string {
+	var out []string
+	if a.Type != b.Type {
+		out = append(out, datatable.ColumnType)
+	}
+	seen := map[string]bool{}
+	for k, v := range a.norm {
+		seen[k] = true
+		if b.norm[k] != v {
+			out = append(out, k)
+		}
+	}
+	for k := range b.norm {
+		if !seen[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// alignTime walks both start-ordered lists, pairing frames closer than tolerance.
+func alignTime(a, b []*Frame, tolerance float64) []Op {
+	var ops []Op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i].Start < b[j].Start-tolerance):
+			ops = append(ops, Op{Kind: Removed, A: a[i]})
+			i++
+		case i >= len(a) || b[j].Start < a[i].Start-tolerance:
+			ops = append(ops, Op{Kind: Inserted, B: b[j]})
+			j++
+		default:
+			ops = append(ops, pair(a[i], b[j]))
+			i++
+			j++
+		}
+	}
+	return ops
+}
+
+func pair(a, b *Frame) Op {
+	if a.key == b.key {
+		return Op{Kind: Equal, A: a, B: b}
+	}
+	return Op{Kind: Changed, A: a, B: b, Fields: changedFields(a, b)}
+}
+
+// alignOrder computes an edit script over frame contents, then reports a removal
+// directly followed by an insertion of the same frame type as a change.
+func alignOrder(a, b []*Frame) []Op {
+	script := editScript(a, b)
+	var ops []Op
+	for k := 0; k < len(script); {
+		if script[k].Kind != Removed {
+			ops = append(ops, script[k])
+			k++
+			continue
+		}
+		var removed, inserted []Op
+		for k < len(script) && script[k].Kind == Removed {
+			removed = append(removed, script[k])
+			k++
+		}
+		for k < len(script) && script[k].Kind == Inserted {
+			inserted = append(inserted, script[k])
+			k++
+		}
+		n := 0
+		for n < len(removed) && n < len(inserted) && removed[n].A.Type == inserted[n].B.Type {
+			ops = append(ops, pair(removed[n].A, inserted[n].B))
+			n++
+		}
+		ops = append(ops, removed[n:]...)
+		ops = append(ops, inserted[n:]...)
+	}
+	return ops
+}
+
+// editScript is Myers' O((N+M)D) shortest edit script in linear space: instead of
+// keeping every round of the search, it finds the middle snake of the script and
+// recurses on both sides. Deletions come before insertions within a run.
+func editScript(a, b []*Frame) []Op {
+	ops := appendScript(nil, a, b)
+	// Myers may interleave deletions and insertions; order each run deletions first.
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == Equal {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].Kind != Equal {
+			j++
+		}
+		sort.SliceStable(ops[i:j], func(p, q int) bool {
+			return ops[i+p].Kind == Removed && ops[i+q].Kind == Inserted
+		})
+		i = j
+	}
+	return ops
+}
+
+// appendScript appends the edit script from a to b to ops.
+func appendScript(ops []Op, a, b []*Frame) []Op {
+	// Common prefix and suffix are cheap to strip and keep D small.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre].key == b[pre].key {
+		ops = append(ops, Op{Kind: Equal, A: a[pre], B: b[pre]})
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf].key == b[len(b)-1-suf].key {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	if x, y, ok := middleSnake(ma, mb); ok {
+		ops = appendScript(ops, ma[:x], mb[:y])
+		ops = appendScript(ops, ma[x:], mb[y:])
+	} else {
+		for _, f := range ma {
+			ops = append(ops, Op{Kind: Removed, A: f})
+		}
+		for _, f := range mb {
+			ops = append(ops, Op{Kind: Inserted, B: f})
+		}
+	}
+	for i := len(a) - suf; i < len(a); i++ {
+		ops = append(ops, Op{Kind: Equal, A: a[i], B: b[i-len(a)+len(b)]})
+	}
+	return ops
+}
+
+// middleSnake runs Myers' search forwards from the start and backwards from the end of
+// a and b at once, and returns where the two paths meet. It returns false when a or b
+// is empty or nothing is shared, in which case the script is all deletions and
+// insertions.
+func middleSnake(a, b []*Frame) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	off := maxD
+	// vf[off+k] and vb[off+k] are the furthest x reached on diagonal k forwards and
+	// backwards (x counted from the end); -1 is unreached.
+	vf := make([]int, 2*maxD+2)
+	vb := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+	delta := n - m
+	// With an odd delta the forward path reaches the overlap first.
+	front := delta%2 != 0
+	// Diagonals that ran off the edge of the grid are skipped in later rounds.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x].key == b[y].key {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if kb := off + delta - k; kb >= 0 && kb < len(vb) && vb[kb] != -1 && x >= n-vb[kb] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1].key == b[m-y-1].key {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				if kf := off + delta - k; kf >= 0 && kf < len(vf) && vf[kf] != -1 {
+					fx := vf[kf]
+					if fx >= n-x {
+						return fx, off + fx - kf, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
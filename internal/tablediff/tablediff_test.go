@@ -0,0 +1,167 @@
+package tablediff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+)
+
+const tableA = `name,type,start_time,duration,"data","address","read","ack"
+"spi","result",1.000100000,0.000002000,0x3C,,,
+"i2c","address",1.000200000,0.000010000,,0x50,true,true
+"spi","result",1.000300000,0.000002000,0xFF,,,
+"spi","result",1.000400000,0.000002000,0x01,,,
+"spi","result",1.000500000,0.000002000,0x02,,,
+`
+
+// tableB is tableA exported in dec from a later capture, with one changed and one
+// extra SPI word.
+const tableB = `name,type,start_time,duration,"data","address","read","ack"
+"spi","result",0.000100000,0.000002000,60,,,
+"i2c","address",0.000200500,0.000010000,,80,true,true
+"spi","result",0.000300000,0.000002000,254,,,
+"spi","result",0.000400000,0.000002000,1,,,
+"spi","result",0.000450000,0.000002000,9,,,
+"spi","result",0.000500000,0.000002000,2,,,
+`
+
+func openTable(t *testing.T, content string) *datatable.Table {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "table.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	table, err := datatable.Open(path)
+	if err != nil {
+		t.Fatalf("datatable.Open: %v", err)
+	}
+	return table
+}
+
+func summary(res Result) string {
+	var parts []string
+	for _, d := range res.Analyzers {
+		parts = append(parts, fmt.Sprintf("%s:=%d-%d+%d~%d", d.Analyzer, d.Equal, d.Removed, d.Inserted, d.Changed))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestDiff_Order(t *testing.T) {
+	res, err := Diff(openTable(t, tableA), openTable(t, tableB), Options{})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if got, want := summary(res), "i2c:=1-0+0~0 spi:=3-0+1~1"; got != want {
+		t.Fatalf("summary: expected %s, got %s", want, got)
+	}
+	spi := res.Analyzers[1]
+	changed := spi.Ops[1]
+	if changed.Kind != Changed || changed.A.Fields["data"] != "0xFF" || changed.B.Fields["data"] != "254" || strings.Join(changed.Fields, ",") != "data" {
+		t.Fatalf("expected changed 0xFF -> 254, got %+v", changed)
+	}
+
+	var out bytes.Buffer
+	if err := WriteUnified(&out, res, "a.csv", "b.csv", 1); err != nil {
+		t.Fatalf("WriteUnified: %v", err)
+	}
+	want := `--- a.csv
++++ b.csv
+@@ spi a:2 b:2 @@
+  result t=0.000000000 data=0x3C
+- result t=0.000200000 data=0xFF
++ result t=0.000200000 data=254
+  result t=0.000300000 data=0x01
++ result t=0.000350000 data=9
+  result t=0.000400000 data=0x02
+`
+	if out.String() != want {
+		t.Fatalf("unified: expected\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+func TestDiff_Time(t *testing.T) {
+	res, err := Diff(openTable(t, tableA), openTable(t, tableB), Options{Align: AlignTime, Tolerance: 1e-6})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if got, want := summary(res), "i2c:=1-0+0~0 spi:=3-0+1~1"; got != want {
+		t.Fatalf("summary: expected %s, got %s", want, got)
+	}
+
+	// Without tolerance the shifted I2C frame no longer pairs up.
+	res, err = Diff(openTable(t, tableA), openTable(t, tableB), Options{Align: AlignTime, Tolerance: 1e-7})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if got := summary(res); !strings.HasPrefix(got, "i2c:=0-1+1~0") {
+		t.Fatalf("expected i2c removed+inserted, got %s", got)
+	}
+}
+
+func TestDiff_ASCII(t *testing.T) {
+	a := openTable(t, "name,type,start_time,duration,data\nuart,data,0.1,0.01,0x41\nuart,data,0.2,0.01,0x31\nuart,data,0.3,0.01,0x0A\n")
+	b := openTable(t, "name,type,start_time,duration,data\nuart,data,0.1,0.01,A\nuart,data,0.2,0.01,1\nuart,data,0.3,0.01,\\n\n")
+	res, err := Diff(a, b, Options{RadixB: "ascii"})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if res.Differs() {
+		t.Fatalf("expected hex and ascii exports to match, got %s", summary(res))
+	}
+}
+
+func TestEditScript_Minimal(t *testing.T) {
+	frames := func(keys string) []*Frame {
+		var out []*Frame
+		for _, k := range keys {
+			out = append(out, &Frame{key: string(k)})
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		a, b  string
+		equal int
+	}{
+		{"abcabba", "cbabac", 4},
+		{"abc", "xyz", 0},
+		{"", "ab", 0},
+		{"aaab", "baaa", 3},
+		{"abcdefg", "axcyegz", 4},
+	} {
+		a, b := frames(tc.a), frames(tc.b)
+		var got []string
+		ia, ib, equal := 0, 0, 0
+		for _, op := range editScript(a, b) {
+			switch op.Kind {
+			case Equal:
+				if op.A != a[ia] || op.B != b[ib] {
+					t.Fatalf("%s -> %s: misaligned equal op", tc.a, tc.b)
+				}
+				ia, ib, equal = ia+1, ib+1, equal+1
+			case Removed:
+				ia++
+			case Inserted:
+				ib++
+			}
+			got = append(got, op.Kind.String())
+		}
+		if ia != len(a) || ib != len(b) || equal != tc.equal {
+			t.Fatalf("%s -> %s: expected %d equal frames covering both tables, got %v", tc.a, tc.b, tc.equal, got)
+		}
+	}
+
+	// Tables that differ everywhere use linear memory.
+	var a, b []*Frame
+	for i := 0; i < 5000; i++ {
+		a = append(a, &Frame{key: fmt.Sprint("a", i)})
+		b = append(b, &Frame{key: fmt.Sprint("b", i)})
+	}
+	if ops := editScript(a, b); len(ops) != 10000 || ops[0].Kind != Removed || ops[9999].Kind != Inserted {
+		t.Fatalf("expected 5000 removals then 5000 insertions, got %d ops", len(ops))
+	}
+}
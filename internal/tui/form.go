@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// form is a small modal of labelled text inputs. Enter submits, Esc cancels.
+type form struct {
+	title  string
+	labels []string
+	inputs []textinput.Model
+	focus  int
+	submit func(values []string) tea.Cmd
+}
+
+type field struct {
+	label, value, placeholder string
+}
+
+func newForm(title string, fields []field, submit func(values []string) tea.Cmd) *form {
+	f := &form{title: title, submit: submit}
+	for i, fd := range fields {
+		in := textinput.New()
+		in.Prompt = ""
+		in.Placeholder = fd.placeholder
+		in.SetValue(fd.value)
+		in.CharLimit = 4096
+		if i == 0 {
+			in.Focus()
+		}
+		f.labels = append(f.labels, fd.label)
+		f.inputs = append(f.inputs, in)
+	}
+	return f
+}
+
+// update handles a key; the bool reports whether the form closed (submitted or cancelled).
+func (f *form) update(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc":
+		return nil, true
+	case "enter":
+		values := make([]string, len(f.inputs))
+		for i, in := range f.inputs {
+			values[i] = strings.TrimSpace(in.Value())
+		}
+		return f.submit(values), true
+	case "tab", "down":
+		f.move(1)
+		return nil, false
+	case "shift+tab", "up":
+		f.move(-1)
+		return nil, false
+	}
+	var cmd tea.Cmd
+	f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	return cmd, false
+}
+
+func (f *form) move(delta int) {
+	f.inputs[f.focus].Blur()
+	f.focus = (f.focus + delta + len(f.inputs)) % len(f.inputs)
+	f.inputs[f.focus].Focus()
+}
+
+func (f *form) view(width int) string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(f.title))
+	b.WriteString("\n")
+	labelWidth := 0
+	for _, l := range f.labels {
+		labelWidth = max(labelWidth, len(l))
+	}
+	for i, in := range f.inputs {
+		in.Width = max(10, width-labelWidth-6)
+		b.WriteString("  " + padRight(f.labels[i], labelWidth) + "  " + in.View() + "\n")
+	}
+	b.WriteString(helpStyle.Render("enter submit · tab next field · esc cancel"))
+	return b.String()
+}
+
+// picker chooses one item from a list.
+type picker struct {
+	title  string
+	items  []string
+	cursor int
+	choose func(item string) tea.Cmd
+}
+
+func (p *picker) update(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc":
+		return nil, true
+	case "enter":
+		return p.choose(p.items[p.cursor]), true
+	case "up", "k":
+		p.cursor = max(0, p.cursor-1)
+	case "down", "j":
+		p.cursor = min(len(p.items)-1, p.cursor+1)
+	}
+	return nil, false
+}
+
+func (p *picker) view(height int) string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(p.title))
+	b.WriteString("\n")
+	first := max(0, min(p.cursor-height/2, len(p.items)-height))
+	for i := first; i < len(p.items) && i < first+height; i++ {
+		b.WriteString(cursorLine(p.items[i], i == p.cursor) + "\n")
+	}
+	b.WriteString(helpStyle.Render("enter choose · esc cancel"))
+	return b.String()
+}
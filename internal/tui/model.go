@@ -0,0 +1,534 @@
+// Package tui implements `salad tui`, a full-screen dashboard for a Logic 2 automation
+// server: devices, app info, the captures and analyzers opened in the session, and a
+// live log of RPCs, with keyboard actions for the common capture workflows.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+	saladconfig "github.com/go-go-golems/salad/internal/config"
+	"github.com/go-go-golems/salad/internal/pipeline"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
+	"github.com/pkg/errors"
+)
+
+// maxLog is the number of RPCs kept for the log pane.
+const maxLog = 500
+
+type pane int
+
+const (
+	paneDevices pane = iota
+	paneCaptures
+	paneAnalyzers
+	numPanes
+)
+
+// Options configure a Model.
+type Options struct {
+	Client *saleae.Client
+	// State tracks captures and analyzers; its interceptor must be installed on Client.
+	State *shell.State
+	// RPCs feeds the log pane; its interceptor must be installed on Client.
+	RPCs *RPCLog
+	// Addr is shown in the header.
+	Addr string
+	// Timeout bounds each action (0: no limit).
+	Timeout time.Duration
+	// Templates is the directory of analyzer settings templates offered by "add analyzer".
+	Templates string
+}
+
+// Model is the bubbletea model of the dashboard.
+type Model struct {
+	opts Options
+
+	app     *pb.AppInfo
+	devices []*pb.Device
+	log     []RPC
+
+	focus  pane
+	cursor [numPanes]int
+	status string
+	failed bool // status reports an error
+	busy   int
+
+	form    *form
+	picker  *picker
+	confirm *confirm
+
+	width, height int
+}
+
+type confirm struct {
+	question string
+	yes      func() tea.Cmd
+}
+
+type infoMsg struct {
+	app     *pb.AppInfo
+	devices []*pb.Device
+	err     error
+}
+
+type rpcMsg RPC
+
+type actionMsg struct {
+	desc string
+	err  error
+}
+
+// New returns the dashboard model.
+func New(opts Options) *Model {
+	return &Model{opts: opts, width: 100, height: 30}
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(m.refresh(), m.waitRPC())
+}
+
+func (m *Model) waitRPC() tea.Cmd {
+	return func() tea.Msg {
+		return rpcMsg(<-m.opts.RPCs.ch)
+	}
+}
+
+func (m *Model) context() (context.Context, context.CancelFunc) {
+	if m.opts.Timeout > 0 {
+		return context.WithTimeout(context.Background(), m.opts.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (m *Model) refresh() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := m.context()
+		defer cancel()
+		app, err := m.opts.Client.GetAppInfo(ctx)
+		if err != nil {
+			return infoMsg{err: err}
+		}
+		devices, err := m.opts.Client.GetDevices(ctx, true)
+		return infoMsg{app: app, devices: devices, err: err}
+	}
+}
+
+// action runs fn in the background and reports its outcome in the status line.
+func (m *Model) action(desc string, fn func(ctx context.Context) error) tea.Cmd {
+	m.busy++
+	m.status, m.failed = desc+"...", false
+	return func() tea.Msg {
+		ctx, cancel := m.context()
+		defer cancel()
+		return actionMsg{desc: desc, err: fn(ctx)}
+	}
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case infoMsg:
+		if msg.err != nil {
+			m.status, m.failed = "refresh: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.app, m.devices = msg.app, msg.devices
+		return m, nil
+	case rpcMsg:
+		m.log = append(m.log, RPC(msg))
+		if len(m.log) > maxLog {
+			m.log = m.log[len(m.log)-maxLog:]
+		}
+		return m, m.waitRPC()
+	case actionMsg:
+		m.busy--
+		m.status, m.failed = msg.desc+": ok", false
+		if msg.err != nil {
+			m.status, m.failed = msg.desc+": "+msg.err.Error(), true
+		}
+		return m, nil
+	case tea.KeyMsg:
+		return m, m.key(msg)
+	}
+	return m, nil
+}
+
+func (m *Model) key(msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "ctrl+c" {
+		return tea.Quit
+	}
+	switch {
+	case m.form != nil:
+		cmd, done := m.form.update(msg)
+		if done {
+			m.form = nil
+		}
+		return cmd
+	case m.picker != nil:
+		cmd, done := m.picker.update(msg)
+		if done {
+			m.picker = nil
+		}
+		return cmd
+	case m.confirm != nil:
+		c := m.confirm
+		m.confirm = nil
+		if msg.String() == "y" {
+			return c.yes()
+		}
+		m.status, m.failed = "", false
+		return nil
+	}
+
+	switch msg.String() {
+	case "q":
+		return tea.Quit
+	case "tab", "right", "l":
+		m.focus = (m.focus + 1) % numPanes
+	case "shift+tab", "left", "h":
+		m.focus = (m.focus + numPanes - 1) % numPanes
+	case "up", "k":
+		m.cursor[m.focus] = max(0, m.cursor[m.focus]-1)
+	case "down", "j":
+		m.cursor[m.focus] = max(0, min(m.paneLen(m.focus)-1, m.cursor[m.focus]+1))
+	case "r":
+		m.status, m.failed = "", false
+		return m.refresh()
+	case "s":
+		return m.startCapture()
+	case "o":
+		return m.loadCapture()
+	case "x":
+		return m.withCapture(func(c shell.Capture) tea.Cmd {
+			return m.action("stop "+c.Name, func(ctx context.Context) error {
+				return m.opts.Client.StopCapture(ctx, c.ID)
+			})
+		})
+	case "w":
+		return m.withCapture(m.saveCapture)
+	case "c":
+		return m.withCapture(func(c shell.Capture) tea.Cmd {
+			return m.ask(fmt.Sprintf("close %s (%s)? unsaved data is lost [y/N]", c.Name, c.Source), func() tea.Cmd {
+				return m.action("close "+c.Name, func(ctx context.Context) error {
+					return m.opts.Client.CloseCapture(ctx, c.ID)
+				})
+			})
+		})
+	case "a":
+		return m.withCapture(m.addAnalyzer)
+	case "d":
+		a, ok := m.selectedAnalyzer()
+		if !ok {
+			m.status, m.failed = "no analyzer selected", true
+			return nil
+		}
+		return m.ask(fmt.Sprintf("remove analyzer %s (%s)? [y/N]", a.Name, a.Analyzer), func() tea.Cmd {
+			return m.action("remove "+a.Name, func(ctx context.Context) error {
+				return m.opts.Client.RemoveAnalyzer(ctx, a.CaptureID, a.ID)
+			})
+		})
+	case "e":
+		return m.withCapture(m.exportRaw)
+	case "t":
+		return m.withCapture(m.exportTable)
+	}
+	return nil
+}
+
+// ask shows a y/N question in the status line; yes runs on "y".
+func (m *Model) ask(question string, yes func() tea.Cmd) tea.Cmd {
+	m.confirm = &confirm{question: question, yes: yes}
+	return nil
+}
+
+func (m *Model) paneLen(p pane) int {
+	switch p {
+	case paneDevices:
+		return len(m.devices)
+	case paneCaptures:
+		return len(m.opts.State.Captures())
+	case paneAnalyzers:
+		return len(m.captureAnalyzers())
+	case numPanes:
+	}
+	return 0
+}
+
+func (m *Model) selectedDevice() (*pb.Device, bool) {
+	if len(m.devices) == 0 {
+		return nil, false
+	}
+	return m.devices[min(m.cursor[paneDevices], len(m.devices)-1)], true
+}
+
+func (m *Model) selectedCapture() (shell.Capture, bool) {
+	captures := m.opts.State.Captures()
+	if len(captures) == 0 {
+		return shell.Capture{}, false
+	}
+	return captures[min(m.cursor[paneCaptures], len(captures)-1)], true
+}
+
+// captureAnalyzers returns the analyzers of the selected capture.
+func (m *Model) captureAnalyzers() []shell.Analyzer {
+	c, ok := m.selectedCapture()
+	if !ok {
+		return nil
+	}
+	var out []shell.Analyzer
+	for _, a := range m.opts.State.Analyzers() {
+		if a.CaptureID == c.ID {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (m *Model) selectedAnalyzer() (shell.Analyzer, bool) {
+	analyzers := m.captureAnalyzers()
+	if len(analyzers) == 0 {
+		return shell.Analyzer{}, false
+	}
+	return analyzers[min(m.cursor[paneAnalyzers], len(analyzers)-1)], true
+}
+
+func (m *Model) withCapture(fn func(shell.Capture) tea.Cmd) tea.Cmd {
+	c, ok := m.selectedCapture()
+	if !ok {
+		m.status, m.failed = "no capture selected (s: start, o: open a .sal file)", true
+		return nil
+	}
+	return fn(c)
+}
+
+func (m *Model) startCapture() tea.Cmd {
+	device := ""
+	if d, ok := m.selectedDevice(); ok {
+		device = d.GetDeviceId()
+	}
+	m.form = newForm("Start capture", []field{
+		{label: "Device ID", value: device, placeholder: "first physical device"},
+		{label: "Digital channels", value: "0,1,2,3"},
+		{label: "Digital sample rate", value: "10000000"},
+		{label: "Duration", placeholder: "empty: manual (stop with x); e.g. 2s: timed"},
+	}, func(v []string) tea.Cmd {
+		channels, err := parseChannels(v[1])
+		if err != nil {
+			return m.fail("start capture", err)
+		}
+		rate, err := strconv.ParseUint(v[2], 10, 32)
+		if err != nil {
+			return m.fail("start capture", errors.Errorf("invalid sample rate %q", v[2]))
+		}
+		capture := &pb.CaptureConfiguration{CaptureMode: &pb.CaptureConfiguration_ManualCaptureMode{ManualCaptureMode: &pb.ManualCaptureMode{}}}
+		if v[3] != "" {
+			d, err := time.ParseDuration(v[3])
+			if err != nil || d <= 0 {
+				return m.fail("start capture", errors.Errorf("invalid duration %q", v[3]))
+			}
+			capture.CaptureMode = &pb.CaptureConfiguration_TimedCaptureMode{TimedCaptureMode: &pb.TimedCaptureMode{DurationSeconds: d.Seconds()}}
+		}
+		deviceConfig := &pb.LogicDeviceConfiguration{
+			EnabledChannels:   &pb.LogicDeviceConfiguration_LogicChannels{LogicChannels: &pb.LogicChannels{DigitalChannels: channels}},
+			DigitalSampleRate: uint32(rate),
+		}
+		return m.action("start capture", func(ctx context.Context) error {
+			_, err := m.opts.Client.StartCapture(ctx, v[0], deviceConfig, capture)
+			return err
+		})
+	})
+	return nil
+}
+
+func (m *Model) loadCapture() tea.Cmd {
+	m.form = newForm("Open capture file", []field{
+		{label: "File", placeholder: "/abs/path/to/capture.sal"},
+	}, func(v []string) tea.Cmd {
+		return m.action("open "+v[0], func(ctx context.Context) error {
+			_, err := m.opts.Client.LoadCapture(ctx, absPath(v[0]))
+			return err
+		})
+	})
+	return nil
+}
+
+func (m *Model) saveCapture(c shell.Capture) tea.Cmd {
+	m.form = newForm("Save "+c.Name, []field{
+		{label: "File", placeholder: "/abs/path/to/capture.sal"},
+	}, func(v []string) tea.Cmd {
+		return m.action("save "+c.Name, func(ctx context.Context) error {
+			return m.opts.Client.SaveCapture(ctx, c.ID, absPath(v[0]))
+		})
+	})
+	return nil
+}
+
+func (m *Model) addAnalyzer(c shell.Capture) tea.Cmd {
+	templates, _ := filepath.Glob(filepath.Join(m.opts.Templates, "*.yaml"))
+	sort.Strings(templates)
+	m.picker = &picker{
+		title: "Add analyzer to " + c.Name + ": settings template",
+		items: append([]string{"(no template)"}, templates...),
+		choose: func(template string) tea.Cmd {
+			name, label := "", ""
+			if template == "(no template)" {
+				template = ""
+			} else {
+				name = guessAnalyzer(template)
+				label = strings.TrimSuffix(filepath.Base(template), filepath.Ext(template))
+			}
+			m.form = newForm("Add analyzer to "+c.Name, []field{
+				{label: "Analyzer", value: name, placeholder: "SPI, I2C, Async Serial, ..."},
+				{label: "Label", value: label},
+			}, func(v []string) tea.Cmd {
+				if v[0] == "" {
+					return m.fail("add analyzer", errors.New("analyzer name is required"))
+				}
+				settings, err := saladconfig.LoadAnalyzerSettings(template)
+				if err != nil {
+					return m.fail("add analyzer", err)
+				}
+				return m.action("add "+v[0], func(ctx context.Context) error {
+					_, err := m.opts.Client.AddAnalyzer(ctx, c.ID, v[0], v[1], settings)
+					return err
+				})
+			})
+			return nil
+		},
+	}
+	return nil
+}
+
+func (m *Model) exportRaw(c shell.Capture) tea.Cmd {
+	m.form = newForm("Export raw data of "+c.Name, []field{
+		{label: "Directory", placeholder: "/abs/path/to/export-dir"},
+		{label: "Digital channels", value: "0,1,2,3"},
+		{label: "Format", value: "binary", placeholder: "binary or csv"},
+	}, func(v []string) tea.Cmd {
+		channels, err := parseChannels(v[1])
+		if err != nil {
+			return m.fail("export", err)
+		}
+		dir, ch := absPath(v[0]), &pb.LogicChannels{DigitalChannels: channels}
+		switch v[2] {
+		case "binary":
+			return m.action("export raw binary to "+dir, func(ctx context.Context) error {
+				return m.opts.Client.ExportRawDataBinary(ctx, c.ID, dir, ch, 1)
+			})
+		case "csv":
+			return m.action("export raw csv to "+dir, func(ctx context.Context) error {
+				return m.opts.Client.ExportRawDataCsv(ctx, c.ID, dir, ch, 1, false)
+			})
+		default:
+			return m.fail("export", errors.Errorf("unknown format %q (expected binary or csv)", v[2]))
+		}
+	})
+	return nil
+}
+
+func (m *Model) exportTable(c shell.Capture) tea.Cmd {
+	analyzers := m.captureAnalyzers()
+	if len(analyzers) == 0 {
+		m.status, m.failed = "export table: "+c.Name+" has no analyzers (a: add one)", true
+		return nil
+	}
+	m.form = newForm(fmt.Sprintf("Export data table of %s (%d analyzers)", c.Name, len(analyzers)), []field{
+		{label: "File", placeholder: "/abs/path/to/table.csv"},
+		{label: "Radix", value: "hex", placeholder: "hex, dec, bin or ascii"},
+	}, func(v []string) tea.Cmd {
+		radix, err := pipeline.ParseRadixType(v[1])
+		if err != nil {
+			return m.fail("export table", err)
+		}
+		configs := make([]*pb.DataTableAnalyzerConfiguration, len(analyzers))
+		for i, a := range analyzers {
+			configs[i] = &pb.DataTableAnalyzerConfiguration{AnalyzerId: a.ID, RadixType: radix}
+		}
+		path := absPath(v[0])
+		return m.action("export table to "+path, func(ctx context.Context) error {
+			return m.opts.Client.ExportDataTableCsv(ctx, c.ID, path, configs, false, nil, nil)
+		})
+	})
+	return nil
+}
+
+// fail reports an input error without running anything.
+func (m *Model) fail(desc string, err error) tea.Cmd {
+	m.busy++ // balanced by the actionMsg
+	return func() tea.Msg { return actionMsg{desc: desc, err: err} }
+}
+
+func parseChannels(s string) ([]uint32, error) {
+	var out []uint32
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid channel %q", p)
+		}
+		out = append(out, uint32(v))
+	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one digital channel is required")
+	}
+	return out, nil
+}
+
+// absPath makes p absolute: Logic 2 resolves paths relative to its own working directory.
+func absPath(p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// guessAnalyzer picks the Logic 2 analyzer a template file is for from its name
+// (configs/analyzers/async-serial.yaml -> "Async Serial"), or "" if none matches.
+func guessAnalyzer(template string) string {
+	base := alnum(filepath.Base(template))
+	best := ""
+	for _, name := range shell.KnownAnalyzers {
+		if n := alnum(name); strings.Contains(base, n) && len(n) > len(alnum(best)) {
+			best = name
+		}
+	}
+	return best
+}
+
+func alnum(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return -1
+	}, s)
+}
+
+// homeRelative shortens paths under the home directory for display.
+func homeRelative(p string) string {
+	if home, err := os.UserHomeDir(); err == nil && home != "" && strings.HasPrefix(p, home+string(filepath.Separator)) {
+		return "~" + p[len(home):]
+	}
+	return p
+}
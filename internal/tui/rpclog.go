@@ -0,0 +1,41 @@
+package tui
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// RPC is one call made on the TUI's connection.
+type RPC struct {
+	Time     time.Time
+	Method   string // short method name, e.g. "StartCapture"
+	Duration time.Duration
+	Err      error
+}
+
+// RPCLog collects the RPCs made on a connection for the log pane.
+type RPCLog struct {
+	ch chan RPC
+}
+
+// NewRPCLog returns an empty RPCLog.
+func NewRPCLog() *RPCLog {
+	return &RPCLog{ch: make(chan RPC, 256)}
+}
+
+// Interceptor returns a gRPC client interceptor that records every unary call. Calls
+// made while the log is full are dropped rather than blocking the caller.
+func (l *RPCLog) Interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		select {
+		case l.ch <- RPC{Time: start, Method: path.Base(method), Duration: time.Since(start), Err: err}:
+		default:
+		}
+		return err
+	}
+}
@@ -0,0 +1,134 @@
+package tui
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	mock "github.com/go-go-golems/salad/internal/mock/saleae"
+	"github.com/go-go-golems/salad/internal/saleae"
+	"github.com/go-go-golems/salad/internal/shell"
+	"google.golang.org/grpc"
+)
+
+func TestGuessAnalyzer(t *testing.T) {
+	for template, want := range map[string]string{
+		"configs/analyzers/spi.yaml":                                   "SPI",
+		"configs/analyzers/async-serial.yaml":                          "Async Serial",
+		"configs/analyzers/i2c-from-session6.yaml":                     "I2C",
+		"configs/analyzers/session6-1-wire-1-wire-nodeid-10056.yaml":   "1-Wire",
+		"configs/analyzers/session6-dmx-512-dmx-512-nodeid-10050.yaml": "DMX-512",
+		"configs/analyzers/custom.yaml":                                "",
+	} {
+		if got := guessAnalyzer(template); got != want {
+			t.Fatalf("guessAnalyzer(%q): expected %q, got %q", template, want, got)
+		}
+	}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// press sends keys to m and runs the command of the last one (an action) to completion.
+func press(t *testing.T, m *Model, keys ...string) {
+	t.Helper()
+	var cmd tea.Cmd
+	for _, k := range keys {
+		_, cmd = m.Update(key(k))
+	}
+	if cmd == nil {
+		t.Fatalf("keys %q started no action (status %q)", keys, m.status)
+	}
+	msg := cmd()
+	if _, ok := msg.(actionMsg); !ok {
+		t.Fatalf("keys %q: expected an action result, got %T", keys, msg)
+	}
+	m.Update(msg)
+	if m.failed {
+		t.Fatalf("keys %q: %s", keys, m.status)
+	}
+}
+
+func TestModel_AgainstMockServer(t *testing.T) {
+	cfg, err := mock.LoadConfig("../../configs/mock/start-capture.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	plan, err := mock.Compile(cfg)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	_, _, listener, cleanup, err := mock.StartMockServer(plan)
+	if err != nil {
+		t.Fatalf("StartMockServer: %v", err)
+	}
+	defer cleanup()
+	_, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	state, rpcs := shell.NewState(), NewRPCLog()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := saleae.New(ctx, saleae.Config{
+		Host:        "127.0.0.1",
+		Port:        port,
+		Timeout:     5 * time.Second,
+		DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(rpcs.Interceptor(), state.Interceptor())},
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	m := New(Options{Client: c, State: state, RPCs: rpcs, Addr: listener.Addr().String(), Timeout: 5 * time.Second, Templates: "../../configs/analyzers"})
+	m.Update(m.refresh()())
+	if len(m.devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d (%s)", len(m.devices), m.status)
+	}
+
+	// Start a manual capture on the selected device with the form defaults.
+	press(t, m, "s", "enter")
+	captures := state.Captures()
+	if len(captures) != 1 || captures[0].Source != "live" {
+		t.Fatalf("unexpected captures %+v", captures)
+	}
+	press(t, m, "x")
+
+	// Pick the first template (async-serial.yaml, after "(no template)") and keep the
+	// analyzer name guessed from it.
+	press(t, m, "a", "j", "enter", "enter")
+	analyzers := state.Analyzers()
+	if len(analyzers) != 1 || analyzers[0].CaptureID != captures[0].ID || analyzers[0].Analyzer != "Async Serial" || analyzers[0].Label != "async-serial" {
+		t.Fatalf("unexpected analyzers %+v", analyzers)
+	}
+
+	// Close needs a confirmation.
+	if _, cmd := m.Update(key("c")); cmd != nil || m.confirm == nil {
+		t.Fatalf("expected close to ask for confirmation")
+	}
+	press(t, m, "y")
+	if len(state.Captures()) != 0 {
+		t.Fatalf("expected the capture to be closed")
+	}
+
+	for len(rpcs.ch) > 0 {
+		m.Update(rpcMsg(<-rpcs.ch))
+	}
+	view := m.View()
+	for _, want := range []string{"2.3.56-mock", "DEV1", "StartCapture", "StopCapture", "AddAnalyzer", "CloseCapture", "close c1: ok"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view is missing %q:\n%s", want, view)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	pb "github.com/go-go-golems/salad/gen/saleae/automation"
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	helpStyle     = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	boxStyle      = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	focusedColor  = lipgloss.Color("12")
+)
+
+const keyHelp = "tab pane · ↑↓ select · s start · o open .sal · x stop · w save · c close · a add analyzer · d remove analyzer · e export raw · t export table · r refresh · q quit"
+
+func (m *Model) View() string {
+	width := max(m.width, 40)
+	header := titleStyle.Render("salad tui") + "  " + m.opts.Addr
+	if m.app != nil {
+		api := m.app.GetApiVersion()
+		header += fmt.Sprintf("  Logic %s  API %d.%d.%d  pid %d", m.app.GetApplicationVersion(), api.GetMajor(), api.GetMinor(), api.GetPatch(), m.app.GetLaunchPid())
+	}
+
+	// Bottom: a modal (form, picker or question) or the status line, then key help.
+	var bottom string
+	switch {
+	case m.form != nil:
+		bottom = m.form.view(width)
+	case m.picker != nil:
+		bottom = m.picker.view(8)
+	case m.confirm != nil:
+		bottom = titleStyle.Render(m.confirm.question)
+	default:
+		bottom = m.statusLine()
+	}
+	bottom += "\n" + helpStyle.Width(width).Render(keyHelp)
+
+	// Three panes of equal width, then the RPC log filling the remaining height.
+	paneWidth := width / 3
+	paneRows := 8
+	captures := m.opts.State.Captures()
+	var captureLines []string
+	for _, c := range captures {
+		captureLines = append(captureLines, fmt.Sprintf("%s  #%d  %s", c.Name, c.ID, homeRelative(c.Source)))
+	}
+	analyzerTitle := "Analyzers"
+	if c, ok := m.selectedCapture(); ok {
+		analyzerTitle += " of " + c.Name
+	}
+	var analyzerLines []string
+	for _, a := range m.captureAnalyzers() {
+		line := fmt.Sprintf("%s  #%d  %s", a.Name, a.ID, a.Analyzer)
+		if a.Label != "" {
+			line += fmt.Sprintf(" (%s)", a.Label)
+		}
+		analyzerLines = append(analyzerLines, line)
+	}
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.pane(paneDevices, "Devices", deviceLines(m.devices), paneWidth, paneRows),
+		m.pane(paneCaptures, "Captures", captureLines, paneWidth, paneRows),
+		m.pane(paneAnalyzers, analyzerTitle, analyzerLines, width-2*paneWidth, paneRows),
+	)
+
+	used := lipgloss.Height(header) + lipgloss.Height(panes) + lipgloss.Height(bottom)
+	logRows := max(3, m.height-used-2)
+	logBox := boxStyle.Width(width - 2).Render(titleStyle.Render("RPC log") + "\n" + strings.Join(m.logLines(logRows-1, width-4), "\n"))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, panes, logBox, bottom)
+}
+
+func (m *Model) statusLine() string {
+	status := m.status
+	if m.busy > 0 && !strings.HasSuffix(status, "...") {
+		status += fmt.Sprintf(" (%d running)", m.busy)
+	}
+	if m.failed {
+		return errorStyle.Render(status)
+	}
+	return status
+}
+
+// pane renders a titled list with the cursor line highlighted when focused.
+func (m *Model) pane(p pane, title string, lines []string, width, rows int) string {
+	style := boxStyle.Width(width - 2)
+	if m.focus == p {
+		style = style.BorderForeground(focusedColor)
+	}
+	cursor := min(m.cursor[p], len(lines)-1)
+	first := max(0, cursor-rows+1)
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(title))
+	for i := 0; i < rows; i++ {
+		b.WriteString("\n")
+		if first+i < len(lines) {
+			b.WriteString(cursorLine(truncate(lines[first+i], width-6), m.focus == p && first+i == cursor))
+		} else if i == 0 && len(lines) == 0 {
+			b.WriteString(helpStyle.Render("(none)"))
+		}
+	}
+	return style.Render(b.String())
+}
+
+func (m *Model) logLines(rows, width int) []string {
+	start := max(0, len(m.log)-rows)
+	var out []string
+	for _, r := range m.log[start:] {
+		result := "ok"
+		if r.Err != nil {
+			result = r.Err.Error()
+		}
+		line := truncate(fmt.Sprintf("%s  %-24s %10s  %s", r.Time.Format("15:04:05.000"), r.Method, r.Duration.Round(time.Microsecond), result), width)
+		if r.Err != nil {
+			line = errorStyle.Render(line)
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func deviceLines(devices []*pb.Device) []string {
+	var out []string
+	for _, d := range devices {
+		line := d.GetDeviceId() + "  " + strings.TrimPrefix(d.GetDeviceType().String(), "DEVICE_TYPE_")
+		if d.GetIsSimulation() {
+			line += " (simulation)"
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func cursorLine(s string, selected bool) string {
+	if selected {
+		return selectedStyle.Render("> " + s)
+	}
+	return "  " + s
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 1 || len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
+
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-len(s)))
+}
@@ -0,0 +1,345 @@
+package vcd
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"strings"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/rawdata"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DigitalScope is the VCD module holding raw digital channels.
+	DigitalScope = "logic"
+	// TableScope is the VCD module holding analyzer data table signals.
+	TableScope = "analyzers"
+	// IdleValue marks the gap between analyzer frames on a string signal.
+	IdleValue = "-"
+)
+
+// ConvertOptions configures Convert.
+type ConvertOptions struct {
+	// Timescale of the dump. The zero value picks one with AutoTimescale.
+	Timescale Timescale
+	// Digital channels to dump, one wire each.
+	Digital []rawdata.DigitalSource
+	// Names renames signals, keyed by source Name() (e.g. "Channel 0" -> "CLK").
+	Names map[string]string
+	// Table, if set, adds one string signal per analyzer label. A frame shows as
+	// "<type>:<field>=<value>,..." from start_time to start_time+duration, IdleValue otherwise.
+	Table *datatable.Table
+}
+
+// Result describes a written VCD.
+type Result struct {
+	Timescale Timescale
+	// Signals is the number of declared signals: digital wires plus analyzer strings.
+	Signals int
+}
+
+// DefaultTimescale is used when the sources give no sample rate and their transitions
+// can't be measured: there are none, or the sources can be read only once.
+var DefaultTimescale = Timescale{Magnitude: 1, Unit: "ns"}
+
+// AutoTimescale picks a timescale that places every transition of sources exactly:
+// the sample period of binary exports, otherwise the coarsest timescale that all
+// transition times are whole multiples of. Only CSV sources are read in full to
+// measure it; binary sources without a sample rate (version 0) can be read only once,
+// so they get DefaultTimescale.
+func AutoTimescale(sources []rawdata.DigitalSource) (Timescale, error) {
+	rate, binary := 0.0, false
+	for _, src := range sources {
+		if r, ok := src.(rawdata.SampleRater); ok {
+			rate = math.Max(rate, r.SampleRate())
+			binary = true
+		}
+	}
+	if rate > 0 {
+		return TimescaleFor(1 / rate), nil
+	}
+	if binary || len(sources) == 0 {
+		return DefaultTimescale, nil
+	}
+
+	var candidates []Timescale
+	for _, unit := range TimescaleUnitNames {
+		for _, mag := range []int{100, 10, 1} {
+			candidates = append(candidates, Timescale{Magnitude: mag, Unit: unit})
+		}
+	}
+	origin := math.Inf(1)
+	for _, src := range sources {
+		origin = math.Min(origin, src.BeginTime())
+	}
+	// Multiples of a timescale are at least one tick apart, so distinct times stay
+	// distinct. CSV times are decimal, so some candidate always fits.
+	i, edges := 0, 0
+	for edge, err := range rawdata.Merge(sources) {
+		if err != nil {
+			return Timescale{}, err
+		}
+		edges++
+		for i < len(candidates)-1 {
+			ticks := (edge.Time - origin) * candidates[i].ticksPerSecond()
+			// Allow for float rounding only: a time 1ns off over a 100s capture is still off.
+			if math.Abs(ticks-math.Round(ticks)) <= 1e-12*math.Abs(ticks) {
+				break
+			}
+			i++
+		}
+	}
+	if edges == 0 {
+		return DefaultTimescale, nil
+	}
+	return candidates[i], nil
+}
+
+// event is one value change, in seconds.
+type event struct {
+	t     float64
+	v     *Var
+	value string
+}
+
+// stream yields events in time order.
+type stream struct {
+	next func() (event, bool, error)
+	stop func()
+	head event
+}
+
+// Convert writes a VCD with one wire per digital source and, optionally, one string
+// signal per analyzer in the data table. Times are shifted so the earliest event is at
+// or after #0; the shift is recorded in the header comment.
+func Convert(out io.Writer, opts ConvertOptions) (Result, error) {
+	if len(opts.Digital) == 0 && opts.Table == nil {
+		return Result{}, errors.New("nothing to convert: no digital channels and no data table")
+	}
+	if opts.Table != nil && opts.Table.ISO8601() {
+		return Result{}, errors.New("data table uses ISO8601 timestamps; re-export it without --iso8601-timestamp so it lines up with the raw data")
+	}
+	if opts.Timescale == (Timescale{}) {
+		ts, err := AutoTimescale(opts.Digital)
+		if err != nil {
+			return Result{}, err
+		}
+		opts.Timescale = ts
+	}
+
+	origin := 0.0
+	for _, src := range opts.Digital {
+		origin = math.Min(origin, src.BeginTime())
+	}
+
+	var labels []string
+	if opts.Table != nil {
+		seen := map[string]bool{}
+		for row, err := range opts.Table.Rows() {
+			if err != nil {
+				return Result{}, err
+			}
+			origin = math.Min(origin, row.Start)
+			if !seen[row.Name] {
+				seen[row.Name] = true
+				labels = append(labels, row.Name)
+			}
+		}
+	}
+
+	w := NewWriter(out, opts.Timescale, fmt.Sprintf("converted from Logic 2 export; #0 = %gs", origin))
+	rename := func(name string) string {
+		if n, ok := opts.Names[name]; ok && n != "" {
+			return n
+		}
+		return name
+	}
+
+	initial := map[*Var]string{}
+	var streams []*stream
+	defer func() {
+		for _, s := range streams {
+			s.stop()
+		}
+	}()
+	for _, src := range opts.Digital {
+		v := w.AddVar(DigitalScope, rename(src.Name()), VarWire)
+		initial[v] = levelString(src.InitialState())
+		streams = append(streams, digitalStream(src, v))
+	}
+	if opts.Table != nil {
+		vars := map[string]*Var{}
+		for _, label := range labels {
+			v := w.AddVar(TableScope, rename(label), VarString)
+			vars[label] = v
+			initial[v] = IdleValue
+		}
+		streams = append(streams, tableStream(opts.Table, vars))
+	}
+
+	if err := w.Begin(initial); err != nil {
+		return Result{}, err
+	}
+
+	h := &streamHeap{}
+	for _, s := range streams {
+		ev, ok, err := s.next()
+		if err != nil {
+			return Result{}, err
+		}
+		if ok {
+			s.head = ev
+			heap.Push(h, s)
+		}
+	}
+	for h.Len() > 0 {
+		s := (*h)[0]
+		if err := w.Change(w.Tick(s.head.t, origin), s.head.v, s.head.value); err != nil {
+			return Result{}, err
+		}
+		ev, ok, err := s.next()
+		if err != nil {
+			return Result{}, err
+		}
+		if ok {
+			s.head = ev
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+
+	end := origin
+	for _, src := range opts.Digital {
+		end = math.Max(end, src.EndTime())
+	}
+	if err := w.Close(w.Tick(end, origin)); err != nil {
+		return Result{}, err
+	}
+	return Result{Timescale: opts.Timescale, Signals: len(w.vars)}, nil
+}
+
+func levelString(state uint8) string {
+	if state != 0 {
+		return "1"
+	}
+	return "0"
+}
+
+func pullStream[T any](seq iter.Seq2[T, error], convert func(T) event) *stream {
+	next, stop := iter.Pull2(seq)
+	return &stream{
+		next: func() (event, bool, error) {
+			v, err, ok := next()
+			if !ok {
+				return event{}, false, nil
+			}
+			if err != nil {
+				return event{}, false, err
+			}
+			return convert(v), true, nil
+		},
+		stop: stop,
+	}
+}
+
+func digitalStream(src rawdata.DigitalSource, v *Var) *stream {
+	return pullStream(src.Transitions(), func(tr rawdata.Transition) event {
+		return event{t: tr.Time, v: v, value: levelString(tr.State)}
+	})
+}
+
+// tableStream turns data table rows (sorted by start_time) into frame start events and
+// IdleValue events at each frame end, unless the next frame on that signal already began.
+func tableStream(table *datatable.Table, vars map[string]*Var) *stream {
+	type pendingEnd struct {
+		t   float64
+		v   *Var
+		seq int
+	}
+	var pending []pendingEnd
+	lastStart := map[*Var]int{}
+	seq := 0
+	prevStart := math.Inf(-1)
+
+	rowEvents := func(yield func(event, error) bool) {
+		// flushBefore emits pending frame ends strictly before t. An end at exactly t is
+		// left for later so a frame starting at t on the same signal supersedes it.
+		flushBefore := func(t float64) bool {
+			for len(pending) > 0 {
+				// Pending ends are kept sorted by time.
+				e := pending[0]
+				if e.t >= t {
+					return true
+				}
+				pending = pending[1:]
+				if lastStart[e.v] != e.seq {
+					continue
+				}
+				if !yield(event{t: e.t, v: e.v, value: IdleValue}, nil) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for row, err := range table.Rows() {
+			if err != nil {
+				yield(event{}, err)
+				return
+			}
+			if row.Start < prevStart {
+				yield(event{}, errors.Errorf("%s:%d: rows are not sorted by start_time", table.Path(), row.Line))
+				return
+			}
+			prevStart = row.Start
+			if !flushBefore(row.Start) {
+				return
+			}
+
+			v := vars[row.Name]
+			seq++
+			lastStart[v] = seq
+			if !yield(event{t: row.Start, v: v, value: frameValue(table, row)}, nil) {
+				return
+			}
+			end := pendingEnd{t: row.Start + row.Duration, v: v, seq: seq}
+			i := len(pending)
+			for i > 0 && pending[i-1].t > end.t {
+				i--
+			}
+			pending = append(pending, pendingEnd{})
+			copy(pending[i+1:], pending[i:])
+			pending[i] = end
+		}
+		flushBefore(math.Inf(1))
+	}
+	return pullStream(rowEvents, func(e event) event { return e })
+}
+
+func frameValue(table *datatable.Table, row datatable.Row) string {
+	fields := table.Fields(row)
+	if len(fields) == 0 {
+		return row.Type
+	}
+	return row.Type + ":" + strings.Join(fields, ",")
+}
+
+// streamHeap orders streams by their head event time. The order of simultaneous changes
+// on different signals doesn't matter: they land under the same timestamp.
+type streamHeap []*stream
+
+func (h streamHeap) Len() int           { return len(h) }
+func (h streamHeap) Less(i, j int) bool { return h[i].head.t < h[j].head.t }
+func (h streamHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *streamHeap) Push(x any)        { *h = append(*h, x.(*stream)) }
+func (h *streamHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
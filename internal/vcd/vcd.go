@@ -0,0 +1,248 @@
+// Package vcd writes IEEE 1364 Value Change Dump files for waveform viewers such as
+// GTKWave and Surfer.
+package vcd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Timescale is a VCD time unit: 1, 10 or 100 of s, ms, us, ns, ps or fs.
+type Timescale struct {
+	Magnitude int
+	Unit      string
+}
+
+// timescaleUnits maps a unit to its count per second.
+var timescaleUnits = map[string]float64{
+	"s":  1,
+	"ms": 1e3,
+	"us": 1e6,
+	"ns": 1e9,
+	"ps": 1e12,
+	"fs": 1e15,
+}
+
+// TimescaleUnitNames lists the accepted units, coarsest first.
+var TimescaleUnitNames = []string{"s", "ms", "us", "ns", "ps", "fs"}
+
+// ParseTimescale parses strings like "1ns", "10 us" or "100ps".
+func ParseTimescale(s string) (Timescale, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i <= 0 {
+		return Timescale{}, errors.Errorf("invalid timescale %q (expected e.g. 1ns, 10us, 100ps)", s)
+	}
+	mag, err := strconv.Atoi(s[:i])
+	if err != nil || (mag != 1 && mag != 10 && mag != 100) {
+		return Timescale{}, errors.Errorf("invalid timescale %q (magnitude must be 1, 10 or 100)", s)
+	}
+	unit := strings.ToLower(s[i:])
+	if _, ok := timescaleUnits[unit]; !ok {
+		return Timescale{}, errors.Errorf("invalid timescale %q (unit must be one of %s)", s, strings.Join(TimescaleUnitNames, ", "))
+	}
+	return Timescale{Magnitude: mag, Unit: unit}, nil
+}
+
+// Seconds is the length of one tick.
+func (t Timescale) Seconds() float64 {
+	return float64(t.Magnitude) / timescaleUnits[t.Unit]
+}
+
+// ticksPerSecond is exact for every valid timescale, unlike 1/Seconds().
+func (t Timescale) ticksPerSecond() float64 {
+	return timescaleUnits[t.Unit] / float64(t.Magnitude)
+}
+
+// TimescaleFor returns the coarsest timescale whose tick is no longer than period, so
+// that events period apart land on distinct ticks. Periods below 1fs get 1fs.
+func TimescaleFor(period float64) Timescale {
+	for _, unit := range TimescaleUnitNames {
+		for _, mag := range []int{100, 10, 1} {
+			// Allow for rounding in periods computed from CSV times or sample rates.
+			if ts := (Timescale{Magnitude: mag, Unit: unit}); ts.Seconds() <= period*(1+1e-6) {
+				return ts
+			}
+		}
+	}
+	return Timescale{Magnitude: 1, Unit: "fs"}
+}
+
+func (t Timescale) String() string {
+	return fmt.Sprintf("%d%s", t.Magnitude, t.Unit)
+}
+
+// VarKind is the VCD variable type.
+type VarKind int
+
+const (
+	// VarWire is a 1-bit wire.
+	VarWire VarKind = iota
+	// VarString is a string-valued signal (a GTKWave/Surfer extension).
+	VarString
+)
+
+// Var is a declared signal.
+type Var struct {
+	Kind  VarKind
+	Scope string
+	Name  string
+	id    string
+}
+
+// Writer emits a VCD file. Declare all variables, call Begin with their initial
+// values, then Change in non-decreasing time order, then Close.
+type Writer struct {
+	w         *bufio.Writer
+	timescale Timescale
+	comment   string
+
+	vars    []*Var
+	began   bool
+	curTick int64
+	err     error
+}
+
+// NewWriter returns a writer using the given timescale. comment, if non-empty, is written
+// as a $comment section.
+func NewWriter(w io.Writer, timescale Timescale, comment string) *Writer {
+	return &Writer{w: bufio.NewWriter(w), timescale: timescale, comment: comment, curTick: -1}
+}
+
+// AddVar declares a signal in scope. Whitespace in names is replaced by underscores.
+func (w *Writer) AddVar(scope string, name string, kind VarKind) *Var {
+	v := &Var{Kind: kind, Scope: sanitize(scope), Name: sanitize(name), id: identifier(len(w.vars))}
+	w.vars = append(w.vars, v)
+	return v
+}
+
+// Begin writes the header and the initial value of every declared variable.
+func (w *Writer) Begin(initial map[*Var]string) error {
+	if w.began {
+		return errors.New("vcd: Begin called twice")
+	}
+	w.began = true
+
+	w.printf("$version salad $end\n")
+	if w.comment != "" {
+		w.printf("$comment %s $end\n", w.comment)
+	}
+	w.printf("$timescale %s $end\n", w.timescale)
+
+	// Variables are grouped by scope in declaration order.
+	var scopes []string
+	byScope := map[string][]*Var{}
+	for _, v := range w.vars {
+		if _, ok := byScope[v.Scope]; !ok {
+			scopes = append(scopes, v.Scope)
+		}
+		byScope[v.Scope] = append(byScope[v.Scope], v)
+	}
+	for _, scope := range scopes {
+		w.printf("$scope module %s $end\n", scope)
+		for _, v := range byScope[scope] {
+			switch v.Kind {
+			case VarWire:
+				w.printf("$var wire 1 %s %s $end\n", v.id, v.Name)
+			case VarString:
+				w.printf("$var string 1 %s %s $end\n", v.id, v.Name)
+			}
+		}
+		w.printf("$upscope $end\n")
+	}
+	w.printf("$enddefinitions $end\n")
+
+	w.printf("#0\n$dumpvars\n")
+	w.curTick = 0
+	for _, v := range w.vars {
+		w.writeValue(v, initial[v])
+	}
+	w.printf("$end\n")
+	return w.err
+}
+
+// Change records value for v at tick. Ticks must not decrease.
+func (w *Writer) Change(tick int64, v *Var, value string) error {
+	if !w.began {
+		return errors.New("vcd: Change before Begin")
+	}
+	if tick < w.curTick {
+		return errors.Errorf("vcd: time went backwards (%d < %d)", tick, w.curTick)
+	}
+	if tick > w.curTick {
+		w.printf("#%d\n", tick)
+		w.curTick = tick
+	}
+	w.writeValue(v, value)
+	return w.err
+}
+
+// Close writes a final timestamp (if later than the last change) and flushes.
+func (w *Writer) Close(endTick int64) error {
+	if endTick > w.curTick {
+		w.printf("#%d\n", endTick)
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return errors.Wrap(w.w.Flush(), "write vcd")
+}
+
+// Tick converts seconds (relative to origin) to timescale ticks.
+func (w *Writer) Tick(seconds float64, origin float64) int64 {
+	return int64(math.Round((seconds - origin) * w.timescale.ticksPerSecond()))
+}
+
+func (w *Writer) writeValue(v *Var, value string) {
+	switch v.Kind {
+	case VarWire:
+		if value == "" {
+			value = "x"
+		}
+		w.printf("%s%s\n", value, v.id)
+	case VarString:
+		if value == "" {
+			value = "-"
+		}
+		w.printf("s%s %s\n", sanitize(value), v.id)
+	}
+}
+
+func (w *Writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	if _, err := fmt.Fprintf(w.w, format, args...); err != nil {
+		w.err = errors.Wrap(err, "write vcd")
+	}
+}
+
+// identifier returns the n-th short VCD identifier code (printable ASCII 33..126).
+func identifier(n int) string {
+	const first, count = 33, 94
+	var b []byte
+	for {
+		b = append(b, byte(first+n%count))
+		n = n/count - 1
+		if n < 0 {
+			break
+		}
+	}
+	return string(b)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,170 @@
+package vcd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-go-golems/salad/internal/datatable"
+	"github.com/go-go-golems/salad/internal/rawdata"
+)
+
+func TestParseTimescale(t *testing.T) {
+	ts, err := ParseTimescale("10 us")
+	if err != nil {
+		t.Fatalf("ParseTimescale: %v", err)
+	}
+	if ts.String() != "10us" || ts.Seconds() != 10e-6 {
+		t.Fatalf("expected 10us, got %s (%v s)", ts, ts.Seconds())
+	}
+	for _, bad := range []string{"", "ns", "3ns", "1min"} {
+		if _, err := ParseTimescale(bad); err == nil {
+			t.Fatalf("ParseTimescale(%q): expected error", bad)
+		}
+	}
+}
+
+func TestConvert_DigitalAndTable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, rawdata.DigitalCSVFilename), []byte(`Time [s],Channel 0,Channel 1
+0.000000000,0,1
+0.000001000,1,1
+0.000002000,1,0
+0.000003000,0,0
+`), 0o644); err != nil {
+		t.Fatalf("write digital.csv: %v", err)
+	}
+	tablePath := filepath.Join(dir, "table.csv")
+	if err := os.WriteFile(tablePath, []byte(`name,type,start_time,duration,"data"
+"uart rx","data",0.000001000,0.000001000,0x41
+"uart rx","data",0.000002000,0.000000500,0x42
+`), 0o644); err != nil {
+		t.Fatalf("write table.csv: %v", err)
+	}
+
+	export, err := rawdata.OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+	table, err := datatable.Open(tablePath)
+	if err != nil {
+		t.Fatalf("datatable.Open: %v", err)
+	}
+	ts, _ := ParseTimescale("1ns")
+
+	var out bytes.Buffer
+	_, err = Convert(&out, ConvertOptions{
+		Timescale: ts,
+		Digital:   export.Digital,
+		Names:     map[string]string{"Channel 0": "CLK"},
+		Table:     table,
+	})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"$timescale 1ns $end",
+		"$var wire 1 ! CLK $end",
+		"$var wire 1 \" Channel_1 $end",
+		"$var string 1 # uart_rx $end",
+		"#0\n$dumpvars\n0!\n1\"\ns- #\n$end\n",
+		"#1000\n1!\nsdata:data=0x41 #\n",
+		"#2500\ns- #\n#3000\n0!\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected VCD to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	// Frame 1 ends exactly when frame 2 starts: only the new value is shown at #2000.
+	_, at2000, _ := strings.Cut(got, "#2000\n")
+	at2000, _, _ = strings.Cut(at2000, "#2500")
+	if !strings.Contains(at2000, "sdata:data=0x42 #") || !strings.Contains(at2000, "0\"") || strings.Contains(at2000, "s- ") {
+		t.Fatalf("#2000: expected frame 2 and Channel_1 low without an idle marker, got %q", at2000)
+	}
+}
+
+func TestConvert_RejectsUnsortedTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.csv")
+	if err := os.WriteFile(path, []byte("name,type,start_time,duration\na,x,0.2,0.1\na,x,0.1,0.1\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	table, err := datatable.Open(path)
+	if err != nil {
+		t.Fatalf("datatable.Open: %v", err)
+	}
+	ts, _ := ParseTimescale("1us")
+	_, err = Convert(&bytes.Buffer{}, ConvertOptions{Timescale: ts, Table: table})
+	if err == nil || !strings.Contains(err.Error(), "sorted") {
+		t.Fatalf("expected unsorted error, got %v", err)
+	}
+}
+
+func TestConvert_AutoTimescale(t *testing.T) {
+	for period, want := range map[float64]string{2e-9: "1ns", 1e-8: "10ns", 8e-8: "10ns", 1.0 / 3: "100ms", 1e-16: "1fs"} {
+		if got := TimescaleFor(period).String(); got != want {
+			t.Fatalf("TimescaleFor(%g): expected %s, got %s", period, want, got)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, rawdata.DigitalCSVFilename), []byte(`Time [s],Channel 0,Channel 1
+0.000000000,0,1
+0.000100000,1,1
+0.000100050,1,0
+0.000300000,0,0
+`), 0o644); err != nil {
+		t.Fatalf("write digital.csv: %v", err)
+	}
+	export, err := rawdata.OpenExport(dir, "Channel 1")
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+	var out bytes.Buffer
+	res, err := Convert(&out, ConvertOptions{Digital: export.Digital})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	// Channel 1 alone changes once, 100.05us after the start.
+	if res.Timescale.String() != "10ns" || res.Signals != 1 || !strings.Contains(out.String(), "#10005\n") {
+		t.Fatalf("expected 1 signal at 10ns, got %+v:\n%s", res, out.String())
+	}
+}
+
+func TestConvert_V0BinaryKeepsTransitions(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "digital_0.bin"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := rawdata.WriteDigitalBinary(f, 0, 0, 4e-6, []float64{1e-6, 2e-6, 3e-6}); err != nil {
+		t.Fatalf("WriteDigitalBinary: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	export, err := rawdata.OpenExport(dir)
+	if err != nil {
+		t.Fatalf("OpenExport: %v", err)
+	}
+	defer func() { _ = export.Close() }()
+
+	var out bytes.Buffer
+	res, err := Convert(&out, ConvertOptions{Digital: export.Digital})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	// A version 0 file has no sample rate and can't be scanned before it is dumped.
+	if res.Timescale != DefaultTimescale {
+		t.Fatalf("expected %s, got %s", DefaultTimescale, res.Timescale)
+	}
+	for _, want := range []string{"#1000\n1!\n", "#2000\n0!\n", "#3000\n1!\n", "#4000\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected VCD to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}